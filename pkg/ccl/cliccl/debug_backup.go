@@ -660,7 +660,13 @@ func processEntryFiles(
 			startKeyMVCC.Key = roachpb.Key(debugBackupArgs.startKey.rawByte)
 		}
 	}
-	kvFetcher := row.MakeBackupSSTKVFetcher(startKeyMVCC, endKeyMVCC, iter, startTime, endTime, debugBackupArgs.withRevisions)
+	kvFetcher, err := row.MakeBackupSSTKVFetcher(
+		startKeyMVCC, endKeyMVCC, iter, startTime, endTime, debugBackupArgs.withRevisions,
+		!debugBackupArgs.withRevisions /* skipDeleted */, false, /* reverse */
+	)
+	if err != nil {
+		return errors.Wrapf(err, "make backup SST kv fetcher")
+	}
 
 	if err := rf.StartScanFrom(ctx, &kvFetcher, false /* traceKV */); err != nil {
 		return errors.Wrapf(err, "row fetcher starts scan")
@@ -32,6 +32,17 @@ var StreamReplicationJobLivenessTimeout = settings.RegisterDurationSetting(
 	time.Minute,
 )
 
+// StreamReplicationStoppedStreamGracePeriod controls how long the protected
+// timestamp of a stopped replication stream is kept for it to be resumed.
+var StreamReplicationStoppedStreamGracePeriod = settings.RegisterDurationSetting(
+	settings.TenantWritable,
+	"stream_replication.stopped_stream_grace_period",
+	"controls how long a stopped replication stream keeps its protected timestamp "+
+		"before it can no longer be resumed",
+	24*time.Hour,
+	settings.NonNegativeDuration,
+)
+
 // StreamReplicationConsumerHeartbeatFrequency controls frequency the stream replication
 // destination cluster sends heartbeat to the source cluster to keep the stream alive.
 var StreamReplicationConsumerHeartbeatFrequency = settings.RegisterDurationSetting(
@@ -9,7 +9,9 @@
 package streamingest
 
 import (
+	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streampb"
 	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
@@ -31,6 +33,14 @@ func (r *streamIngestManagerImpl) CompleteStreamIngestion(
 	return completeStreamIngestion(evalCtx, txn, streamID, cutoverTimestamp)
 }
 
+// GetStreamIngestionStats implements streaming.StreamIngestManager interface.
+func (r *streamIngestManagerImpl) GetStreamIngestionStats(
+	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID,
+) (*streampb.StreamIngestionStats, error) {
+	registry := evalCtx.Planner.ExecutorConfig().(*sql.ExecutorConfig).JobRegistry
+	return getStreamIngestionStats(evalCtx.Ctx(), registry, txn, jobspb.JobID(streamID))
+}
+
 func newStreamIngestManagerWithPrivilegesCheck(
 	evalCtx *tree.EvalContext,
 ) (streaming.StreamIngestManager, error) {
@@ -70,6 +70,10 @@ type streamIngestionFrontier struct {
 
 	lastPartitionUpdate time.Time
 	partitionProgress   map[string]jobspb.StreamIngestionProgress_PartitionProgress
+
+	// pendingStats accumulates the number of KVs and bytes ingested since the
+	// last update of the partition progress.
+	pendingStats jobspb.ResolvedSpans_Stats
 }
 
 var _ execinfra.Processor = &streamIngestionFrontier{}
@@ -253,11 +257,6 @@ func (sf *streamIngestionFrontier) Next() (
 			break
 		}
 
-		if err := sf.maybeUpdatePartitionProgress(); err != nil {
-			// Updating the partition progress isn't a fatal error.
-			log.Errorf(sf.Ctx, "failed to update partition progress: %+v", err)
-		}
-
 		var frontierChanged bool
 		var err error
 		if frontierChanged, err = sf.noteResolvedTimestamps(row[0]); err != nil {
@@ -265,6 +264,11 @@ func (sf *streamIngestionFrontier) Next() (
 			break
 		}
 
+		if err := sf.maybeUpdatePartitionProgress(); err != nil {
+			// Updating the partition progress isn't a fatal error.
+			log.Errorf(sf.Ctx, "failed to update partition progress: %+v", err)
+		}
+
 		// Send back a row to the job so that it can update the progress.
 		newResolvedTS := sf.frontier.Frontier()
 		select {
@@ -325,6 +329,8 @@ func (sf *streamIngestionFrontier) noteResolvedTimestamps(
 		return frontierChanged, errors.NewAssertionErrorWithWrappedErrf(err,
 			`unmarshalling resolved timestamp: %x`, raw)
 	}
+	sf.pendingStats.RecentKvCount += resolvedSpans.Stats.RecentKvCount
+	sf.pendingStats.RecentByteCount += resolvedSpans.Stats.RecentByteCount
 
 	for _, resolved := range resolvedSpans.ResolvedSpans {
 		// Inserting a timestamp less than the one the ingestion flow started at could
@@ -382,13 +388,19 @@ func (sf *streamIngestionFrontier) maybeUpdatePartitionProgress() error {
 
 	sf.lastPartitionUpdate = timeutil.Now()
 	// TODO(pbardea): Only update partitions that have changed.
-	return job.FractionProgressed(ctx, nil, /* txn */
+	if err := job.FractionProgressed(ctx, nil, /* txn */
 		func(ctx context.Context, details jobspb.ProgressDetails) float32 {
 			prog := details.(*jobspb.Progress_StreamIngest).StreamIngest
 			prog.PartitionProgress = partitionFrontiers
+			prog.IngestedKVs += int64(sf.pendingStats.RecentKvCount)
+			prog.IngestedBytes += int64(sf.pendingStats.RecentByteCount)
 			// "FractionProgressed" isn't relevant on jobs that are streaming in
 			// changes.
 			return 0.0
 		},
-	)
+	); err != nil {
+		return err
+	}
+	sf.pendingStats = jobspb.ResolvedSpans_Stats{}
+	return nil
 }
@@ -10,18 +10,22 @@ package streamingest
 
 import (
 	"context"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl"
 	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streamclient"
+	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streampb"
 	"github.com/cockroachdb/cockroach/pkg/jobs"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvclient"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
 	"github.com/cockroachdb/cockroach/pkg/streaming"
+	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
@@ -46,7 +50,8 @@ func completeStreamIngestion(
 	// If an entry does not exist for the provided job_id we return an
 	// error.
 	if row == nil {
-		return errors.Newf("job %d: not found in system.jobs table", streamID)
+		return streaming.NewCompleteStreamIngestionError(streamID, streaming.StreamIngestionNotFound,
+			"job %d: not found in system.jobs table", streamID)
 	}
 
 	progress, err := jobs.UnmarshalProgress(row[0])
@@ -68,8 +73,9 @@ func completeStreamIngestion(
 		if hw != nil {
 			highWaterTimestamp = *hw
 		}
-		return errors.Newf("cannot cutover to a timestamp %s that is after the latest resolved time"+
-			" %s for job %d", cutoverTimestamp.String(), highWaterTimestamp.String(), streamID)
+		return streaming.NewCompleteStreamIngestionError(streamID, streaming.CutoverTimestampNotResolved,
+			"cannot cutover to a timestamp %s that is after the latest resolved time %s for job %d",
+			cutoverTimestamp.String(), highWaterTimestamp.String(), streamID)
 	}
 
 	// Reject setting a cutover time, if an earlier request to cutover has already
@@ -78,8 +84,9 @@ func completeStreamIngestion(
 	// allowed to correct their cutover time if the process of reverting the job
 	// has not started.
 	if !sp.StreamIngest.CutoverTime.IsEmpty() {
-		return errors.Newf("cutover timestamp already set to %s, "+
-			"job %d is in the process of cutting over", sp.StreamIngest.CutoverTime.String(), streamID)
+		return streaming.NewCompleteStreamIngestionError(streamID, streaming.StreamIngestionAlreadyCompleted,
+			"cutover timestamp already set to %s, job %d is in the process of cutting over",
+			sp.StreamIngest.CutoverTime.String(), streamID)
 	}
 
 	// Update the sentinel being polled by the stream ingestion job to
@@ -182,6 +189,10 @@ func (s *streamIngestionResumer) Resume(resumeCtx context.Context, execCtx inter
 	return ingest(resumeCtx, p, streamAddress, details.TenantID, details.NewTenantID, details.StartTime, s.job.Progress(), s.job.ID())
 }
 
+// cutoverProgressInterval is the frequency at which the progress of the revert
+// to the cutover timestamp is reported. It is mutable for testing.
+var cutoverProgressInterval = 5 * time.Second
+
 // revertToCutoverTimestamp reads the job progress for the cutover time and
 // issues a RevertRangeRequest with the target time set to that cutover time, to
 // bring the ingesting cluster to a consistent state.
@@ -213,6 +224,14 @@ func revertToCutoverTimestamp(
 			"cannot revert to a consistent state")
 	}
 
+	// The progress of the revert is reported in the job progress as the fraction
+	// of the ranges of the span which have been reverted, at most every
+	// cutoverProgressInterval and once the whole span has been reverted.
+	rangeSpans, err := splitSpanByRanges(ctx, db, sd.Span)
+	if err != nil {
+		return err
+	}
+	progressUpdates := util.Every(cutoverProgressInterval)
 	spans := []roachpb.Span{sd.Span}
 	for len(spans) != 0 {
 		var b kv.Batch
@@ -241,11 +260,103 @@ func revertToCutoverTimestamp(
 				spans = append(spans, *r.ResumeSpan)
 			}
 		}
+
+		if len(spans) != 0 && !progressUpdates.ShouldProcess(timeutil.Now()) {
+			continue
+		}
+		cutoverProgress := revertProgress(rangeSpans, spans)
+		if err := j.Update(ctx, nil /* txn */, func(txn *kv.Txn, md jobs.JobMetadata, ju *jobs.JobUpdater) error {
+			md.Progress.GetStreamIngest().CutoverProgress = cutoverProgress
+			ju.UpdateProgress(md.Progress)
+			return nil
+		}); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// splitSpanByRanges returns the parts of the span covered by each of the ranges
+// it intersects with.
+func splitSpanByRanges(ctx context.Context, db *kv.DB, span roachpb.Span) ([]roachpb.Span, error) {
+	var ranges []kv.KeyValue
+	if err := db.Txn(ctx, func(ctx context.Context, txn *kv.Txn) (err error) {
+		ranges, err = kvclient.ScanMetaKVs(ctx, txn, span)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	spans := make([]roachpb.Span, 0, len(ranges))
+	for _, r := range ranges {
+		var desc roachpb.RangeDescriptor
+		if err := r.ValueProto(&desc); err != nil {
+			return nil, err
+		}
+		if rangeSpan := span.Intersect(desc.RSpan().AsRawSpanWithNoLocals()); rangeSpan.Valid() {
+			spans = append(spans, rangeSpan)
+		}
+	}
+	if len(spans) == 0 {
+		// The span is always expected to intersect with at least one range, but
+		// we revert the whole span in case it does not.
+		spans = append(spans, span)
+	}
+	return spans, nil
+}
+
+// revertProgress returns the fraction of the given range spans which have been
+// reverted, given the resume spans of the revert which remain to be reverted.
+// A range span has been reverted once it precedes all the resume spans.
+func revertProgress(rangeSpans []roachpb.Span, resumeSpans []roachpb.Span) float32 {
+	if len(resumeSpans) == 0 {
+		return 1
+	}
+	resumeKey := resumeSpans[0].Key
+	for _, sp := range resumeSpans[1:] {
+		if sp.Key.Compare(resumeKey) < 0 {
+			resumeKey = sp.Key
+		}
+	}
+	var reverted int
+	for _, sp := range rangeSpans {
+		if sp.EndKey.Compare(resumeKey) <= 0 {
+			reverted++
+		}
+	}
+	return float32(reverted) / float32(len(rangeSpans))
+}
+
+// getStreamIngestionStats returns the statistics of the stream ingestion job
+// with the specified ID.
+func getStreamIngestionStats(
+	ctx context.Context, registry *jobs.Registry, txn *kv.Txn, ingestionJobID jobspb.JobID,
+) (*streampb.StreamIngestionStats, error) {
+	j, err := registry.LoadJobWithTxn(ctx, ingestionJobID, txn)
+	if err != nil {
+		return nil, err
+	}
+	progress := j.Progress()
+	sp, ok := progress.GetDetails().(*jobspb.Progress_StreamIngest)
+	if !ok {
+		return nil, errors.Newf("job %d: not of expected type StreamIngest", ingestionJobID)
+	}
+	stats := &streampb.StreamIngestionStats{
+		IngestedKVs:      sp.StreamIngest.IngestedKVs,
+		IngestedBytes:    sp.StreamIngest.IngestedBytes,
+		CutoverTimestamp: sp.StreamIngest.CutoverTime,
+		CutoverProgress:  sp.StreamIngest.CutoverProgress,
+	}
+	if hw := progress.GetHighWater(); hw != nil {
+		stats.ResolvedTimestamp = *hw
+	} else if j.Status() == jobs.StatusSucceeded {
+		// The high watermark is replaced by the fraction completed once the job
+		// succeeds, at which point the data has been reverted to the cutover time.
+		stats.ResolvedTimestamp = sp.StreamIngest.CutoverTime
+	}
+	return stats, nil
+}
+
 // OnFailOrCancel is part of the jobs.Resumer interface.
 // There is a know race between the ingestion processors shutting down, and
 // OnFailOrCancel being invoked. As a result of which we might see some keys
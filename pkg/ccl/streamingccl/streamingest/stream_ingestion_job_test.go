@@ -20,6 +20,7 @@ import (
 	_ "github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl"
 	"github.com/cockroachdb/cockroach/pkg/ccl/changefeedccl/changefeedbase"
 	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streamingtest"
+	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streampb"
 	_ "github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streamproducer"
 	"github.com/cockroachdb/cockroach/pkg/jobs"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
@@ -88,6 +89,7 @@ SET CLUSTER SETTING stream_replication.min_checkpoint_frequency = '1s';
 SET CLUSTER SETTING stream_replication.consumer_heartbeat_frequency = '2s';
 SET CLUSTER SETTING bulkio.stream_ingestion.minimum_flush_interval = '5us';
 SET CLUSTER SETTING bulkio.stream_ingestion.cutover_signal_poll_interval = '100ms';
+SET CLUSTER SETTING streaming.partition_progress_frequency = '10ms';
 SET enable_experimental_stream_replication = true;
 `,
 		";")...)
@@ -143,6 +145,15 @@ INSERT INTO d.t2 VALUES (2);
 	sourceData := sourceSQL.QueryStr(t, query)
 	destData := hDest.Tenant.SQL.QueryStr(t, query)
 	require.Equal(t, sourceData, destData)
+
+	registry := hDest.SysServer.JobRegistry().(*jobs.Registry)
+	stats, err := getStreamIngestionStats(ctx, registry, nil /* txn */, jobspb.JobID(ingestionJobID))
+	require.NoError(t, err)
+	require.Equal(t, hlc.Timestamp{WallTime: cutoverTime.UnixNano()}, stats.CutoverTimestamp)
+	require.False(t, stats.ResolvedTimestamp.Less(stats.CutoverTimestamp))
+	require.Equal(t, float32(1), stats.CutoverProgress)
+	require.Positive(t, stats.IngestedKVs)
+	require.Positive(t, stats.IngestedBytes)
 }
 
 func TestCutoverBuiltin(t *testing.T) {
@@ -239,4 +250,36 @@ func TestCutoverBuiltin(t *testing.T) {
 	sp, ok = progress.GetDetails().(*jobspb.Progress_StreamIngest)
 	require.True(t, ok)
 	require.Equal(t, hlc.Timestamp{WallTime: highWater.UnixNano()}, sp.StreamIngest.CutoverTime)
+
+	// The cutover timestamp is reported in the stats of the job, but the job has
+	// not started reverting yet.
+	stats, err := getStreamIngestionStats(ctx, registry, nil /* txn */, job.ID())
+	require.NoError(t, err)
+	require.Equal(t, streampb.StreamIngestionStats{
+		ResolvedTimestamp: hlc.Timestamp{WallTime: highWater.UnixNano()},
+		CutoverTimestamp:  hlc.Timestamp{WallTime: highWater.UnixNano()},
+	}, *stats)
+}
+
+func TestRevertProgress(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	sp := func(start, end string) roachpb.Span {
+		return roachpb.Span{Key: roachpb.Key(start), EndKey: roachpb.Key(end)}
+	}
+	rangeSpans := []roachpb.Span{sp("a", "c"), sp("c", "e"), sp("e", "g"), sp("g", "i")}
+	for _, tc := range []struct {
+		resumeSpans []roachpb.Span
+		expected    float32
+	}{
+		{resumeSpans: []roachpb.Span{sp("a", "i")}, expected: 0},
+		{resumeSpans: []roachpb.Span{sp("b", "i")}, expected: 0},
+		{resumeSpans: []roachpb.Span{sp("c", "i")}, expected: 0.25},
+		{resumeSpans: []roachpb.Span{sp("f", "i")}, expected: 0.5},
+		{resumeSpans: []roachpb.Span{sp("h", "i"), sp("c", "d")}, expected: 0.25},
+		{resumeSpans: nil, expected: 1},
+	} {
+		require.Equal(t, tc.expected, revertProgress(rangeSpans, tc.resumeSpans), "%v", tc.resumeSpans)
+	}
 }
@@ -609,6 +609,8 @@ func (sip *streamIngestionProcessor) flush() (*jobspb.ResolvedSpans, error) {
 	sip.metrics.Flushes.Inc(1)
 	sip.metrics.IngestedBytes.Inc(int64(totalSize))
 	sip.metrics.IngestedEvents.Inc(int64(len(sip.curBatch)))
+	flushedCheckpoints.Stats.RecentKvCount = uint64(len(sip.curBatch))
+	flushedCheckpoints.Stats.RecentByteCount = uint64(totalSize)
 
 	// Go through buffered checkpoint events, and put them on the channel to be
 	// emitted to the downstream frontier processor.
@@ -13,7 +13,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl"
 	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streampb"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/kv/kvclient/rangefeed"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
@@ -33,11 +36,12 @@ import (
 )
 
 type eventStream struct {
-	streamID streaming.StreamID
-	execCfg  *sql.ExecutorConfig
-	spec     streampb.StreamPartitionSpec
-	mon      *mon.BytesMonitor
-	acc      mon.BoundAccount
+	streamID    streaming.StreamID
+	partitionID roachpb.NodeID
+	execCfg     *sql.ExecutorConfig
+	spec        streampb.StreamPartitionSpec
+	mon         *mon.BytesMonitor
+	acc         mon.BoundAccount
 
 	data tree.Datums // Data to send to the consumer
 
@@ -47,7 +51,9 @@ type eventStream struct {
 	eventsCh    chan roachpb.RangeFeedEvent // Channel receiving rangefeed events.
 	errCh       chan error                  // Signaled when error occurs in rangefeed.
 	streamCh    chan tree.Datums            // Channel signaled to forward datums to consumer.
+	stoppedCh   chan struct{}               // Closed when the stream has been stopped.
 	sp          *tracing.Span               // Span representing the lifetime of the eventStream.
+	stats       *partitionStats             // Statistics of the streamed partition.
 }
 
 var _ tree.ValueGenerator = (*eventStream)(nil)
@@ -65,8 +71,8 @@ func (s *eventStream) ResolvedType() *types.T {
 // Start implements tree.ValueGenerator interface.
 func (s *eventStream) Start(ctx context.Context, txn *kv.Txn) error {
 	// ValueGenerator API indicates that Start maybe called again if Next returned
-	// false.  However, this generator never terminates without an error,
-	// so this method should be called once.  Be defensive and return an error
+	// false.  However, this generator never terminates without an error unless
+	// the stream has been stopped, so this method should be called once.  Be defensive and return an error
 	// if this method is called again.
 	if s.errCh != nil {
 		return errors.AssertionFailedf("expected to be started once")
@@ -74,6 +80,9 @@ func (s *eventStream) Start(ctx context.Context, txn *kv.Txn) error {
 
 	s.acc = s.mon.MakeBoundAccount()
 
+	// Track the events emitted by this stream until it is closed.
+	s.stats = partitionStatsRegistry.register(s.streamID, s.partitionID)
+
 	// errCh consumed by ValueGenerator and is signaled when go routines encounter error.
 	s.errCh = make(chan error)
 
@@ -83,6 +92,9 @@ func (s *eventStream) Start(ctx context.Context, txn *kv.Txn) error {
 	// Stream channel receives datums to be sent to the consumer.
 	s.streamCh = make(chan tree.Datums)
 
+	// Stopped channel is closed once the stream stops emitting events.
+	s.stoppedCh = make(chan struct{})
+
 	// Common rangefeed options.
 	opts := []rangefeed.Option{
 		rangefeed.WithOnCheckpoint(s.onCheckpoint),
@@ -193,6 +205,8 @@ func (s *eventStream) Next(ctx context.Context) (bool, error) {
 		return false, err
 	case s.data = <-s.streamCh:
 		return true, nil
+	case <-s.stoppedCh:
+		return false, nil
 	}
 }
 
@@ -212,6 +226,9 @@ func (s *eventStream) Close(ctx context.Context) {
 	}
 
 	s.sp.Finish()
+	if s.stats != nil {
+		partitionStatsRegistry.unregister(s.streamID, s.partitionID)
+	}
 }
 
 func (s *eventStream) onEvent(ctx context.Context, value *roachpb.RangeFeedValue) {
@@ -272,6 +289,10 @@ func (s *eventStream) flushEvent(ctx context.Context, event *streampb.StreamEven
 	case <-ctx.Done():
 		return ctx.Err()
 	case s.streamCh <- tree.Datums{tree.NewDBytes(tree.DBytes(data))}:
+		s.stats.recordEvent(len(data))
+		if event.Checkpoint != nil {
+			s.stats.recordCheckpoint(event.Checkpoint)
+		}
 		return nil
 	}
 }
@@ -354,6 +375,33 @@ func (s *eventStream) streamLoop(ctx context.Context, frontier *span.Frontier) e
 	const forceFlush = true
 	const flushIfNeeded = false
 
+	// The producer job of the stream is checked periodically, which is also when
+	// the last checkpoint emitted, if not yet persisted, is persisted in the
+	// progress of the job. The stream stops emitting events once it is stopped. While the stream is lag-limited
+	// and its consumer has not caught up with the checkpoints emitted so far, the
+	// stream stops consuming rangefeed events, which applies backpressure on the
+	// rangefeed instead of buffering events.
+	jobCheckTimer := timeutil.NewTimer()
+	defer jobCheckTimer.Stop()
+	jobCheckFrequency := streamingccl.StreamReplicationStreamLivenessTrackFrequency.Get(&s.execCfg.Settings.SV)
+	jobCheckTimer.Reset(jobCheckFrequency)
+	eventsCh := s.eventsCh
+	var emittedFrontier hlc.Timestamp
+	var unpersistedCheckpoint *streampb.StreamEvent_StreamCheckpoint
+	persistCheckpoint := func() {
+		if unpersistedCheckpoint == nil {
+			return
+		}
+		if err := persistReplicationStreamCheckpoint(ctx, s.execCfg.JobRegistry, s.streamID,
+			unpersistedCheckpoint); err != nil {
+			// Failing to persist the checkpoint isn't a reason to stop the stream,
+			// it is persisted again with the next check of the job.
+			log.Warningf(ctx, "failed to persist the checkpoint of event stream %d: %v", s.streamID, err)
+			return
+		}
+		unpersistedCheckpoint = nil
+	}
+
 	// Note: we rely on the closed timestamp system to publish events periodically.
 	// Thus, we don't need to worry about flushing batched data on a timer -- we simply
 	// piggy-back on the fact that eventually, frontier must advance, and we must emit
@@ -362,7 +410,43 @@ func (s *eventStream) streamLoop(ctx context.Context, frontier *span.Frontier) e
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case ev := <-s.eventsCh:
+		case <-jobCheckTimer.C:
+			jobCheckTimer.Read = true
+			jobCheckTimer.Reset(jobCheckFrequency)
+			persistCheckpoint()
+			state, err := s.loadStreamJobState(ctx)
+			if err != nil {
+				// Failing to load the job isn't a reason to stop the stream.
+				log.Warningf(ctx, "failed to load the job of event stream %d: %v", s.streamID, err)
+				continue
+			}
+			if state.lagLimited && state.consumerFrontier.Less(emittedFrontier) {
+				if eventsCh != nil {
+					log.VEventf(ctx, 2, "event stream %d is lag-limited at consumer frontier %s",
+						s.streamID, state.consumerFrontier)
+				}
+				eventsCh = nil
+			} else {
+				eventsCh = s.eventsCh
+			}
+			if !state.stopped {
+				continue
+			}
+			if state.drain {
+				if err := maybeFlushBatch(forceFlush); err != nil {
+					return err
+				}
+				checkpoint := makeCheckpoint(frontier)
+				if err := s.flushEvent(ctx, &streampb.StreamEvent{Checkpoint: &checkpoint}); err != nil {
+					return err
+				}
+				unpersistedCheckpoint = &checkpoint
+				persistCheckpoint()
+			}
+			log.Infof(ctx, "event stream %d stopped (drain=%t)", s.streamID, state.drain)
+			close(s.stoppedCh)
+			return nil
+		case ev := <-eventsCh:
 			switch {
 			case ev.Val != nil:
 				addValue(ev.Val)
@@ -383,6 +467,8 @@ func (s *eventStream) streamLoop(ctx context.Context, frontier *span.Frontier) e
 					if err := s.flushEvent(ctx, &streampb.StreamEvent{Checkpoint: &checkpoint}); err != nil {
 						return err
 					}
+					emittedFrontier = frontier.Frontier()
+					unpersistedCheckpoint = &checkpoint
 				}
 			default:
 				// TODO(yevgeniy): Handle SSTs.
@@ -392,6 +478,53 @@ func (s *eventStream) streamLoop(ctx context.Context, frontier *span.Frontier) e
 	}
 }
 
+// streamJobState is the state of the producer job of a stream which affects
+// the emission of events.
+type streamJobState struct {
+	// stopped is set if the stream has been stopped or the job has been paused,
+	// in which case drain is set if the events in flight should be drained to
+	// the consumer.
+	stopped, drain bool
+	// lagLimited is set if the consumer frontier lags behind by more than the
+	// lag limit of the stream.
+	lagLimited       bool
+	consumerFrontier hlc.Timestamp
+}
+
+// loadStreamJobState loads the state of the producer job of the stream.
+func (s *eventStream) loadStreamJobState(ctx context.Context) (streamJobState, error) {
+	j, err := s.execCfg.JobRegistry.LoadJob(ctx, jobspb.JobID(s.streamID))
+	if err != nil {
+		return streamJobState{}, err
+	}
+	p := j.Progress()
+	progress := p.GetStreamReplication()
+	status := j.Status()
+	return streamJobState{
+		stopped:          progress.Stopped || status == jobs.StatusPaused || status == jobs.StatusPauseRequested,
+		drain:            progress.Drain,
+		lagLimited:       isLagLimited(progress, timeutil.Now()),
+		consumerFrontier: progress.ConsumerFrontier,
+	}, nil
+}
+
+// validatePartitionSpans returns an error if the spans of a partition are not
+// all replicated by the stream.
+func validatePartitionSpans(
+	streamID streaming.StreamID, details jobspb.StreamReplicationDetails, spans []roachpb.Span,
+) error {
+	var replicatedSpans roachpb.SpanGroup
+	for _, sp := range details.Spans {
+		replicatedSpans.Add(*sp)
+	}
+	for _, sp := range spans {
+		if !replicatedSpans.Encloses(sp) {
+			return errors.Newf("span %s is not replicated by stream %d", sp, streamID)
+		}
+	}
+	return nil
+}
+
 func setConfigDefaults(cfg *streampb.StreamPartitionSpec_ExecutionConfig) {
 	const defaultInitialScanParallelism = 16
 	const defaultMinCheckpointFrequency = 10 * time.Second
@@ -430,10 +563,43 @@ func streamPartition(
 
 	execCfg := evalCtx.Planner.ExecutorConfig().(*sql.ExecutorConfig)
 
+	j, err := execCfg.JobRegistry.LoadJob(evalCtx.Ctx(), jobspb.JobID(streamID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "replication stream %d has error", streamID)
+	}
+	details, ok := j.Details().(jobspb.StreamReplicationDetails)
+	if !ok {
+		return nil, errors.Errorf("job %d is not a replication stream", streamID)
+	}
+	if err := validatePartitionSpans(streamID, details, spec.Spans); err != nil {
+		return nil, err
+	}
+
+	if resumeFrom := spec.PreviousReplicatedTimestamp; !resumeFrom.IsEmpty() {
+		// The consumer reconnects after having replicated the partition up to
+		// resumeFrom, so stream it from there instead of performing an initial
+		// scan. Below the protected timestamp of the stream, the data might have
+		// been garbage collected already.
+		status, err := loadReplicationStreamStatus(evalCtx.Ctx(), execCfg.DB,
+			execCfg.ProtectedTimestampProvider, execCfg.JobRegistry, streamID)
+		if err != nil {
+			return nil, err
+		}
+		if pts := status.ProtectedTimestamp; pts != nil && resumeFrom.Less(*pts) {
+			return nil, errors.Newf(
+				"cannot resume replication stream %d from %s: below its protected timestamp %s",
+				streamID, resumeFrom, *pts)
+		}
+		spec.StartFrom = resumeFrom
+	}
+
 	return tree.MakeStreamingValueGenerator(&eventStream{
 		streamID: streamID,
-		spec:     spec,
-		execCfg:  execCfg,
-		mon:      evalCtx.Mon,
+		// Partitions are identified by the ID of the SQL instance streaming them,
+		// see getReplicationStreamSpec.
+		partitionID: roachpb.NodeID(execCfg.NodeID.SQLInstanceID()),
+		spec:        spec,
+		execCfg:     execCfg,
+		mon:         evalCtx.Mon,
 	}), nil
 }
@@ -0,0 +1,150 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package streamproducer
+
+import (
+	"sync/atomic"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streampb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/streaming"
+	"github.com/cockroachdb/cockroach/pkg/util/span"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// partitionStats tracks the events emitted by the event streams of a
+// partition. Its counters are updated atomically so that they can be read
+// while the partition is being streamed.
+type partitionStats struct {
+	events int64
+	bytes  int64
+
+	mu struct {
+		syncutil.Mutex
+		// checkpoint is the last checkpoint emitted by an event stream of the
+		// partition.
+		checkpoint []streampb.StreamEvent_SpanCheckpoint
+	}
+
+	// refs is the number of event streams of the partition, and is protected
+	// by the mutex of the registry.
+	refs int
+}
+
+func (p *partitionStats) recordEvent(size int) {
+	atomic.AddInt64(&p.events, 1)
+	atomic.AddInt64(&p.bytes, int64(size))
+}
+
+func (p *partitionStats) recordCheckpoint(checkpoint *streampb.StreamEvent_StreamCheckpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mu.checkpoint = checkpoint.Spans
+}
+
+func (p *partitionStats) lastCheckpoint() []streampb.StreamEvent_SpanCheckpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.mu.checkpoint
+}
+
+func (p *partitionStats) get() streaming.PartitionStats {
+	return streaming.PartitionStats{
+		EmittedEvents: atomic.LoadInt64(&p.events),
+		EmittedBytes:  atomic.LoadInt64(&p.bytes),
+	}
+}
+
+type partitionKey struct {
+	streamID    streaming.StreamID
+	partitionID roachpb.NodeID
+}
+
+// partitionStatsRegistryImpl holds the statistics of the partitions streamed
+// on this node. The statistics of a partition are kept as long as it has a
+// running event stream, and accumulate over the event streams running
+// concurrently, e.g. while a consumer reconnects.
+type partitionStatsRegistryImpl struct {
+	mu struct {
+		syncutil.Mutex
+		partitions map[partitionKey]*partitionStats
+	}
+}
+
+var partitionStatsRegistry = func() *partitionStatsRegistryImpl {
+	r := &partitionStatsRegistryImpl{}
+	r.mu.partitions = make(map[partitionKey]*partitionStats)
+	return r
+}()
+
+// register returns the statistics of the specified partition, which the
+// caller must unregister once done streaming it.
+func (r *partitionStatsRegistryImpl) register(
+	streamID streaming.StreamID, partitionID roachpb.NodeID,
+) *partitionStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := partitionKey{streamID: streamID, partitionID: partitionID}
+	p, ok := r.mu.partitions[key]
+	if !ok {
+		p = &partitionStats{}
+		r.mu.partitions[key] = p
+	}
+	p.refs++
+	return p
+}
+
+func (r *partitionStatsRegistryImpl) unregister(
+	streamID streaming.StreamID, partitionID roachpb.NodeID,
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := partitionKey{streamID: streamID, partitionID: partitionID}
+	p, ok := r.mu.partitions[key]
+	if !ok {
+		return
+	}
+	if p.refs--; p.refs == 0 {
+		delete(r.mu.partitions, key)
+	}
+}
+
+// get returns the statistics of the specified partition, or false if it is
+// not being streamed on this node.
+func (r *partitionStatsRegistryImpl) get(
+	streamID streaming.StreamID, partitionID roachpb.NodeID,
+) (streaming.PartitionStats, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.mu.partitions[partitionKey{streamID: streamID, partitionID: partitionID}]
+	if !ok {
+		return streaming.PartitionStats{}, false
+	}
+	return p.get(), true
+}
+
+// forwardResolved forwards the given frontier to the last checkpoints emitted
+// by the event streams of the partitions of a stream on this node.
+func (r *partitionStatsRegistryImpl) forwardResolved(
+	streamID streaming.StreamID, frontier *span.Frontier,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, p := range r.mu.partitions {
+		if key.streamID != streamID {
+			continue
+		}
+		for _, checkpoint := range p.lastCheckpoint() {
+			if _, err := frontier.Forward(checkpoint.Span, checkpoint.Timestamp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
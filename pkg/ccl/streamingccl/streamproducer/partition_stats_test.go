@@ -0,0 +1,132 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/licenses/CCL.txt
+
+package streamproducer
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streampb"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/streaming"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/span"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionStatsRegistry(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const streamID, partitionID = streaming.StreamID(1), 2
+	_, ok := partitionStatsRegistry.get(streamID, partitionID)
+	require.False(t, ok)
+
+	// Concurrent event streams of a partition share its statistics.
+	p := partitionStatsRegistry.register(streamID, partitionID)
+	require.Same(t, p, partitionStatsRegistry.register(streamID, partitionID))
+
+	// The statistics can be read while events are being emitted.
+	const numEvents = 100
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < numEvents; i++ {
+			p.recordEvent(10)
+		}
+	}()
+	for i := 0; i < numEvents; i++ {
+		stats, ok := partitionStatsRegistry.get(streamID, partitionID)
+		require.True(t, ok)
+		require.LessOrEqual(t, stats.EmittedEvents, int64(numEvents))
+	}
+	wg.Wait()
+
+	stats, ok := partitionStatsRegistry.get(streamID, partitionID)
+	require.True(t, ok)
+	require.Equal(t, streaming.PartitionStats{EmittedEvents: numEvents, EmittedBytes: 10 * numEvents}, stats)
+
+	// Other partitions are tracked separately.
+	_, ok = partitionStatsRegistry.get(streamID, partitionID+1)
+	require.False(t, ok)
+
+	// The statistics are kept until the last event stream is closed.
+	partitionStatsRegistry.unregister(streamID, partitionID)
+	_, ok = partitionStatsRegistry.get(streamID, partitionID)
+	require.True(t, ok)
+	partitionStatsRegistry.unregister(streamID, partitionID)
+	_, ok = partitionStatsRegistry.get(streamID, partitionID)
+	require.False(t, ok)
+}
+
+func TestPartitionStatsRegistryForwardResolved(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const streamID = streaming.StreamID(3)
+	sp := func(start, end string) roachpb.Span {
+		return roachpb.Span{Key: roachpb.Key(start), EndKey: roachpb.Key(end)}
+	}
+	ts := func(wallTime int64) hlc.Timestamp {
+		return hlc.Timestamp{WallTime: wallTime}
+	}
+	checkpoint := func(spans ...streampb.StreamEvent_SpanCheckpoint) *streampb.StreamEvent_StreamCheckpoint {
+		return &streampb.StreamEvent_StreamCheckpoint{Spans: spans}
+	}
+	requireResolved := func(expected hlc.Timestamp, s roachpb.Span) {
+		t.Helper()
+		frontier, err := span.MakeFrontier(s)
+		require.NoError(t, err)
+		require.NoError(t, partitionStatsRegistry.forwardResolved(streamID, frontier))
+		require.Equal(t, expected, frontier.Frontier(), "%s", s)
+	}
+
+	// Nothing is known before a checkpoint is emitted.
+	p1 := partitionStatsRegistry.register(streamID, 1)
+	defer partitionStatsRegistry.unregister(streamID, 1)
+	requireResolved(hlc.Timestamp{}, sp("a", "c"))
+
+	p1.recordCheckpoint(checkpoint(
+		streampb.StreamEvent_SpanCheckpoint{Span: sp("a", "b"), Timestamp: ts(2)},
+		streampb.StreamEvent_SpanCheckpoint{Span: sp("b", "c"), Timestamp: ts(4)},
+	))
+	requireResolved(ts(2), sp("a", "c"))
+	requireResolved(ts(4), sp("b", "c"))
+	requireResolved(ts(4), sp("b", "b\x00"))
+	// Spans which are only partially covered by checkpoints are not resolved,
+	// as are those streamed by other partitions, e.g. after a split.
+	requireResolved(hlc.Timestamp{}, sp("b", "d"))
+	requireResolved(hlc.Timestamp{}, sp("c", "d"))
+
+	// The checkpoints of all the partitions of the stream are considered.
+	p2 := partitionStatsRegistry.register(streamID, 2)
+	p2.recordCheckpoint(checkpoint(
+		streampb.StreamEvent_SpanCheckpoint{Span: sp("c", "d"), Timestamp: ts(3)},
+	))
+	requireResolved(ts(3), sp("b", "d"))
+
+	// Only the last checkpoint of a partition is considered.
+	p1.recordCheckpoint(checkpoint(
+		streampb.StreamEvent_SpanCheckpoint{Span: sp("a", "c"), Timestamp: ts(5)},
+	))
+	requireResolved(ts(5), sp("a", "c"))
+	requireResolved(ts(3), sp("a", "d"))
+
+	// Checkpoints of other streams are not considered.
+	other := partitionStatsRegistry.register(streamID+1, 2)
+	other.recordCheckpoint(checkpoint(
+		streampb.StreamEvent_SpanCheckpoint{Span: sp("d", "e"), Timestamp: ts(6)},
+	))
+	requireResolved(hlc.Timestamp{}, sp("d", "e"))
+	partitionStatsRegistry.unregister(streamID+1, 2)
+
+	// Checkpoints are forgotten once the partition is no longer streamed.
+	partitionStatsRegistry.unregister(streamID, 2)
+	requireResolved(hlc.Timestamp{}, sp("c", "d"))
+}
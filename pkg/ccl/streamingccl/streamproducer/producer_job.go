@@ -33,9 +33,33 @@ func makeTenantSpan(tenantID uint64) *roachpb.Span {
 	return &roachpb.Span{Key: prefix, EndKey: prefix.PrefixEnd()}
 }
 
+// makeReplicatedSpans returns the spans replicated by a stream of the specified
+// tenant, which are the specified spans or, if there are none, the whole
+// keyspace of the tenant. It returns an error if any of the spans is outside of
+// the keyspace of the tenant.
+func makeReplicatedSpans(tenantID uint64, spans []roachpb.Span) ([]*roachpb.Span, error) {
+	tenantSpan := makeTenantSpan(tenantID)
+	if len(spans) == 0 {
+		return []*roachpb.Span{tenantSpan}, nil
+	}
+	for _, sp := range spans {
+		if !sp.Valid() || !tenantSpan.Contains(sp) {
+			return nil, errors.Newf("span %s is outside of the keyspace of tenant %d", sp, tenantID)
+		}
+	}
+	merged := append([]roachpb.Span(nil), spans...)
+	merged, _ = roachpb.MergeSpans(&merged)
+	replicatedSpans := make([]*roachpb.Span, len(merged))
+	for i := range merged {
+		replicatedSpans[i] = &merged[i]
+	}
+	return replicatedSpans, nil
+}
+
 func makeProducerJobRecord(
 	registry *jobs.Registry,
 	tenantID uint64,
+	spans []*roachpb.Span,
 	timeout time.Duration,
 	username security.SQLUsername,
 	ptsID uuid.UUID,
@@ -46,7 +70,7 @@ func makeProducerJobRecord(
 		Username:    username,
 		Details: jobspb.StreamReplicationDetails{
 			ProtectedTimestampRecord: &ptsID,
-			Spans:                    []*roachpb.Span{makeTenantSpan(tenantID)},
+			Spans:                    spans,
 		},
 		Progress: jobspb.StreamReplicationProgress{
 			Expiration: timeutil.Now().Add(timeout),
@@ -176,7 +176,7 @@ func TestStreamReplicationProducerJob(t *testing.T) {
 		{ // Job times out at the beginning
 			ts := hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}
 			ptsID := uuid.MakeV4()
-			jr := makeProducerJobRecord(registry, 10, timeout, username, ptsID)
+			jr := makeProducerJobRecord(registry, 10, []*roachpb.Span{makeTenantSpan(10)}, timeout, username, ptsID)
 			defer jobs.ResetConstructors()()
 			_, _, _, waitJobFinishReverting := registerConstructor(expirationTime(jr).Add(1 * time.Millisecond))
 
@@ -193,10 +193,21 @@ func TestStreamReplicationProducerJob(t *testing.T) {
 			require.NoError(t, source.DB().Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
 				status, err = updateReplicationStreamProgress(
 					ctx, timeutil.Now(), ptp, registry, streaming.StreamID(jr.JobID),
-					hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}, txn)
+					hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}, true /* advanceProtectedTimestamp */, txn)
 				return err
 			}))
 			require.Equal(t, streampb.StreamReplicationStatus_STREAM_INACTIVE, status.StreamStatus)
+
+			status, err = loadReplicationStreamStatus(ctx, source.DB(), ptp, registry, streaming.StreamID(jr.JobID))
+			require.NoError(t, err)
+			require.Equal(t, streampb.StreamReplicationStatus_STREAM_INACTIVE, status.StreamStatus)
+			require.Nil(t, status.ProtectedTimestamp)
+			require.NotEmpty(t, status.Error)
+
+			// A stream without a producer job is inactive.
+			status, err = loadReplicationStreamStatus(ctx, source.DB(), ptp, registry, streaming.StreamID(-1))
+			require.NoError(t, err)
+			require.Equal(t, streampb.StreamReplicationStatus_STREAM_INACTIVE, status.StreamStatus)
 		}
 
 		{ // Job starts running and eventually fails after it's timed out
@@ -204,7 +215,7 @@ func TestStreamReplicationProducerJob(t *testing.T) {
 			ts := hlc.Timestamp{WallTime: ptsTime.UnixNano()}
 			ptsID := uuid.MakeV4()
 
-			jr := makeProducerJobRecord(registry, 20, timeout, username, ptsID)
+			jr := makeProducerJobRecord(registry, 20, []*roachpb.Span{makeTenantSpan(20)}, timeout, username, ptsID)
 			defer jobs.ResetConstructors()()
 			mt, timeGiven, waitForTimeRequest, waitJobFinishReverting := registerConstructor(expirationTime(jr).Add(-5 * time.Millisecond))
 
@@ -224,7 +235,8 @@ func TestStreamReplicationProducerJob(t *testing.T) {
 			require.NoError(t, source.DB().Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
 				streamStatus, err = updateReplicationStreamProgress(
 					ctx, expire,
-					ptp, registry, streaming.StreamID(jr.JobID), updatedFrontier, txn)
+					ptp, registry, streaming.StreamID(jr.JobID), updatedFrontier,
+					true /* advanceProtectedTimestamp */, txn)
 				return err
 			}))
 			require.Equal(t, streampb.StreamReplicationStatus_STREAM_ACTIVE, streamStatus.StreamStatus)
@@ -235,6 +247,26 @@ func TestStreamReplicationProducerJob(t *testing.T) {
 			// Ensure the timestamp is updated on the PTS record
 			require.Equal(t, updatedFrontier, r.Timestamp)
 
+			// Heartbeat the stream without advancing the protected timestamp.
+			require.NoError(t, source.DB().Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+				streamStatus, err = updateReplicationStreamProgress(
+					ctx, expire, ptp, registry, streaming.StreamID(jr.JobID),
+					updatedFrontier.Add(time.Second.Nanoseconds(), 0),
+					false /* advanceProtectedTimestamp */, txn)
+				return err
+			}))
+			require.Equal(t, streampb.StreamReplicationStatus_STREAM_ACTIVE, streamStatus.StreamStatus)
+			require.Equal(t, updatedFrontier, *streamStatus.ProtectedTimestamp)
+			r, err = getPTSRecord(ptsID)
+			require.NoError(t, err)
+			require.Equal(t, updatedFrontier, r.Timestamp)
+
+			streamStatus, err = loadReplicationStreamStatus(ctx, source.DB(), ptp, registry, streaming.StreamID(jr.JobID))
+			require.NoError(t, err)
+			require.Equal(t, streampb.StreamReplicationStatus_STREAM_ACTIVE, streamStatus.StreamStatus)
+			require.Equal(t, updatedFrontier, *streamStatus.ProtectedTimestamp)
+			require.Empty(t, streamStatus.Error)
+
 			// Reset the time to be after the timeout
 			mt.AdvanceTo(expire.Add(12 * time.Millisecond))
 			timeGiven()
@@ -247,5 +279,263 @@ func TestStreamReplicationProducerJob(t *testing.T) {
 			// need to change
 			require.True(t, testutils.IsError(err, "protected timestamp record does not exist"), err)
 		}
+
+		{ // Job is stopped, keeps its protected timestamp while paused and fails after the grace period
+			ts := hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}
+			ptsID := uuid.MakeV4()
+
+			jr := makeProducerJobRecord(registry, 30, []*roachpb.Span{makeTenantSpan(30)}, timeout, username, ptsID)
+			defer jobs.ResetConstructors()()
+			mt, timeGiven, waitForTimeRequest, waitJobFinishReverting := registerConstructor(expirationTime(jr).Add(-5 * time.Millisecond))
+
+			require.NoError(t, runJobWithProtectedTimestamp(ptsID, ts, jr))
+			waitForTimeRequest()
+			sql.CheckQueryResults(t, jobsQuery(jr.JobID), [][]string{{"running"}})
+
+			gracePeriodEnd := expirationTime(jr).Add(time.Hour)
+			require.NoError(t, source.DB().Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+				return pauseReplicationStream(ctx, gracePeriodEnd, registry,
+					streaming.StreamID(jr.JobID), true /* drain */, txn)
+			}))
+			// The job keeps running idle while the stream is stopped.
+			sql.CheckQueryResults(t, jobsQuery(jr.JobID), [][]string{{"running"}})
+
+			j, err := registry.LoadJob(ctx, jr.JobID)
+			require.NoError(t, err)
+			progress := j.Progress()
+			require.True(t, progress.GetStreamReplication().Stopped)
+			require.True(t, progress.GetStreamReplication().Drain)
+			require.Equal(t, gracePeriodEnd.UnixNano(), progress.GetStreamReplication().Expiration.UnixNano())
+
+			// Consumers learn that the stream is paused, and heartbeats do not advance
+			// its protected timestamp.
+			var status streampb.StreamReplicationStatus
+			require.NoError(t, source.DB().Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+				status, err = updateReplicationStreamProgress(
+					ctx, timeutil.Now(), ptp, registry, streaming.StreamID(jr.JobID),
+					ts.Add(time.Second.Nanoseconds(), 0), true /* advanceProtectedTimestamp */, txn)
+				return err
+			}))
+			require.Equal(t, streampb.StreamReplicationStatus_STREAM_PAUSED, status.StreamStatus)
+			require.Equal(t, ts, *status.ProtectedTimestamp)
+			r, err := getPTSRecord(ptsID)
+			require.NoError(t, err)
+			require.Equal(t, ts, r.Timestamp)
+			status, err = loadReplicationStreamStatus(ctx, source.DB(), ptp, registry, streaming.StreamID(jr.JobID))
+			require.NoError(t, err)
+			require.Equal(t, streampb.StreamReplicationStatus_STREAM_PAUSED, status.StreamStatus)
+			require.Equal(t, ts, *status.ProtectedTimestamp)
+
+			// A stream that has been stopped cannot be stopped again.
+			require.Regexp(t, "is already stopped",
+				source.DB().Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+					return pauseReplicationStream(ctx, gracePeriodEnd, registry,
+						streaming.StreamID(jr.JobID), false /* drain */, txn)
+				}))
+
+			// A stopped stream can be resumed, after which consumers learn that it is
+			// active again and heartbeats advance its protected timestamp.
+			require.NoError(t, source.DB().Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+				return unpauseReplicationStream(ctx, gracePeriodEnd, registry,
+					streaming.StreamID(jr.JobID), txn)
+			}))
+			j, err = registry.LoadJob(ctx, jr.JobID)
+			require.NoError(t, err)
+			progress = j.Progress()
+			require.False(t, progress.GetStreamReplication().Stopped)
+			require.False(t, progress.GetStreamReplication().Drain)
+			resumedFrontier := ts.Add(time.Second.Nanoseconds(), 0)
+			require.NoError(t, source.DB().Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+				status, err = updateReplicationStreamProgress(
+					ctx, gracePeriodEnd, ptp, registry, streaming.StreamID(jr.JobID),
+					resumedFrontier, true /* advanceProtectedTimestamp */, txn)
+				return err
+			}))
+			require.Equal(t, streampb.StreamReplicationStatus_STREAM_ACTIVE, status.StreamStatus)
+			require.Equal(t, resumedFrontier, *status.ProtectedTimestamp)
+
+			// A stream that is not stopped cannot be resumed.
+			require.Regexp(t, "is not stopped",
+				source.DB().Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+					return unpauseReplicationStream(ctx, gracePeriodEnd, registry,
+						streaming.StreamID(jr.JobID), txn)
+				}))
+
+			// Stop the stream again, for it to expire after the grace period.
+			require.NoError(t, source.DB().Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+				return pauseReplicationStream(ctx, gracePeriodEnd, registry,
+					streaming.StreamID(jr.JobID), false /* drain */, txn)
+			}))
+
+			// The job fails once the grace period has passed, which releases the
+			// protected timestamp record.
+			mt.AdvanceTo(gracePeriodEnd.Add(time.Millisecond))
+			timeGiven()
+			waitJobFinishReverting()
+
+			jobStatus := sql.QueryStr(t, jobsQuery(jr.JobID))[0][0]
+			require.True(t, jobStatus == "reverting" || jobStatus == "failed")
+			_, err = getPTSRecord(ptsID)
+			require.True(t, testutils.IsError(err, "protected timestamp record does not exist"), err)
+		}
+
+		{ // Job reports that it is lag-limited while the consumer frontier lags behind
+			ptsTime := timeutil.Now().Add(-time.Hour)
+			ts := hlc.Timestamp{WallTime: ptsTime.UnixNano()}
+			ptsID := uuid.MakeV4()
+
+			jr := makeProducerJobRecord(registry, 40, []*roachpb.Span{makeTenantSpan(40)}, timeout, username, ptsID)
+			defer jobs.ResetConstructors()()
+			_, timeGiven, waitForTimeRequest, _ := registerConstructor(expirationTime(jr).Add(-5 * time.Millisecond))
+			defer timeGiven()
+
+			require.NoError(t, runJobWithProtectedTimestamp(ptsID, ts, jr))
+			waitForTimeRequest()
+			streamID := streaming.StreamID(jr.JobID)
+
+			setLagLimit := func(lagLimit time.Duration) error {
+				return source.DB().Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+					return updateReplicationStreamLagLimit(ctx, registry, streamID, lagLimit, txn)
+				})
+			}
+			heartbeat := func(frontier hlc.Timestamp) (status streampb.StreamReplicationStatus) {
+				require.NoError(t, source.DB().Txn(ctx, func(ctx context.Context, txn *kv.Txn) (err error) {
+					status, err = updateReplicationStreamProgress(ctx, expirationTime(jr), ptp, registry,
+						streamID, frontier, false /* advanceProtectedTimestamp */, txn)
+					return err
+				}))
+				return status
+			}
+
+			require.Regexp(t, "must not be negative", setLagLimit(-time.Second))
+			require.NoError(t, setLagLimit(time.Minute))
+
+			// The consumer frontier is an hour behind.
+			require.True(t, heartbeat(ts).LagLimited)
+			status, err := loadReplicationStreamStatus(ctx, source.DB(), ptp, registry, streamID)
+			require.NoError(t, err)
+			require.True(t, status.LagLimited)
+
+			// The consumer frontier does not regress.
+			caughtUp := hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}
+			require.False(t, heartbeat(caughtUp).LagLimited)
+			require.False(t, heartbeat(ts).LagLimited)
+			j, err := registry.LoadJob(ctx, jr.JobID)
+			require.NoError(t, err)
+			progress := j.Progress()
+			require.Equal(t, caughtUp, progress.GetStreamReplication().ConsumerFrontier)
+
+			// Disabling the lag limit.
+			require.NoError(t, setLagLimit(0))
+			status, err = loadReplicationStreamStatus(ctx, source.DB(), ptp, registry, streamID)
+			require.NoError(t, err)
+			require.False(t, status.LagLimited)
+		}
+
+		{ // Job persists the checkpoints of the event streams of the stream
+			ts := hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}
+			ptsID := uuid.MakeV4()
+
+			tenantSpan := makeTenantSpan(50)
+			jr := makeProducerJobRecord(registry, 50, []*roachpb.Span{tenantSpan}, timeout, username, ptsID)
+			defer jobs.ResetConstructors()()
+			_, timeGiven, waitForTimeRequest, _ := registerConstructor(expirationTime(jr).Add(-5 * time.Millisecond))
+			defer timeGiven()
+
+			require.NoError(t, runJobWithProtectedTimestamp(ptsID, ts, jr))
+			waitForTimeRequest()
+			streamID := streaming.StreamID(jr.JobID)
+
+			mid := append(tenantSpan.Key[:len(tenantSpan.Key):len(tenantSpan.Key)], 'm')
+			left := roachpb.Span{Key: tenantSpan.Key, EndKey: mid}
+			right := roachpb.Span{Key: mid, EndKey: tenantSpan.EndKey}
+			persist := func(spans ...streampb.StreamEvent_SpanCheckpoint) {
+				require.NoError(t, persistReplicationStreamCheckpoint(ctx, registry, streamID,
+					&streampb.StreamEvent_StreamCheckpoint{Spans: spans}))
+			}
+			resolvedSpans := func() []jobspb.ResolvedSpan {
+				j, err := registry.LoadJob(ctx, jr.JobID)
+				require.NoError(t, err)
+				progress := j.Progress()
+				return progress.GetStreamReplication().ResolvedSpans
+			}
+
+			// A checkpoint of the partition streaming the left half.
+			persist(streampb.StreamEvent_SpanCheckpoint{Span: left, Timestamp: ts})
+			require.Equal(t, []jobspb.ResolvedSpan{{Span: left, Timestamp: ts}}, resolvedSpans())
+
+			// A checkpoint of the partition streaming the right half, along with a
+			// stale checkpoint of the left half which does not regress it.
+			persist(
+				streampb.StreamEvent_SpanCheckpoint{Span: left, Timestamp: ts.Prev()},
+				streampb.StreamEvent_SpanCheckpoint{Span: right, Timestamp: ts.Next()},
+			)
+			require.Equal(t, []jobspb.ResolvedSpan{
+				{Span: left, Timestamp: ts},
+				{Span: right, Timestamp: ts.Next()},
+			}, resolvedSpans())
+		}
 	})
 }
+
+func TestMakeReplicatedSpans(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	const tenantID = 10
+	tenantSpan := makeTenantSpan(tenantID)
+	prefix := tenantSpan.Key
+	span := func(start, end string) roachpb.Span {
+		return roachpb.Span{Key: append(prefix[:len(prefix):len(prefix)], start...),
+			EndKey: append(prefix[:len(prefix):len(prefix)], end...)}
+	}
+
+	// Without spans, the whole tenant is replicated.
+	spans, err := makeReplicatedSpans(tenantID, nil)
+	require.NoError(t, err)
+	require.Equal(t, []*roachpb.Span{tenantSpan}, spans)
+
+	// Overlapping spans are merged.
+	spans, err = makeReplicatedSpans(tenantID, []roachpb.Span{span("c", "e"), span("a", "b"), span("d", "f")})
+	require.NoError(t, err)
+	ab, cf := span("a", "b"), span("c", "f")
+	require.Equal(t, []*roachpb.Span{&ab, &cf}, spans)
+
+	// Spans must be within the keyspace of the tenant.
+	otherTenantSpan := makeTenantSpan(tenantID + 1)
+	_, err = makeReplicatedSpans(tenantID, []roachpb.Span{span("a", "b"), *otherTenantSpan})
+	require.Regexp(t, "outside of the keyspace of tenant 10", err)
+	_, err = makeReplicatedSpans(tenantID, []roachpb.Span{{Key: prefix, EndKey: otherTenantSpan.EndKey}})
+	require.Regexp(t, "outside of the keyspace of tenant 10", err)
+
+	// Partitions can only contain replicated spans, which may have been split.
+	details := jobspb.StreamReplicationDetails{Spans: spans}
+	require.NoError(t, validatePartitionSpans(1, details, []roachpb.Span{span("a", "b")}))
+	require.NoError(t, validatePartitionSpans(1, details, []roachpb.Span{span("c", "d"), span("d", "f")}))
+	require.Regexp(t, "is not replicated by stream 1",
+		validatePartitionSpans(1, details, []roachpb.Span{span("a", "c")}))
+}
+
+func TestIsLagLimited(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	now := timeutil.Now()
+	frontier := hlc.Timestamp{WallTime: now.Add(-time.Minute).UnixNano()}
+	for _, tc := range []struct {
+		name     string
+		progress jobspb.StreamReplicationProgress
+		expected bool
+	}{
+		{"no lag limit", jobspb.StreamReplicationProgress{ConsumerFrontier: frontier}, false},
+		{"no consumer frontier", jobspb.StreamReplicationProgress{LagLimit: time.Second}, false},
+		{"within lag limit", jobspb.StreamReplicationProgress{
+			ConsumerFrontier: frontier, LagLimit: time.Hour}, false},
+		{"beyond lag limit", jobspb.StreamReplicationProgress{
+			ConsumerFrontier: frontier, LagLimit: time.Second}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, isLagLimited(&tc.progress, now))
+		})
+	}
+}
@@ -9,15 +9,19 @@
 package streamproducer
 
 import (
+	"time"
+
 	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streampb"
 	"github.com/cockroachdb/cockroach/pkg/ccl/utilccl"
 	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/streaming"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/errors"
 )
 
 type replicationStreamManagerImpl struct{}
@@ -26,14 +30,38 @@ type replicationStreamManagerImpl struct{}
 func (r *replicationStreamManagerImpl) StartReplicationStream(
 	evalCtx *tree.EvalContext, txn *kv.Txn, tenantID uint64,
 ) (streaming.StreamID, error) {
-	return startReplicationStreamJob(evalCtx, txn, tenantID)
+	return startReplicationStreamJob(evalCtx, txn, tenantID, nil /* spans */)
+}
+
+// StartReplicationStreamForSpans implements streaming.ReplicationStreamManager interface.
+func (r *replicationStreamManagerImpl) StartReplicationStreamForSpans(
+	evalCtx *tree.EvalContext, txn *kv.Txn, tenantID uint64, spans []roachpb.Span,
+) (streaming.StreamID, error) {
+	if len(spans) == 0 {
+		return streaming.InvalidStreamID, errors.New("expected at least one span to replicate")
+	}
+	return startReplicationStreamJob(evalCtx, txn, tenantID, spans)
 }
 
 // UpdateReplicationStreamProgress implements streaming.ReplicationStreamManager interface.
 func (r *replicationStreamManagerImpl) UpdateReplicationStreamProgress(
 	evalCtx *tree.EvalContext, streamID streaming.StreamID, frontier hlc.Timestamp, txn *kv.Txn,
 ) (streampb.StreamReplicationStatus, error) {
-	return heartbeatReplicationStream(evalCtx, streamID, frontier, txn)
+	return heartbeatReplicationStream(evalCtx, streamID, frontier, true /* advanceProtectedTimestamp */, txn)
+}
+
+// HeartbeatReplicationStream implements streaming.ReplicationStreamManager interface.
+func (r *replicationStreamManagerImpl) HeartbeatReplicationStream(
+	evalCtx *tree.EvalContext, streamID streaming.StreamID, frontier hlc.Timestamp, txn *kv.Txn,
+) (streampb.StreamReplicationStatus, error) {
+	return heartbeatReplicationStream(evalCtx, streamID, frontier, false /* advanceProtectedTimestamp */, txn)
+}
+
+// GetReplicationStreamStatus implements streaming.ReplicationStreamManager interface.
+func (r *replicationStreamManagerImpl) GetReplicationStreamStatus(
+	evalCtx *tree.EvalContext, streamID streaming.StreamID,
+) (streampb.StreamReplicationStatus, error) {
+	return getReplicationStreamStatus(evalCtx, streamID)
 }
 
 // StreamPartition implements streaming.ReplicationStreamManager interface.
@@ -43,6 +71,19 @@ func (r *replicationStreamManagerImpl) StreamPartition(
 	return streamPartition(evalCtx, streamID, opaqueSpec)
 }
 
+// GetPartitionStats implements streaming.ReplicationStreamManager interface.
+func (r *replicationStreamManagerImpl) GetPartitionStats(
+	evalCtx *tree.EvalContext, streamID streaming.StreamID, partitionID roachpb.NodeID,
+) (streaming.PartitionStats, error) {
+	stats, ok := partitionStatsRegistry.get(streamID, partitionID)
+	if !ok {
+		return streaming.PartitionStats{}, errors.Newf(
+			"partition %d of replication stream %d is not being streamed on this node",
+			partitionID, streamID)
+	}
+	return stats, nil
+}
+
 // GetReplicationStreamSpec implements ReplicationStreamManager interface.
 func (r *replicationStreamManagerImpl) GetReplicationStreamSpec(
 	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID,
@@ -50,6 +91,13 @@ func (r *replicationStreamManagerImpl) GetReplicationStreamSpec(
 	return getReplicationStreamSpec(evalCtx, txn, streamID)
 }
 
+// ListReplicationStreamPartitions implements ReplicationStreamManager interface.
+func (r *replicationStreamManagerImpl) ListReplicationStreamPartitions(
+	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID,
+) ([]streaming.PartitionProgress, error) {
+	return listReplicationStreamPartitions(evalCtx, txn, streamID)
+}
+
 // CompleteReplicationStream implements ReplicationStreamManager interface.
 func (r *replicationStreamManagerImpl) CompleteReplicationStream(
 	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID,
@@ -57,6 +105,27 @@ func (r *replicationStreamManagerImpl) CompleteReplicationStream(
 	return completeReplicationStream(evalCtx, txn, streamID)
 }
 
+// StopReplicationStream implements ReplicationStreamManager interface.
+func (r *replicationStreamManagerImpl) StopReplicationStream(
+	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID, drain bool,
+) error {
+	return stopReplicationStream(evalCtx, txn, streamID, drain)
+}
+
+// ResumeReplicationStream implements ReplicationStreamManager interface.
+func (r *replicationStreamManagerImpl) ResumeReplicationStream(
+	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID,
+) error {
+	return resumeReplicationStream(evalCtx, txn, streamID)
+}
+
+// SetReplicationStreamLagLimit implements ReplicationStreamManager interface.
+func (r *replicationStreamManagerImpl) SetReplicationStreamLagLimit(
+	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID, lagLimit time.Duration,
+) error {
+	return setReplicationStreamLagLimit(evalCtx, txn, streamID, lagLimit)
+}
+
 func newReplicationStreamManagerWithPrivilegesCheck(
 	evalCtx *tree.EvalContext,
 ) (streaming.ReplicationStreamManager, error) {
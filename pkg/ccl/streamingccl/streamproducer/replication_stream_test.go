@@ -11,6 +11,7 @@ package streamproducer_test
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
@@ -21,7 +22,9 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streamingtest"
 	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streampb"
 	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/desctestutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
@@ -326,6 +329,56 @@ USE d;
 		require.Equal(t, expected.Value.RawBytes, secondObserved.Value.RawBytes)
 	})
 
+	t.Run("stream-table-resume-from-previous-replicated-timestamp", func(t *testing.T) {
+		h.Tenant.SQL.Exec(t, `UPDATE d.t1 SET b = 'world' WHERE i = 42`)
+		beforeUpdateTS := h.SysServer.Clock().Now()
+		h.Tenant.SQL.Exec(t, `UPDATE d.t1 SET a = 'hello' WHERE i = 42`)
+		h.Tenant.SQL.Exec(t, `UPDATE d.t1 SET b = 'again' WHERE i = 42`)
+
+		spec := makePartitionSpec(hlc.Timestamp{}, "t1")
+		spec.PreviousReplicatedTimestamp = beforeUpdateTS
+		opaqueSpec, err := protoutil.Marshal(spec)
+		require.NoError(t, err)
+		_, feed := startReplication(t, h, makePartitionStreamDecoder,
+			streamPartitionQuery, streamID, opaqueSpec)
+		defer feed.Close(ctx)
+
+		// Rather than performing an initial scan, which would only observe the
+		// latest version, both versions written after the previous replicated
+		// timestamp are streamed.
+		expected := streamingtest.EncodeKV(t, h.Tenant.Codec, t1Descr, 42, "hello", "world")
+		firstObserved := feed.ObserveKey(ctx, expected.Key)
+		require.Equal(t, expected.Value.RawBytes, firstObserved.Value.RawBytes)
+
+		expected = streamingtest.EncodeKV(t, h.Tenant.Codec, t1Descr, 42, "hello", "again")
+		secondObserved := feed.ObserveKey(ctx, expected.Key)
+		require.Equal(t, expected.Value.RawBytes, secondObserved.Value.RawBytes)
+	})
+
+	t.Run("stream-table-resume-below-protected-timestamp", func(t *testing.T) {
+		spec := makePartitionSpec(hlc.Timestamp{}, "t1")
+		spec.PreviousReplicatedTimestamp = hlc.Timestamp{WallTime: 1}
+		opaqueSpec, err := protoutil.Marshal(spec)
+		require.NoError(t, err)
+
+		pgxConfig, err := pgx.ParseConfig(h.PGUrl.String())
+		require.NoError(t, err)
+		conn, err := pgx.ConnectConfig(ctx, pgxConfig)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, conn.Close(ctx)) }()
+
+		_, err = conn.Exec(ctx, `SET avoid_buffering = true`)
+		require.NoError(t, err)
+		rows, err := conn.Query(ctx, streamPartitionQuery, streamID, opaqueSpec)
+		if err == nil {
+			for rows.Next() {
+			}
+			err = rows.Err()
+			rows.Close()
+		}
+		require.Regexp(t, "below its protected timestamp", err)
+	})
+
 	t.Run("stream-batches-events", func(t *testing.T) {
 		h.Tenant.SQL.Exec(t, `
 CREATE TABLE t2(
@@ -366,4 +419,59 @@ CREATE TABLE t2(
 			}
 		}
 	})
+
+	// This test stops the stream, so it must run last.
+	t.Run("stream-stops-when-stopped-and-resumes", func(t *testing.T) {
+		h.SysDB.Exec(t, "SET CLUSTER SETTING stream_replication.stream_liveness_track_frequency = '10ms'")
+
+		source, feed := startReplication(t, h, makePartitionStreamDecoder,
+			streamPartitionQuery, streamID, encodeSpec(hlc.Timestamp{}, "t1"))
+		defer feed.Close(ctx)
+
+		expected := streamingtest.EncodeKV(t, h.Tenant.Codec, t1Descr, 42)
+		feed.ObserveKey(ctx, expected.Key)
+
+		// Stop the stream the way StopReplicationStream does, draining the events
+		// in flight.
+		id, err := strconv.Atoi(streamID)
+		require.NoError(t, err)
+		registry := h.SysServer.JobRegistry().(*jobs.Registry)
+		const useReadLock = false
+		require.NoError(t, registry.UpdateJobWithTxn(ctx, jobspb.JobID(id), nil /* txn */, useReadLock,
+			func(txn *kv.Txn, md jobs.JobMetadata, ju *jobs.JobUpdater) error {
+				md.Progress.GetStreamReplication().Stopped = true
+				md.Progress.GetStreamReplication().Drain = true
+				ju.UpdateProgress(md.Progress)
+				return nil
+			}))
+
+		// The stream ends without an error after draining the events in flight.
+		var sawCheckpoint bool
+		for source.rows.Next() {
+			source.codec.decode()
+			sawCheckpoint = source.codec.(*partitionStreamDecoder).e.Checkpoint != nil
+		}
+		require.NoError(t, source.rows.Err())
+		require.True(t, sawCheckpoint, "expected the stream to end with a checkpoint")
+		// The producer job keeps running until the stream expires.
+		h.SysDB.CheckQueryResults(t, fmt.Sprintf("SELECT status FROM system.jobs WHERE id = %s", streamID),
+			[][]string{{"running"}})
+
+		// Resume the stream the way ResumeReplicationStream does, after which its
+		// partitions can be streamed again.
+		require.NoError(t, registry.UpdateJobWithTxn(ctx, jobspb.JobID(id), nil /* txn */, useReadLock,
+			func(txn *kv.Txn, md jobs.JobMetadata, ju *jobs.JobUpdater) error {
+				md.Progress.GetStreamReplication().Stopped = false
+				md.Progress.GetStreamReplication().Drain = false
+				ju.UpdateProgress(md.Progress)
+				return nil
+			}))
+		_, resumedFeed := startReplication(t, h, makePartitionStreamDecoder,
+			streamPartitionQuery, streamID, encodeSpec(hlc.Timestamp{}, "t1"))
+		defer resumedFeed.Close(ctx)
+
+		h.Tenant.SQL.Exec(t, `INSERT INTO d.t1 (i) VALUES (43)`)
+		expected = streamingtest.EncodeKV(t, h.Tenant.Codec, t1Descr, 43)
+		resumedFeed.ObserveKey(ctx, expected.Key)
+	})
 }
@@ -26,6 +26,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/streaming"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/span"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 	"github.com/cockroachdb/errors"
@@ -34,8 +36,9 @@ import (
 // startReplicationStreamJob initializes a replication stream producer job on the source cluster that
 // 1. Tracks the liveness of the replication stream consumption
 // 2. TODO(casper): Updates the protected timestamp for spans being replicated
+// The stream replicates the specified spans of the tenant or, if there are none, its whole keyspace.
 func startReplicationStreamJob(
-	evalCtx *tree.EvalContext, txn *kv.Txn, tenantID uint64,
+	evalCtx *tree.EvalContext, txn *kv.Txn, tenantID uint64, spans []roachpb.Span,
 ) (streaming.StreamID, error) {
 	execConfig := evalCtx.Planner.ExecutorConfig().(*sql.ExecutorConfig)
 	hasAdminRole, err := evalCtx.SessionAccessor.HasAdminRole(evalCtx.Ctx())
@@ -48,10 +51,15 @@ func startReplicationStreamJob(
 		return streaming.InvalidStreamID, errors.New("admin role required to start stream replication jobs")
 	}
 
+	replicatedSpans, err := makeReplicatedSpans(tenantID, spans)
+	if err != nil {
+		return streaming.InvalidStreamID, err
+	}
+
 	registry := execConfig.JobRegistry
 	timeout := streamingccl.StreamReplicationJobLivenessTimeout.Get(&evalCtx.Settings.SV)
 	ptsID := uuid.MakeV4()
-	jr := makeProducerJobRecord(registry, tenantID, timeout, evalCtx.SessionData().User(), ptsID)
+	jr := makeProducerJobRecord(registry, tenantID, replicatedSpans, timeout, evalCtx.SessionData().User(), ptsID)
 	if _, err := registry.CreateAdoptableJobWithTxn(evalCtx.Ctx(), jr, jr.JobID, txn); err != nil {
 		return streaming.InvalidStreamID, err
 	}
@@ -75,6 +83,9 @@ func startReplicationStreamJob(
 
 // updateReplicationStreamProgress updates the job progress for an active replication
 // stream specified by 'streamID' and returns error if the stream is no longer active.
+// 'ts' is recorded as the consumer frontier of the stream, and the protected
+// timestamp record of the stream is advanced to it only if
+// 'advanceProtectedTimestamp' is set.
 func updateReplicationStreamProgress(
 	ctx context.Context,
 	expiration time.Time,
@@ -82,19 +93,15 @@ func updateReplicationStreamProgress(
 	registry *jobs.Registry,
 	streamID streaming.StreamID,
 	ts hlc.Timestamp,
+	advanceProtectedTimestamp bool,
 	txn *kv.Txn,
 ) (status streampb.StreamReplicationStatus, err error) {
 	const useReadLock = false
 	err = registry.UpdateJobWithTxn(ctx, jobspb.JobID(streamID), txn, useReadLock,
 		func(txn *kv.Txn, md jobs.JobMetadata, ju *jobs.JobUpdater) error {
-			if md.Status == jobs.StatusRunning {
-				status.StreamStatus = streampb.StreamReplicationStatus_STREAM_ACTIVE
-			} else if md.Status == jobs.StatusPaused {
-				status.StreamStatus = streampb.StreamReplicationStatus_STREAM_PAUSED
-			} else if md.Status.Terminal() {
-				status.StreamStatus = streampb.StreamReplicationStatus_STREAM_INACTIVE
-			} else {
-				status.StreamStatus = streampb.StreamReplicationStatus_UNKNOWN_STREAM_STATUS_RETRY
+			status.StreamStatus = streamStatusFromJobStatus(md.Status, md.Progress.GetStreamReplication())
+			if md.Status.Terminal() {
+				status.Error = md.Payload.Error
 			}
 			// Skip checking PTS record in cases that it might already be released
 			if status.StreamStatus != streampb.StreamReplicationStatus_STREAM_ACTIVE &&
@@ -112,17 +119,24 @@ func updateReplicationStreamProgress(
 				return nil
 			}
 
-			if shouldUpdatePTS := ptsRecord.Timestamp.Less(ts); shouldUpdatePTS {
+			if shouldUpdatePTS := advanceProtectedTimestamp && ptsRecord.Timestamp.Less(ts); shouldUpdatePTS {
 				if err = ptsProvider.UpdateTimestamp(ctx, txn, ptsID, ts); err != nil {
 					return err
 				}
 				status.ProtectedTimestamp = &ts
 			}
 
-			if p := md.Progress; expiration.After(p.GetStreamReplication().Expiration) {
-				p.GetStreamReplication().Expiration = expiration
+			p := md.Progress
+			progress := p.GetStreamReplication()
+			updated := progress.ConsumerFrontier.Forward(ts)
+			if expiration.After(progress.Expiration) {
+				progress.Expiration = expiration
+				updated = true
+			}
+			if updated {
 				ju.UpdateProgress(p)
 			}
+			status.LagLimited = isLagLimited(progress, timeutil.Now())
 			return nil
 		})
 
@@ -134,32 +148,130 @@ func updateReplicationStreamProgress(
 	return status, err
 }
 
-// heartbeatReplicationStream updates replication stream progress and advances protected timestamp
-// record to the specified frontier.
+// streamStatusFromJobStatus returns the status of a replication stream given the
+// status and the progress of its producer job. A stream is paused if it has
+// been stopped, even though its producer job keeps running.
+func streamStatusFromJobStatus(
+	jobStatus jobs.Status, progress *jobspb.StreamReplicationProgress,
+) streampb.StreamReplicationStatus_StreamStatus {
+	if jobStatus == jobs.StatusRunning && progress.Stopped {
+		return streampb.StreamReplicationStatus_STREAM_PAUSED
+	} else if jobStatus == jobs.StatusRunning {
+		return streampb.StreamReplicationStatus_STREAM_ACTIVE
+	} else if jobStatus == jobs.StatusPaused || jobStatus == jobs.StatusPauseRequested {
+		return streampb.StreamReplicationStatus_STREAM_PAUSED
+	} else if jobStatus.Terminal() {
+		return streampb.StreamReplicationStatus_STREAM_INACTIVE
+	}
+	return streampb.StreamReplicationStatus_UNKNOWN_STREAM_STATUS_RETRY
+}
+
+// isLagLimited returns whether the consumer frontier of a replication stream
+// lags behind 'now' by more than the lag limit of the stream, if it has one.
+func isLagLimited(progress *jobspb.StreamReplicationProgress, now time.Time) bool {
+	if progress.LagLimit <= 0 || progress.ConsumerFrontier.IsEmpty() {
+		return false
+	}
+	return now.Sub(progress.ConsumerFrontier.GoTime()) > progress.LagLimit
+}
+
+// loadReplicationStreamStatus returns the status of the replication stream specified by
+// 'streamID' without updating its progress. Unlike updateReplicationStreamProgress, it
+// only reads the producer job and the protected timestamp record of the stream.
+func loadReplicationStreamStatus(
+	ctx context.Context,
+	db *kv.DB,
+	ptsProvider protectedts.Provider,
+	registry *jobs.Registry,
+	streamID streaming.StreamID,
+) (status streampb.StreamReplicationStatus, err error) {
+	err = db.Txn(ctx, func(ctx context.Context, txn *kv.Txn) error {
+		status = streampb.StreamReplicationStatus{}
+		j, err := registry.LoadJobWithTxn(ctx, jobspb.JobID(streamID), txn)
+		if err != nil {
+			return err
+		}
+		jobStatus := j.Status()
+		progress := j.Progress()
+		status.StreamStatus = streamStatusFromJobStatus(jobStatus, progress.GetStreamReplication())
+		if jobStatus.Terminal() {
+			status.Error = j.Payload().Error
+		}
+		// Skip checking PTS record in cases that it might already be released
+		if status.StreamStatus != streampb.StreamReplicationStatus_STREAM_ACTIVE &&
+			status.StreamStatus != streampb.StreamReplicationStatus_STREAM_PAUSED {
+			return nil
+		}
+
+		ptsID := *j.Details().(jobspb.StreamReplicationDetails).ProtectedTimestampRecord
+		ptsRecord, err := ptsProvider.GetRecord(ctx, txn, ptsID)
+		if err != nil {
+			return err
+		}
+		status.ProtectedTimestamp = &ptsRecord.Timestamp
+		if status.StreamStatus == streampb.StreamReplicationStatus_STREAM_ACTIVE {
+			status.LagLimited = isLagLimited(progress.GetStreamReplication(), timeutil.Now())
+		}
+		return nil
+	})
+
+	if jobs.HasJobNotFoundError(err) || testutils.IsError(err, "not found in system.jobs table") {
+		status = streampb.StreamReplicationStatus{
+			StreamStatus: streampb.StreamReplicationStatus_STREAM_INACTIVE,
+		}
+		err = nil
+	}
+
+	return status, err
+}
+
+// heartbeatReplicationStream extends the liveness of a replication stream and, if
+// 'advanceProtectedTimestamp' is set, advances its protected timestamp record to the
+// specified frontier.
 func heartbeatReplicationStream(
-	evalCtx *tree.EvalContext, streamID streaming.StreamID, frontier hlc.Timestamp, txn *kv.Txn,
+	evalCtx *tree.EvalContext,
+	streamID streaming.StreamID,
+	frontier hlc.Timestamp,
+	advanceProtectedTimestamp bool,
+	txn *kv.Txn,
 ) (streampb.StreamReplicationStatus, error) {
 
 	execConfig := evalCtx.Planner.ExecutorConfig().(*sql.ExecutorConfig)
 	timeout := streamingccl.StreamReplicationJobLivenessTimeout.Get(&evalCtx.Settings.SV)
 	expirationTime := timeutil.Now().Add(timeout)
 
+	if !advanceProtectedTimestamp {
+		log.VEventf(evalCtx.Ctx(), 2, "heartbeating replication stream %d at frontier %s",
+			streamID, frontier)
+	}
 	return updateReplicationStreamProgress(evalCtx.Ctx(),
-		expirationTime, execConfig.ProtectedTimestampProvider, execConfig.JobRegistry, streamID, frontier, txn)
+		expirationTime, execConfig.ProtectedTimestampProvider, execConfig.JobRegistry, streamID,
+		frontier, advanceProtectedTimestamp, txn)
 }
 
-// getReplicationStreamSpec gets a replication stream specification for the specified stream.
-func getReplicationStreamSpec(
-	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID,
-) (*streampb.ReplicationStreamSpec, error) {
+// getReplicationStreamStatus returns the status of the specified stream.
+func getReplicationStreamStatus(
+	evalCtx *tree.EvalContext, streamID streaming.StreamID,
+) (streampb.StreamReplicationStatus, error) {
+	execConfig := evalCtx.Planner.ExecutorConfig().(*sql.ExecutorConfig)
+	return loadReplicationStreamStatus(evalCtx.Ctx(), execConfig.DB,
+		execConfig.ProtectedTimestampProvider, execConfig.JobRegistry, streamID)
+}
+
+// partitionReplicatedSpans partitions the spans replicated by the specified
+// stream, which must be running, by the node which should stream them based on
+// the current ranges of the spans.
+func partitionReplicatedSpans(
+	evalCtx *tree.EvalContext, streamID streaming.StreamID,
+) (*jobs.Job, *sql.DistSQLPlanner, []sql.SpanPartition, error) {
 	jobExecCtx := evalCtx.JobExecContext.(sql.JobExecContext)
 	// Returns error if the replication stream is not active
 	j, err := jobExecCtx.ExecCfg().JobRegistry.LoadJob(evalCtx.Ctx(), jobspb.JobID(streamID))
 	if err != nil {
-		return nil, errors.Wrapf(err, "Replication stream %d has error", streamID)
+		return nil, nil, nil, errors.Wrapf(err, "Replication stream %d has error", streamID)
 	}
 	if j.Status() != jobs.StatusRunning {
-		return nil, errors.Errorf("Replication stream %d is not running", streamID)
+		return nil, nil, nil, errors.Errorf("Replication stream %d is not running", streamID)
 	}
 
 	// Partition the spans with SQLPlanner
@@ -174,6 +286,17 @@ func getReplicationStreamSpec(
 		spans = append(spans, *span)
 	}
 	spanPartitions, err := dsp.PartitionSpans(evalCtx.Ctx(), planCtx, spans)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return j, dsp, spanPartitions, nil
+}
+
+// getReplicationStreamSpec gets a replication stream specification for the specified stream.
+func getReplicationStreamSpec(
+	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID,
+) (*streampb.ReplicationStreamSpec, error) {
+	_, dsp, spanPartitions, err := partitionReplicatedSpans(evalCtx, streamID)
 	if err != nil {
 		return nil, err
 	}
@@ -201,6 +324,95 @@ func getReplicationStreamSpec(
 	return res, nil
 }
 
+// listReplicationStreamPartitions lists the spans of the current partitions of
+// the specified stream, along with the timestamps up to which they have been
+// resolved. These are the checkpoints persisted in the progress of the producer
+// job by the nodes streaming the partitions, forwarded to the fresher
+// checkpoints of the event streams running on this node.
+func listReplicationStreamPartitions(
+	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID,
+) ([]streaming.PartitionProgress, error) {
+	j, _, spanPartitions, err := partitionReplicatedSpans(evalCtx, streamID)
+	if err != nil {
+		return nil, err
+	}
+	progress := j.Progress()
+	resolvedSpans := progress.GetStreamReplication().ResolvedSpans
+
+	var res []streaming.PartitionProgress
+	for _, sp := range spanPartitions {
+		for _, partitionSpan := range sp.Spans {
+			frontier, err := span.MakeFrontier(partitionSpan)
+			if err != nil {
+				return nil, err
+			}
+			for _, rs := range resolvedSpans {
+				if _, err := frontier.Forward(rs.Span, rs.Timestamp); err != nil {
+					return nil, err
+				}
+			}
+			if err := partitionStatsRegistry.forwardResolved(streamID, frontier); err != nil {
+				return nil, err
+			}
+			res = append(res, streaming.PartitionProgress{
+				Span:              partitionSpan,
+				NodeID:            roachpb.NodeID(sp.SQLInstanceID),
+				ResolvedTimestamp: frontier.Frontier(),
+			})
+		}
+	}
+	return res, nil
+}
+
+// persistReplicationStreamCheckpoint records the timestamps up to which a
+// checkpoint of an event stream resolved the spans of the specified stream in
+// the progress of its producer job, so that they can be listed on any node.
+func persistReplicationStreamCheckpoint(
+	ctx context.Context,
+	registry *jobs.Registry,
+	streamID streaming.StreamID,
+	checkpoint *streampb.StreamEvent_StreamCheckpoint,
+) error {
+	const useReadLock = false
+	return registry.UpdateJobWithTxn(ctx, jobspb.JobID(streamID), nil /* txn */, useReadLock,
+		func(txn *kv.Txn, md jobs.JobMetadata, ju *jobs.JobUpdater) error {
+			if md.Status.Terminal() {
+				return nil
+			}
+			replicatedSpans := md.Payload.GetStreamReplication().Spans
+			spans := make([]roachpb.Span, 0, len(replicatedSpans))
+			for _, sp := range replicatedSpans {
+				spans = append(spans, *sp)
+			}
+			frontier, err := span.MakeFrontier(spans...)
+			if err != nil {
+				return err
+			}
+			p := md.Progress
+			progress := p.GetStreamReplication()
+			for _, rs := range progress.ResolvedSpans {
+				if _, err := frontier.Forward(rs.Span, rs.Timestamp); err != nil {
+					return err
+				}
+			}
+			for _, sc := range checkpoint.Spans {
+				if _, err := frontier.Forward(sc.Span, sc.Timestamp); err != nil {
+					return err
+				}
+			}
+			progress.ResolvedSpans = progress.ResolvedSpans[:0]
+			frontier.Entries(func(sp roachpb.Span, ts hlc.Timestamp) (done span.OpResult) {
+				if !ts.IsEmpty() {
+					progress.ResolvedSpans = append(progress.ResolvedSpans,
+						jobspb.ResolvedSpan{Span: sp, Timestamp: ts})
+				}
+				return span.ContinueMatch
+			})
+			ju.UpdateProgress(p)
+			return nil
+		})
+}
+
 func completeReplicationStream(
 	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID,
 ) error {
@@ -218,3 +430,124 @@ func completeReplicationStream(
 			return nil
 		})
 }
+
+// pauseReplicationStream stops the replication stream specified by 'streamID',
+// which leaves the stream resumable. The producer job of the stream keeps
+// running idle rather than being paused, so that it still enforces the
+// expiration of the stream: the protected timestamp record of the stream is
+// kept until the stream expires at 'expiration', when the job fails and
+// releases it. If 'drain' is set, the events in flight are drained to the
+// consumer before the stream stops emitting events.
+func pauseReplicationStream(
+	ctx context.Context,
+	expiration time.Time,
+	registry *jobs.Registry,
+	streamID streaming.StreamID,
+	drain bool,
+	txn *kv.Txn,
+) error {
+	const useReadLock = false
+	return registry.UpdateJobWithTxn(ctx, jobspb.JobID(streamID), txn, useReadLock,
+		func(txn *kv.Txn, md jobs.JobMetadata, ju *jobs.JobUpdater) error {
+			if md.Status != jobs.StatusRunning && md.Status != jobs.StatusPending {
+				return errors.Errorf("replication stream %d is not running, status is %s",
+					streamID, md.Status)
+			}
+			p := md.Progress
+			if p.GetStreamReplication().Stopped {
+				return errors.Errorf("replication stream %d is already stopped", streamID)
+			}
+			p.GetStreamReplication().Stopped = true
+			p.GetStreamReplication().Expiration = expiration
+			p.GetStreamReplication().Drain = drain
+			ju.UpdateProgress(p)
+			return nil
+		})
+}
+
+// stopReplicationStream stops the specified stream so that it can be resumed
+// within the grace period for stopped streams.
+func stopReplicationStream(
+	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID, drain bool,
+) error {
+	execConfig := evalCtx.Planner.ExecutorConfig().(*sql.ExecutorConfig)
+	gracePeriod := streamingccl.StreamReplicationStoppedStreamGracePeriod.Get(&evalCtx.Settings.SV)
+	return pauseReplicationStream(evalCtx.Ctx(), timeutil.Now().Add(gracePeriod),
+		execConfig.JobRegistry, streamID, drain, txn)
+}
+
+// unpauseReplicationStream resumes the replication stream specified by
+// 'streamID' after it has been stopped by pauseReplicationStream, so that the
+// consumer can stream its partitions again. The liveness of the stream is
+// extended to 'expiration', replacing the grace period of the stopped stream.
+func unpauseReplicationStream(
+	ctx context.Context,
+	expiration time.Time,
+	registry *jobs.Registry,
+	streamID streaming.StreamID,
+	txn *kv.Txn,
+) error {
+	const useReadLock = false
+	return registry.UpdateJobWithTxn(ctx, jobspb.JobID(streamID), txn, useReadLock,
+		func(txn *kv.Txn, md jobs.JobMetadata, ju *jobs.JobUpdater) error {
+			if md.Status != jobs.StatusRunning {
+				return errors.Errorf("replication stream %d is not running, status is %s",
+					streamID, md.Status)
+			}
+			p := md.Progress
+			if !p.GetStreamReplication().Stopped {
+				return errors.Errorf("replication stream %d is not stopped", streamID)
+			}
+			p.GetStreamReplication().Stopped = false
+			p.GetStreamReplication().Drain = false
+			p.GetStreamReplication().Expiration = expiration
+			ju.UpdateProgress(p)
+			return nil
+		})
+}
+
+// resumeReplicationStream resumes the specified stream, which must have been
+// stopped by stopReplicationStream.
+func resumeReplicationStream(
+	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID,
+) error {
+	execConfig := evalCtx.Planner.ExecutorConfig().(*sql.ExecutorConfig)
+	timeout := streamingccl.StreamReplicationJobLivenessTimeout.Get(&evalCtx.Settings.SV)
+	return unpauseReplicationStream(evalCtx.Ctx(), timeutil.Now().Add(timeout),
+		execConfig.JobRegistry, streamID, txn)
+}
+
+// updateReplicationStreamLagLimit sets the lag limit of the replication stream
+// specified by 'streamID'. A lag limit of zero disables it.
+func updateReplicationStreamLagLimit(
+	ctx context.Context,
+	registry *jobs.Registry,
+	streamID streaming.StreamID,
+	lagLimit time.Duration,
+	txn *kv.Txn,
+) error {
+	if lagLimit < 0 {
+		return errors.Errorf("lag limit of replication stream %d must not be negative, got %s",
+			streamID, lagLimit)
+	}
+	const useReadLock = false
+	return registry.UpdateJobWithTxn(ctx, jobspb.JobID(streamID), txn, useReadLock,
+		func(txn *kv.Txn, md jobs.JobMetadata, ju *jobs.JobUpdater) error {
+			if md.Status.Terminal() {
+				return errors.Errorf("replication stream %d is not running, status is %s",
+					streamID, md.Status)
+			}
+			p := md.Progress
+			p.GetStreamReplication().LagLimit = lagLimit
+			ju.UpdateProgress(p)
+			return nil
+		})
+}
+
+// setReplicationStreamLagLimit sets the lag limit of the specified stream.
+func setReplicationStreamLagLimit(
+	evalCtx *tree.EvalContext, txn *kv.Txn, streamID streaming.StreamID, lagLimit time.Duration,
+) error {
+	execConfig := evalCtx.Planner.ExecutorConfig().(*sql.ExecutorConfig)
+	return updateReplicationStreamLagLimit(evalCtx.Ctx(), execConfig.JobRegistry, streamID, lagLimit, txn)
+}
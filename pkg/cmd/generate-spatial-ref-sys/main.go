@@ -18,6 +18,7 @@
 package main
 
 import (
+	"compress/gzip"
 	"context"
 	"crypto/sha256"
 	"encoding/csv"
@@ -53,18 +54,52 @@ var (
 		"proj.json.gz",
 		"The resulting .json.gz file.",
 	)
+	flagFormat = flag.String(
+		"format",
+		"json",
+		"The serialization format of the resulting file, either json or gob.",
+	)
+	flagCompressionLevel = flag.Int(
+		"compression-level",
+		gzip.BestCompression,
+		"The gzip compression level of the resulting file.",
+	)
+	flagDiff = flag.String(
+		"diff",
+		"",
+		"If set, an existing data file to compare the resulting data with. The changes are printed.",
+	)
 )
 
 func main() {
 	flag.Parse()
 
+	var format embeddedproj.Format
+	switch *flagFormat {
+	case "json":
+		format = embeddedproj.FormatJSON
+	case "gob":
+		format = embeddedproj.FormatGob
+	default:
+		log.Fatalf("unknown format '%s'", *flagFormat)
+	}
+
 	data := buildData()
+	if err := data.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if *flagDiff != "" {
+		printDiff(*flagDiff, data)
+	}
 
 	out, err := os.Create(*flagDEST)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if err := embeddedproj.Encode(data, out); err != nil {
+	if err := embeddedproj.EncodeWithOptions(data, out, embeddedproj.EncodeOptions{
+		Format:           format,
+		CompressionLevel: *flagCompressionLevel,
+	}); err != nil {
 		log.Fatal(err)
 	}
 
@@ -73,6 +108,26 @@ func main() {
 	}
 }
 
+// printDiff prints the changes between the data in the existing file and the
+// new data.
+func printDiff(path string, data embeddedproj.Data) {
+	in, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+	existing, err := embeddedproj.Decode(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	diff := embeddedproj.Diff(existing, data)
+	if diff.Empty() {
+		fmt.Println("no changes")
+		return
+	}
+	fmt.Print(diff)
+}
+
 func buildData() embeddedproj.Data {
 	type spheroidKey struct {
 		majorAxis           float64
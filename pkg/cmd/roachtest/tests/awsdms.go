@@ -12,36 +12,44 @@ package tests
 
 import (
 	"context"
+	gosql "database/sql"
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	dms "github.com/aws/aws-sdk-go-v2/service/databasemigrationservice"
 	dmstypes "github.com/aws/aws-sdk-go-v2/service/databasemigrationservice/types"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
 	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/cockroachdb/apd/v3"
 	"github.com/cockroachdb/cockroach/pkg/cmd/roachtest/cluster"
 	"github.com/cockroachdb/cockroach/pkg/cmd/roachtest/option"
 	"github.com/cockroachdb/cockroach/pkg/cmd/roachtest/registry"
+	"github.com/cockroachdb/cockroach/pkg/cmd/roachtest/spec"
 	"github.com/cockroachdb/cockroach/pkg/cmd/roachtest/test"
 	"github.com/cockroachdb/cockroach/pkg/roachprod/install"
 	"github.com/cockroachdb/cockroach/pkg/roachprod/logger"
 	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
 	"github.com/cockroachdb/cockroach/pkg/util/retry"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/errors"
 	"github.com/jackc/pgx/v4"
 	"google.golang.org/protobuf/proto"
 )
 
 const (
-	awsdmsRoachtestRDSClusterName = "roachtest-awsdms-rds-cluster"
-
-	awsdmsRoachtestDMSParameterGroup          = "roachtest-awsdms-param-group"
-	awsdmsRoachtestDMSTaskName                = "roachtest-awsdms-dms-task"
-	awsdmsRoachtestDMSReplicationInstanceName = "roachtest-awsdms-replication-instance"
-	awsdmsRoachtestDMSRDSEndpointName         = "roachtest-awsdms-rds-endpoint"
-	awsdmsRoachtestDMSCRDBEndpointName        = "roachtest-awsdms-crdb-endpoint"
+	// awsdmsRoachtestResourcePrefix prefixes the identifiers of all the AWS
+	// resources created by the test. See makeAWSDMSNames.
+	awsdmsRoachtestResourcePrefix = "roachtest-awsdms"
 
 	awsdmsWaitTimeLimit  = 30 * time.Minute
 	awsdmsUser           = "cockroachdbtest"
@@ -49,58 +57,588 @@ const (
 	awsdmsCRDBDatabase   = "defaultdb"
 	awsdmsCRDBUser       = "dms"
 	awsdmsNumInitialRows = 100000
+
+	// The AWS region is derived from the zones of the cluster when it runs on
+	// AWS; the region environment variable is only used as a fallback when the
+	// cluster does not specify any zones. The instance classes may be
+	// overridden using environment variables, as not every instance class is
+	// available in every region.
+	awsdmsRegionEnvVar                    = "AWSDMS_REGION"
+	awsdmsReplicationInstanceClassEnvVar  = "AWSDMS_REPLICATION_INSTANCE_CLASS"
+	awsdmsRDSInstanceClassEnvVar          = "AWSDMS_RDS_INSTANCE_CLASS"
+	awsdmsDefaultRegion                   = "us-east-1"
+	awsdmsDefaultReplicationInstanceClass = "dms.c4.large"
+	awsdmsDefaultRDSInstanceClass         = "db.r5.large"
+	awsdmsDefaultAllocatedStorageGB       = 50
+
+	// awsdmsDefaultMaxReplicationLagP95 is the default upper bound on the p95
+	// replication lag observed between a mutation on the source and it being
+	// visible on the target. It can be overridden using the
+	// awsdmsMaxReplicationLagP95EnvVar environment variable.
+	awsdmsDefaultMaxReplicationLagP95 = 30 * time.Second
+	awsdmsMaxReplicationLagP95EnvVar  = "AWSDMS_MAX_REPLICATION_LAG_P95"
+	awsdmsNumLagSamples               = 30
+	awsdmsLagPollInterval             = 10 * time.Millisecond
+	awsdmsLagArtifactsFile            = "replication_lag.json"
+
+	awsdmsTableStatisticsPollInterval  = 30 * time.Second
+	awsdmsTableStatisticsArtifactsFile = "dms_table_statistics.log"
+
+	// awsdmsUnreachableAddr is an address from a block reserved for
+	// documentation (RFC 5737), which is never routed.
+	awsdmsUnreachableAddr        = "192.0.2.1"
+	awsdmsTaskStatusPollInterval = 30 * time.Second
+	awsdmsFullLoadPollInterval   = 5 * time.Second
+
+	// awsdmsMaxParallelTasks is the maximum number of DMS tasks, each with its
+	// own replication instance, which a variant may run in parallel.
+	awsdmsMaxParallelTasks = 4
 )
 
-var (
-	rdsClusterFilters = []rdstypes.Filter{
+// awsdmsNames are the identifiers of the AWS resources created by a variant
+// of the test.
+type awsdmsNames struct {
+	rdsCluster     string
+	parameterGroup string
+	// task and replicationInstance name the families of DMS tasks and
+	// replication instances created by setup. See awsdmsParallelName.
+	task                string
+	replicationInstance string
+	// cdcTask is the DMS task created by the full-load-then-cdc variant once
+	// the full load has completed.
+	cdcTask         string
+	rdsEndpoint     string
+	crdbEndpoint    string
+	crdbCertificate string
+}
+
+// makeAWSDMSNames returns the identifiers of the AWS resources created by the
+// given variant. They are derived from the name of the variant, so that
+// variants running concurrently never share resources, nor delete each
+// other's on startup and teardown.
+func makeAWSDMSNames(spec awsdmsSpec) awsdmsNames {
+	prefix := awsdmsRoachtestResourcePrefix
+	if spec.name != "" {
+		prefix += "-" + spec.name
+	}
+	return awsdmsNames{
+		rdsCluster:          prefix + "-rds-cluster",
+		parameterGroup:      prefix + "-param-group",
+		task:                prefix + "-dms-task",
+		replicationInstance: prefix + "-replication-instance",
+		cdcTask:             prefix + "-dms-cdc-task",
+		rdsEndpoint:         prefix + "-rds-endpoint",
+		crdbEndpoint:        prefix + "-crdb-endpoint",
+		crdbCertificate:     prefix + "-crdb-certificate",
+	}
+}
+
+// rdsInstance is the identifier of the RDS instance of the RDS cluster.
+func (n awsdmsNames) rdsInstance() string {
+	return n.rdsCluster + "-1"
+}
+
+func (n awsdmsNames) rdsClusterFilters() []rdstypes.Filter {
+	return []rdstypes.Filter{
 		{
 			Name:   proto.String("db-cluster-id"),
-			Values: []string{awsdmsRoachtestRDSClusterName},
+			Values: []string{n.rdsCluster},
 		},
 	}
-	rdsDescribeInstancesInput = &rds.DescribeDBInstancesInput{
-		Filters: rdsClusterFilters,
+}
+
+func (n awsdmsNames) rdsDescribeInstancesInput() *rds.DescribeDBInstancesInput {
+	return &rds.DescribeDBInstancesInput{
+		Filters: n.rdsClusterFilters(),
 	}
-	dmsDescribeInstancesInput = &dms.DescribeReplicationInstancesInput{
+}
+
+func (n awsdmsNames) dmsDescribeInstancesInput() *dms.DescribeReplicationInstancesInput {
+	return &dms.DescribeReplicationInstancesInput{
 		Filters: []dmstypes.Filter{
 			{
 				Name:   proto.String("replication-instance-id"),
-				Values: []string{awsdmsRoachtestDMSReplicationInstanceName},
+				Values: awsdmsParallelNames(n.replicationInstance),
+			},
+		},
+	}
+}
+
+func (n awsdmsNames) dmsDescribeCertificatesInput() *dms.DescribeCertificatesInput {
+	return &dms.DescribeCertificatesInput{
+		Filters: []dmstypes.Filter{
+			{
+				Name:   proto.String("certificate-id"),
+				Values: []string{n.crdbCertificate},
 			},
 		},
 	}
-	dmsDescribeTasksInput = &dms.DescribeReplicationTasksInput{
+}
+
+// dmsDescribeTasksInput describes all the DMS tasks which may be created by
+// the variant.
+func (n awsdmsNames) dmsDescribeTasksInput() *dms.DescribeReplicationTasksInput {
+	return &dms.DescribeReplicationTasksInput{
 		Filters: []dmstypes.Filter{
 			{
 				Name:   proto.String("replication-task-id"),
-				Values: []string{awsdmsRoachtestDMSTaskName},
+				Values: append(awsdmsParallelNames(n.task), n.cdcTask),
 			},
 		},
 	}
-)
+}
+
+// awsdmsParallelName returns the identifier of the i-th resource of the family
+// named after base, such as the replication instances and tasks of variants
+// running tasks in parallel. The first resource is named base.
+func awsdmsParallelName(base string, i int) string {
+	if i == 0 {
+		return base
+	}
+	return fmt.Sprintf("%s-%d", base, i)
+}
+
+// awsdmsParallelNames returns the identifiers of all the resources of the
+// family named after base, so that they are all described when tearing down.
+func awsdmsParallelNames(base string) []string {
+	names := make([]string, awsdmsMaxParallelTasks)
+	for i := range names {
+		names[i] = awsdmsParallelName(base, i)
+	}
+	return names
+}
+
+// dmsDescribeInstanceInput describes the DMS replication instance with the
+// given identifier.
+func dmsDescribeInstanceInput(instanceName string) *dms.DescribeReplicationInstancesInput {
+	return &dms.DescribeReplicationInstancesInput{
+		Filters: []dmstypes.Filter{
+			{
+				Name:   proto.String("replication-instance-id"),
+				Values: []string{instanceName},
+			},
+		},
+	}
+}
+
+// dmsDescribeTaskInput describes the DMS task with the given identifier.
+func dmsDescribeTaskInput(taskName string) *dms.DescribeReplicationTasksInput {
+	return &dms.DescribeReplicationTasksInput{
+		Filters: []dmstypes.Filter{
+			{
+				Name:   proto.String("replication-task-id"),
+				Values: []string{taskName},
+			},
+		},
+	}
+}
+
+// awsdmsSpec describes a variant of the awsdms roachtest.
+type awsdmsSpec struct {
+	// name is appended to the test name, if set.
+	name string
+	// secure starts CockroachDB in secure mode, and has DMS connect to it
+	// over TLS using a password.
+	secure bool
+	// unreachableTarget points the target endpoint of the DMS task at an
+	// unreachable address instead of CockroachDB. The task is then expected to
+	// fail, so setup does not wait for it to be running and verify is expected
+	// to check the failure.
+	unreachableTarget bool
+	// replicationInstanceClass and allocatedStorageGB configure the DMS
+	// replication instance. Defaults are used if unset, and the instance
+	// class may be overridden by the environment.
+	replicationInstanceClass string
+	allocatedStorageGB       int32
+	// tableMappings are the rules used by the DMS task to select and
+	// transform tables. If unset, all tables are replicated.
+	tableMappings awsdmsTableMappings
+	// parallelTableMappings, if set, makes setup create a replication instance
+	// and a DMS task for each of the table mappings, which run in parallel,
+	// rather than a single task using tableMappings. There may be at most
+	// awsdmsMaxParallelTasks of them.
+	parallelTableMappings []awsdmsTableMappings
+	// migrationType is the migration type of the DMS task created by setup.
+	// If unset, the task performs a full load followed by CDC.
+	migrationType dmstypes.MigrationTypeValue
+	// startType is how the DMS task created by setup is started. If unset, the
+	// default of createAndStartDMSTask for the migration type is used.
+	startType dmstypes.StartReplicationTaskTypeValue
+	// fullLOBMode configures the DMS task created by setup to replicate LOB
+	// columns, such as large TEXT values, in full LOB mode. Otherwise, DMS
+	// defaults to limited LOB mode, which truncates large values.
+	fullLOBMode bool
+	// sourceReplicationSlot, if set, is the name of a logical replication slot
+	// created on the source by sourceSetupStmts, which the source endpoint
+	// reads changes from. This allows CDC tasks to start replicating from a
+	// position preceding their creation.
+	sourceReplicationSlot string
+	// sourceSetupStmts are run against the RDS source before the DMS task is
+	// started. They are expected to create and populate the tables which are
+	// migrated.
+	sourceSetupStmts []string
+	// validation enables DMS data validation for the DMS tasks created by
+	// setup, which compares the rows of the source and target tables. Once
+	// verify succeeds, the test then waits for DMS to report every replicated
+	// table as validated.
+	validation bool
+	// targetSetupStmts are run against CockroachDB, as the root user, before
+	// the DMS task is started. Tables they create are truncated rather than
+	// recreated by the full load, so that their schema is preserved.
+	targetSetupStmts []string
+	// verify checks that the data on the target converges with the source,
+	// including any changes it makes on the source whilst the DMS task is
+	// running. dmsCli may be used to interact with the running DMS task,
+	// whose resources are identified by names.
+	verify func(
+		ctx context.Context,
+		t test.Test,
+		dmsCli *dms.Client,
+		names awsdmsNames,
+		sourcePGConn *pgx.Conn,
+		targetPGConn *gosql.DB,
+	) error
+}
+
+// awsdmsTableMappings describes the table mappings of a DMS task, which
+// marshal into the JSON format expected by DMS.
+type awsdmsTableMappings []awsdmsTableMappingRule
+
+// awsdmsTableMappingRule is a single selection or transformation rule. The
+// rule ID and name are assigned by marshal based on the rule's position.
+type awsdmsTableMappingRule struct {
+	RuleType      string              `json:"rule-type"`
+	RuleID        string              `json:"rule-id"`
+	RuleName      string              `json:"rule-name"`
+	RuleAction    string              `json:"rule-action"`
+	RuleTarget    string              `json:"rule-target,omitempty"`
+	ObjectLocator awsdmsObjectLocator `json:"object-locator"`
+	Value         string              `json:"value,omitempty"`
+}
+
+// awsdmsObjectLocator selects the schemas and tables a rule applies to.
+// The "%" wildcard may be used to match any sequence of characters.
+type awsdmsObjectLocator struct {
+	SchemaName string `json:"schema-name"`
+	TableName  string `json:"table-name"`
+}
+
+// awsdmsSelectionRule returns a rule which includes or excludes the matching
+// tables, depending on action.
+func awsdmsSelectionRule(action string, schemaName, tableName string) awsdmsTableMappingRule {
+	return awsdmsTableMappingRule{
+		RuleType:   "selection",
+		RuleAction: action,
+		ObjectLocator: awsdmsObjectLocator{
+			SchemaName: schemaName,
+			TableName:  tableName,
+		},
+	}
+}
+
+// awsdmsRenameTableRule returns a transformation rule which renames the
+// matching table to newName on the target.
+func awsdmsRenameTableRule(schemaName, tableName, newName string) awsdmsTableMappingRule {
+	return awsdmsTableMappingRule{
+		RuleType:   "transformation",
+		RuleAction: "rename",
+		RuleTarget: "table",
+		ObjectLocator: awsdmsObjectLocator{
+			SchemaName: schemaName,
+			TableName:  tableName,
+		},
+		Value: newName,
+	}
+}
+
+// marshal returns the DMS JSON representation of the table mappings.
+func (m awsdmsTableMappings) marshal() (string, error) {
+	rules := m
+	if len(rules) == 0 {
+		rules = awsdmsTableMappings{awsdmsSelectionRule("include", "%", "%")}
+	}
+	out := struct {
+		Rules []awsdmsTableMappingRule `json:"rules"`
+	}{
+		Rules: make([]awsdmsTableMappingRule, len(rules)),
+	}
+	for i, rule := range rules {
+		rule.RuleID = strconv.Itoa(i + 1)
+		rule.RuleName = rule.RuleID
+		out.Rules[i] = rule
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// awsdmsTaskSettings is the subset of the settings of a DMS task configured by
+// the test, which marshal into the JSON format expected by DMS. Settings which
+// are unset keep their DMS defaults.
+type awsdmsTaskSettings struct {
+	TargetMetadata     *awsdmsTargetMetadataSettings `json:"TargetMetadata,omitempty"`
+	FullLoadSettings   *awsdmsFullLoadSettings       `json:"FullLoadSettings,omitempty"`
+	ValidationSettings *awsdmsValidationSettings     `json:"ValidationSettings,omitempty"`
+}
+
+// awsdmsTargetMetadataSettings configure how LOB columns are replicated.
+type awsdmsTargetMetadataSettings struct {
+	SupportLobs        bool  `json:"SupportLobs"`
+	FullLobMode        bool  `json:"FullLobMode"`
+	LobChunkSize       int32 `json:"LobChunkSize"`
+	LimitedSizeLobMode bool  `json:"LimitedSizeLobMode"`
+}
+
+// awsdmsFullLoadSettings configure how the target is prepared for the full
+// load.
+type awsdmsFullLoadSettings struct {
+	TargetTablePrepMode string `json:"TargetTablePrepMode"`
+}
+
+// awsdmsValidationSettings configure DMS data validation.
+type awsdmsValidationSettings struct {
+	EnableValidation bool `json:"EnableValidation"`
+}
+
+// taskSettings returns the DMS JSON representation of the settings of the
+// task created by setup, or an empty string if the defaults are used.
+func (s awsdmsSpec) taskSettings() (string, error) {
+	var settings awsdmsTaskSettings
+	if s.fullLOBMode {
+		// Replicate LOB columns in full, fetching them from the source in
+		// chunks of LobChunkSize kilobytes.
+		settings.TargetMetadata = &awsdmsTargetMetadataSettings{
+			SupportLobs:  true,
+			FullLobMode:  true,
+			LobChunkSize: 64,
+		}
+	}
+	if len(s.targetSetupStmts) > 0 {
+		// By default, DMS drops and recreates the target tables, which would
+		// discard the schema created by targetSetupStmts.
+		settings.FullLoadSettings = &awsdmsFullLoadSettings{
+			TargetTablePrepMode: "TRUNCATE_BEFORE_LOAD",
+		}
+	}
+	if s.validation {
+		settings.ValidationSettings = &awsdmsValidationSettings{EnableValidation: true}
+	}
+	if settings == (awsdmsTaskSettings{}) {
+		return "", nil
+	}
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (s awsdmsSpec) testName() string {
+	if s.name == "" {
+		return "awsdms"
+	}
+	return "awsdms/" + s.name
+}
+
+// awsdmsTestTableSetupStmts creates test_table, which is verified by
+// verifyAWSDMSTestTable.
+var awsdmsTestTableSetupStmts = []string{
+	`CREATE TABLE test_table(id integer PRIMARY KEY, t TEXT)`,
+	fmt.Sprintf(
+		`INSERT INTO test_table(id, t) SELECT i, md5(random()::text) FROM generate_series(1, %d) AS t(i)`,
+		awsdmsNumInitialRows,
+	),
+}
+
+var awsdmsSpecs = []awsdmsSpec{
+	{
+		validation:       true,
+		sourceSetupStmts: awsdmsTestTableSetupStmts,
+		verify:           verifyAWSDMSTestTable,
+	},
+	{
+		name:             "rich-types",
+		validation:       true,
+		sourceSetupStmts: awsdmsRichTypesSetupStmts,
+		verify:           verifyAWSDMSRichTypes,
+	},
+	{
+		name:             "secure",
+		secure:           true,
+		validation:       true,
+		sourceSetupStmts: awsdmsTestTableSetupStmts,
+		verify:           verifyAWSDMSTestTable,
+	},
+	{
+		name: "table-filtering",
+		tableMappings: awsdmsTableMappings{
+			awsdmsSelectionRule("include", "public", "%"),
+			awsdmsSelectionRule("exclude", "public", "excluded_%"),
+			awsdmsRenameTableRule("public", "renamed_source_table", "renamed_target_table"),
+		},
+		sourceSetupStmts: awsdmsTableFilteringSetupStmts,
+		verify:           verifyAWSDMSTableFiltering,
+	},
+	{
+		name:             "concurrent-full-load",
+		sourceSetupStmts: awsdmsConcurrentFullLoadSetupStmts,
+		verify:           verifyAWSDMSConcurrentFullLoad,
+	},
+	{
+		name:             "resume",
+		sourceSetupStmts: awsdmsTestTableSetupStmts,
+		verify:           verifyAWSDMSResume,
+	},
+	{
+		name:              "unreachable-target",
+		unreachableTarget: true,
+		sourceSetupStmts:  awsdmsTestTableSetupStmts,
+		verify:            verifyAWSDMSTaskFailed,
+	},
+	{
+		name:             "sequences-and-defaults",
+		sourceSetupStmts: awsdmsDefaultsSetupStmts,
+		verify:           verifyAWSDMSDefaults,
+	},
+	{
+		name:                  "full-load-then-cdc",
+		migrationType:         dmstypes.MigrationTypeValueFullLoad,
+		sourceReplicationSlot: awsdmsSourceReplicationSlot,
+		sourceSetupStmts: append(
+			append([]string(nil), awsdmsTestTableSetupStmts...),
+			fmt.Sprintf(
+				`SELECT pg_create_logical_replication_slot('%s', 'test_decoding')`,
+				awsdmsSourceReplicationSlot,
+			),
+		),
+		verify: verifyAWSDMSFullLoadThenCDC,
+	},
+	{
+		name:        "large-values",
+		fullLOBMode: true,
+		// Replicating multi-megabyte values in full LOB mode needs more memory
+		// and storage on the replication instance than the defaults.
+		replicationInstanceClass: "dms.c5.xlarge",
+		allocatedStorageGB:       100,
+		// The target is empty, so there is no need to reload it, which is slow
+		// for large values.
+		startType:        dmstypes.StartReplicationTaskTypeValueStartReplication,
+		sourceSetupStmts: awsdmsLargeValuesSetupStmts,
+		verify:           verifyAWSDMSLargeValues,
+	},
+	{
+		name:             "computed-columns",
+		sourceSetupStmts: awsdmsComputedSourceSetupStmts,
+		targetSetupStmts: awsdmsComputedTargetSetupStmts,
+		verify:           verifyAWSDMSComputed,
+	},
+	{
+		name:             "composite-key",
+		validation:       true,
+		sourceSetupStmts: awsdmsCompositeKeySetupStmts,
+		verify:           verifyAWSDMSCompositeKey,
+	},
+	{
+		name:                  "parallel-tasks",
+		parallelTableMappings: awsdmsParallelTableMappings,
+		sourceSetupStmts:      awsdmsParallelTablesSetupStmts,
+		verify:                verifyAWSDMSParallelTasks,
+	},
+}
+
+// awsdmsConfig contains the AWS region and instance configuration used by the
+// test.
+type awsdmsConfig struct {
+	region                   string
+	replicationInstanceClass string
+	allocatedStorageGB       int32
+	rdsInstanceClass         string
+}
+
+// makeAWSDMSConfig returns the awsdmsConfig for the given variant and
+// cluster, taking into account any overrides set in the environment.
+func makeAWSDMSConfig(s awsdmsSpec, clusterSpec spec.ClusterSpec) awsdmsConfig {
+	cfg := awsdmsConfig{
+		region:                   awsdmsDefaultRegion,
+		replicationInstanceClass: awsdmsDefaultReplicationInstanceClass,
+		allocatedStorageGB:       awsdmsDefaultAllocatedStorageGB,
+		rdsInstanceClass:         awsdmsDefaultRDSInstanceClass,
+	}
+	if s.replicationInstanceClass != "" {
+		cfg.replicationInstanceClass = s.replicationInstanceClass
+	}
+	if s.allocatedStorageGB != 0 {
+		cfg.allocatedStorageGB = s.allocatedStorageGB
+	}
+	if region := awsdmsRegionFromZones(clusterSpec); region != "" {
+		cfg.region = region
+	} else if v := os.Getenv(awsdmsRegionEnvVar); v != "" {
+		cfg.region = v
+	}
+	for _, override := range []struct {
+		envVar string
+		val    *string
+	}{
+		{awsdmsReplicationInstanceClassEnvVar, &cfg.replicationInstanceClass},
+		{awsdmsRDSInstanceClassEnvVar, &cfg.rdsInstanceClass},
+	} {
+		if v := os.Getenv(override.envVar); v != "" {
+			*override.val = v
+		}
+	}
+	return cfg
+}
+
+// awsdmsRegionFromZones returns the AWS region of the first zone the cluster
+// is configured with, e.g. us-east-2 for us-east-2a. It returns an empty
+// string if the cluster does not run on AWS or does not specify any zones.
+func awsdmsRegionFromZones(clusterSpec spec.ClusterSpec) string {
+	if clusterSpec.Cloud != spec.AWS || clusterSpec.Zones == "" {
+		return ""
+	}
+	zone := strings.TrimSpace(strings.Split(clusterSpec.Zones, ",")[0])
+	if len(zone) < 2 {
+		return ""
+	}
+	return zone[:len(zone)-1]
+}
 
 func registerAWSDMS(r registry.Registry) {
-	r.Add(registry.TestSpec{
-		Name:    "awsdms",
-		Owner:   registry.OwnerSQLExperience, // TODO(otan): add a migrations OWNERS team
-		Cluster: r.MakeClusterSpec(1),
-		Tags:    []string{`default`, `awsdms`},
-		Run:     runAWSDMS,
-	})
+	for _, spec := range awsdmsSpecs {
+		spec := spec
+		r.Add(registry.TestSpec{
+			Name:    spec.testName(),
+			Owner:   registry.OwnerSQLExperience, // TODO(otan): add a migrations OWNERS team
+			Cluster: r.MakeClusterSpec(1),
+			Tags:    []string{`default`, `awsdms`},
+			Run: func(ctx context.Context, t test.Test, c cluster.Cluster) {
+				runAWSDMS(ctx, t, c, spec)
+			},
+		})
+	}
 }
 
 // runAWSDMS creates Amazon RDS instances to import into CRDB using AWS DMS.
 //
-// The RDS and DMS instances are always created with the same names, so that
-// we can always start afresh with a new instance and that we can assume
-// there is only ever one of these at any time. On startup and teardown,
-// we will attempt to delete previously created instances.
-func runAWSDMS(ctx context.Context, t test.Test, c cluster.Cluster) {
+// The RDS and DMS instances of a variant are always created with the same
+// names, so that we can always start afresh with a new instance and that we
+// can assume there is only ever one of these per variant at any time. On
+// startup and teardown, we will attempt to delete the instances previously
+// created by the variant.
+func runAWSDMS(ctx context.Context, t test.Test, c cluster.Cluster, spec awsdmsSpec) {
 	if c.IsLocal() {
 		t.Fatal("cannot be run in local mode")
 	}
 
-	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithDefaultRegion("us-east-1"))
+	cfg := makeAWSDMSConfig(spec, c.Spec())
+	names := makeAWSDMSNames(spec)
+	t.L().Printf(
+		"using region %s, replication instance class %s (%dGB) and RDS instance class %s",
+		cfg.region, cfg.replicationInstanceClass, cfg.allocatedStorageGB, cfg.rdsInstanceClass,
+	)
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithDefaultRegion(cfg.region))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -109,7 +647,7 @@ func runAWSDMS(ctx context.Context, t test.Test, c cluster.Cluster) {
 
 	// Attempt a clean-up of old instances on startup.
 	t.L().Printf("attempting to delete old instances")
-	if err := tearDownAWSDMS(ctx, t.L(), rdsCli, dmsCli); err != nil {
+	if err := tearDownAWSDMS(ctx, t.L(), rdsCli, dmsCli, names); err != nil {
 		t.Fatal(err)
 	}
 
@@ -121,136 +659,1727 @@ func runAWSDMS(ctx context.Context, t test.Test, c cluster.Cluster) {
 		}
 		t.L().Printf("attempting to cleanup instances")
 		// Try to delete from a new context, in case the previous one is cancelled.
-		if err := tearDownAWSDMS(context.Background(), t.L(), rdsCli, dmsCli); err != nil {
+		if err := tearDownAWSDMS(context.Background(), t.L(), rdsCli, dmsCli, names); err != nil {
 			t.L().Printf("failed to delete old instances on cleanup: %+v", err)
 		}
 	}()
 
-	sourcePGConn, err := setupAWSDMS(ctx, t, c, rdsCli, dmsCli)
+	sourcePGConn, err := setupAWSDMS(ctx, t, c, rdsCli, dmsCli, cfg, names, spec)
 	if err != nil {
 		t.Fatal(err)
 	}
-	targetPGConn := c.Conn(ctx, t.L(), 1)
+	targetPGConn := c.Conn(ctx, t.L(), 1)
+
+	// Periodically dump the table statistics of the DMS task into the
+	// artifacts, so that we can tell where replication stalled on failure.
+	// This is stopped before the teardown above runs.
+	statsCtx, cancelStats := context.WithCancel(ctx)
+	statsGroup := ctxgroup.WithContext(statsCtx)
+	statsGroup.GoCtx(func(ctx context.Context) error {
+		return collectDMSTableStatistics(ctx, t, dmsCli, names)
+	})
+	defer func() {
+		cancelStats()
+		if err := statsGroup.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+			t.L().Printf("failed to collect DMS table statistics: %+v", err)
+		}
+	}()
+
+	if err := spec.verify(ctx, t, dmsCli, names, sourcePGConn, targetPGConn); err != nil {
+		t.Fatal(err)
+	}
+	if spec.validation {
+		if err := waitForDMSTableValidation(ctx, t, dmsCli, names); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.L().Printf("testing complete")
+}
+
+// awsdmsWaitForReplication retries check until it succeeds, or gives up
+// after the replication retry limit is reached.
+func awsdmsWaitForReplication(ctx context.Context, t test.Test, check func() error) error {
+	waitForReplicationRetryOpts := retry.Options{
+		MaxBackoff: time.Second,
+		MaxRetries: 90,
+	}
+	var err error
+	for r := retry.StartWithCtx(ctx, waitForReplicationRetryOpts); r.Next(); {
+		if err = check(); err == nil {
+			return nil
+		}
+		t.L().Printf("replication not up to date, retrying: %+v", err)
+	}
+	return errors.Wrapf(err, "failed to find target in sync")
+}
+
+// awsdmsCompareRows runs sourceQuery on the source and targetQuery on the
+// target, which must return the same columns in the same order, and checks
+// that both return as many rows and that equal returns nil for each pair of
+// source and target rows. The rows are allocated by scan, which also returns
+// the destinations of their columns.
+func awsdmsCompareRows(
+	ctx context.Context,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+	sourceQuery, targetQuery string,
+	scan func() (row interface{}, dest []interface{}),
+	equal func(src, dst interface{}) error,
+) error {
+	var sourceRows []interface{}
+	rows, err := sourcePGConn.Query(ctx, sourceQuery)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		r, dest := scan()
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return err
+		}
+		sourceRows = append(sourceRows, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var targetRows []interface{}
+	targetQueryRows, err := targetPGConn.Query(targetQuery)
+	if err != nil {
+		return err
+	}
+	defer targetQueryRows.Close()
+	for targetQueryRows.Next() {
+		r, dest := scan()
+		if err := targetQueryRows.Scan(dest...); err != nil {
+			return err
+		}
+		targetRows = append(targetRows, r)
+	}
+	if err := targetQueryRows.Err(); err != nil {
+		return err
+	}
+
+	if len(sourceRows) != len(targetRows) {
+		return errors.Newf("found %d rows on target when expecting %d", len(targetRows), len(sourceRows))
+	}
+	for i := range sourceRows {
+		if err := equal(sourceRows[i], targetRows[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyAWSDMSTestTable verifies the replication of test_table, which
+// contains a simple set of rows with an integer primary key and a TEXT
+// column. Specs using it must enable validation, which compares the contents
+// of the rows.
+func verifyAWSDMSTestTable(
+	ctx context.Context,
+	t test.Test,
+	_ *dms.Client,
+	_ awsdmsNames,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+) error {
+	// Only compare the number of rows here. The contents of the rows are
+	// compared by DMS data validation once verification completes.
+	t.L().Printf("testing all data gets replicated")
+	if err := awsdmsWaitForReplication(ctx, t, func() error {
+		var numRows int
+		if err := targetPGConn.QueryRow("SELECT count(1) FROM test_table").Scan(&numRows); err != nil {
+			return err
+		}
+		if numRows != awsdmsNumInitialRows {
+			return errors.Newf("found %d rows when expecting %d", numRows, awsdmsNumInitialRows)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Now check an INSERT, UPDATE and DELETE all gets replicated.
+	const (
+		numExtraRows   = 10
+		numDeletedRows = 1
+		deleteRowID    = 55
+		updateRowID    = 742
+		updateRowText  = "from now on the baby sleeps in the crib"
+	)
+
+	for _, stmt := range []string{
+		fmt.Sprintf(
+			`INSERT INTO test_table(id, t) SELECT i, md5(random()::text) FROM generate_series(%d, %d) AS t(i)`,
+			awsdmsNumInitialRows+1,
+			awsdmsNumInitialRows+numExtraRows,
+		),
+		fmt.Sprintf(`UPDATE test_table SET t = '%s' WHERE id = %d`, updateRowText, updateRowID),
+		fmt.Sprintf(`DELETE FROM test_table WHERE id = %d`, deleteRowID),
+	} {
+		if _, err := sourcePGConn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	t.L().Printf("testing all subsequent updates get replicated")
+	if err := awsdmsWaitForReplication(ctx, t, func() error {
+		var countOfDeletedRow int
+		if err := targetPGConn.QueryRow("SELECT count(1) FROM test_table WHERE id = $1", deleteRowID).Scan(&countOfDeletedRow); err != nil {
+			return err
+		}
+		if countOfDeletedRow != 0 {
+			return errors.Newf("expected row to be deleted, still found")
+		}
+
+		var seenText string
+		if err := targetPGConn.QueryRow("SELECT t FROM test_table WHERE id = $1", updateRowID).Scan(&seenText); err != nil {
+			return err
+		}
+		if seenText != updateRowText {
+			return errors.Newf("expected row to be updated, still found %s", seenText)
+		}
+
+		var numRows int
+		if err := targetPGConn.QueryRow("SELECT count(1) FROM test_table").Scan(&numRows); err != nil {
+			return err
+		}
+		expectedRows := awsdmsNumInitialRows + numExtraRows - numDeletedRows
+		if numRows != expectedRows {
+			return errors.Newf("found %d rows when expecting %d", numRows, expectedRows)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	t.L().Printf("measuring replication lag")
+	maxLagP95, err := awsdmsMaxReplicationLagP95()
+	if err != nil {
+		return err
+	}
+	lagStats, err := measureAWSDMSReplicationLag(
+		ctx, t, sourcePGConn, targetPGConn, awsdmsNumInitialRows+numExtraRows+1,
+	)
+	if err != nil {
+		return err
+	}
+	t.L().Printf(
+		"replication lag over %d samples: p50=%s p95=%s max=%s",
+		len(lagStats.Samples), lagStats.P50, lagStats.P95, lagStats.Max,
+	)
+	if err := lagStats.writeArtifact(t); err != nil {
+		return err
+	}
+	if lagStats.P95 > maxLagP95 {
+		return errors.Newf("p95 replication lag %s exceeds maximum of %s", lagStats.P95, maxLagP95)
+	}
+	return nil
+}
+
+// awsdmsMaxReplicationLagP95 returns the maximum p95 replication lag the test
+// tolerates, taking into account any override set in the environment.
+func awsdmsMaxReplicationLagP95() (time.Duration, error) {
+	v := os.Getenv(awsdmsMaxReplicationLagP95EnvVar)
+	if v == "" {
+		return awsdmsDefaultMaxReplicationLagP95, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to parse %s", awsdmsMaxReplicationLagP95EnvVar)
+	}
+	return d, nil
+}
+
+// awsdmsReplicationLagStats summarizes the lag observed between issuing
+// mutations on the source and observing them on the target.
+type awsdmsReplicationLagStats struct {
+	Samples []time.Duration `json:"samples_ns"`
+	P50     time.Duration   `json:"p50_ns"`
+	P95     time.Duration   `json:"p95_ns"`
+	Max     time.Duration   `json:"max_ns"`
+}
+
+func makeAWSDMSReplicationLagStats(samples []time.Duration) awsdmsReplicationLagStats {
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) time.Duration {
+		if len(sorted) == 0 {
+			return 0
+		}
+		idx := int(float64(len(sorted)-1) * p)
+		return sorted[idx]
+	}
+	return awsdmsReplicationLagStats{
+		Samples: samples,
+		P50:     percentile(0.50),
+		P95:     percentile(0.95),
+		Max:     percentile(1),
+	}
+}
+
+// writeArtifact persists the lag stats into the test's artifacts directory so
+// that the lag can be tracked across runs.
+func (s awsdmsReplicationLagStats) writeArtifact(t test.Test) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(t.ArtifactsDir(), awsdmsLagArtifactsFile), b, 0644)
+}
+
+// measureAWSDMSReplicationLag issues a series of INSERTs, UPDATEs and DELETEs
+// on the source and records how long it takes for each one to become visible
+// on the target. Every sample inserts, updates and then deletes a row with an
+// id starting at firstID, so the table is left with the same contents as
+// before the measurement.
+func measureAWSDMSReplicationLag(
+	ctx context.Context, t test.Test, sourcePGConn *pgx.Conn, targetPGConn *gosql.DB, firstID int,
+) (awsdmsReplicationLagStats, error) {
+	var samples []time.Duration
+	for i := 0; i < awsdmsNumLagSamples; i++ {
+		id := firstID + i/3
+		text := fmt.Sprintf("lag-sample-%d", i)
+		var stmt string
+		var seen func() (bool, error)
+		switch i % 3 {
+		case 0:
+			stmt = fmt.Sprintf(`INSERT INTO test_table(id, t) VALUES (%d, '%s')`, id, text)
+			seen = func() (bool, error) {
+				var count int
+				err := targetPGConn.QueryRow("SELECT count(1) FROM test_table WHERE id = $1", id).Scan(&count)
+				return count == 1, err
+			}
+		case 1:
+			stmt = fmt.Sprintf(`UPDATE test_table SET t = '%s' WHERE id = %d`, text, id)
+			seen = func() (bool, error) {
+				var seenText string
+				err := targetPGConn.QueryRow("SELECT t FROM test_table WHERE id = $1", id).Scan(&seenText)
+				if errors.Is(err, gosql.ErrNoRows) {
+					return false, nil
+				}
+				return seenText == text, err
+			}
+		case 2:
+			stmt = fmt.Sprintf(`DELETE FROM test_table WHERE id = %d`, id)
+			seen = func() (bool, error) {
+				var count int
+				err := targetPGConn.QueryRow("SELECT count(1) FROM test_table WHERE id = $1", id).Scan(&count)
+				return count == 0, err
+			}
+		}
+
+		start := timeutil.Now()
+		if _, err := sourcePGConn.Exec(ctx, stmt); err != nil {
+			return awsdmsReplicationLagStats{}, err
+		}
+		if err := func() error {
+			for {
+				ok, err := seen()
+				if err != nil {
+					return err
+				}
+				if ok {
+					return nil
+				}
+				if timeutil.Since(start) > awsdmsWaitTimeLimit {
+					return errors.Newf("mutation %q not replicated after %s", stmt, awsdmsWaitTimeLimit)
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(awsdmsLagPollInterval):
+				}
+			}
+		}(); err != nil {
+			return awsdmsReplicationLagStats{}, err
+		}
+		lag := timeutil.Since(start)
+		t.L().Printf("observed lag of %s for %q", lag, stmt)
+		samples = append(samples, lag)
+	}
+	return makeAWSDMSReplicationLagStats(samples), nil
+}
+
+// awsdmsRichTypesSetupStmts creates a table containing a variety of types
+// which are commonly mishandled by migrations, including edge cases such as
+// NULLs, empty arrays, JSONB objects whose keys are not sorted and timestamps
+// in non-UTC timezones.
+var awsdmsRichTypesSetupStmts = []string{
+	`CREATE TABLE rich_types_table(
+		id integer PRIMARY KEY,
+		j JSONB,
+		ia BIGINT[],
+		ta TEXT[],
+		n NUMERIC(38,10),
+		ts TIMESTAMPTZ,
+		u UUID,
+		b BYTEA
+	)`,
+	`INSERT INTO rich_types_table VALUES
+		(1, '{"b": 1, "a": [1, 2, {"d": null, "c": "x"}]}', ARRAY[1, 2, 3], ARRAY['a', 'b c'], 1234567890123456789012345678.0123456789, '2022-01-01 12:00:00+05', 'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11', '\xdeadbeef'),
+		(2, '{}', ARRAY[]::BIGINT[], ARRAY[]::TEXT[], 0, '1970-01-01 00:00:00+00', '00000000-0000-0000-0000-000000000000', ''),
+		(3, NULL, NULL, NULL, NULL, NULL, NULL, NULL),
+		(4, '[]', ARRAY[-9223372036854775808, 9223372036854775807], ARRAY['', 'NULL'], -0.0000000001, '2038-01-19 03:14:08-08', 'ffffffff-ffff-ffff-ffff-ffffffffffff', '\x00')`,
+}
+
+// awsdmsRichTypesRow is a row of rich_types_table. Columns which cannot be
+// compared in their textual form are normalized before comparison.
+type awsdmsRichTypesRow struct {
+	id int
+	j  *string
+	ia *string
+	ta *string
+	n  *string
+	ts *time.Time
+	u  *string
+	b  *string
+}
+
+// awsdmsRichTypesQuery is valid on both PostgreSQL and CockroachDB.
+const awsdmsRichTypesQuery = `SELECT id, j::TEXT, ia::TEXT, ta::TEXT, n::TEXT, ts, u::TEXT, encode(b, 'hex')
+FROM rich_types_table ORDER BY id`
+
+func (r *awsdmsRichTypesRow) scanDest() []interface{} {
+	return []interface{}{&r.id, &r.j, &r.ia, &r.ta, &r.n, &r.ts, &r.u, &r.b}
+}
+
+// diff returns a description of the first column which differs between the
+// rows, or an empty string if they are equivalent.
+func (r *awsdmsRichTypesRow) diff(o *awsdmsRichTypesRow) (string, error) {
+	if r.id != o.id {
+		return fmt.Sprintf("id: %d != %d", r.id, o.id), nil
+	}
+	if (r.j == nil) != (o.j == nil) {
+		return fmt.Sprintf("j: %v != %v", r.j, o.j), nil
+	}
+	if r.j != nil {
+		// JSONB key ordering differs between PostgreSQL and CockroachDB, so
+		// compare the decoded values instead.
+		var left, right interface{}
+		if err := json.Unmarshal([]byte(*r.j), &left); err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal([]byte(*o.j), &right); err != nil {
+			return "", err
+		}
+		if !reflect.DeepEqual(left, right) {
+			return fmt.Sprintf("j: %s != %s", *r.j, *o.j), nil
+		}
+	}
+	if (r.n == nil) != (o.n == nil) {
+		return fmt.Sprintf("n: %v != %v", r.n, o.n), nil
+	}
+	if r.n != nil {
+		// Compare numerics by value, as trailing zeroes may differ.
+		left, _, err := apd.NewFromString(*r.n)
+		if err != nil {
+			return "", err
+		}
+		right, _, err := apd.NewFromString(*o.n)
+		if err != nil {
+			return "", err
+		}
+		if left.Cmp(right) != 0 {
+			return fmt.Sprintf("n: %s != %s", *r.n, *o.n), nil
+		}
+	}
+	if (r.ts == nil) != (o.ts == nil) || (r.ts != nil && !r.ts.Equal(*o.ts)) {
+		// Timestamps are compared as instants, so differing timezones in
+		// the session are irrelevant.
+		return fmt.Sprintf("ts: %v != %v", r.ts, o.ts), nil
+	}
+	for _, col := range []struct {
+		name        string
+		left, right *string
+	}{
+		{"ia", r.ia, o.ia},
+		{"ta", r.ta, o.ta},
+		{"u", r.u, o.u},
+		{"b", r.b, o.b},
+	} {
+		if (col.left == nil) != (col.right == nil) || (col.left != nil && *col.left != *col.right) {
+			return fmt.Sprintf("%s: %v != %v", col.name, col.left, col.right), nil
+		}
+	}
+	return "", nil
+}
+
+// verifyAWSDMSRichTypes verifies that each column of rich_types_table matches
+// on the source and target after the full load and after CDC.
+func verifyAWSDMSRichTypes(
+	ctx context.Context,
+	t test.Test,
+	_ *dms.Client,
+	_ awsdmsNames,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+) error {
+	compare := func() error {
+		return awsdmsCompareRows(
+			ctx, sourcePGConn, targetPGConn, awsdmsRichTypesQuery, awsdmsRichTypesQuery,
+			func() (interface{}, []interface{}) {
+				r := &awsdmsRichTypesRow{}
+				return r, r.scanDest()
+			},
+			func(src, dst interface{}) error {
+				srcRow := src.(*awsdmsRichTypesRow)
+				d, err := srcRow.diff(dst.(*awsdmsRichTypesRow))
+				if err != nil {
+					return err
+				}
+				if d != "" {
+					return errors.Newf("row %d differs: %s", srcRow.id, d)
+				}
+				return nil
+			},
+		)
+	}
+
+	t.L().Printf("testing all data gets replicated")
+	if err := awsdmsWaitForReplication(ctx, t, compare); err != nil {
+		return err
+	}
+
+	for _, stmt := range []string{
+		`INSERT INTO rich_types_table VALUES
+			(5, '{"z": {"y": [true, false]}, "a": 1.5}', ARRAY[NULL, 1]::BIGINT[], ARRAY[NULL, 'x']::TEXT[], 99999999999999999999999999.9999999999, '2000-02-29 23:59:59.999999-11', gen_random_uuid(), '\x0102')`,
+		`UPDATE rich_types_table SET j = '{"c": 3, "b": 2, "a": 1}', ia = ARRAY[]::BIGINT[], ts = '2022-06-01 08:00:00+09' WHERE id = 1`,
+		`UPDATE rich_types_table SET j = NULL, n = NULL, b = NULL WHERE id = 2`,
+		`UPDATE rich_types_table SET ta = ARRAY['now', 'set'], u = gen_random_uuid() WHERE id = 3`,
+		`DELETE FROM rich_types_table WHERE id = 4`,
+	} {
+		if _, err := sourcePGConn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	t.L().Printf("testing all subsequent updates get replicated")
+	return awsdmsWaitForReplication(ctx, t, compare)
+}
+
+const awsdmsDefaultsNumRows = 1000
+
+// awsdmsDefaultsSetupStmts creates defaults_table, whose rows are populated
+// using a sequence-backed primary key and column DEFAULTs.
+var awsdmsDefaultsSetupStmts = []string{
+	`CREATE TABLE defaults_table(
+		id bigserial PRIMARY KEY,
+		label TEXT NOT NULL,
+		tag TEXT NOT NULL DEFAULT 'tag-' || upper(substr(md5(random()::text), 1, 8)),
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now() - random() * INTERVAL '30 days'
+	)`,
+	fmt.Sprintf(
+		`INSERT INTO defaults_table(label) SELECT 'row-' || i FROM generate_series(1, %d) AS t(i)`,
+		awsdmsDefaultsNumRows,
+	),
+}
+
+// awsdmsDefaultsRow is a row of defaults_table.
+type awsdmsDefaultsRow struct {
+	id        int64
+	label     string
+	tag       string
+	createdAt time.Time
+}
+
+// awsdmsDefaultsQuery is valid on both PostgreSQL and CockroachDB.
+const awsdmsDefaultsQuery = `SELECT id, label, tag, created_at FROM defaults_table ORDER BY id`
+
+// verifyAWSDMSDefaults verifies that the values generated on the source by a
+// sequence and by column DEFAULTs are replicated as is, both by the full load
+// and for rows inserted during CDC, rather than being generated again on the
+// target.
+func verifyAWSDMSDefaults(
+	ctx context.Context,
+	t test.Test,
+	_ *dms.Client,
+	_ awsdmsNames,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+) error {
+	compare := func() error {
+		if err := awsdmsCompareRows(
+			ctx, sourcePGConn, targetPGConn, awsdmsDefaultsQuery, awsdmsDefaultsQuery,
+			func() (interface{}, []interface{}) {
+				r := &awsdmsDefaultsRow{}
+				return r, []interface{}{&r.id, &r.label, &r.tag, &r.createdAt}
+			},
+			func(src, dst interface{}) error {
+				srcRow, dstRow := src.(*awsdmsDefaultsRow), dst.(*awsdmsDefaultsRow)
+				if srcRow.id != dstRow.id || srcRow.label != dstRow.label || srcRow.tag != dstRow.tag ||
+					!srcRow.createdAt.Equal(dstRow.createdAt) {
+					return errors.Newf("found row %+v on target when expecting %+v", *dstRow, *srcRow)
+				}
+				return nil
+			},
+		); err != nil {
+			return err
+		}
+
+		// The largest id on the target must be the last value allocated by the
+		// sequence.
+		var lastValue int64
+		if err := sourcePGConn.QueryRow(
+			ctx, `SELECT last_value FROM defaults_table_id_seq`,
+		).Scan(&lastValue); err != nil {
+			return err
+		}
+		var maxID gosql.NullInt64
+		if err := targetPGConn.QueryRow(`SELECT max(id) FROM defaults_table`).Scan(&maxID); err != nil {
+			return err
+		}
+		if !maxID.Valid || maxID.Int64 != lastValue {
+			return errors.Newf("expected the largest id on target to be %d", lastValue)
+		}
+		return nil
+	}
+
+	t.L().Printf("testing all data gets replicated")
+	if err := awsdmsWaitForReplication(ctx, t, compare); err != nil {
+		return err
+	}
+
+	for _, stmt := range []string{
+		fmt.Sprintf(
+			`INSERT INTO defaults_table(label) SELECT 'cdc-row-' || i FROM generate_series(1, %d) AS t(i)`,
+			awsdmsDefaultsNumRows/10,
+		),
+		`INSERT INTO defaults_table(label, tag) VALUES ('cdc-explicit-tag', 'explicit')`,
+		`UPDATE defaults_table SET label = label || '-updated' WHERE id % 7 = 0`,
+		`DELETE FROM defaults_table WHERE id <= 10`,
+	} {
+		if _, err := sourcePGConn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	t.L().Printf("testing rows inserted during CDC get replicated")
+	return awsdmsWaitForReplication(ctx, t, compare)
+}
+
+const awsdmsTableFilteringNumRows = 1000
+
+// awsdmsTableFilteringSetupStmts creates a set of tables, some of which are
+// excluded or renamed by the table mappings of the table-filtering variant.
+var awsdmsTableFilteringSetupStmts = func() []string {
+	var stmts []string
+	for _, tableName := range []string{
+		"included_table_1",
+		"included_table_2",
+		"excluded_table_1",
+		"excluded_table_2",
+		"renamed_source_table",
+	} {
+		stmts = append(
+			stmts,
+			fmt.Sprintf(`CREATE TABLE %s(id integer PRIMARY KEY, t TEXT)`, tableName),
+			fmt.Sprintf(
+				`INSERT INTO %s(id, t) SELECT i, md5(random()::text) FROM generate_series(1, %d) AS t(i)`,
+				tableName,
+				awsdmsTableFilteringNumRows,
+			),
+		)
+	}
+	return stmts
+}()
+
+// verifyAWSDMSTableFiltering verifies that only the included tables are
+// replicated, and that renamed tables appear under their new name.
+func verifyAWSDMSTableFiltering(
+	ctx context.Context,
+	t test.Test,
+	_ *dms.Client,
+	_ awsdmsNames,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+) error {
+	replicatedTables := []string{"included_table_1", "included_table_2", "renamed_target_table"}
+	checkReplicated := func(expectedRows int) func() error {
+		return func() error {
+			for _, tableName := range replicatedTables {
+				var numRows int
+				if err := targetPGConn.QueryRow(
+					fmt.Sprintf("SELECT count(1) FROM %s", tableName),
+				).Scan(&numRows); err != nil {
+					return err
+				}
+				if numRows != expectedRows {
+					return errors.Newf("found %d rows in %s when expecting %d", numRows, tableName, expectedRows)
+				}
+			}
+			return nil
+		}
+	}
+	checkAbsent := func() error {
+		for _, tableName := range []string{"excluded_table_1", "excluded_table_2", "renamed_source_table"} {
+			var count int
+			if err := targetPGConn.QueryRow(
+				"SELECT count(1) FROM information_schema.tables WHERE table_name = $1", tableName,
+			).Scan(&count); err != nil {
+				return err
+			}
+			if count != 0 {
+				return errors.Newf("expected table %s to be absent on the target", tableName)
+			}
+		}
+		return nil
+	}
+
+	t.L().Printf("testing included tables get replicated")
+	if err := awsdmsWaitForReplication(ctx, t, checkReplicated(awsdmsTableFilteringNumRows)); err != nil {
+		return err
+	}
+	if err := checkAbsent(); err != nil {
+		return err
+	}
+
+	for _, tableName := range []string{
+		"included_table_1",
+		"included_table_2",
+		"excluded_table_1",
+		"excluded_table_2",
+		"renamed_source_table",
+	} {
+		if _, err := sourcePGConn.Exec(
+			ctx,
+			fmt.Sprintf(`INSERT INTO %s(id, t) VALUES (%d, 'cdc')`, tableName, awsdmsTableFilteringNumRows+1),
+		); err != nil {
+			return err
+		}
+	}
+
+	t.L().Printf("testing subsequent updates are only replicated for included tables")
+	if err := awsdmsWaitForReplication(ctx, t, checkReplicated(awsdmsTableFilteringNumRows+1)); err != nil {
+		return err
+	}
+	return checkAbsent()
+}
+
+const awsdmsNumConcurrentMutations = 5000
+
+// awsdmsTableFingerprintQuery returns a query computing a fingerprint of the
+// contents of the given table, which has the same columns as test_table. The
+// query is valid on both PostgreSQL and CockroachDB.
+func awsdmsTableFingerprintQuery(tableName string) string {
+	return fmt.Sprintf(
+		`SELECT count(1), coalesce(md5(string_agg(id::TEXT || ':' || coalesce(t, ''), ',' ORDER BY id)), '')
+FROM %s`,
+		tableName,
+	)
+}
+
+// awsdmsTestTableMutator issues random INSERTs, UPDATEs and DELETEs against
+// test_table, or a table with the same columns and initial rows, on the
+// source.
+type awsdmsTestTableMutator struct {
+	tableName string
+	rng       *rand.Rand
+	// nextID is the id of the next inserted row.
+	nextID int
+}
+
+func makeAWSDMSTestTableMutator() *awsdmsTestTableMutator {
+	return makeAWSDMSTableMutator("test_table")
+}
+
+// makeAWSDMSTableMutator returns a mutator for the given table, which has the
+// same columns and initial rows as test_table.
+func makeAWSDMSTableMutator(tableName string) *awsdmsTestTableMutator {
+	return &awsdmsTestTableMutator{
+		tableName: tableName,
+		rng:       rand.New(rand.NewSource(timeutil.Now().UnixNano())),
+		nextID:    awsdmsNumInitialRows + 1,
+	}
+}
+
+// run issues numMutations random mutations against the source.
+func (m *awsdmsTestTableMutator) run(
+	ctx context.Context, sourcePGConn *pgx.Conn, numMutations int,
+) error {
+	for i := 0; i < numMutations; i++ {
+		var stmt string
+		switch m.rng.Intn(3) {
+		case 0:
+			stmt = fmt.Sprintf(
+				`INSERT INTO %s(id, t) VALUES (%d, md5(random()::text))`, m.tableName, m.nextID,
+			)
+			m.nextID++
+		case 1:
+			stmt = fmt.Sprintf(
+				`UPDATE %s SET t = md5(random()::text) WHERE id = %d`,
+				m.tableName, 1+m.rng.Intn(m.nextID-1),
+			)
+		case 2:
+			stmt = fmt.Sprintf(`DELETE FROM %s WHERE id = %d`, m.tableName, 1+m.rng.Intn(m.nextID-1))
+		}
+		if _, err := sourcePGConn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// awsdmsWaitForTestTableFingerprint waits for test_table on the target to have
+// the same contents as on the source.
+func awsdmsWaitForTestTableFingerprint(
+	ctx context.Context, t test.Test, sourcePGConn *pgx.Conn, targetPGConn *gosql.DB,
+) error {
+	return awsdmsWaitForTableFingerprint(ctx, t, sourcePGConn, targetPGConn, "test_table")
+}
+
+// awsdmsWaitForTableFingerprint waits for the given table, which has the same
+// columns as test_table, to have the same contents on the target as on the
+// source.
+func awsdmsWaitForTableFingerprint(
+	ctx context.Context, t test.Test, sourcePGConn *pgx.Conn, targetPGConn *gosql.DB, tableName string,
+) error {
+	return awsdmsWaitForFingerprint(
+		ctx, t, sourcePGConn, targetPGConn, tableName, awsdmsTableFingerprintQuery(tableName),
+	)
+}
+
+// awsdmsWaitForFingerprint waits for the given table to have the same contents
+// on the target as on the source, as computed by fingerprintQuery, which
+// returns the number of rows of the table and a fingerprint of their contents.
+func awsdmsWaitForFingerprint(
+	ctx context.Context,
+	t test.Test,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+	tableName string,
+	fingerprintQuery string,
+) error {
+	var sourceCount int
+	var sourceFingerprint string
+	if err := sourcePGConn.QueryRow(ctx, fingerprintQuery).Scan(&sourceCount, &sourceFingerprint); err != nil {
+		return err
+	}
+
+	t.L().Printf("testing the target converges with the source for %s (%d rows)", tableName, sourceCount)
+	return awsdmsWaitForReplication(ctx, t, func() error {
+		var targetCount int
+		var targetFingerprint string
+		if err := targetPGConn.QueryRow(fingerprintQuery).Scan(&targetCount, &targetFingerprint); err != nil {
+			return err
+		}
+		if targetCount != sourceCount || targetFingerprint != sourceFingerprint {
+			return errors.Newf(
+				"found %d rows (fingerprint %s) in %s when expecting %d rows (fingerprint %s)",
+				targetCount, targetFingerprint, tableName, sourceCount, sourceFingerprint,
+			)
+		}
+		return nil
+	})
+}
+
+// awsdmsConcurrentFullLoadValueHashes is the number of md5 hashes making up
+// each value of test_table in the concurrent-full-load variant, so that its
+// full load takes long enough for it to still be in progress once the DMS
+// task is running and the mutations are issued.
+const awsdmsConcurrentFullLoadValueHashes = 16
+
+// awsdmsConcurrentFullLoadSetupStmts creates test_table with the same rows as
+// awsdmsTestTableSetupStmts, but with larger values.
+var awsdmsConcurrentFullLoadSetupStmts = []string{
+	`CREATE TABLE test_table(id integer PRIMARY KEY, t TEXT)`,
+	fmt.Sprintf(
+		`INSERT INTO test_table(id, t) SELECT i, repeat(md5(random()::text), %d) FROM generate_series(1, %d) AS t(i)`,
+		awsdmsConcurrentFullLoadValueHashes,
+		awsdmsNumInitialRows,
+	),
+}
+
+// verifyAWSDMSConcurrentFullLoad issues a stream of INSERTs, UPDATEs and
+// DELETEs against the source whilst the DMS task is still performing the full
+// load, and verifies that the target converges with the final state of the
+// source.
+func verifyAWSDMSConcurrentFullLoad(
+	ctx context.Context,
+	t test.Test,
+	dmsCli *dms.Client,
+	names awsdmsNames,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+) error {
+	// The DMS task has just started running, so the full load should be in
+	// progress, which is checked around the first mutation so that the variant
+	// does not silently degrade into a CDC test.
+	mutator := makeAWSDMSTestTableMutator()
+	t.L().Printf("issuing mutations during the full load")
+	if err := awsdmsCheckFullLoadInProgress(ctx, dmsCli, names, "test_table"); err != nil {
+		return err
+	}
+	if err := mutator.run(ctx, sourcePGConn, 1); err != nil {
+		return err
+	}
+	if err := awsdmsCheckFullLoadInProgress(ctx, dmsCli, names, "test_table"); err != nil {
+		return errors.Wrap(err, "after the first mutation")
+	}
+
+	// The remaining mutations run in the background whilst the state of the
+	// full load is polled.
+	mutationsDone := make(chan struct{})
+	g := ctxgroup.WithContext(ctx)
+	g.GoCtx(func(ctx context.Context) error {
+		defer close(mutationsDone)
+		return mutator.run(ctx, sourcePGConn, awsdmsNumConcurrentMutations-1)
+	})
+	g.GoCtx(func(ctx context.Context) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-mutationsDone:
+				return nil
+			case <-time.After(awsdmsFullLoadPollInterval):
+			}
+			state, err := awsdmsTableState(ctx, dmsCli, names, "test_table")
+			if err != nil {
+				return err
+			}
+			t.L().Printf("test_table is in state %q whilst issuing mutations", state)
+		}
+	})
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	return awsdmsWaitForTestTableFingerprint(ctx, t, sourcePGConn, targetPGConn)
+}
+
+// awsdmsFullLoadInProgressStates are the states of a table in the statistics
+// of a DMS task before its full load has completed.
+var awsdmsFullLoadInProgressStates = map[string]struct{}{
+	"Before load": {},
+	"Full load":   {},
+}
+
+// awsdmsCheckFullLoadInProgress returns an error unless the table statistics
+// of the DMS tasks show the full load of the given table in progress.
+func awsdmsCheckFullLoadInProgress(
+	ctx context.Context, dmsCli *dms.Client, names awsdmsNames, tableName string,
+) error {
+	state, err := awsdmsTableState(ctx, dmsCli, names, tableName)
+	if err != nil {
+		return err
+	}
+	if _, ok := awsdmsFullLoadInProgressStates[state]; !ok {
+		return errors.Newf("expected the full load of %s to be in progress, found state %q", tableName, state)
+	}
+	return nil
+}
+
+// awsdmsTableState returns the state of the given table in the table
+// statistics of the DMS tasks.
+func awsdmsTableState(
+	ctx context.Context, dmsCli *dms.Client, names awsdmsNames, tableName string,
+) (string, error) {
+	tasks, err := dmsCli.DescribeReplicationTasks(ctx, names.dmsDescribeTasksInput())
+	if err != nil {
+		return "", err
+	}
+	for _, task := range tasks.ReplicationTasks {
+		paginator := dms.NewDescribeTableStatisticsPaginator(
+			dmsCli,
+			&dms.DescribeTableStatisticsInput{ReplicationTaskArn: task.ReplicationTaskArn},
+		)
+		for paginator.HasMorePages() {
+			out, err := paginator.NextPage(ctx)
+			if err != nil {
+				return "", err
+			}
+			for _, stats := range out.TableStatistics {
+				if aws.ToString(stats.TableName) == tableName {
+					return aws.ToString(stats.TableState), nil
+				}
+			}
+		}
+	}
+	return "", errors.Newf("no table statistics found for %s", tableName)
+}
+
+// awsdmsNumResumeMutations is the number of mutations issued on the source
+// before, whilst and after the DMS task is stopped by the resume variant.
+const awsdmsNumResumeMutations = 500
+
+// verifyAWSDMSResume simulates a transient failure of the DMS task during CDC
+// by stopping and resuming it, and verifies that the target converges with
+// the source without losing or duplicating any of the changes made before,
+// whilst and after the task was stopped.
+func verifyAWSDMSResume(
+	ctx context.Context,
+	t test.Test,
+	dmsCli *dms.Client,
+	names awsdmsNames,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+) error {
+	t.L().Printf("waiting for the full load to complete")
+	if err := awsdmsWaitForTestTableFingerprint(ctx, t, sourcePGConn, targetPGConn); err != nil {
+		return err
+	}
+
+	mutator := makeAWSDMSTestTableMutator()
+	t.L().Printf("issuing mutations during CDC")
+	if err := mutator.run(ctx, sourcePGConn, awsdmsNumResumeMutations); err != nil {
+		return err
+	}
+	if err := stopDMSTasks(ctx, t.L(), dmsCli, names); err != nil {
+		return err
+	}
+	t.L().Printf("issuing mutations whilst the task is stopped")
+	if err := mutator.run(ctx, sourcePGConn, awsdmsNumResumeMutations); err != nil {
+		return err
+	}
+	if err := resumeDMSTasks(ctx, t.L(), dmsCli, names); err != nil {
+		return err
+	}
+	t.L().Printf("issuing mutations after the task is resumed")
+	if err := mutator.run(ctx, sourcePGConn, awsdmsNumResumeMutations); err != nil {
+		return err
+	}
+	return awsdmsWaitForTestTableFingerprint(ctx, t, sourcePGConn, targetPGConn)
+}
+
+// awsdmsSourceReplicationSlot is the logical replication slot created on the
+// source by the full-load-then-cdc variant before the full load starts.
+const awsdmsSourceReplicationSlot = "roachtest_awsdms_slot"
+
+// awsdmsNumStagedMutations is the number of mutations issued on the source
+// during each stage of the full-load-then-cdc variant.
+const awsdmsNumStagedMutations = 500
+
+// verifyAWSDMSFullLoadThenCDC stages the migration like users of large
+// databases commonly do: the DMS task created by setup only performs the full
+// load, and a second task then replicates the changes made on the source since
+// before the full load started. It verifies that the target converges with
+// the source, without losing or duplicating the changes made during the full
+// load or between the two tasks.
+func verifyAWSDMSFullLoadThenCDC(
+	ctx context.Context,
+	t test.Test,
+	dmsCli *dms.Client,
+	names awsdmsNames,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+) error {
+	// The replication slot was created before the full load started, and no
+	// changes have been made since, so the CDC task may start from its
+	// position.
+	var cdcStartPosition string
+	if err := sourcePGConn.QueryRow(
+		ctx,
+		`SELECT confirmed_flush_lsn::TEXT FROM pg_replication_slots WHERE slot_name = $1`,
+		awsdmsSourceReplicationSlot,
+	).Scan(&cdcStartPosition); err != nil {
+		return err
+	}
+	t.L().Printf("CDC will start from LSN %s", cdcStartPosition)
+
+	mutator := makeAWSDMSTestTableMutator()
+	t.L().Printf("issuing mutations during the full load")
+	if err := mutator.run(ctx, sourcePGConn, awsdmsNumStagedMutations); err != nil {
+		return err
+	}
+	t.L().Printf("waiting for the full load task to complete")
+	if err := dms.NewReplicationTaskStoppedWaiter(dmsCli).Wait(
+		ctx, dmsDescribeTaskInput(names.task), awsdmsWaitTimeLimit,
+	); err != nil {
+		return err
+	}
+	t.L().Printf("issuing mutations between the tasks")
+	if err := mutator.run(ctx, sourcePGConn, awsdmsNumStagedMutations); err != nil {
+		return err
+	}
+
+	// Create the CDC task using the same endpoints and table mappings as the
+	// full load task.
+	tasks, err := dmsCli.DescribeReplicationTasks(ctx, dmsDescribeTaskInput(names.task))
+	if err != nil {
+		return err
+	}
+	if len(tasks.ReplicationTasks) != 1 {
+		return errors.Newf("expected 1 full load task, found %d", len(tasks.ReplicationTasks))
+	}
+	fullLoadTask := tasks.ReplicationTasks[0]
+	if err := createAndStartDMSTask(ctx, t.L(), dmsCli, awsdmsTaskConfig{
+		name:             names.cdcTask,
+		migrationType:    dmstypes.MigrationTypeValueCdc,
+		replicationARN:   aws.ToString(fullLoadTask.ReplicationInstanceArn),
+		sourceARN:        aws.ToString(fullLoadTask.SourceEndpointArn),
+		targetARN:        aws.ToString(fullLoadTask.TargetEndpointArn),
+		tableMappings:    aws.ToString(fullLoadTask.TableMappings),
+		cdcStartPosition: cdcStartPosition,
+	}); err != nil {
+		return err
+	}
+	t.L().Printf("waiting for CDC task to be running")
+	if err := dms.NewReplicationTaskRunningWaiter(dmsCli).Wait(
+		ctx, dmsDescribeTaskInput(names.cdcTask), awsdmsWaitTimeLimit,
+	); err != nil {
+		return err
+	}
+
+	t.L().Printf("issuing mutations during CDC")
+	if err := mutator.run(ctx, sourcePGConn, awsdmsNumStagedMutations); err != nil {
+		return err
+	}
+	return awsdmsWaitForTestTableFingerprint(ctx, t, sourcePGConn, targetPGConn)
+}
+
+// awsdmsParallelNumTasks is the number of DMS tasks run in parallel by the
+// parallel-tasks variant, across which awsdmsParallelNumTables tables are
+// distributed.
+const (
+	awsdmsParallelNumTasks  = awsdmsMaxParallelTasks
+	awsdmsParallelNumTables = 2 * awsdmsParallelNumTasks
+)
+
+// awsdmsParallelTableName returns the name of the i-th table of the
+// parallel-tasks variant.
+func awsdmsParallelTableName(i int) string {
+	return fmt.Sprintf("parallel_table_%d", i)
+}
+
+// awsdmsParallelTablesSetupStmts creates the tables of the parallel-tasks
+// variant, which have the same columns and initial rows as test_table.
+var awsdmsParallelTablesSetupStmts = func() []string {
+	var stmts []string
+	for i := 0; i < awsdmsParallelNumTables; i++ {
+		tableName := awsdmsParallelTableName(i)
+		stmts = append(
+			stmts,
+			fmt.Sprintf(`CREATE TABLE %s(id integer PRIMARY KEY, t TEXT)`, tableName),
+			fmt.Sprintf(
+				`INSERT INTO %s(id, t) SELECT i, md5(random()::text) FROM generate_series(1, %d) AS t(i)`,
+				tableName,
+				awsdmsNumInitialRows,
+			),
+		)
+	}
+	return stmts
+}()
+
+// awsdmsParallelTableMappings distributes the tables of the parallel-tasks
+// variant across its tasks in a round-robin fashion, so that each table is
+// replicated by exactly one task.
+var awsdmsParallelTableMappings = func() []awsdmsTableMappings {
+	mappings := make([]awsdmsTableMappings, awsdmsParallelNumTasks)
+	for i := 0; i < awsdmsParallelNumTables; i++ {
+		task := i % awsdmsParallelNumTasks
+		mappings[task] = append(
+			mappings[task],
+			awsdmsSelectionRule("include", "public", awsdmsParallelTableName(i)),
+		)
+	}
+	return mappings
+}()
+
+// verifyAWSDMSParallelTasks verifies that the tables replicated by DMS tasks
+// running in parallel on separate replication instances all converge with the
+// source, both after the full load and after changes made during CDC.
+func verifyAWSDMSParallelTasks(
+	ctx context.Context,
+	t test.Test,
+	dmsCli *dms.Client,
+	names awsdmsNames,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+) error {
+	tasks, err := dmsCli.DescribeReplicationTasks(ctx, names.dmsDescribeTasksInput())
+	if err != nil {
+		return err
+	}
+	replicationARNs := make(map[string]struct{})
+	for _, task := range tasks.ReplicationTasks {
+		replicationARNs[aws.ToString(task.ReplicationInstanceArn)] = struct{}{}
+	}
+	if len(tasks.ReplicationTasks) != awsdmsParallelNumTasks || len(replicationARNs) != awsdmsParallelNumTasks {
+		return errors.Newf(
+			"found %d tasks on %d replication instances when expecting %d of each",
+			len(tasks.ReplicationTasks), len(replicationARNs), awsdmsParallelNumTasks,
+		)
+	}
+
+	waitForTables := func() error {
+		for i := 0; i < awsdmsParallelNumTables; i++ {
+			if err := awsdmsWaitForTableFingerprint(
+				ctx, t, sourcePGConn, targetPGConn, awsdmsParallelTableName(i),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	t.L().Printf("testing all tables get replicated")
+	if err := waitForTables(); err != nil {
+		return err
+	}
+
+	t.L().Printf("issuing mutations against all tables during CDC")
+	for i := 0; i < awsdmsParallelNumTables; i++ {
+		mutator := makeAWSDMSTableMutator(awsdmsParallelTableName(i))
+		if err := mutator.run(ctx, sourcePGConn, awsdmsNumStagedMutations); err != nil {
+			return err
+		}
+	}
+	return waitForTables()
+}
+
+// awsdmsLargeValuesNumRows is the number of rows of test_table created by the
+// large-values variant, whose values are between 1 and awsdmsLargeValuesMaxMB
+// megabytes long.
+const (
+	awsdmsLargeValuesNumRows = 20
+	awsdmsLargeValuesMaxMB   = 4
+)
+
+// awsdmsLargeValueExpr returns a PostgreSQL expression generating a random
+// string of sizeMBExpr megabytes. The string is made of distinct md5 hashes so
+// that truncated or reordered chunks change its hash.
+func awsdmsLargeValueExpr(sizeMBExpr string) string {
+	// Each md5 hash is 32 characters long.
+	return fmt.Sprintf(
+		`(SELECT string_agg(md5(random()::text), '') FROM generate_series(1, (%s) * %d))`,
+		sizeMBExpr, (1<<20)/32,
+	)
+}
+
+// awsdmsLargeValuesSetupStmts creates test_table with multi-megabyte values,
+// which is verified by verifyAWSDMSLargeValues.
+var awsdmsLargeValuesSetupStmts = []string{
+	`CREATE TABLE test_table(id integer PRIMARY KEY, t TEXT)`,
+	fmt.Sprintf(
+		`INSERT INTO test_table(id, t) SELECT i, %s FROM generate_series(1, %d) AS t(i)`,
+		awsdmsLargeValueExpr(fmt.Sprintf("1 + i %% %d", awsdmsLargeValuesMaxMB)),
+		awsdmsLargeValuesNumRows,
+	),
+}
+
+// awsdmsLargeValuesQuery returns the length and hash of each value of
+// test_table, so that large values can be compared without transferring or
+// logging them. It is valid on both PostgreSQL and CockroachDB.
+const awsdmsLargeValuesQuery = `SELECT id, length(t), md5(t) FROM test_table ORDER BY id`
+
+// awsdmsLargeValuesRow is a row returned by awsdmsLargeValuesQuery.
+type awsdmsLargeValuesRow struct {
+	id     int
+	length int
+	hash   string
+}
+
+// verifyAWSDMSLargeValues verifies that the multi-megabyte values of
+// test_table are replicated intact, both by the full load and during CDC, by
+// comparing their lengths and hashes on the source and target.
+func verifyAWSDMSLargeValues(
+	ctx context.Context,
+	t test.Test,
+	_ *dms.Client,
+	_ awsdmsNames,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+) error {
+	compare := func() error {
+		return awsdmsCompareRows(
+			ctx, sourcePGConn, targetPGConn, awsdmsLargeValuesQuery, awsdmsLargeValuesQuery,
+			func() (interface{}, []interface{}) {
+				r := &awsdmsLargeValuesRow{}
+				return r, []interface{}{&r.id, &r.length, &r.hash}
+			},
+			func(src, dst interface{}) error {
+				if srcRow, dstRow := src.(*awsdmsLargeValuesRow), dst.(*awsdmsLargeValuesRow); *srcRow != *dstRow {
+					return errors.Newf(
+						"found row %d with length %d (md5 %s) on target when expecting row %d with length %d (md5 %s)",
+						dstRow.id, dstRow.length, dstRow.hash, srcRow.id, srcRow.length, srcRow.hash,
+					)
+				}
+				return nil
+			},
+		)
+	}
+
+	t.L().Printf("testing all large values get replicated")
+	if err := awsdmsWaitForReplication(ctx, t, compare); err != nil {
+		return err
+	}
+
+	for _, stmt := range []string{
+		fmt.Sprintf(
+			`INSERT INTO test_table(id, t) VALUES (%d, %s)`,
+			awsdmsLargeValuesNumRows+1, awsdmsLargeValueExpr(fmt.Sprint(awsdmsLargeValuesMaxMB)),
+		),
+		fmt.Sprintf(
+			`UPDATE test_table SET t = %s WHERE id %% 5 = 0`,
+			awsdmsLargeValueExpr(fmt.Sprint(awsdmsLargeValuesMaxMB)),
+		),
+		// Shrinking a large value must not leave any of the old value behind.
+		`UPDATE test_table SET t = 'small' WHERE id = 1`,
+		`DELETE FROM test_table WHERE id = 2`,
+	} {
+		if _, err := sourcePGConn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	t.L().Printf("testing all subsequent updates of large values get replicated")
+	return awsdmsWaitForReplication(ctx, t, compare)
+}
+
+const awsdmsComputedNumRows = 1000
+
+// awsdmsComputedSourceSetupStmts creates computed_table on the source, which
+// only has the columns the computed column on the target is derived from.
+var awsdmsComputedSourceSetupStmts = []string{
+	`CREATE TABLE computed_table(id integer PRIMARY KEY, a integer NOT NULL, b integer NOT NULL)`,
+	fmt.Sprintf(
+		`INSERT INTO computed_table(id, a, b) SELECT i, i, i * 2 FROM generate_series(1, %d) AS t(i)`,
+		awsdmsComputedNumRows,
+	),
+}
+
+// awsdmsComputedTargetSetupStmts creates computed_table on CockroachDB with an
+// additional stored computed column, which DMS cannot write to.
+//
+// The computed column is NOT VISIBLE, so that the COPY statements DMS issues
+// for the full load rely on the expect_and_ignore_not_visible_columns_in_copy
+// session variable, set for the DMS user by setupCockroachDBCluster, to
+// discard the field DMS sends for it. Were the column visible, CockroachDB
+// would reject the value DMS copies into it.
+var awsdmsComputedTargetSetupStmts = []string{
+	`CREATE TABLE computed_table(
+		id INT8 PRIMARY KEY,
+		a INT8 NOT NULL,
+		b INT8 NOT NULL,
+		total INT8 NOT VISIBLE AS (a + b) STORED
+	)`,
+}
+
+// awsdmsComputedRow is a row of computed_table. total is only set for rows
+// read from the target.
+type awsdmsComputedRow struct {
+	id, a, b, total int64
+}
+
+// verifyAWSDMSComputed verifies that the rows replicated into a target table
+// with a stored computed column match the source, and that the computed
+// column is computed by CockroachDB, both by the full load and for rows
+// changed during CDC.
+func verifyAWSDMSComputed(
+	ctx context.Context,
+	t test.Test,
+	_ *dms.Client,
+	_ awsdmsNames,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+) error {
+	compare := func() error {
+		// The total is only computed on the target, by CockroachDB, so the
+		// source computes the expected total on the fly.
+		return awsdmsCompareRows(
+			ctx, sourcePGConn, targetPGConn,
+			`SELECT id, a, b, a + b FROM computed_table ORDER BY id`,
+			`SELECT id, a, b, total FROM computed_table ORDER BY id`,
+			func() (interface{}, []interface{}) {
+				r := &awsdmsComputedRow{}
+				return r, []interface{}{&r.id, &r.a, &r.b, &r.total}
+			},
+			func(src, dst interface{}) error {
+				srcRow, dstRow := src.(*awsdmsComputedRow), dst.(*awsdmsComputedRow)
+				if srcRow.id != dstRow.id || srcRow.a != dstRow.a || srcRow.b != dstRow.b {
+					return errors.Newf("found row %+v on target when expecting %+v", *dstRow, *srcRow)
+				}
+				if dstRow.total != srcRow.total {
+					return errors.Newf(
+						"found computed total %d on target for row %+v when expecting %d", dstRow.total, *dstRow, srcRow.total,
+					)
+				}
+				return nil
+			},
+		)
+	}
+
+	t.L().Printf("testing all data gets replicated")
+	if err := awsdmsWaitForReplication(ctx, t, compare); err != nil {
+		return err
+	}
+
+	for _, stmt := range []string{
+		fmt.Sprintf(
+			`INSERT INTO computed_table(id, a, b) SELECT i, i, -i FROM generate_series(%d, %d) AS t(i)`,
+			awsdmsComputedNumRows+1,
+			awsdmsComputedNumRows+awsdmsComputedNumRows/10,
+		),
+		`UPDATE computed_table SET a = a + 100 WHERE id % 7 = 0`,
+		`DELETE FROM computed_table WHERE id <= 10`,
+	} {
+		if _, err := sourcePGConn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	t.L().Printf("testing rows changed during CDC get replicated")
+	return awsdmsWaitForReplication(ctx, t, compare)
+}
+
+const (
+	// awsdmsCompositeKeyNumTenants and awsdmsCompositeKeyRowsPerTenant
+	// determine the initial rows of composite_key_table. Every tenant has rows
+	// with the same ids, so that a row is only identified by its full key.
+	awsdmsCompositeKeyNumTenants    = 10
+	awsdmsCompositeKeyRowsPerTenant = 1000
+)
+
+// awsdmsCompositeKeySetupStmts creates composite_key_table, which has a
+// composite primary key.
+var awsdmsCompositeKeySetupStmts = []string{
+	`CREATE TABLE composite_key_table(tenant_id integer, id integer, t TEXT, PRIMARY KEY (tenant_id, id))`,
+	fmt.Sprintf(
+		`INSERT INTO composite_key_table(tenant_id, id, t)
+SELECT tenant_id, id, md5(random()::text)
+FROM generate_series(1, %d) AS tenants(tenant_id), generate_series(1, %d) AS ids(id)`,
+		awsdmsCompositeKeyNumTenants,
+		awsdmsCompositeKeyRowsPerTenant,
+	),
+}
+
+// awsdmsCompositeKeyFingerprintQuery computes a fingerprint of the contents of
+// composite_key_table. The query is valid on both PostgreSQL and CockroachDB.
+const awsdmsCompositeKeyFingerprintQuery = `SELECT count(1), coalesce(md5(string_agg(
+	tenant_id::TEXT || '/' || id::TEXT || ':' || coalesce(t, ''), ',' ORDER BY tenant_id, id
+)), '')
+FROM composite_key_table`
+
+// verifyAWSDMSCompositeKey verifies the replication of composite_key_table.
+// DMS identifies the rows changed during CDC by their full primary key, so the
+// UPDATEs and DELETEs on the source must only change the rows on the target
+// matching both key columns, and not the rows of other tenants with the same
+// id.
+func verifyAWSDMSCompositeKey(
+	ctx context.Context,
+	t test.Test,
+	_ *dms.Client,
+	_ awsdmsNames,
+	sourcePGConn *pgx.Conn,
+	targetPGConn *gosql.DB,
+) error {
+	t.L().Printf("testing all data gets replicated")
+	if err := awsdmsWaitForFingerprint(
+		ctx, t, sourcePGConn, targetPGConn, "composite_key_table", awsdmsCompositeKeyFingerprintQuery,
+	); err != nil {
+		return err
+	}
+
+	const (
+		updateTenantID = 1
+		deleteTenantID = 2
+		// movedTenantID is the tenant whose rows have their id changed, which
+		// DMS replicates using the key of the rows prior to the change.
+		movedTenantID = 3
+		changedRowID  = 7
+		updateRowText = "only this tenant's row is updated"
+	)
+	for _, stmt := range []string{
+		// The new tenant has rows with the same ids as the existing tenants.
+		fmt.Sprintf(
+			`INSERT INTO composite_key_table(tenant_id, id, t) SELECT %d, i, md5(random()::text) FROM generate_series(1, %d) AS t(i)`,
+			awsdmsCompositeKeyNumTenants+1,
+			awsdmsCompositeKeyRowsPerTenant/10,
+		),
+		fmt.Sprintf(
+			`UPDATE composite_key_table SET t = '%s' WHERE tenant_id = %d AND id = %d`,
+			updateRowText, updateTenantID, changedRowID,
+		),
+		fmt.Sprintf(
+			`DELETE FROM composite_key_table WHERE tenant_id = %d AND id = %d`, deleteTenantID, changedRowID,
+		),
+		fmt.Sprintf(
+			`UPDATE composite_key_table SET id = id + %d WHERE tenant_id = %d AND id %% 100 = 0`,
+			awsdmsCompositeKeyRowsPerTenant, movedTenantID,
+		),
+	} {
+		if _, err := sourcePGConn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
 
-	waitForReplicationRetryOpts := retry.Options{
-		MaxBackoff: time.Second,
-		MaxRetries: 90,
-	}
+	t.L().Printf("testing changes only apply to the rows matching the composite key")
+	if err := awsdmsWaitForReplication(ctx, t, func() error {
+		var countOfDeletedRow int
+		if err := targetPGConn.QueryRow(
+			"SELECT count(1) FROM composite_key_table WHERE tenant_id = $1 AND id = $2",
+			deleteTenantID, changedRowID,
+		).Scan(&countOfDeletedRow); err != nil {
+			return err
+		}
+		if countOfDeletedRow != 0 {
+			return errors.Newf("expected row to be deleted, still found")
+		}
 
-	// Unfortunately validation isn't available in the SDK. For now, just assert
-	// both tables have the same number of rows.
-	t.L().Printf("testing all data gets replicated")
-	if err := func() error {
-		for r := retry.StartWithCtx(ctx, waitForReplicationRetryOpts); r.Next(); {
-			var numRows int
-			if err := targetPGConn.QueryRow("SELECT count(1) FROM test_table").Scan(&numRows); err != nil {
+		var updatedTenantIDs []int
+		rows, err := targetPGConn.Query(
+			"SELECT tenant_id FROM composite_key_table WHERE id = $1 AND t = $2 ORDER BY tenant_id",
+			changedRowID, updateRowText,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var tenantID int
+			if err := rows.Scan(&tenantID); err != nil {
 				return err
 			}
-			if numRows == awsdmsNumInitialRows {
-				return nil
-			}
-			t.L().Printf("found %d rows when expecting %d, retrying", numRows, awsdmsNumInitialRows)
+			updatedTenantIDs = append(updatedTenantIDs, tenantID)
 		}
-		return errors.Newf("failed to find target in sync")
-	}(); err != nil {
-		t.Fatal(err)
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(updatedTenantIDs) != 1 || updatedTenantIDs[0] != updateTenantID {
+			return errors.Newf(
+				"expected only the row of tenant %d to be updated, found the rows of tenants %v",
+				updateTenantID, updatedTenantIDs,
+			)
+		}
+		return nil
+	}); err != nil {
+		return err
 	}
 
-	// Now check an INSERT, UPDATE and DELETE all gets replicated.
-	const (
-		numExtraRows   = 10
-		numDeletedRows = 1
-		deleteRowID    = 55
-		updateRowID    = 742
-		updateRowText  = "from now on the baby sleeps in the crib"
+	// The fingerprint verifies that the rows of the other tenants, including
+	// those sharing ids with the changed rows, are intact.
+	return awsdmsWaitForFingerprint(
+		ctx, t, sourcePGConn, targetPGConn, "composite_key_table", awsdmsCompositeKeyFingerprintQuery,
 	)
+}
 
-	for _, stmt := range []string{
-		fmt.Sprintf(
-			`INSERT INTO test_table(id, t) SELECT i, md5(random()::text) FROM generate_series(%d, %d) AS t(i)`,
-			awsdmsNumInitialRows+1,
-			awsdmsNumInitialRows+numExtraRows,
-		),
-		fmt.Sprintf(`UPDATE test_table SET t = '%s' WHERE id = %d`, updateRowText, updateRowID),
-		fmt.Sprintf(`DELETE FROM test_table WHERE id = %d`, deleteRowID),
-	} {
-		if _, err := sourcePGConn.Exec(ctx, stmt); err != nil {
-			t.Fatal(err)
+// verifyAWSDMSTaskFailed verifies that the DMS task, whose target is
+// unreachable, fails within awsdmsWaitTimeLimit rather than hanging or
+// reporting success. The failed task is cleaned up by the teardown.
+func verifyAWSDMSTaskFailed(
+	ctx context.Context, t test.Test, dmsCli *dms.Client, names awsdmsNames, _ *pgx.Conn, _ *gosql.DB,
+) error {
+	t.L().Printf("waiting for replication task to fail")
+	failureMessage, err := waitForDMSTaskFailure(ctx, dmsCli, names)
+	if err != nil {
+		return err
+	}
+	t.L().Printf("replication task failed as expected: %s", failureMessage)
+	return nil
+}
+
+// waitForDMSTaskFailure waits for the DMS task to fail, and returns its last
+// failure message. An error is returned if the task does not fail within
+// awsdmsWaitTimeLimit.
+func waitForDMSTaskFailure(
+	ctx context.Context, dmsCli *dms.Client, names awsdmsNames,
+) (string, error) {
+	start := timeutil.Now()
+	for {
+		tasks, err := dmsCli.DescribeReplicationTasks(ctx, names.dmsDescribeTasksInput())
+		if err != nil {
+			return "", err
+		}
+		for _, task := range tasks.ReplicationTasks {
+			failureMessage := aws.ToString(task.LastFailureMessage)
+			// A task which fails after starting may be reported as stopped
+			// with a failure message rather than as failed.
+			if status := aws.ToString(task.Status); status == "failed" ||
+				(status == "stopped" && failureMessage != "") {
+				return failureMessage, nil
+			}
+		}
+		if timeutil.Since(start) > awsdmsWaitTimeLimit {
+			return "", errors.Newf("replication task did not fail after %s", awsdmsWaitTimeLimit)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(awsdmsTaskStatusPollInterval):
 		}
 	}
+}
 
-	t.L().Printf("testing all subsequent updates get replicated")
-	if err := func() error {
-		for r := retry.StartWithCtx(ctx, waitForReplicationRetryOpts); r.Next(); {
-			err := func() error {
-				var countOfDeletedRow int
-				if err := targetPGConn.QueryRow("SELECT count(1) FROM test_table WHERE id = $1", deleteRowID).Scan(&countOfDeletedRow); err != nil {
-					return err
-				}
-				if countOfDeletedRow != 0 {
-					return errors.Newf("expected row to be deleted, still found")
-				}
+// awsdmsValidatedState is the validation state of a table once all of its
+// rows have been validated.
+const awsdmsValidatedState = "Validated"
 
-				var seenText string
-				if err := targetPGConn.QueryRow("SELECT t FROM test_table WHERE id = $1", updateRowID).Scan(&seenText); err != nil {
+// awsdmsValidationFailedStates are the validation states of a table which
+// indicate that it cannot be validated, or that the validation found
+// differences between the source and the target.
+var awsdmsValidationFailedStates = map[string]struct{}{
+	"Mismatched records": {},
+	"Suspended records":  {},
+	"No primary key":     {},
+	"Table error":        {},
+	"Error":              {},
+}
+
+// waitForDMSTableValidation waits for DMS data validation to report every
+// table replicated by the DMS tasks as validated. An error is returned if the
+// validation of any table fails, or if it does not complete within
+// awsdmsWaitTimeLimit.
+func waitForDMSTableValidation(
+	ctx context.Context, t test.Test, dmsCli *dms.Client, names awsdmsNames,
+) error {
+	t.L().Printf("waiting for DMS to validate all tables")
+	start := timeutil.Now()
+	for {
+		tasks, err := dmsCli.DescribeReplicationTasks(ctx, names.dmsDescribeTasksInput())
+		if err != nil {
+			return err
+		}
+		var numTables int
+		var pending []string
+		for _, task := range tasks.ReplicationTasks {
+			paginator := dms.NewDescribeTableStatisticsPaginator(
+				dmsCli,
+				&dms.DescribeTableStatisticsInput{ReplicationTaskArn: task.ReplicationTaskArn},
+			)
+			for paginator.HasMorePages() {
+				out, err := paginator.NextPage(ctx)
+				if err != nil {
 					return err
 				}
-				if seenText != updateRowText {
-					return errors.Newf("expected row to be updated, still found %s", seenText)
+				for _, stats := range out.TableStatistics {
+					numTables++
+					tableName := fmt.Sprintf("%s.%s", aws.ToString(stats.SchemaName), aws.ToString(stats.TableName))
+					state := aws.ToString(stats.ValidationState)
+					if _, failed := awsdmsValidationFailedStates[state]; failed {
+						return errors.Newf(
+							"validation of table %s by task %s failed with state %q: %d records failed, %d suspended",
+							tableName,
+							aws.ToString(task.ReplicationTaskIdentifier),
+							state,
+							stats.ValidationFailedRecords,
+							stats.ValidationSuspendedRecords,
+						)
+					}
+					if state != awsdmsValidatedState {
+						pending = append(pending, fmt.Sprintf("%s (%s)", tableName, state))
+					}
 				}
+			}
+		}
+		if numTables > 0 && len(pending) == 0 {
+			t.L().Printf("all %d tables validated", numTables)
+			return nil
+		}
+		if timeutil.Since(start) > awsdmsWaitTimeLimit {
+			return errors.Newf(
+				"tables not validated after %s: %s", awsdmsWaitTimeLimit, strings.Join(pending, ", "),
+			)
+		}
+		t.L().Printf("waiting for the validation of %d/%d tables: %s", len(pending), numTables, strings.Join(pending, ", "))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(awsdmsTaskStatusPollInterval):
+		}
+	}
+}
 
-				var numRows int
-				if err := targetPGConn.QueryRow("SELECT count(1) FROM test_table").Scan(&numRows); err != nil {
+// collectDMSTableStatistics periodically writes the table statistics of the
+// DMS task to a file in the artifacts directory until ctx is cancelled.
+func collectDMSTableStatistics(
+	ctx context.Context, t test.Test, dmsCli *dms.Client, names awsdmsNames,
+) error {
+	f, err := os.Create(filepath.Join(t.ArtifactsDir(), awsdmsTableStatisticsArtifactsFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tasks, err := dmsCli.DescribeReplicationTasks(ctx, names.dmsDescribeTasksInput())
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(awsdmsTableStatisticsPollInterval)
+	defer ticker.Stop()
+	for {
+		for _, task := range tasks.ReplicationTasks {
+			paginator := dms.NewDescribeTableStatisticsPaginator(
+				dmsCli,
+				&dms.DescribeTableStatisticsInput{ReplicationTaskArn: task.ReplicationTaskArn},
+			)
+			for paginator.HasMorePages() {
+				out, err := paginator.NextPage(ctx)
+				if err != nil {
 					return err
 				}
-				expectedRows := awsdmsNumInitialRows + numExtraRows - numDeletedRows
-				if numRows != expectedRows {
-					return errors.Newf("found %d rows when expecting %d, retrying", numRows, expectedRows)
+				for _, stats := range out.TableStatistics {
+					if _, err := fmt.Fprintf(
+						f,
+						"%s task=%s table=%s.%s state=%s full_load_rows=%d inserts=%d updates=%d deletes=%d validation_state=%s\n",
+						timeutil.Now().Format(time.RFC3339),
+						aws.ToString(task.ReplicationTaskIdentifier),
+						aws.ToString(stats.SchemaName),
+						aws.ToString(stats.TableName),
+						aws.ToString(stats.TableState),
+						stats.FullLoadRows,
+						stats.Inserts,
+						stats.Updates,
+						stats.Deletes,
+						aws.ToString(stats.ValidationState),
+					); err != nil {
+						return err
+					}
 				}
-				return nil
-			}()
-			if err == nil {
-				return nil
 			}
-			t.L().Printf("replication not up to date, retrying: %+v", err)
 		}
-		return errors.Newf("failed to find target in sync")
-	}(); err != nil {
-		t.Fatal(err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
 	}
-
-	t.L().Printf("testing complete")
 }
 
 // setupAWSDMS sets up an RDS instance and a DMS instance which sets up a
 // migration task from the RDS instance to the CockroachDB cluster.
 func setupAWSDMS(
-	ctx context.Context, t test.Test, c cluster.Cluster, rdsCli *rds.Client, dmsCli *dms.Client,
+	ctx context.Context,
+	t test.Test,
+	c cluster.Cluster,
+	rdsCli *rds.Client,
+	dmsCli *dms.Client,
+	cfg awsdmsConfig,
+	names awsdmsNames,
+	spec awsdmsSpec,
 ) (*pgx.Conn, error) {
 	var sourcePGConn *pgx.Conn
 	if err := func() error {
 		var rdsCluster *rdstypes.DBCluster
-		var replicationARN string
+		numTasks := len(spec.parallelTableMappings)
+		if numTasks == 0 {
+			numTasks = 1
+		} else if numTasks > awsdmsMaxParallelTasks {
+			return errors.Newf(
+				"found %d parallel tasks when at most %d are supported", numTasks, awsdmsMaxParallelTasks,
+			)
+		}
+		replicationARNs := make([]string, numTasks)
 
-		var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
-		awsdmsPassword := func() string {
-			b := make([]rune, 32)
-			for i := range b {
-				b[i] = letters[rand.Intn(len(letters))]
-			}
-			return string(b)
-		}()
+		awsdmsPassword := makeAWSDMSPassword()
+		// The password is only used by CockroachDB in secure mode.
+		crdbPassword := makeAWSDMSPassword()
 
 		g := ctxgroup.WithContext(ctx)
-		g.Go(setupRDSCluster(ctx, t, rdsCli, awsdmsPassword, &rdsCluster, &sourcePGConn))
-		g.Go(setupCockroachDBCluster(ctx, t, c))
-		g.Go(setupDMSReplicationInstance(ctx, t, dmsCli, &replicationARN))
+		g.Go(setupRDSCluster(ctx, t, rdsCli, names, cfg.rdsInstanceClass, awsdmsPassword, spec.sourceSetupStmts, &rdsCluster, &sourcePGConn))
+		g.Go(setupCockroachDBCluster(ctx, t, c, spec.secure, crdbPassword, spec.targetSetupStmts))
+		for i := range replicationARNs {
+			g.Go(setupDMSReplicationInstance(
+				ctx, t, dmsCli, cfg, awsdmsParallelName(names.replicationInstance, i), &replicationARNs[i],
+			))
+		}
 
 		if err := g.Wait(); err != nil {
 			return err
 		}
 
-		if err := setupDMSEndpointsAndTask(ctx, t, c, dmsCli, rdsCluster, awsdmsPassword, replicationARN); err != nil {
+		if err := setupDMSEndpointsAndTasks(
+			ctx, t, c, dmsCli, names, rdsCluster, awsdmsPassword, crdbPassword, replicationARNs, spec,
+		); err != nil {
 			return err
 		}
 		return nil
@@ -260,18 +2389,48 @@ func setupAWSDMS(
 	return sourcePGConn, nil
 }
 
-func setupCockroachDBCluster(ctx context.Context, t test.Test, c cluster.Cluster) func() error {
+// makeAWSDMSPassword generates a random password.
+func makeAWSDMSPassword() string {
+	var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	b := make([]rune, 32)
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+// setupCockroachDBCluster starts CockroachDB, creates the user DMS uses to
+// connect and runs targetSetupStmts. If secure is set, the cluster is started
+// in secure mode and the user is created with crdbPassword.
+func setupCockroachDBCluster(
+	ctx context.Context,
+	t test.Test,
+	c cluster.Cluster,
+	secure bool,
+	crdbPassword string,
+	targetSetupStmts []string,
+) func() error {
 	return func() error {
 		t.L().Printf("setting up cockroach")
 		c.Put(ctx, t.Cockroach(), "./cockroach", c.All())
-		c.Start(ctx, t.L(), option.DefaultStartOpts(), install.MakeClusterSettings(), c.All())
+		c.Start(ctx, t.L(), option.DefaultStartOpts(), install.MakeClusterSettings(install.SecureOption(secure)), c.All())
 
+		createUserStmt := fmt.Sprintf("CREATE USER %s", awsdmsCRDBUser)
+		if secure {
+			createUserStmt = fmt.Sprintf("CREATE USER %s WITH PASSWORD '%s'", awsdmsCRDBUser, crdbPassword)
+		}
 		db := c.Conn(ctx, t.L(), 1)
-		for _, stmt := range []string{
-			fmt.Sprintf("CREATE USER %s", awsdmsCRDBUser),
+		stmts := []string{
+			createUserStmt,
 			fmt.Sprintf("GRANT admin TO %s", awsdmsCRDBUser),
+			// DMS discovers the columns of the target tables from the catalog,
+			// which includes not visible columns such as the implicit rowid or
+			// NOT VISIBLE computed columns, and sends a field for each of them
+			// in COPY statements without column names. This makes CockroachDB
+			// expect and discard these fields, rather than failing the COPY.
 			fmt.Sprintf("ALTER USER %s SET expect_and_ignore_not_visible_columns_in_copy = true", awsdmsCRDBUser),
-		} {
+		}
+		for _, stmt := range append(stmts, targetSetupStmts...) {
 			if _, err := db.Exec(stmt); err != nil {
 				return err
 			}
@@ -280,16 +2439,68 @@ func setupCockroachDBCluster(ctx context.Context, t test.Test, c cluster.Cluster
 	}
 }
 
+// checkDMSReplicationInstanceAvailable returns a descriptive error if the
+// replication instance class or allocated storage in cfg cannot be used in the
+// configured region, rather than letting CreateReplicationInstance fail with a
+// generic fault.
+func checkDMSReplicationInstanceAvailable(
+	ctx context.Context, dmsCli *dms.Client, cfg awsdmsConfig,
+) error {
+	paginator := dms.NewDescribeOrderableReplicationInstancesPaginator(
+		dmsCli,
+		&dms.DescribeOrderableReplicationInstancesInput{},
+	)
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, instance := range out.OrderableReplicationInstances {
+			if aws.ToString(instance.ReplicationInstanceClass) != cfg.replicationInstanceClass {
+				continue
+			}
+			if cfg.allocatedStorageGB < instance.MinAllocatedStorage ||
+				cfg.allocatedStorageGB > instance.MaxAllocatedStorage {
+				return errors.Newf(
+					"replication instance class %s requires between %dGB and %dGB of allocated storage, found %dGB",
+					cfg.replicationInstanceClass,
+					instance.MinAllocatedStorage,
+					instance.MaxAllocatedStorage,
+					cfg.allocatedStorageGB,
+				)
+			}
+			return nil
+		}
+	}
+	return errors.Newf(
+		"replication instance class %s is not available in region %s; %s can be used to override it",
+		cfg.replicationInstanceClass,
+		cfg.region,
+		awsdmsReplicationInstanceClassEnvVar,
+	)
+}
+
+// setupDMSReplicationInstance creates the DMS replication instance with the
+// given identifier and waits for it to be available.
 func setupDMSReplicationInstance(
-	ctx context.Context, t test.Test, dmsCli *dms.Client, replicationARN *string,
+	ctx context.Context,
+	t test.Test,
+	dmsCli *dms.Client,
+	cfg awsdmsConfig,
+	instanceName string,
+	replicationARN *string,
 ) func() error {
 	return func() error {
-		t.L().Printf("setting up DMS replication instance")
+		t.L().Printf("setting up DMS replication instance %s", instanceName)
+		if err := checkDMSReplicationInstanceAvailable(ctx, dmsCli, cfg); err != nil {
+			return err
+		}
 		createReplOut, err := dmsCli.CreateReplicationInstance(
 			ctx,
 			&dms.CreateReplicationInstanceInput{
-				ReplicationInstanceClass:      proto.String("dms.c4.large"),
-				ReplicationInstanceIdentifier: proto.String(awsdmsRoachtestDMSReplicationInstanceName),
+				ReplicationInstanceClass:      proto.String(cfg.replicationInstanceClass),
+				ReplicationInstanceIdentifier: proto.String(instanceName),
+				AllocatedStorage:              proto.Int32(cfg.allocatedStorageGB),
 			},
 		)
 		if err != nil {
@@ -297,8 +2508,10 @@ func setupDMSReplicationInstance(
 		}
 		*replicationARN = *createReplOut.ReplicationInstance.ReplicationInstanceArn
 		// Wait for replication instance to become available
-		t.L().Printf("waiting for all replication instance to be available")
-		if err := dms.NewReplicationInstanceAvailableWaiter(dmsCli).Wait(ctx, dmsDescribeInstancesInput, awsdmsWaitTimeLimit); err != nil {
+		t.L().Printf("waiting for replication instance %s to be available", instanceName)
+		if err := dms.NewReplicationInstanceAvailableWaiter(dmsCli).Wait(
+			ctx, dmsDescribeInstanceInput(instanceName), awsdmsWaitTimeLimit,
+		); err != nil {
 			return err
 		}
 		return nil
@@ -309,7 +2522,10 @@ func setupRDSCluster(
 	ctx context.Context,
 	t test.Test,
 	rdsCli *rds.Client,
+	names awsdmsNames,
+	rdsInstanceClass string,
 	awsdmsPassword string,
+	sourceSetupStmts []string,
 	rdsCluster **rdstypes.DBCluster,
 	sourcePGConn **pgx.Conn,
 ) func() error {
@@ -320,7 +2536,7 @@ func setupRDSCluster(
 			ctx,
 			&rds.CreateDBClusterParameterGroupInput{
 				DBParameterGroupFamily:      proto.String("aurora-postgresql13"),
-				DBClusterParameterGroupName: proto.String(awsdmsRoachtestDMSParameterGroup),
+				DBClusterParameterGroupName: proto.String(names.parameterGroup),
 				Description:                 proto.String("roachtest awsdms parameter groups"),
 			},
 		)
@@ -352,9 +2568,9 @@ func setupRDSCluster(
 		rdsClusterOutput, err := rdsCli.CreateDBCluster(
 			ctx,
 			&rds.CreateDBClusterInput{
-				DBClusterIdentifier:         proto.String(awsdmsRoachtestRDSClusterName),
+				DBClusterIdentifier:         proto.String(names.rdsCluster),
 				Engine:                      proto.String("aurora-postgresql"),
-				DBClusterParameterGroupName: proto.String(awsdmsRoachtestDMSParameterGroup),
+				DBClusterParameterGroupName: proto.String(names.parameterGroup),
 				MasterUsername:              proto.String(awsdmsUser),
 				MasterUserPassword:          proto.String(awsdmsPassword),
 				DatabaseName:                proto.String(awsdmsDatabase),
@@ -369,10 +2585,10 @@ func setupRDSCluster(
 		if _, err := rdsCli.CreateDBInstance(
 			ctx,
 			&rds.CreateDBInstanceInput{
-				DBInstanceClass:      proto.String("db.r5.large"),
-				DBInstanceIdentifier: proto.String(awsdmsRoachtestRDSClusterName + "-1"),
+				DBInstanceClass:      proto.String(rdsInstanceClass),
+				DBInstanceIdentifier: proto.String(names.rdsInstance()),
 				Engine:               proto.String("aurora-postgresql"),
-				DBClusterIdentifier:  proto.String(awsdmsRoachtestRDSClusterName),
+				DBClusterIdentifier:  proto.String(names.rdsCluster),
 				PubliclyAccessible:   proto.Bool(true),
 			},
 		); err != nil {
@@ -380,7 +2596,7 @@ func setupRDSCluster(
 		}
 
 		t.L().Printf("waiting for RDS instances to become available")
-		if err := rds.NewDBInstanceAvailableWaiter(rdsCli).Wait(ctx, rdsDescribeInstancesInput, awsdmsWaitTimeLimit); err != nil {
+		if err := rds.NewDBInstanceAvailableWaiter(rdsCli).Wait(ctx, names.rdsDescribeInstancesInput(), awsdmsWaitTimeLimit); err != nil {
 			return err
 		}
 		pgURL := fmt.Sprintf(
@@ -398,13 +2614,7 @@ func setupRDSCluster(
 		if err != nil {
 			return err
 		}
-		for _, stmt := range []string{
-			`CREATE TABLE test_table(id integer PRIMARY KEY, t TEXT)`,
-			fmt.Sprintf(
-				`INSERT INTO test_table(id, t) SELECT i, md5(random()::text) FROM generate_series(1, %d) AS t(i)`,
-				awsdmsNumInitialRows,
-			),
-		} {
+		for _, stmt := range sourceSetupStmts {
 			if _, err := pgConn.Exec(
 				ctx,
 				stmt,
@@ -417,14 +2627,17 @@ func setupRDSCluster(
 	}
 }
 
-func setupDMSEndpointsAndTask(
+func setupDMSEndpointsAndTasks(
 	ctx context.Context,
 	t test.Test,
 	c cluster.Cluster,
 	dmsCli *dms.Client,
+	names awsdmsNames,
 	rdsCluster *rdstypes.DBCluster,
 	awsdmsPassword string,
-	replicationARN string,
+	crdbPassword string,
+	replicationARNs []string,
+	spec awsdmsSpec,
 ) error {
 	// Setup AWS DMS to replicate to CockroachDB.
 	externalCRDBAddr, err := c.ExternalIP(ctx, t.L(), option.NodeListOption{1})
@@ -432,6 +2645,45 @@ func setupDMSEndpointsAndTask(
 		return err
 	}
 
+	crdbServerName := externalCRDBAddr[0]
+	if spec.unreachableTarget {
+		crdbServerName = awsdmsUnreachableAddr
+	}
+
+	// Password is a required field, but CockroachDB doesn't take passwords in
+	// --insecure mode. As such, put in some garbage.
+	crdbSSLMode := dmstypes.DmsSslModeValueNone
+	crdbEndpointPassword := "garbage"
+	var crdbCertificateARN *string
+	if spec.secure {
+		crdbSSLMode = dmstypes.DmsSslModeValueRequire
+		crdbEndpointPassword = crdbPassword
+		t.L().Printf("importing CockroachDB CA certificate into DMS")
+		caCert, err := c.RunWithDetailsSingleNode(ctx, t.L(), c.Node(1), "cat", "certs/ca.crt")
+		if err != nil {
+			return err
+		}
+		importCertOut, err := dmsCli.ImportCertificate(
+			ctx,
+			&dms.ImportCertificateInput{
+				CertificateIdentifier: proto.String(names.crdbCertificate),
+				CertificatePem:        proto.String(caCert.Stdout),
+			},
+		)
+		if err != nil {
+			return err
+		}
+		crdbCertificateARN = importCertOut.Certificate.CertificateArn
+	}
+
+	var sourcePGSettings *dmstypes.PostgreSQLSettings
+	if spec.sourceReplicationSlot != "" {
+		sourcePGSettings = &dmstypes.PostgreSQLSettings{
+			SlotName:   proto.String(spec.sourceReplicationSlot),
+			PluginName: dmstypes.PluginNameValueTestDecoding,
+		}
+	}
+
 	var sourceARN, targetARN string
 	for _, ep := range []struct {
 		in  dms.CreateEndpointInput
@@ -439,7 +2691,7 @@ func setupDMSEndpointsAndTask(
 	}{
 		{
 			in: dms.CreateEndpointInput{
-				EndpointIdentifier: proto.String(awsdmsRoachtestDMSRDSEndpointName),
+				EndpointIdentifier: proto.String(names.rdsEndpoint),
 				EndpointType:       dmstypes.ReplicationEndpointTypeValueSource,
 				EngineName:         proto.String("aurora-postgresql"),
 				DatabaseName:       proto.String(awsdmsDatabase),
@@ -447,23 +2699,23 @@ func setupDMSEndpointsAndTask(
 				Password:           proto.String(awsdmsPassword),
 				Port:               rdsCluster.Port,
 				ServerName:         rdsCluster.Endpoint,
+				PostgreSQLSettings: sourcePGSettings,
 			},
 			arn: &sourceARN,
 		},
 		{
 			in: dms.CreateEndpointInput{
-				EndpointIdentifier: proto.String(awsdmsRoachtestDMSCRDBEndpointName),
+				EndpointIdentifier: proto.String(names.crdbEndpoint),
 				EndpointType:       dmstypes.ReplicationEndpointTypeValueTarget,
 				EngineName:         proto.String("postgres"),
-				SslMode:            dmstypes.DmsSslModeValueNone,
+				SslMode:            crdbSSLMode,
+				CertificateArn:     crdbCertificateARN,
 				PostgreSQLSettings: &dmstypes.PostgreSQLSettings{
 					DatabaseName: proto.String(awsdmsCRDBDatabase),
 					Username:     proto.String(awsdmsCRDBUser),
-					// Password is a required field, but CockroachDB doesn't take passwords in
-					// --insecure mode. As such, put in some garbage.
-					Password:   proto.String("garbage"),
-					Port:       proto.Int32(26257),
-					ServerName: proto.String(externalCRDBAddr[0]),
+					Password:     proto.String(crdbEndpointPassword),
+					Port:         proto.Int32(26257),
+					ServerName:   proto.String(crdbServerName),
 				},
 			},
 			arn: &targetARN,
@@ -477,75 +2729,160 @@ func setupDMSEndpointsAndTask(
 		*ep.arn = *epOut.Endpoint.EndpointArn
 	}
 
-	t.L().Printf("creating replication task")
-	replTaskOut, err := dmsCli.CreateReplicationTask(
-		ctx,
-		&dms.CreateReplicationTaskInput{
-			MigrationType:             dmstypes.MigrationTypeValueFullLoadAndCdc,
-			ReplicationInstanceArn:    proto.String(replicationARN),
-			ReplicationTaskIdentifier: proto.String(awsdmsRoachtestDMSTaskName),
-			SourceEndpointArn:         proto.String(sourceARN),
-			TargetEndpointArn:         proto.String(targetARN),
-			// TODO(#migrations): when AWS API supports EnableValidation, add it here.
-			TableMappings: proto.String(`{
-    "rules": [
-        {
-            "rule-type": "selection",
-            "rule-id": "1",
-            "rule-name": "1",
-            "object-locator": {
-                "schema-name": "%",
-                "table-name": "%"
-            },
-            "rule-action": "include"
-        }
-    ]
-}`),
-		},
-	)
+	migrationType := spec.migrationType
+	if migrationType == "" {
+		migrationType = dmstypes.MigrationTypeValueFullLoadAndCdc
+	}
+	taskSettings, err := spec.taskSettings()
 	if err != nil {
 		return err
 	}
-	t.L().Printf("waiting for replication task to be ready")
-	if err := dms.NewReplicationTaskReadyWaiter(dmsCli).Wait(ctx, dmsDescribeTasksInput, awsdmsWaitTimeLimit); err != nil {
+	// Each task runs on its own replication instance. Unless the variant runs
+	// tasks in parallel, there is a single one.
+	taskTableMappings := spec.parallelTableMappings
+	if len(taskTableMappings) == 0 {
+		taskTableMappings = []awsdmsTableMappings{spec.tableMappings}
+	}
+	var taskNames []string
+	for i, mappings := range taskTableMappings {
+		tableMappings, err := mappings.marshal()
+		if err != nil {
+			return err
+		}
+		taskName := awsdmsParallelName(names.task, i)
+		if err := createAndStartDMSTask(ctx, t.L(), dmsCli, awsdmsTaskConfig{
+			name:           taskName,
+			migrationType:  migrationType,
+			startType:      spec.startType,
+			replicationARN: replicationARNs[i],
+			sourceARN:      sourceARN,
+			targetARN:      targetARN,
+			tableMappings:  tableMappings,
+			taskSettings:   taskSettings,
+		}); err != nil {
+			return err
+		}
+		taskNames = append(taskNames, taskName)
+	}
+	if spec.unreachableTarget {
+		// The task is expected to fail, which is checked by verify.
+		return nil
+	}
+	for _, taskName := range taskNames {
+		t.L().Printf("waiting for replication task %s to be running", taskName)
+		if err := dms.NewReplicationTaskRunningWaiter(dmsCli).Wait(
+			ctx, dmsDescribeTaskInput(taskName), awsdmsWaitTimeLimit,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// awsdmsTaskConfig configures a DMS task created by createAndStartDMSTask.
+type awsdmsTaskConfig struct {
+	// name is the identifier of the task, which must be described by
+	// awsdmsNames.dmsDescribeTasksInput for the task to be torn down.
+	name          string
+	migrationType dmstypes.MigrationTypeValue
+	// startType is how the task is started. If unset, tasks performing a full
+	// load reload the target, and CDC tasks start replicating.
+	startType dmstypes.StartReplicationTaskTypeValue
+	// replicationARN, sourceARN and targetARN are the ARNs of the replication
+	// instance and of the endpoints used by the task.
+	replicationARN string
+	sourceARN      string
+	targetARN      string
+	// tableMappings is the JSON encoding of the table mappings of the task.
+	tableMappings string
+	// taskSettings, if set, is the JSON encoding of the settings of the task.
+	// Otherwise, the default settings are used.
+	taskSettings string
+	// cdcStartPosition, if set, is the position on the source from which a CDC
+	// task starts replicating changes. Otherwise, it replicates the changes
+	// made from the time it starts.
+	cdcStartPosition string
+}
+
+// createAndStartDMSTask creates a DMS task, waits for it to be ready and starts
+// it according to its start type.
+func createAndStartDMSTask(
+	ctx context.Context, l *logger.Logger, dmsCli *dms.Client, cfg awsdmsTaskConfig,
+) error {
+	l.Printf("creating replication task %s (%s)", cfg.name, cfg.migrationType)
+	createInput := &dms.CreateReplicationTaskInput{
+		MigrationType:             cfg.migrationType,
+		ReplicationInstanceArn:    proto.String(cfg.replicationARN),
+		ReplicationTaskIdentifier: proto.String(cfg.name),
+		SourceEndpointArn:         proto.String(cfg.sourceARN),
+		TargetEndpointArn:         proto.String(cfg.targetARN),
+		TableMappings:             proto.String(cfg.tableMappings),
+	}
+	if cfg.taskSettings != "" {
+		createInput.ReplicationTaskSettings = proto.String(cfg.taskSettings)
+	}
+	replTaskOut, err := dmsCli.CreateReplicationTask(ctx, createInput)
+	if err != nil {
 		return err
 	}
-	t.L().Printf("starting replication task")
-	if _, err := dmsCli.StartReplicationTask(
-		ctx,
-		&dms.StartReplicationTaskInput{
-			ReplicationTaskArn:       replTaskOut.ReplicationTask.ReplicationTaskArn,
-			StartReplicationTaskType: dmstypes.StartReplicationTaskTypeValueReloadTarget,
-		},
+	l.Printf("waiting for replication task %s to be ready", cfg.name)
+	if err := dms.NewReplicationTaskReadyWaiter(dmsCli).Wait(
+		ctx, dmsDescribeTaskInput(cfg.name), awsdmsWaitTimeLimit,
 	); err != nil {
 		return err
 	}
-	t.L().Printf("waiting for replication task to be running")
-	if err := dms.NewReplicationTaskRunningWaiter(dmsCli).Wait(ctx, dmsDescribeTasksInput, awsdmsWaitTimeLimit); err != nil {
-		return err
+	startInput := &dms.StartReplicationTaskInput{
+		ReplicationTaskArn:       replTaskOut.ReplicationTask.ReplicationTaskArn,
+		StartReplicationTaskType: cfg.startType,
 	}
-	return nil
+	if startInput.StartReplicationTaskType == "" {
+		startInput.StartReplicationTaskType = dmstypes.StartReplicationTaskTypeValueReloadTarget
+		if cfg.migrationType == dmstypes.MigrationTypeValueCdc {
+			// There is no full load, so there is nothing to reload.
+			startInput.StartReplicationTaskType = dmstypes.StartReplicationTaskTypeValueStartReplication
+		}
+	}
+	if cfg.cdcStartPosition != "" {
+		startInput.CdcStartPosition = proto.String(cfg.cdcStartPosition)
+	}
+	l.Printf("starting replication task %s (%s)", cfg.name, startInput.StartReplicationTaskType)
+	_, err = dmsCli.StartReplicationTask(ctx, startInput)
+	return err
 }
 
 func isDMSResourceNotFound(err error) bool {
 	return errors.HasType(err, &dmstypes.ResourceNotFoundFault{})
 }
 
+// isDMSInvalidResourceState returns whether the error indicates that a DMS
+// resource cannot be modified in its current state, e.g. because an endpoint
+// is still used by a task or is already being deleted. Such errors are
+// expected to resolve themselves once the resource settles.
+func isDMSInvalidResourceState(err error) bool {
+	return errors.HasType(err, &dmstypes.InvalidResourceStateFault{})
+}
+
+// tearDownAWSDMS deletes the AWS resources which may have been created by the
+// variant whose resources are identified by names.
 func tearDownAWSDMS(
-	ctx context.Context, l *logger.Logger, rdsCli *rds.Client, dmsCli *dms.Client,
+	ctx context.Context, l *logger.Logger, rdsCli *rds.Client, dmsCli *dms.Client, names awsdmsNames,
 ) error {
 	if err := func() error {
-		if err := tearDownDMSTasks(ctx, l, dmsCli); err != nil {
+		if err := tearDownDMSTasks(ctx, l, dmsCli, names); err != nil {
+			return err
+		}
+		if err := tearDownDMSEndpoints(ctx, l, dmsCli, names); err != nil {
 			return err
 		}
-		if err := tearDownDMSEndpoints(ctx, l, dmsCli); err != nil {
+		// Certificates can only be deleted once no endpoints use them.
+		if err := tearDownDMSCertificates(ctx, l, dmsCli, names); err != nil {
 			return err
 		}
 
 		// Delete the replication and rds instances in parallel.
 		g := ctxgroup.WithContext(ctx)
-		g.Go(tearDownDMSInstances(ctx, l, dmsCli))
-		g.Go(tearDownRDSInstances(ctx, l, rdsCli))
+		g.Go(tearDownDMSInstances(ctx, l, dmsCli, names))
+		g.Go(tearDownRDSInstances(ctx, l, rdsCli, names))
 		return g.Wait()
 	}(); err != nil {
 		return errors.Wrapf(err, "failed to tear down DMS")
@@ -553,30 +2890,81 @@ func tearDownAWSDMS(
 	return nil
 }
 
+// stopRunningDMSTasks stops those of the given DMS tasks which are running,
+// and waits for them to be stopped.
+func stopRunningDMSTasks(
+	ctx context.Context,
+	l *logger.Logger,
+	dmsCli *dms.Client,
+	names awsdmsNames,
+	tasks []dmstypes.ReplicationTask,
+) error {
+	wasRunning := false
+	for _, task := range tasks {
+		if *task.Status == "running" {
+			l.Printf("stopping DMS task %s (arn: %s)", *task.ReplicationTaskIdentifier, *task.ReplicationTaskArn)
+			if _, err := dmsCli.StopReplicationTask(ctx, &dms.StopReplicationTaskInput{ReplicationTaskArn: task.ReplicationTaskArn}); err != nil {
+				return err
+			}
+			wasRunning = true
+		}
+	}
+	if wasRunning {
+		l.Printf("waiting for task to be stopped")
+		if err := dms.NewReplicationTaskStoppedWaiter(dmsCli).Wait(ctx, names.dmsDescribeTasksInput(), awsdmsWaitTimeLimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopDMSTasks stops the running DMS tasks, and waits for them to be stopped.
+func stopDMSTasks(ctx context.Context, l *logger.Logger, dmsCli *dms.Client, names awsdmsNames) error {
+	dmsTasks, err := dmsCli.DescribeReplicationTasks(ctx, names.dmsDescribeTasksInput())
+	if err != nil {
+		return err
+	}
+	return stopRunningDMSTasks(ctx, l, dmsCli, names, dmsTasks.ReplicationTasks)
+}
+
+// resumeDMSTasks resumes the processing of the stopped DMS tasks from their
+// last checkpoint, and waits for them to be running.
+func resumeDMSTasks(
+	ctx context.Context, l *logger.Logger, dmsCli *dms.Client, names awsdmsNames,
+) error {
+	dmsTasks, err := dmsCli.DescribeReplicationTasks(ctx, names.dmsDescribeTasksInput())
+	if err != nil {
+		return err
+	}
+	for _, task := range dmsTasks.ReplicationTasks {
+		l.Printf("resuming DMS task %s (arn: %s)", *task.ReplicationTaskIdentifier, *task.ReplicationTaskArn)
+		if _, err := dmsCli.StartReplicationTask(
+			ctx,
+			&dms.StartReplicationTaskInput{
+				ReplicationTaskArn:       task.ReplicationTaskArn,
+				StartReplicationTaskType: dmstypes.StartReplicationTaskTypeValueResumeProcessing,
+			},
+		); err != nil {
+			return err
+		}
+	}
+	l.Printf("waiting for task to be running")
+	return dms.NewReplicationTaskRunningWaiter(dmsCli).Wait(ctx, names.dmsDescribeTasksInput(), awsdmsWaitTimeLimit)
+}
+
 // tearDownDMSTasks tears down the DMS task, endpoints and replication instance
 // that may have been created.
-func tearDownDMSTasks(ctx context.Context, l *logger.Logger, dmsCli *dms.Client) error {
-	dmsTasks, err := dmsCli.DescribeReplicationTasks(ctx, dmsDescribeTasksInput)
+func tearDownDMSTasks(
+	ctx context.Context, l *logger.Logger, dmsCli *dms.Client, names awsdmsNames,
+) error {
+	dmsTasks, err := dmsCli.DescribeReplicationTasks(ctx, names.dmsDescribeTasksInput())
 	if err != nil {
 		if !isDMSResourceNotFound(err) {
 			return err
 		}
 	} else {
-		wasRunning := false
-		for _, task := range dmsTasks.ReplicationTasks {
-			if *task.Status == "running" {
-				l.Printf("stopping DMS task %s (arn: %s)", *task.ReplicationTaskIdentifier, *task.ReplicationTaskArn)
-				if _, err := dmsCli.StopReplicationTask(ctx, &dms.StopReplicationTaskInput{ReplicationTaskArn: task.ReplicationTaskArn}); err != nil {
-					return err
-				}
-				wasRunning = true
-			}
-		}
-		if wasRunning {
-			l.Printf("waiting for task to be stopped")
-			if err := dms.NewReplicationTaskStoppedWaiter(dmsCli).Wait(ctx, dmsDescribeTasksInput, awsdmsWaitTimeLimit); err != nil {
-				return err
-			}
+		if err := stopRunningDMSTasks(ctx, l, dmsCli, names, dmsTasks.ReplicationTasks); err != nil {
+			return err
 		}
 		for _, task := range dmsTasks.ReplicationTasks {
 			l.Printf("deleting DMS task %s (arn: %s)", *task.ReplicationTaskIdentifier, *task.ReplicationTaskArn)
@@ -585,15 +2973,17 @@ func tearDownDMSTasks(ctx context.Context, l *logger.Logger, dmsCli *dms.Client)
 			}
 		}
 		l.Printf("waiting for task to be deleted")
-		if err := dms.NewReplicationTaskDeletedWaiter(dmsCli).Wait(ctx, dmsDescribeTasksInput, awsdmsWaitTimeLimit); err != nil {
+		if err := dms.NewReplicationTaskDeletedWaiter(dmsCli).Wait(ctx, names.dmsDescribeTasksInput(), awsdmsWaitTimeLimit); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func tearDownDMSEndpoints(ctx context.Context, l *logger.Logger, dmsCli *dms.Client) error {
-	for _, ep := range []string{awsdmsRoachtestDMSRDSEndpointName, awsdmsRoachtestDMSCRDBEndpointName} {
+func tearDownDMSEndpoints(
+	ctx context.Context, l *logger.Logger, dmsCli *dms.Client, names awsdmsNames,
+) error {
+	for _, ep := range []string{names.rdsEndpoint, names.crdbEndpoint} {
 		dmsEndpoints, err := dmsCli.DescribeEndpoints(ctx, &dms.DescribeEndpointsInput{
 			Filters: []dmstypes.Filter{
 				{
@@ -609,8 +2999,31 @@ func tearDownDMSEndpoints(ctx context.Context, l *logger.Logger, dmsCli *dms.Cli
 		} else {
 			for _, dmsEndpoint := range dmsEndpoints.Endpoints {
 				l.Printf("deleting DMS endpoint %s (arn: %s)", *dmsEndpoint.EndpointIdentifier, *dmsEndpoint.EndpointArn)
-				if _, err := dmsCli.DeleteEndpoint(ctx, &dms.DeleteEndpointInput{EndpointArn: dmsEndpoint.EndpointArn}); err != nil {
-					return err
+
+				// This can fail if a previous run was interrupted, as the endpoint
+				// may still be used by a task that is being deleted, or may itself
+				// be in the middle of being deleted. Retry until it settles, at
+				// which point it is either deleted or already gone.
+				r := retry.StartWithCtx(ctx, retry.Options{
+					InitialBackoff: 10 * time.Second,
+					MaxBackoff:     time.Minute,
+					MaxRetries:     30,
+				})
+				var lastErr error
+				for r.Next() {
+					_, err := dmsCli.DeleteEndpoint(ctx, &dms.DeleteEndpointInput{EndpointArn: dmsEndpoint.EndpointArn})
+					if err == nil || isDMSResourceNotFound(err) {
+						lastErr = nil
+						break
+					}
+					if !isDMSInvalidResourceState(err) {
+						return err
+					}
+					lastErr = err
+					l.Printf("expected error: failed to delete DMS endpoint %s, retrying: %+v", *dmsEndpoint.EndpointIdentifier, err)
+				}
+				if lastErr != nil {
+					return errors.Wrapf(lastErr, "failed to delete DMS endpoint %s", *dmsEndpoint.EndpointIdentifier)
 				}
 			}
 		}
@@ -618,9 +3031,30 @@ func tearDownDMSEndpoints(ctx context.Context, l *logger.Logger, dmsCli *dms.Cli
 	return nil
 }
 
-func tearDownDMSInstances(ctx context.Context, l *logger.Logger, dmsCli *dms.Client) func() error {
+func tearDownDMSCertificates(
+	ctx context.Context, l *logger.Logger, dmsCli *dms.Client, names awsdmsNames,
+) error {
+	dmsCertificates, err := dmsCli.DescribeCertificates(ctx, names.dmsDescribeCertificatesInput())
+	if err != nil {
+		if !isDMSResourceNotFound(err) {
+			return err
+		}
+	} else {
+		for _, cert := range dmsCertificates.Certificates {
+			l.Printf("deleting DMS certificate %s (arn: %s)", *cert.CertificateIdentifier, *cert.CertificateArn)
+			if _, err := dmsCli.DeleteCertificate(ctx, &dms.DeleteCertificateInput{CertificateArn: cert.CertificateArn}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func tearDownDMSInstances(
+	ctx context.Context, l *logger.Logger, dmsCli *dms.Client, names awsdmsNames,
+) func() error {
 	return func() error {
-		dmsInstances, err := dmsCli.DescribeReplicationInstances(ctx, dmsDescribeInstancesInput)
+		dmsInstances, err := dmsCli.DescribeReplicationInstances(ctx, names.dmsDescribeInstancesInput())
 		if err != nil {
 			if !isDMSResourceNotFound(err) {
 				return err
@@ -637,7 +3071,7 @@ func tearDownDMSInstances(ctx context.Context, l *logger.Logger, dmsCli *dms.Cli
 
 			// Wait for the replication instance to be deleted.
 			l.Printf("waiting for all replication instances to be deleted")
-			if err := dms.NewReplicationInstanceDeletedWaiter(dmsCli).Wait(ctx, dmsDescribeInstancesInput, awsdmsWaitTimeLimit); err != nil {
+			if err := dms.NewReplicationInstanceDeletedWaiter(dmsCli).Wait(ctx, names.dmsDescribeInstancesInput(), awsdmsWaitTimeLimit); err != nil {
 				return err
 			}
 		}
@@ -645,9 +3079,11 @@ func tearDownDMSInstances(ctx context.Context, l *logger.Logger, dmsCli *dms.Cli
 	}
 }
 
-func tearDownRDSInstances(ctx context.Context, l *logger.Logger, rdsCli *rds.Client) func() error {
+func tearDownRDSInstances(
+	ctx context.Context, l *logger.Logger, rdsCli *rds.Client, names awsdmsNames,
+) func() error {
 	return func() error {
-		rdsInstances, err := rdsCli.DescribeDBInstances(ctx, rdsDescribeInstancesInput)
+		rdsInstances, err := rdsCli.DescribeDBInstances(ctx, names.rdsDescribeInstancesInput())
 		if err != nil {
 			if !errors.HasType(err, &rdstypes.ResourceNotFoundFault{}) {
 				return err
@@ -667,14 +3103,14 @@ func tearDownRDSInstances(ctx context.Context, l *logger.Logger, rdsCli *rds.Cli
 				}
 			}
 			l.Printf("waiting for all cluster db instances to be deleted")
-			if err := rds.NewDBInstanceDeletedWaiter(rdsCli).Wait(ctx, rdsDescribeInstancesInput, awsdmsWaitTimeLimit); err != nil {
+			if err := rds.NewDBInstanceDeletedWaiter(rdsCli).Wait(ctx, names.rdsDescribeInstancesInput(), awsdmsWaitTimeLimit); err != nil {
 				return err
 			}
 		}
 
 		// Delete RDS clusters that may be created.
 		rdsClusters, err := rdsCli.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
-			Filters: rdsClusterFilters,
+			Filters: names.rdsClusterFilters(),
 		})
 		if err != nil {
 			if !errors.HasType(err, &rdstypes.ResourceNotFoundFault{}) {
@@ -695,7 +3131,7 @@ func tearDownRDSInstances(ctx context.Context, l *logger.Logger, rdsCli *rds.Cli
 			}
 		}
 		rdsParamGroups, err := rdsCli.DescribeDBClusterParameterGroups(ctx, &rds.DescribeDBClusterParameterGroupsInput{
-			DBClusterParameterGroupName: proto.String(awsdmsRoachtestDMSParameterGroup),
+			DBClusterParameterGroupName: proto.String(names.parameterGroup),
 		})
 		if err != nil {
 			// Sometimes they don't deserialize to DBClusterParameterGroupNotFoundFault :\.
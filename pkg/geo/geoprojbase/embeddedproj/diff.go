@@ -0,0 +1,153 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package embeddedproj
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DataDiff describes the changes between two Data sets. Projections are
+// identified by their SRID and spheroids by their hash; all the slices are
+// sorted by them.
+type DataDiff struct {
+	AddedProjections    []Projection
+	RemovedProjections  []Projection
+	ModifiedProjections []ProjectionChange
+	AddedSpheroids      []Spheroid
+	RemovedSpheroids    []Spheroid
+	ModifiedSpheroids   []SpheroidChange
+}
+
+// ProjectionChange describes the changes to the projection with SRID.
+type ProjectionChange struct {
+	SRID    int
+	Changes []FieldChange
+}
+
+// SpheroidChange describes the changes to the spheroid with Hash.
+type SpheroidChange struct {
+	Hash    int64
+	Changes []FieldChange
+}
+
+// FieldChange describes the change of a field, with its old and new values
+// formatted for display.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Diff returns the changes from oldData to newData.
+func Diff(oldData, newData Data) DataDiff {
+	var diff DataDiff
+	for _, p := range newData.Projections {
+		if o, ok := oldData.Projection(p.SRID); !ok {
+			diff.AddedProjections = append(diff.AddedProjections, p)
+		} else if changes := diffFields(*o, p); len(changes) > 0 {
+			diff.ModifiedProjections = append(diff.ModifiedProjections,
+				ProjectionChange{SRID: p.SRID, Changes: changes})
+		}
+	}
+	for _, p := range oldData.Projections {
+		if _, ok := newData.Projection(p.SRID); !ok {
+			diff.RemovedProjections = append(diff.RemovedProjections, p)
+		}
+	}
+	for _, s := range newData.Spheroids {
+		if o, ok := oldData.SpheroidByHash(s.Hash); !ok {
+			diff.AddedSpheroids = append(diff.AddedSpheroids, s)
+		} else if changes := diffFields(*o, s); len(changes) > 0 {
+			diff.ModifiedSpheroids = append(diff.ModifiedSpheroids,
+				SpheroidChange{Hash: s.Hash, Changes: changes})
+		}
+	}
+	for _, s := range oldData.Spheroids {
+		if _, ok := newData.SpheroidByHash(s.Hash); !ok {
+			diff.RemovedSpheroids = append(diff.RemovedSpheroids, s)
+		}
+	}
+
+	sortProjections := func(ps []Projection) {
+		sort.Slice(ps, func(i, j int) bool { return ps[i].SRID < ps[j].SRID })
+	}
+	sortSpheroids := func(ss []Spheroid) {
+		sort.Slice(ss, func(i, j int) bool { return ss[i].Hash < ss[j].Hash })
+	}
+	sortProjections(diff.AddedProjections)
+	sortProjections(diff.RemovedProjections)
+	sort.Slice(diff.ModifiedProjections, func(i, j int) bool {
+		return diff.ModifiedProjections[i].SRID < diff.ModifiedProjections[j].SRID
+	})
+	sortSpheroids(diff.AddedSpheroids)
+	sortSpheroids(diff.RemovedSpheroids)
+	sort.Slice(diff.ModifiedSpheroids, func(i, j int) bool {
+		return diff.ModifiedSpheroids[i].Hash < diff.ModifiedSpheroids[j].Hash
+	})
+	return diff
+}
+
+// diffFields returns the changes to the fields of the struct oldVal, which
+// must have the same type as newVal.
+func diffFields(oldVal, newVal interface{}) []FieldChange {
+	var changes []FieldChange
+	ov, nv := reflect.ValueOf(oldVal), reflect.ValueOf(newVal)
+	for i := 0; i < ov.NumField(); i++ {
+		o, n := ov.Field(i).Interface(), nv.Field(i).Interface()
+		if !reflect.DeepEqual(o, n) {
+			changes = append(changes, FieldChange{
+				Field: ov.Type().Field(i).Name,
+				Old:   fmt.Sprintf("%+v", o),
+				New:   fmt.Sprintf("%+v", n),
+			})
+		}
+	}
+	return changes
+}
+
+// Empty returns whether there are no changes.
+func (d DataDiff) Empty() bool {
+	return len(d.AddedProjections) == 0 && len(d.RemovedProjections) == 0 &&
+		len(d.ModifiedProjections) == 0 && len(d.AddedSpheroids) == 0 &&
+		len(d.RemovedSpheroids) == 0 && len(d.ModifiedSpheroids) == 0
+}
+
+// String returns a human-readable changelog, with one line per added or
+// removed projection or spheroid and per changed field.
+func (d DataDiff) String() string {
+	var b strings.Builder
+	for _, s := range d.AddedSpheroids {
+		fmt.Fprintf(&b, "+ spheroid %x: radius %v, flattening %v\n", s.Hash, s.Radius, s.Flattening)
+	}
+	for _, s := range d.RemovedSpheroids {
+		fmt.Fprintf(&b, "- spheroid %x\n", s.Hash)
+	}
+	for _, c := range d.ModifiedSpheroids {
+		for _, f := range c.Changes {
+			fmt.Fprintf(&b, "~ spheroid %x: %s: %s -> %s\n", c.Hash, f.Field, f.Old, f.New)
+		}
+	}
+	for _, p := range d.AddedProjections {
+		fmt.Fprintf(&b, "+ SRID %d: %s\n", p.SRID, p.Proj4Text)
+	}
+	for _, p := range d.RemovedProjections {
+		fmt.Fprintf(&b, "- SRID %d\n", p.SRID)
+	}
+	for _, c := range d.ModifiedProjections {
+		for _, f := range c.Changes {
+			fmt.Fprintf(&b, "~ SRID %d: %s: %s -> %s\n", c.SRID, f.Field, f.Old, f.New)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package embeddedproj
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	oldData := testData()
+	require.True(t, Diff(oldData, testData()).Empty())
+
+	newData := testData()
+	newData.Spheroids[1].Flattening = 0
+	newData.Spheroids = append(newData.Spheroids, Spheroid{Hash: 3, Radius: 1, Flattening: 0.5})
+	newData.Projections[0].Proj4Text = "+proj=longlat +ellps=WGS84 +no_defs"
+	newData.Projections[0].Bounds.MinX = -179
+	newData.Projections[1] = Projection{SRID: 3000, Proj4Text: "+proj=merc", Spheroid: 3}
+
+	diff := Diff(oldData, newData)
+	require.False(t, diff.Empty())
+	require.Equal(t, DataDiff{
+		AddedProjections:   []Projection{newData.Projections[1]},
+		RemovedProjections: []Projection{oldData.Projections[1]},
+		ModifiedProjections: []ProjectionChange{{
+			SRID: 4326,
+			Changes: []FieldChange{
+				{
+					Field: "Proj4Text",
+					Old:   "+proj=longlat +datum=WGS84 +no_defs",
+					New:   "+proj=longlat +ellps=WGS84 +no_defs",
+				},
+				{
+					Field: "Bounds",
+					Old:   "{MinX:-180 MaxX:180 MinY:-90 MaxY:90}",
+					New:   "{MinX:-179 MaxX:180 MinY:-90 MaxY:90}",
+				},
+			},
+		}},
+		AddedSpheroids: []Spheroid{{Hash: 3, Radius: 1, Flattening: 0.5}},
+		ModifiedSpheroids: []SpheroidChange{{
+			Hash:    2,
+			Changes: []FieldChange{{Field: "Flattening", Old: "0.003367003367003367", New: "0"}},
+		}},
+	}, diff)
+
+	require.Equal(t, `+ spheroid 3: radius 1, flattening 0.5
+~ spheroid 2: Flattening: 0.003367003367003367 -> 0
++ SRID 3000: +proj=merc
+- SRID 2000
+~ SRID 4326: Proj4Text: +proj=longlat +datum=WGS84 +no_defs -> +proj=longlat +ellps=WGS84 +no_defs
+~ SRID 4326: Bounds: {MinX:-180 MaxX:180 MinY:-90 MaxY:90} -> {MinX:-179 MaxX:180 MinY:-90 MaxY:90}
+`, diff.String())
+}
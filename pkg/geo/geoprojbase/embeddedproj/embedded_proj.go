@@ -14,9 +14,22 @@
 package embeddedproj
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
+	"embed"
+	"encoding/binary"
+	"encoding/gob"
 	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/crc32"
 	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
 )
 
 // Spheroid stores the metadata for a spheroid. Each spheroid is referenced by
@@ -54,33 +67,533 @@ type Bounds struct {
 type Data struct {
 	Spheroids   []Spheroid
 	Projections []Projection
+
+	// index is built by the first lookup and reused by later ones. It is not
+	// serialized.
+	index *dataIndex
+}
+
+// dataIndex maps SRIDs and hashes to the projections and spheroids of Data,
+// and authority names to their projections.
+type dataIndex struct {
+	projections map[int]*Projection
+	spheroids   map[int64]*Spheroid
+	byAuthName  map[string][]*Projection
+}
+
+// getIndex returns the index of the data, building it if necessary. The index
+// must be reset if Spheroids or Projections are reallocated.
+func (d *Data) getIndex() *dataIndex {
+	if d.index != nil {
+		return d.index
+	}
+	idx := &dataIndex{
+		projections: make(map[int]*Projection, len(d.Projections)),
+		spheroids:   make(map[int64]*Spheroid, len(d.Spheroids)),
+		byAuthName:  make(map[string][]*Projection),
+	}
+	for i := range d.Projections {
+		p := &d.Projections[i]
+		idx.projections[p.SRID] = p
+		idx.byAuthName[p.AuthName] = append(idx.byAuthName[p.AuthName], p)
+	}
+	for i := range d.Spheroids {
+		idx.spheroids[d.Spheroids[i].Hash] = &d.Spheroids[i]
+	}
+	d.index = idx
+	return idx
+}
+
+// Projection returns the projection with the given SRID, if any. The first
+// lookup builds an index of the data, so Data must not be looked up
+// concurrently before that.
+func (d *Data) Projection(srid int) (*Projection, bool) {
+	p, ok := d.getIndex().projections[srid]
+	return p, ok
+}
+
+// SpheroidByHash returns the spheroid with the given hash, if any. Like
+// Projection, the first lookup builds an index of the data.
+func (d *Data) SpheroidByHash(h int64) (*Spheroid, bool) {
+	s, ok := d.getIndex().spheroids[h]
+	return s, ok
 }
 
-// Encode writes serializes Data as a gzip-compressed json.
+// ProjectionsByAuthName returns the projections under the given authority, e.g.
+// "EPSG", in the order of Projections. Like Projection, the first lookup builds
+// an index of the data. The returned slice must not be modified.
+func (d *Data) ProjectionsByAuthName(auth string) []*Projection {
+	return d.getIndex().byAuthName[auth]
+}
+
+// Validate checks that every projection references a spheroid in the data, has
+// well-formed bounds and a unique SRID. The returned error lists all the
+// problems found. Whether the data can be encoded losslessly is only checked
+// by EncodeWithOptions.
+func (d *Data) Validate() error {
+	spheroids := make(map[int64]struct{}, len(d.Spheroids))
+	for _, s := range d.Spheroids {
+		spheroids[s.Hash] = struct{}{}
+	}
+
+	var problems []string
+	srids := make(map[int]struct{}, len(d.Projections))
+	for _, p := range d.Projections {
+		if _, ok := spheroids[p.Spheroid]; !ok {
+			problems = append(problems,
+				fmt.Sprintf("SRID %d references unknown spheroid %x", p.SRID, p.Spheroid))
+		}
+		if p.Bounds.MinX > p.Bounds.MaxX || p.Bounds.MinY > p.Bounds.MaxY {
+			problems = append(problems, fmt.Sprintf("SRID %d has invalid bounds %+v", p.SRID, p.Bounds))
+		}
+		if _, ok := srids[p.SRID]; ok {
+			problems = append(problems, fmt.Sprintf("SRID %d is duplicated", p.SRID))
+		}
+		srids[p.SRID] = struct{}{}
+	}
+	if len(problems) > 0 {
+		return errors.Newf("invalid embedded projection data: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// roundTripProblems returns the values of the data which cannot be encoded and
+// decoded without loss in every format: JSON cannot represent non-finite
+// floats and replaces invalid UTF-8 in strings, and gob decodes negative zeros
+// as positive zeros.
+func (d *Data) roundTripProblems() []string {
+	var problems []string
+	for _, s := range d.Spheroids {
+		if !isEncodable(s.Radius) || !isEncodable(s.Flattening) {
+			problems = append(problems, fmt.Sprintf("spheroid %x has unencodable parameters %+v", s.Hash, s))
+		}
+	}
+	for _, p := range d.Projections {
+		b := p.Bounds
+		if !isEncodable(b.MinX) || !isEncodable(b.MaxX) || !isEncodable(b.MinY) || !isEncodable(b.MaxY) {
+			problems = append(problems, fmt.Sprintf("SRID %d has unencodable bounds %+v", p.SRID, b))
+		}
+		for _, f := range []struct {
+			name, value string
+		}{
+			{name: "AuthName", value: p.AuthName},
+			{name: "SRText", value: p.SRText},
+			{name: "Proj4Text", value: p.Proj4Text},
+		} {
+			if !utf8.ValidString(f.value) {
+				problems = append(problems, fmt.Sprintf("SRID %d has invalid UTF-8 in %s", p.SRID, f.name))
+			}
+		}
+	}
+	return problems
+}
+
+// isEncodable returns whether the float survives a round trip in every format,
+// i.e. whether it is finite and not a negative zero.
+func isEncodable(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0) && !(f == 0 && math.Signbit(f))
+}
+
+// Add adds a projection, and optionally the spheroid it references, to the
+// data, keeping any index up to date. If s is nil, p must reference a spheroid
+// already in the data. Adding a projection with an existing SRID fails unless
+// overwrite is set, in which case the existing projection is replaced.
+func (d *Data) Add(p Projection, s *Spheroid, overwrite bool) error {
+	if p.Bounds.MinX > p.Bounds.MaxX || p.Bounds.MinY > p.Bounds.MaxY {
+		return errors.Newf("SRID %d has invalid bounds %+v", p.SRID, p.Bounds)
+	}
+	idx := d.getIndex()
+	if s != nil {
+		if s.Hash != p.Spheroid {
+			return errors.Newf("SRID %d references spheroid %x, but spheroid %x was provided",
+				p.SRID, p.Spheroid, s.Hash)
+		}
+		if existing, ok := idx.spheroids[s.Hash]; ok && *existing != *s {
+			return errors.Newf("spheroid %x already exists with different parameters", s.Hash)
+		}
+	} else if _, ok := idx.spheroids[p.Spheroid]; !ok {
+		return errors.Newf("SRID %d references unknown spheroid %x", p.SRID, p.Spheroid)
+	}
+	existing, exists := idx.projections[p.SRID]
+	if exists && !overwrite {
+		return errors.Newf("SRID %d already exists", p.SRID)
+	}
+
+	if s != nil {
+		if _, ok := idx.spheroids[s.Hash]; !ok {
+			d.Spheroids = append(d.Spheroids, *s)
+		}
+	}
+	if exists {
+		*existing = p
+	} else {
+		d.Projections = append(d.Projections, p)
+	}
+	// Appending may have reallocated the slices, so rebuild the index.
+	d.index = nil
+	d.getIndex()
+	return nil
+}
+
+// Format is the serialization format of Data.
+type Format byte
+
+const (
+	// FormatJSON serializes Data as indented JSON, which is easy to inspect.
+	FormatJSON Format = iota
+	// FormatGob serializes Data using encoding/gob, which is more compact and
+	// faster to deserialize.
+	FormatGob
+)
+
+// String implements the fmt.Stringer interface.
+func (f Format) String() string {
+	switch f {
+	case FormatJSON:
+		return "json"
+	case FormatGob:
+		return "gob"
+	default:
+		return fmt.Sprintf("Format(%d)", byte(f))
+	}
+}
+
+// Version is the version of the encoding written by Encode. It must be bumped
+// whenever the schema of Data or the layout of the header changes.
+const Version = 1
+
+// The header written by Encode consists of magic, the version of the encoding,
+// the format of the data, and the length and CRC32 checksum of the
+// uncompressed payload that follows it. Data without the header is
+// gzip-compressed JSON, which predates versioning.
+var magic = []byte("CRPJ")
+
+const (
+	versionOffset  = 4
+	formatOffset   = versionOffset + 2
+	lengthOffset   = formatOffset + 1
+	checksumOffset = lengthOffset + 4
+	headerLen      = checksumOffset + 4
+)
+
+// EncodeOptions are the options of EncodeWithOptions.
+type EncodeOptions struct {
+	// Format is the serialization format of the data.
+	Format Format
+	// CompressionLevel is the gzip compression level of the data. If zero,
+	// gzip.BestCompression is used; gzip.NoCompression can thus not be
+	// requested, but gzip.HuffmanOnly and gzip.BestSpeed are cheap alternatives.
+	CompressionLevel int
+}
+
+// Encode serializes Data as JSON compressed with gzip.BestCompression.
 func Encode(d Data, w io.Writer) error {
-	data, err := json.MarshalIndent(d, "", " ")
-	if err != nil {
+	return EncodeWithOptions(d, w, EncodeOptions{})
+}
+
+// EncodeWithOptions serializes Data in the specified format, after a header
+// identifying the format and checksumming the payload, and compresses it with
+// gzip. Data which Decode could not return unchanged in every format, e.g.
+// because of non-finite floats or invalid UTF-8, is rejected regardless of the
+// format.
+func EncodeWithOptions(d Data, w io.Writer, opts EncodeOptions) error {
+	if problems := d.roundTripProblems(); len(problems) > 0 {
+		return errors.Newf("embedded projection data cannot be encoded losslessly: %s",
+			strings.Join(problems, "; "))
+	}
+	var buf bytes.Buffer
+	switch opts.Format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(d, "", " ")
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	case FormatGob:
+		if err := gob.NewEncoder(&buf).Encode(d); err != nil {
+			return err
+		}
+	default:
+		return errors.Newf("unknown embedded projection data format %s", opts.Format)
+	}
+
+	header := make([]byte, headerLen)
+	copy(header, magic)
+	binary.BigEndian.PutUint16(header[versionOffset:], Version)
+	header[formatOffset] = byte(opts.Format)
+	binary.BigEndian.PutUint32(header[lengthOffset:], uint32(buf.Len()))
+	binary.BigEndian.PutUint32(header[checksumOffset:], crc32.ChecksumIEEE(buf.Bytes()))
+	if _, err := w.Write(header); err != nil {
 		return err
 	}
-	zw, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	level := opts.CompressionLevel
+	if level == 0 {
+		level = gzip.BestCompression
+	}
+	zw, err := gzip.NewWriterLevel(w, level)
 	if err != nil {
 		return err
 	}
-	if _, err := zw.Write(data); err != nil {
+	if _, err := zw.Write(buf.Bytes()); err != nil {
 		return err
 	}
 	return zw.Close()
 }
 
-// Decode deserializes Data from a gzip-compressed json generated by Encode().
+// DecodeOptions are the options of DecodeWithOptions.
+type DecodeOptions struct {
+	// Validate, if set, validates the decoded data using Data.Validate.
+	Validate bool
+}
+
+// Decode deserializes Data generated by Encode() or EncodeWithOptions(),
+// detecting its format from its header and verifying its version and checksum.
 func Decode(r io.Reader) (Data, error) {
-	zr, err := gzip.NewReader(r)
+	return DecodeWithOptions(r, DecodeOptions{})
+}
+
+// DecodeWithOptions is like Decode, but with the specified options.
+func DecodeWithOptions(r io.Reader, opts DecodeOptions) (Data, error) {
+	spheroids, next, err := DecodeStream(r)
 	if err != nil {
 		return Data{}, err
 	}
-	var result Data
-	if err := json.NewDecoder(zr).Decode(&result); err != nil {
-		return Data{}, err
+	result := Data{Spheroids: spheroids}
+	for {
+		p, err := next()
+		if err != nil {
+			return Data{}, err
+		}
+		if p == nil {
+			break
+		}
+		result.Projections = append(result.Projections, *p)
+	}
+	if opts.Validate {
+		if err := result.Validate(); err != nil {
+			return Data{}, err
+		}
 	}
 	return result, nil
 }
+
+// DecodeStream is like Decode, but only decodes the spheroids eagerly. The
+// projections are decoded one at a time by the returned function, which
+// returns nil once all of them have been returned, so that they don't need to
+// be held in memory at once. The version and the checksum of the data are
+// verified, but the checksum can only be verified once all the projections
+// have been returned, so the function can return an error at that point.
+//
+// Only data in the JSON format can be streamed; data in the gob format is
+// decoded at once.
+func DecodeStream(r io.Reader) ([]Spheroid, func() (*Projection, error), error) {
+	br := bufio.NewReader(r)
+	format := FormatJSON
+	var header []byte
+	if prefix, err := br.Peek(len(magic)); err == nil && bytes.Equal(prefix, magic) {
+		header = make([]byte, headerLen)
+		if _, err := io.ReadFull(br, header[:formatOffset]); err != nil {
+			return nil, nil, errors.New("embedded projection data corrupt: truncated header")
+		}
+		// The rest of the header depends on the version, so check it first.
+		if v := binary.BigEndian.Uint16(header[versionOffset:]); v != Version {
+			return nil, nil, errors.Newf(
+				"unsupported embedded projection data version %d, expected version %d", v, Version)
+		}
+		if _, err := io.ReadFull(br, header[formatOffset:]); err != nil {
+			return nil, nil, errors.New("embedded projection data corrupt: truncated header")
+		}
+		format = Format(header[formatOffset])
+	}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload := &payloadReader{r: zr, header: header, checksum: crc32.NewIEEE()}
+
+	switch format {
+	case FormatJSON:
+		it := &projectionIterator{dec: json.NewDecoder(payload), payload: payload}
+		if err := it.expectDelim('{'); err != nil {
+			return nil, nil, err
+		}
+		if err := it.advance(); err != nil {
+			return nil, nil, err
+		}
+		return it.spheroids, it.next, nil
+	case FormatGob:
+		var result Data
+		if err := gob.NewDecoder(payload).Decode(&result); err != nil {
+			return nil, nil, err
+		}
+		if err := payload.verify(); err != nil {
+			return nil, nil, err
+		}
+		projections := result.Projections
+		return result.Spheroids, func() (*Projection, error) {
+			if len(projections) == 0 {
+				return nil, nil
+			}
+			p := &projections[0]
+			projections = projections[1:]
+			return p, nil
+		}, nil
+	default:
+		return nil, nil, errors.Newf("unknown embedded projection data format %s", format)
+	}
+}
+
+// payloadReader reads the uncompressed payload of the data, keeping track of
+// its length and checksum so that they can be verified against the header.
+type payloadReader struct {
+	r io.Reader
+	// header is the header of the data, or nil for legacy data.
+	header   []byte
+	length   int
+	checksum hash.Hash32
+}
+
+// Read implements the io.Reader interface.
+func (r *payloadReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.length += n
+	_, _ = r.checksum.Write(p[:n])
+	return n, err
+}
+
+// verify reads the rest of the payload and verifies its length and checksum.
+func (r *payloadReader) verify() error {
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return err
+	}
+	if r.header == nil {
+		return nil
+	}
+	if uint32(r.length) != binary.BigEndian.Uint32(r.header[lengthOffset:]) {
+		return errors.New("embedded projection data corrupt: length mismatch")
+	}
+	if r.checksum.Sum32() != binary.BigEndian.Uint32(r.header[checksumOffset:]) {
+		return errors.New("embedded projection data corrupt: checksum mismatch")
+	}
+	return nil
+}
+
+// projectionIterator decodes the JSON serialization of Data one projection at
+// a time.
+type projectionIterator struct {
+	dec     *json.Decoder
+	payload *payloadReader
+
+	spheroids []Spheroid
+	// seenProjections is set once the projections have been reached.
+	seenProjections bool
+	// inProjections is set while the decoder is within the projections array.
+	inProjections bool
+	done          bool
+	err           error
+}
+
+func (it *projectionIterator) expectDelim(delim json.Delim) error {
+	tok, err := it.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return errors.Newf("embedded projection data corrupt: expected %s, found %v", delim, tok)
+	}
+	return nil
+}
+
+// advance decodes the fields of Data until it reaches the next projection or
+// the end of the data, in which case the payload is verified.
+func (it *projectionIterator) advance() error {
+	for it.dec.More() {
+		tok, err := it.dec.Token()
+		if err != nil {
+			return err
+		}
+		// Like encoding/json, match the field names case-insensitively.
+		key, _ := tok.(string)
+		switch {
+		case strings.EqualFold(key, "Spheroids"):
+			if it.seenProjections {
+				// The projections may need the spheroids, so they must come
+				// first, as they do when encoded by Encode.
+				return errors.New("embedded projection data corrupt: spheroids after projections")
+			}
+			if err := it.dec.Decode(&it.spheroids); err != nil {
+				return err
+			}
+		case strings.EqualFold(key, "Projections"):
+			if it.seenProjections {
+				return errors.New("embedded projection data corrupt: duplicated projections")
+			}
+			it.seenProjections = true
+			tok, err := it.dec.Token()
+			if err != nil {
+				return err
+			}
+			if tok == nil {
+				// The projections are null.
+				continue
+			}
+			if tok != json.Delim('[') {
+				return errors.Newf("embedded projection data corrupt: expected [, found %v", tok)
+			}
+			it.inProjections = true
+			return nil
+		default:
+			var skipped json.RawMessage
+			if err := it.dec.Decode(&skipped); err != nil {
+				return err
+			}
+		}
+	}
+	if err := it.expectDelim('}'); err != nil {
+		return err
+	}
+	it.done = true
+	return it.payload.verify()
+}
+
+// next returns the next projection, or nil if there are no more projections.
+func (it *projectionIterator) next() (*Projection, error) {
+	if it.err != nil || it.done {
+		return nil, it.err
+	}
+	for it.inProjections {
+		if it.dec.More() {
+			p := &Projection{}
+			if err := it.dec.Decode(p); err != nil {
+				it.err = err
+				return nil, err
+			}
+			return p, nil
+		}
+		it.inProjections = false
+		if err := it.expectDelim(']'); err != nil {
+			it.err = err
+			return nil, err
+		}
+	}
+	if err := it.advance(); err != nil {
+		it.err = err
+		return nil, err
+	}
+	return nil, nil
+}
+
+// DecodeFromFS deserializes the Data stored in the named file of fsys, as
+// generated by Encode() or EncodeWithOptions(). See Decode.
+func DecodeFromFS(fsys embed.FS, name string) (Data, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return Data{}, errors.Wrapf(err, "opening embedded projection data %s", name)
+	}
+	defer f.Close()
+	d, err := Decode(f)
+	if err != nil {
+		return Data{}, errors.Wrapf(err, "decoding embedded projection data %s", name)
+	}
+	return d, nil
+}
@@ -0,0 +1,416 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package embeddedproj
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testData() Data {
+	return Data{
+		Spheroids: []Spheroid{
+			{Hash: 1, Radius: 6378137, Flattening: 1 / 298.257223563},
+			{Hash: 2, Radius: 6378388, Flattening: 1 / 297.0},
+		},
+		Projections: []Projection{
+			{
+				SRID:      4326,
+				AuthName:  "EPSG",
+				AuthSRID:  4326,
+				SRText:    `GEOGCS["WGS 84"]`,
+				Proj4Text: "+proj=longlat +datum=WGS84 +no_defs",
+				Bounds:    Bounds{MinX: -180, MaxX: 180, MinY: -90, MaxY: 90},
+				IsLatLng:  true,
+				Spheroid:  1,
+			},
+			{
+				SRID:      2000,
+				AuthName:  "EPSG",
+				AuthSRID:  2000,
+				Proj4Text: "+proj=tmerc +lat_0=0 +lon_0=-62 +ellps=clrk80 +units=m +no_defs",
+				Bounds:    Bounds{MinX: -1, MaxX: 1, MinY: -2, MaxY: 2},
+				Spheroid:  2,
+			},
+		},
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	d := testData()
+	for _, format := range []Format{FormatJSON, FormatGob} {
+		t.Run(format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, EncodeWithOptions(d, &buf, EncodeOptions{Format: format}))
+			require.Equal(t, magic, buf.Bytes()[:len(magic)])
+			require.Equal(t, byte(format), buf.Bytes()[formatOffset])
+
+			decoded, err := Decode(&buf)
+			require.NoError(t, err)
+			require.Equal(t, d, decoded)
+		})
+	}
+
+	t.Run("compression level", func(t *testing.T) {
+		for _, level := range []int{gzip.HuffmanOnly, gzip.BestSpeed, gzip.DefaultCompression} {
+			var buf bytes.Buffer
+			require.NoError(t, EncodeWithOptions(d, &buf, EncodeOptions{CompressionLevel: level}))
+			decoded, err := Decode(&buf)
+			require.NoError(t, err)
+			require.Equal(t, d, decoded)
+		}
+
+		var buf bytes.Buffer
+		require.EqualError(t,
+			EncodeWithOptions(d, &buf, EncodeOptions{CompressionLevel: 42}),
+			"gzip: invalid compression level: 42")
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.EqualError(t,
+			EncodeWithOptions(d, &buf, EncodeOptions{Format: 42}),
+			"unknown embedded projection data format Format(42)")
+
+		buf.Reset()
+		require.NoError(t, Encode(d, &buf))
+		b := buf.Bytes()
+		b[formatOffset] = 42
+		_, err := Decode(bytes.NewReader(b))
+		require.EqualError(t, err, "unknown embedded projection data format Format(42)")
+	})
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	d := Data{
+		Spheroids: []Spheroid{
+			{Hash: math.MinInt64, Radius: 6378137, Flattening: 0},
+			{Hash: math.MaxInt64, Radius: math.MaxFloat64, Flattening: math.SmallestNonzeroFloat64},
+			{Hash: 0, Radius: 0.1 + 0.2, Flattening: 1 / 298.257223563},
+		},
+		Projections: []Projection{
+			{
+				SRID:      -1,
+				AuthName:  "ÉPSG",
+				AuthSRID:  math.MaxInt32,
+				SRText:    `PROJCS["Réseau géodésique français 1993 / 日本測地系 ✓ 🌍"]`,
+				Proj4Text: strings.Repeat("+proj=tmerc +lat_0=0 +lon_0=-62 ", 1<<12),
+				Bounds: Bounds{
+					MinX: -math.MaxFloat64,
+					MaxX: -math.SmallestNonzeroFloat64,
+					MinY: math.Nextafter(-90, 0),
+					MaxY: math.Nextafter(-1/3.0, 0),
+				},
+				IsLatLng: true,
+				Spheroid: math.MinInt64,
+			},
+			{
+				SRID:     math.MaxInt32,
+				Bounds:   Bounds{MinX: 1e-300, MaxX: 1e300, MinY: 0, MaxY: 0},
+				Spheroid: math.MaxInt64,
+			},
+			{
+				SRID:      0,
+				SRText:    "\x00\t\"quoted\"\n</script>\u2028",
+				Proj4Text: "+proj=longlat +a=6378137.000000001",
+				Bounds:    Bounds{MinX: -180, MaxX: 180, MinY: -90, MaxY: 90},
+				Spheroid:  0,
+			},
+		},
+	}
+	require.NoError(t, d.Validate())
+
+	for _, format := range []Format{FormatJSON, FormatGob} {
+		t.Run(format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, EncodeWithOptions(d, &buf, EncodeOptions{Format: format}))
+			decoded, err := DecodeWithOptions(&buf, DecodeOptions{Validate: true})
+			require.NoError(t, err)
+			require.Equal(t, d, decoded)
+			// require.Equal considers zeros of different signs equal, so compare
+			// the bounds bit for bit.
+			for i, p := range decoded.Projections {
+				for j, f := range []float64{p.Bounds.MinX, p.Bounds.MaxX, p.Bounds.MinY, p.Bounds.MaxY} {
+					expected := d.Projections[i].Bounds
+					require.Equal(t,
+						math.Float64bits([]float64{expected.MinX, expected.MaxX, expected.MinY, expected.MaxY}[j]),
+						math.Float64bits(f), "SRID %d bound %d", p.SRID, j)
+				}
+			}
+		})
+	}
+
+	t.Run("lossy values", func(t *testing.T) {
+		lossy := testData()
+		lossy.Spheroids[0].Flattening = math.NaN()
+		lossy.Projections[0].Bounds.MaxX = math.Inf(1)
+		lossy.Projections[1].Bounds.MinX = math.Copysign(0, -1)
+		lossy.Projections[1].SRText = "\xff"
+		const problems = "spheroid 1 has unencodable parameters {Hash:1 Radius:6.378137e+06 Flattening:NaN}; " +
+			"SRID 4326 has unencodable bounds {MinX:-180 MaxX:+Inf MinY:-90 MaxY:90}; " +
+			"SRID 2000 has unencodable bounds {MinX:-0 MaxX:1 MinY:-2 MaxY:2}; " +
+			"SRID 2000 has invalid UTF-8 in SRText"
+		// The data is structurally valid, it just cannot be encoded.
+		require.NoError(t, lossy.Validate())
+		for _, format := range []Format{FormatJSON, FormatGob} {
+			var buf bytes.Buffer
+			require.EqualError(t, EncodeWithOptions(lossy, &buf, EncodeOptions{Format: format}),
+				"embedded projection data cannot be encoded losslessly: "+problems)
+			require.Zero(t, buf.Len())
+		}
+	})
+}
+
+func TestDecodeLegacy(t *testing.T) {
+	// Data generated before the header was introduced is gzip-compressed JSON.
+	d := testData()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	require.NoError(t, json.NewEncoder(zw).Encode(d))
+	require.NoError(t, zw.Close())
+
+	decoded, err := Decode(&buf)
+	require.NoError(t, err)
+	require.Equal(t, d, decoded)
+}
+
+func TestDecodeStream(t *testing.T) {
+	d := testData()
+	for _, format := range []Format{FormatJSON, FormatGob} {
+		t.Run(format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, EncodeWithOptions(d, &buf, EncodeOptions{Format: format}))
+
+			spheroids, next, err := DecodeStream(&buf)
+			require.NoError(t, err)
+			require.Equal(t, d.Spheroids, spheroids)
+			for i := range d.Projections {
+				p, err := next()
+				require.NoError(t, err)
+				require.Equal(t, &d.Projections[i], p)
+			}
+			for i := 0; i < 2; i++ {
+				p, err := next()
+				require.NoError(t, err)
+				require.Nil(t, p)
+			}
+		})
+	}
+
+	t.Run("field order", func(t *testing.T) {
+		// Unknown fields are skipped, and the fields are matched
+		// case-insensitively, like encoding/json does.
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write([]byte(`{"unknown": [1, {"a": 2}], "spheroids": [{"Hash": 1}],
+"projections": [{"SRID": 1, "Spheroid": 1}], "Other": null}`))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+
+		spheroids, next, err := DecodeStream(&buf)
+		require.NoError(t, err)
+		require.Equal(t, []Spheroid{{Hash: 1}}, spheroids)
+		p, err := next()
+		require.NoError(t, err)
+		require.Equal(t, &Projection{SRID: 1, Spheroid: 1}, p)
+		p, err = next()
+		require.NoError(t, err)
+		require.Nil(t, p)
+	})
+
+	t.Run("checksum", func(t *testing.T) {
+		// The checksum can only be verified once all the projections have been
+		// decoded.
+		var buf bytes.Buffer
+		require.NoError(t, Encode(d, &buf))
+		buf.Bytes()[checksumOffset]++
+
+		_, next, err := DecodeStream(&buf)
+		require.NoError(t, err)
+		for range d.Projections {
+			_, err := next()
+			require.NoError(t, err)
+		}
+		_, err = next()
+		require.EqualError(t, err, "embedded projection data corrupt: checksum mismatch")
+		// The error is sticky.
+		_, err = next()
+		require.EqualError(t, err, "embedded projection data corrupt: checksum mismatch")
+	})
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Encode(testData(), &buf))
+	encoded := buf.Bytes()
+
+	for _, tc := range []struct {
+		name    string
+		corrupt func(b []byte) []byte
+		err     string
+	}{
+		{
+			name:    "truncated header",
+			corrupt: func(b []byte) []byte { return b[:headerLen-1] },
+			err:     "embedded projection data corrupt: truncated header",
+		},
+		{
+			name:    "version",
+			corrupt: func(b []byte) []byte { b[versionOffset+1]++; return b },
+			err:     "unsupported embedded projection data version 2, expected version 1",
+		},
+		{
+			name:    "length",
+			corrupt: func(b []byte) []byte { b[lengthOffset]++; return b },
+			err:     "embedded projection data corrupt: length mismatch",
+		},
+		{
+			name:    "checksum",
+			corrupt: func(b []byte) []byte { b[checksumOffset]++; return b },
+			err:     "embedded projection data corrupt: checksum mismatch",
+		},
+		{
+			name:    "truncated payload",
+			corrupt: func(b []byte) []byte { return b[:len(b)-10] },
+			err:     "unexpected EOF",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := tc.corrupt(append([]byte(nil), encoded...))
+			_, err := Decode(bytes.NewReader(b))
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	d := testData()
+	require.NoError(t, d.Validate())
+
+	d.Projections[0].Spheroid = 3
+	d.Projections[1].Bounds.MinY = 3
+	d.Projections = append(d.Projections, d.Projections[1])
+	require.EqualError(t, d.Validate(), "invalid embedded projection data: "+
+		"SRID 4326 references unknown spheroid 3; "+
+		"SRID 2000 has invalid bounds {MinX:-1 MaxX:1 MinY:3 MaxY:2}; "+
+		"SRID 2000 has invalid bounds {MinX:-1 MaxX:1 MinY:3 MaxY:2}; "+
+		"SRID 2000 is duplicated")
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(d, &buf))
+	encoded := buf.Bytes()
+	_, err := Decode(bytes.NewReader(encoded))
+	require.NoError(t, err)
+	_, err = DecodeWithOptions(bytes.NewReader(encoded), DecodeOptions{Validate: true})
+	require.EqualError(t, err, d.Validate().Error())
+}
+
+func TestLookup(t *testing.T) {
+	d := testData()
+
+	p, ok := d.Projection(2000)
+	require.True(t, ok)
+	require.Same(t, &d.Projections[1], p)
+	_, ok = d.Projection(1)
+	require.False(t, ok)
+
+	s, ok := d.SpheroidByHash(2)
+	require.True(t, ok)
+	require.Same(t, &d.Spheroids[1], s)
+	_, ok = d.SpheroidByHash(3)
+	require.False(t, ok)
+
+	// The index is built once and reused.
+	idx := d.index
+	require.NotNil(t, idx)
+	_, _ = d.Projection(4326)
+	require.Same(t, idx, d.index)
+}
+
+func TestProjectionsByAuthName(t *testing.T) {
+	d := testData()
+	require.NoError(t, d.Add(Projection{
+		SRID:     900913,
+		AuthName: "spatialreferencing.org",
+		AuthSRID: 900913,
+		Spheroid: 1,
+	}, nil /* s */, false /* overwrite */))
+
+	require.Equal(t, []*Projection{&d.Projections[0], &d.Projections[1]}, d.ProjectionsByAuthName("EPSG"))
+	require.Equal(t, []*Projection{&d.Projections[2]}, d.ProjectionsByAuthName("spatialreferencing.org"))
+	require.Empty(t, d.ProjectionsByAuthName("ESRI"))
+
+	// Every code of a reference list can be checked for.
+	codes := make(map[int]struct{})
+	for _, p := range d.ProjectionsByAuthName("EPSG") {
+		codes[p.AuthSRID] = struct{}{}
+	}
+	for _, code := range []int{4326, 2000} {
+		require.Contains(t, codes, code)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	d := testData()
+	custom := Projection{
+		SRID:      900913,
+		AuthName:  "EPSG",
+		AuthSRID:  900913,
+		Proj4Text: "+proj=merc +a=6378137 +b=6378137 +units=m +no_defs",
+		Bounds:    Bounds{MinX: -1, MaxX: 1, MinY: -1, MaxY: 1},
+		Spheroid:  3,
+	}
+	spheroid := Spheroid{Hash: 3, Radius: 6378137}
+
+	// The spheroid must be resolvable.
+	require.EqualError(t, d.Add(custom, nil, false /* overwrite */),
+		"SRID 900913 references unknown spheroid 3")
+	require.EqualError(t, d.Add(custom, &Spheroid{Hash: 4}, false /* overwrite */),
+		"SRID 900913 references spheroid 3, but spheroid 4 was provided")
+	require.EqualError(t, d.Add(custom, &Spheroid{Hash: 1}, false /* overwrite */),
+		"SRID 900913 references spheroid 3, but spheroid 1 was provided")
+	conflicting := custom
+	conflicting.Spheroid = 1
+	require.EqualError(t, d.Add(conflicting, &Spheroid{Hash: 1}, false /* overwrite */),
+		"spheroid 1 already exists with different parameters")
+	invalid := custom
+	invalid.Bounds.MinX = 2
+	require.EqualError(t, d.Add(invalid, &spheroid, false /* overwrite */),
+		"SRID 900913 has invalid bounds {MinX:2 MaxX:1 MinY:-1 MaxY:1}")
+
+	require.NoError(t, d.Add(custom, &spheroid, false /* overwrite */))
+	p, ok := d.Projection(900913)
+	require.True(t, ok)
+	require.Equal(t, custom, *p)
+	s, ok := d.SpheroidByHash(3)
+	require.True(t, ok)
+	require.Equal(t, spheroid, *s)
+	require.NoError(t, d.Validate())
+
+	// Duplicate SRIDs require overwrite.
+	replacement := custom
+	replacement.Spheroid = 1
+	require.EqualError(t, d.Add(replacement, nil, false /* overwrite */),
+		"SRID 900913 already exists")
+	require.NoError(t, d.Add(replacement, nil, true /* overwrite */))
+	p, ok = d.Projection(900913)
+	require.True(t, ok)
+	require.Equal(t, replacement, *p)
+	require.Len(t, d.Projections, 3)
+	require.Len(t, d.Spheroids, 3)
+	require.NoError(t, d.Validate())
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package embeddedproj
+
+import "github.com/cockroachdb/errors"
+
+// MergeOptions are the options of MergeWithOptions.
+type MergeOptions struct {
+	// Overwrite, if set, makes the projections of the extra data replace those
+	// of the base data with the same SRID and a different definition.
+	Overwrite bool
+}
+
+// Merge returns the union of the projections and spheroids of base and extra.
+// Spheroids are deduplicated by hash, and projections by SRID. Conflicting
+// definitions of a spheroid or SRID result in an error. The result is
+// validated using Data.Validate.
+func Merge(base, extra Data) (Data, error) {
+	return MergeWithOptions(base, extra, MergeOptions{})
+}
+
+// MergeWithOptions is like Merge, but with the specified options. Neither
+// base nor extra are modified.
+func MergeWithOptions(base, extra Data, opts MergeOptions) (Data, error) {
+	result := Data{
+		Spheroids:   append([]Spheroid(nil), base.Spheroids...),
+		Projections: append([]Projection(nil), base.Projections...),
+	}
+	spheroids := make(map[int64]Spheroid, len(result.Spheroids)+len(extra.Spheroids))
+	for _, s := range result.Spheroids {
+		spheroids[s.Hash] = s
+	}
+	for _, s := range extra.Spheroids {
+		if existing, ok := spheroids[s.Hash]; ok {
+			if existing != s {
+				return Data{}, errors.Newf("spheroid %x has conflicting definitions", s.Hash)
+			}
+			continue
+		}
+		spheroids[s.Hash] = s
+		result.Spheroids = append(result.Spheroids, s)
+	}
+
+	for _, p := range extra.Projections {
+		if existing, ok := result.Projection(p.SRID); ok {
+			if *existing == p {
+				continue
+			}
+			if !opts.Overwrite {
+				return Data{}, errors.Newf("SRID %d has conflicting definitions", p.SRID)
+			}
+		}
+		if err := result.Add(p, nil /* s */, true /* overwrite */); err != nil {
+			return Data{}, err
+		}
+	}
+	if err := result.Validate(); err != nil {
+		return Data{}, err
+	}
+	return result, nil
+}
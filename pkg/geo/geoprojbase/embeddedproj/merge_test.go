@@ -0,0 +1,81 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package embeddedproj
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	base := testData()
+
+	// Merging identical data is a no-op.
+	merged, err := Merge(base, testData())
+	require.NoError(t, err)
+	require.True(t, Diff(base, merged).Empty())
+
+	extra := Data{
+		Spheroids: []Spheroid{
+			base.Spheroids[0],
+			{Hash: 3, Radius: 1, Flattening: 0.5},
+		},
+		Projections: []Projection{
+			base.Projections[0],
+			{SRID: 3000, Proj4Text: "+proj=merc", Spheroid: 3},
+			// Projections may reference the spheroids of the base data.
+			{SRID: 3001, Proj4Text: "+proj=merc", Spheroid: 2},
+		},
+	}
+	merged, err = Merge(base, extra)
+	require.NoError(t, err)
+	require.Equal(t, DataDiff{
+		AddedProjections: []Projection{extra.Projections[1], extra.Projections[2]},
+		AddedSpheroids:   []Spheroid{extra.Spheroids[1]},
+	}, Diff(base, merged))
+	// The inputs are not modified.
+	require.Equal(t, testData(), base)
+
+	t.Run("conflicting SRID", func(t *testing.T) {
+		conflicting := testData()
+		conflicting.Projections[1].Proj4Text = "+proj=merc"
+		_, err := Merge(base, conflicting)
+		require.EqualError(t, err, "SRID 2000 has conflicting definitions")
+
+		merged, err := MergeWithOptions(base, conflicting, MergeOptions{Overwrite: true})
+		require.NoError(t, err)
+		p, ok := merged.Projection(2000)
+		require.True(t, ok)
+		require.Equal(t, "+proj=merc", p.Proj4Text)
+		require.Len(t, merged.Projections, len(base.Projections))
+	})
+
+	t.Run("conflicting spheroid", func(t *testing.T) {
+		conflicting := testData()
+		conflicting.Spheroids[1].Flattening = 0
+		_, err := MergeWithOptions(base, conflicting, MergeOptions{Overwrite: true})
+		require.EqualError(t, err, "spheroid 2 has conflicting definitions")
+	})
+
+	t.Run("unknown spheroid", func(t *testing.T) {
+		invalid := Data{Projections: []Projection{{SRID: 3000, Spheroid: 0x42}}}
+		_, err := Merge(base, invalid)
+		require.EqualError(t, err, "SRID 3000 references unknown spheroid 42")
+	})
+
+	t.Run("unencodable values", func(t *testing.T) {
+		// Data which cannot be encoded losslessly can still be merged.
+		unencodable := Data{Projections: []Projection{{SRID: 3000, SRText: "\xff", Spheroid: 2}}}
+		_, err := Merge(base, unencodable)
+		require.NoError(t, err)
+	})
+}
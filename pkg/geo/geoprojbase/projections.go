@@ -13,8 +13,7 @@
 package geoprojbase
 
 import (
-	"bytes"
-	_ "embed" // required for go:embed
+	"embed"
 	"sync"
 
 	"github.com/cockroachdb/cockroach/pkg/geo/geographiclib"
@@ -23,8 +22,11 @@ import (
 	"github.com/cockroachdb/errors"
 )
 
+// projDataFile is the file of projFS containing the projection data.
+const projDataFile = "data/proj.json.gz"
+
 //go:embed data/proj.json.gz
-var projData []byte
+var projFS embed.FS
 
 var once sync.Once
 var projectionsInternal map[geopb.SRID]ProjInfo
@@ -33,7 +35,7 @@ var projectionsInternal map[geopb.SRID]ProjInfo
 // Use the `Projection` function to obtain one.
 func getProjections() map[geopb.SRID]ProjInfo {
 	once.Do(func() {
-		d, err := embeddedproj.Decode(bytes.NewReader(projData))
+		d, err := embeddedproj.DecodeFromFS(projFS, projDataFile)
 		if err != nil {
 			panic(errors.NewAssertionErrorWithWrappedErrf(err, "error decoding embedded projection data"))
 		}
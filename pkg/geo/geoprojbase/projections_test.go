@@ -11,9 +11,13 @@
 package geoprojbase
 
 import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
 	"strconv"
 	"testing"
 
+	"github.com/cockroachdb/cockroach/pkg/geo/geoprojbase/embeddedproj"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,3 +30,38 @@ func TestProjections(t *testing.T) {
 		})
 	}
 }
+
+func TestEmbeddedDataIsValid(t *testing.T) {
+	d, err := embeddedproj.DecodeFromFS(projFS, projDataFile)
+	require.NoError(t, err)
+	require.NoError(t, d.Validate())
+}
+
+func TestDecodeFromFSMissingFile(t *testing.T) {
+	_, err := embeddedproj.DecodeFromFS(projFS, "data/missing.json.gz")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "opening embedded projection data data/missing.json.gz")
+}
+
+// BenchmarkEncodeCompressionLevels compares the encoding time and the size of
+// the embedded projection data across formats and compression levels.
+func BenchmarkEncodeCompressionLevels(b *testing.B) {
+	d, err := embeddedproj.DecodeFromFS(projFS, projDataFile)
+	require.NoError(b, err)
+
+	for _, format := range []embeddedproj.Format{embeddedproj.FormatJSON, embeddedproj.FormatGob} {
+		for _, level := range []int{gzip.BestSpeed, gzip.DefaultCompression, gzip.BestCompression} {
+			b.Run(fmt.Sprintf("format=%s/level=%d", format, level), func(b *testing.B) {
+				opts := embeddedproj.EncodeOptions{Format: format, CompressionLevel: level}
+				var buf bytes.Buffer
+				for i := 0; i < b.N; i++ {
+					buf.Reset()
+					if err := embeddedproj.EncodeWithOptions(d, &buf, opts); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(buf.Len()), "bytes")
+			})
+		}
+	}
+}
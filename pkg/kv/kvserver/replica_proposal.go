@@ -682,7 +682,7 @@ func (r *Replica) evaluateProposal(
 	// proposer was explored as an optimization but resulted in no performance
 	// benefit.
 	if batch != nil {
-		defer batch.Close()
+		defer closeWriteBatch(batch)
 	}
 
 	if pErr != nil {
@@ -476,7 +476,7 @@ func (r *Replica) evaluate1PC(
 		// Close the batch unless it's passed to the caller (when the evaluation
 		// succeeds).
 		if onePCRes.success != onePCSucceeded {
-			batch.Close()
+			closeWriteBatch(batch)
 		}
 	}()
 
@@ -536,7 +536,7 @@ func (r *Replica) evaluate1PC(
 	// If the end transaction is not committed, clear the batch and mark the status aborted.
 	if !etArg.Commit {
 		clonedTxn.Status = roachpb.ABORTED
-		batch.Close()
+		closeWriteBatch(batch)
 		batch = r.store.Engine().NewBatch()
 		ms = new(enginepb.MVCCStats)
 	} else {
@@ -608,7 +608,7 @@ func (r *Replica) evaluateWriteBatchWithServersideRefreshes(
 		if batch != nil {
 			// Reset the stats.
 			*ms = goldenMS
-			batch.Close()
+			closeWriteBatch(batch)
 		}
 
 		batch, br, res, pErr = r.evaluateWriteBatchWrapper(ctx, idKey, rec, ms, ba, ui, g)
@@ -715,6 +715,13 @@ func (r *Replica) newBatchedEngine(
 	return batch, opLogger
 }
 
+// closeWriteBatch closes a batch created by newBatchedEngine and, if it asserts
+// access against the latch spans, returns its wrapper to the spanset pool.
+func closeWriteBatch(batch storage.Batch) {
+	batch.Close()
+	spanset.ReleaseBatch(batch)
+}
+
 // isOnePhaseCommit returns true iff the BatchRequest contains all writes in the
 // transaction and ends with an EndTxn. One phase commits are disallowed if any
 // of the following conditions are true:
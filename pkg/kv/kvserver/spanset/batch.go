@@ -15,18 +15,87 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sync"
+	"sync/atomic"
 
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/buildutil"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/cockroachdb/errors"
 	"github.com/cockroachdb/pebble"
 )
 
+// ViolationCounter counts the accesses rejected by the assertions of a
+// spanset ReadWriter and the iterators it creates. A nil *ViolationCounter is
+// valid and counts nothing.
+type ViolationCounter struct {
+	count int64 // accessed atomically
+
+	// metric, if set, is incremented alongside count.
+	metric *metric.Counter
+
+	// logOnly, if set, makes the assertions log and count violations instead
+	// of rejecting the accesses. See NewLogOnlyViolationCounter.
+	logOnly bool
+}
+
+// NewViolationCounter returns a ViolationCounter. If m is non-nil, it is
+// incremented on every violation in addition to the counter itself.
+func NewViolationCounter(m *metric.Counter) *ViolationCounter {
+	return &ViolationCounter{metric: m}
+}
+
+// NewLogOnlyViolationCounter returns a ViolationCounter which makes the
+// assertions using it log violations, along with the offending span and a
+// stack trace, and count them, but not reject the accesses. This lets a command
+// under development run to completion so that all of its violations can be
+// seen at once. It must never be used in production.
+func NewLogOnlyViolationCounter(m *metric.Counter) *ViolationCounter {
+	return &ViolationCounter{metric: m, logOnly: true}
+}
+
+// Count returns the number of violations recorded so far.
+func (c *ViolationCounter) Count() int64 {
+	if c == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&c.count)
+}
+
+// record records a violation if err is non-nil and returns err, unless the
+// counter is log-only, in which case the violation is logged and nil is
+// returned.
+func (c *ViolationCounter) record(err error) error {
+	if err == nil {
+		return nil
+	}
+	c.inc()
+	if c != nil && c.logOnly {
+		// SpanAccessError includes the offending span and a stack trace.
+		log.Warningf(context.TODO(), "ignoring spanset violation in log-only mode: %v", err)
+		return nil
+	}
+	return err
+}
+
+// inc records a violation.
+func (c *ViolationCounter) inc() {
+	if c == nil {
+		return
+	}
+	atomic.AddInt64(&c.count, 1)
+	if c.metric != nil {
+		c.metric.Inc(1)
+	}
+}
+
 // MVCCIterator wraps an storage.MVCCIterator and ensures that it can
 // only be used to access spans in a SpanSet.
 type MVCCIterator struct {
@@ -42,11 +111,23 @@ type MVCCIterator struct {
 	// considered non-MVCC. If spansOnly is set to true, ts is not consulted.
 	ts hlc.Timestamp
 
+	// allowsAll is set if the spans allow all keys, in which case accesses
+	// are not checked. See SpanSet.AllowsAllKeys.
+	allowsAll bool
+
 	// Seeking to an invalid key puts the iterator in an error state.
 	err error
 	// Reaching an out-of-bounds key with Next/Prev invalidates the
 	// iterator but does not set err.
 	invalid bool
+
+	// violations, if set, counts the accesses rejected by the iterator.
+	violations *ViolationCounter
+
+	// trackMaxTS controls whether maxObservedTS is maintained, see
+	// TrackMaxObservedTimestamp.
+	trackMaxTS    bool
+	maxObservedTS hlc.Timestamp
 }
 
 var _ storage.MVCCIterator = &MVCCIterator{}
@@ -55,13 +136,39 @@ var _ storage.MVCCIterator = &MVCCIterator{}
 // iterator against the given SpanSet. Timestamps associated with the spans
 // in the spanset are not considered, only the span boundaries are checked.
 func NewIterator(iter storage.MVCCIterator, spans *SpanSet) *MVCCIterator {
-	return &MVCCIterator{i: iter, spans: spans, spansOnly: true}
+	return &MVCCIterator{i: iter, spans: spans, spansOnly: true, allowsAll: spans.AllowsAllKeys()}
 }
 
 // NewIteratorAt constructs an iterator that verifies access of the underlying
 // iterator against the given SpanSet at the given timestamp.
 func NewIteratorAt(iter storage.MVCCIterator, spans *SpanSet, ts hlc.Timestamp) *MVCCIterator {
-	return &MVCCIterator{i: iter, spans: spans, ts: ts}
+	return &MVCCIterator{i: iter, spans: spans, ts: ts, allowsAll: spans.AllowsAllKeys()}
+}
+
+// TrackMaxObservedTimestamp makes the iterator keep track of the maximum
+// timestamp of the keys it is positioned at, as returned by
+// MaxObservedTimestamp. It is meant for tests, and iterators that don't call it
+// don't pay for the bookkeeping.
+func (i *MVCCIterator) TrackMaxObservedTimestamp() {
+	i.trackMaxTS = true
+}
+
+// MaxObservedTimestamp returns the maximum timestamp of the keys the iterator
+// has been positioned at since TrackMaxObservedTimestamp was called. Keys that
+// the iterator rejected are not considered.
+func (i *MVCCIterator) MaxObservedTimestamp() hlc.Timestamp {
+	return i.maxObservedTS
+}
+
+// observeTimestamp forwards maxObservedTS to the timestamp of the current key,
+// if it is tracked and the iterator is valid.
+func (i *MVCCIterator) observeTimestamp() {
+	if !i.trackMaxTS {
+		return
+	}
+	if ok, _ := i.Valid(); ok {
+		i.maxObservedTS.Forward(i.i.UnsafeKey().Timestamp)
+	}
 }
 
 // Close is part of the storage.MVCCIterator interface.
@@ -84,13 +191,13 @@ func (i *MVCCIterator) Valid() (bool, error) {
 // SeekGE is part of the storage.MVCCIterator interface.
 func (i *MVCCIterator) SeekGE(key storage.MVCCKey) {
 	i.i.SeekGE(key)
-	i.checkAllowed(roachpb.Span{Key: key.Key}, true)
+	i.checkAllowed(roachpb.Span{Key: key.Key})
 }
 
 // SeekIntentGE is part of the storage.MVCCIterator interface.
 func (i *MVCCIterator) SeekIntentGE(key roachpb.Key, txnUUID uuid.UUID) {
 	i.i.SeekIntentGE(key, txnUUID)
-	i.checkAllowed(roachpb.Span{Key: key}, true)
+	i.checkAllowed(roachpb.Span{Key: key})
 }
 
 // SeekLT is part of the storage.MVCCIterator interface.
@@ -98,28 +205,30 @@ func (i *MVCCIterator) SeekLT(key storage.MVCCKey) {
 	i.i.SeekLT(key)
 	// CheckAllowed{At} supports the span representation of [,key), which
 	// corresponds to the span [key.Prev(),).
-	i.checkAllowed(roachpb.Span{EndKey: key.Key}, true)
+	i.checkAllowed(roachpb.Span{EndKey: key.Key})
 }
 
 // Next is part of the storage.MVCCIterator interface.
 func (i *MVCCIterator) Next() {
 	i.i.Next()
-	i.checkAllowed(roachpb.Span{Key: i.UnsafeKey().Key}, false)
+	i.checkCurrentKeyAllowed()
 }
 
 // Prev is part of the storage.MVCCIterator interface.
 func (i *MVCCIterator) Prev() {
 	i.i.Prev()
-	i.checkAllowed(roachpb.Span{Key: i.UnsafeKey().Key}, false)
+	i.checkCurrentKeyAllowed()
 }
 
 // NextKey is part of the storage.MVCCIterator interface.
 func (i *MVCCIterator) NextKey() {
 	i.i.NextKey()
-	i.checkAllowed(roachpb.Span{Key: i.UnsafeKey().Key}, false)
+	i.checkCurrentKeyAllowed()
 }
 
-func (i *MVCCIterator) checkAllowed(span roachpb.Span, errIfDisallowed bool) {
+// checkAllowed sets the error of the iterator if the span, accessed by a seek,
+// may not be accessed.
+func (i *MVCCIterator) checkAllowed(span roachpb.Span) {
 	i.invalid = false
 	i.err = nil
 	if ok, _ := i.i.Valid(); !ok {
@@ -128,17 +237,52 @@ func (i *MVCCIterator) checkAllowed(span roachpb.Span, errIfDisallowed bool) {
 		// as long as the iterator itself is configured with proper boundaries.
 		return
 	}
+	if i.allowsAll {
+		i.observeTimestamp()
+		return
+	}
 	var err error
 	if i.spansOnly {
 		err = i.spans.CheckAllowed(SpanReadOnly, span)
 	} else {
 		err = i.spans.CheckAllowedAt(SpanReadOnly, span, i.ts)
 	}
-	if errIfDisallowed {
-		i.err = err
+	i.err = i.violations.record(err)
+	i.observeTimestamp()
+}
+
+// checkCurrentKeyAllowed makes the iterator invalid if, after stepping, it is
+// positioned at a key that may not be accessed.
+func (i *MVCCIterator) checkCurrentKeyAllowed() {
+	i.invalid = false
+	i.err = nil
+	if ok, _ := i.i.Valid(); !ok {
+		// See checkAllowed.
+		return
+	}
+	if i.allowsAll {
+		i.observeTimestamp()
+		return
+	}
+	key := i.UnsafeKey().Key
+	var allowed bool
+	if i.spansOnly {
+		allowed = i.spans.ContainsKey(SpanReadOnly, key)
 	} else {
-		i.invalid = err != nil
+		allowed = i.spans.ContainsKeyAt(SpanReadOnly, key, i.ts)
 	}
+	if !allowed {
+		// Only construct the error, which is expensive, once a violation has
+		// been found.
+		var err error
+		if i.spansOnly {
+			err = i.spans.CheckAllowed(SpanReadOnly, roachpb.Span{Key: key})
+		} else {
+			err = i.spans.CheckAllowedAt(SpanReadOnly, roachpb.Span{Key: key}, i.ts)
+		}
+		i.invalid = i.violations.record(err) != nil
+	}
+	i.observeTimestamp()
 }
 
 // Key is part of the storage.MVCCIterator interface.
@@ -176,18 +320,24 @@ func (i *MVCCIterator) UnsafeValue() []byte {
 	return i.i.UnsafeValue()
 }
 
+// checkSpanAllowed returns an error if the given span, accessed without
+// positioning the iterator, may not be accessed.
+func (i *MVCCIterator) checkSpanAllowed(span roachpb.Span) error {
+	if i.allowsAll {
+		return nil
+	}
+	if i.spansOnly {
+		return i.violations.record(i.spans.CheckAllowed(SpanReadOnly, span))
+	}
+	return i.violations.record(i.spans.CheckAllowedAt(SpanReadOnly, span, i.ts))
+}
+
 // ComputeStats is part of the storage.MVCCIterator interface.
 func (i *MVCCIterator) ComputeStats(
 	start, end roachpb.Key, nowNanos int64,
 ) (enginepb.MVCCStats, error) {
-	if i.spansOnly {
-		if err := i.spans.CheckAllowed(SpanReadOnly, roachpb.Span{Key: start, EndKey: end}); err != nil {
-			return enginepb.MVCCStats{}, err
-		}
-	} else {
-		if err := i.spans.CheckAllowedAt(SpanReadOnly, roachpb.Span{Key: start, EndKey: end}, i.ts); err != nil {
-			return enginepb.MVCCStats{}, err
-		}
+	if err := i.checkSpanAllowed(roachpb.Span{Key: start, EndKey: end}); err != nil {
+		return enginepb.MVCCStats{}, err
 	}
 	return i.i.ComputeStats(start, end, nowNanos)
 }
@@ -196,14 +346,8 @@ func (i *MVCCIterator) ComputeStats(
 func (i *MVCCIterator) FindSplitKey(
 	start, end, minSplitKey roachpb.Key, targetSize int64,
 ) (storage.MVCCKey, error) {
-	if i.spansOnly {
-		if err := i.spans.CheckAllowed(SpanReadOnly, roachpb.Span{Key: start, EndKey: end}); err != nil {
-			return storage.MVCCKey{}, err
-		}
-	} else {
-		if err := i.spans.CheckAllowedAt(SpanReadOnly, roachpb.Span{Key: start, EndKey: end}, i.ts); err != nil {
-			return storage.MVCCKey{}, err
-		}
+	if err := i.checkSpanAllowed(roachpb.Span{Key: start, EndKey: end}); err != nil {
+		return storage.MVCCKey{}, err
 	}
 	return i.i.FindSplitKey(start, end, minSplitKey, targetSize)
 }
@@ -226,10 +370,49 @@ func (i *MVCCIterator) SupportsPrev() bool {
 // EngineIterator wraps a storage.EngineIterator and ensures that it can
 // only be used to access spans in a SpanSet.
 type EngineIterator struct {
-	i         storage.EngineIterator
-	spans     *SpanSet
-	spansOnly bool
-	ts        hlc.Timestamp
+	i          storage.EngineIterator
+	spans      *SpanSet
+	spansOnly  bool
+	ts         hlc.Timestamp
+	violations *ViolationCounter
+
+	// allowsAll is set if the spans allow all keys, in which case accesses
+	// are not checked. See SpanSet.AllowsAllKeys.
+	allowsAll bool
+
+	// trackMaxTS controls whether maxObservedTS is maintained, see
+	// TrackMaxObservedTimestamp.
+	trackMaxTS    bool
+	maxObservedTS hlc.Timestamp
+}
+
+// TrackMaxObservedTimestamp makes the iterator keep track of the maximum
+// timestamp of the MVCC keys it is positioned at, as returned by
+// MaxObservedTimestamp. See MVCCIterator.TrackMaxObservedTimestamp.
+func (i *EngineIterator) TrackMaxObservedTimestamp() {
+	i.trackMaxTS = true
+}
+
+// MaxObservedTimestamp returns the maximum timestamp of the MVCC keys the
+// iterator has been positioned at since TrackMaxObservedTimestamp was called.
+// Keys that the iterator rejected are not considered.
+func (i *EngineIterator) MaxObservedTimestamp() hlc.Timestamp {
+	return i.maxObservedTS
+}
+
+// observeTimestamp forwards maxObservedTS to the timestamp of the current key,
+// if it is tracked and an MVCC key. The iterator must be valid.
+func (i *EngineIterator) observeTimestamp() {
+	if !i.trackMaxTS {
+		return
+	}
+	key, err := i.i.UnsafeEngineKey()
+	if err != nil || !key.IsMVCCKey() {
+		return
+	}
+	if mvccKey, err := key.ToMVCCKey(); err == nil {
+		i.maxObservedTS.Forward(mvccKey.Timestamp)
+	}
 }
 
 // Close is part of the storage.EngineIterator interface.
@@ -243,14 +426,10 @@ func (i *EngineIterator) SeekEngineKeyGE(key storage.EngineKey) (valid bool, err
 	if !valid {
 		return valid, err
 	}
-	if key.IsMVCCKey() && !i.spansOnly {
-		mvccKey, _ := key.ToMVCCKey()
-		if err := i.spans.CheckAllowedAt(SpanReadOnly, roachpb.Span{Key: mvccKey.Key}, i.ts); err != nil {
-			return false, err
-		}
-	} else if err = i.spans.CheckAllowed(SpanReadOnly, roachpb.Span{Key: key.Key}); err != nil {
+	if err := i.checkAllowed(key, roachpb.Span{Key: key.Key}); err != nil {
 		return false, err
 	}
+	i.observeTimestamp()
 	return valid, err
 }
 
@@ -260,14 +439,10 @@ func (i *EngineIterator) SeekEngineKeyLT(key storage.EngineKey) (valid bool, err
 	if !valid {
 		return valid, err
 	}
-	if key.IsMVCCKey() && !i.spansOnly {
-		mvccKey, _ := key.ToMVCCKey()
-		if err := i.spans.CheckAllowedAt(SpanReadOnly, roachpb.Span{Key: mvccKey.Key}, i.ts); err != nil {
-			return false, err
-		}
-	} else if err = i.spans.CheckAllowed(SpanReadOnly, roachpb.Span{EndKey: key.Key}); err != nil {
+	if err := i.checkAllowed(key, roachpb.Span{EndKey: key.Key}); err != nil {
 		return false, err
 	}
+	i.observeTimestamp()
 	return valid, err
 }
 
@@ -297,9 +472,10 @@ func (i *EngineIterator) SeekEngineKeyGEWithLimit(
 	if state != pebble.IterValid {
 		return state, err
 	}
-	if err = i.spans.CheckAllowed(SpanReadOnly, roachpb.Span{Key: key.Key}); err != nil {
+	if err = i.checkAllowed(key, roachpb.Span{Key: key.Key}); err != nil {
 		return pebble.IterExhausted, err
 	}
+	i.observeTimestamp()
 	return state, err
 }
 
@@ -311,9 +487,10 @@ func (i *EngineIterator) SeekEngineKeyLTWithLimit(
 	if state != pebble.IterValid {
 		return state, err
 	}
-	if err = i.spans.CheckAllowed(SpanReadOnly, roachpb.Span{EndKey: key.Key}); err != nil {
+	if err = i.checkAllowed(key, roachpb.Span{EndKey: key.Key}); err != nil {
 		return pebble.IterExhausted, err
 	}
+	i.observeTimestamp()
 	return state, err
 }
 
@@ -321,34 +498,112 @@ func (i *EngineIterator) SeekEngineKeyLTWithLimit(
 func (i *EngineIterator) NextEngineKeyWithLimit(
 	limit roachpb.Key,
 ) (state pebble.IterValidityState, err error) {
-	return i.i.NextEngineKeyWithLimit(limit)
+	state, err = i.i.NextEngineKeyWithLimit(limit)
+	if state != pebble.IterValid {
+		return state, err
+	}
+	if valid, err := i.checkKeyAllowed(); !valid {
+		return pebble.IterExhausted, err
+	}
+	return state, err
 }
 
 // PrevEngineKeyWithLimit is part of the storage.EngineIterator interface.
 func (i *EngineIterator) PrevEngineKeyWithLimit(
 	limit roachpb.Key,
 ) (state pebble.IterValidityState, err error) {
-	return i.i.PrevEngineKeyWithLimit(limit)
+	state, err = i.i.PrevEngineKeyWithLimit(limit)
+	if state != pebble.IterValid {
+		return state, err
+	}
+	if valid, err := i.checkKeyAllowed(); !valid {
+		return pebble.IterExhausted, err
+	}
+	return state, err
 }
 
-func (i *EngineIterator) checkKeyAllowed() (valid bool, err error) {
+// PeekNextAllowed returns whether the key following the current key of the
+// iterator, i.e. the key NextEngineKey would position it at, may be accessed,
+// without changing the position of the iterator. It returns false if there is
+// no next key. Unlike stepping onto a disallowed key, peeking at one is not
+// counted as a violation. The iterator must be positioned at a valid key.
+//
+// Callers can use it to stop at the last key of the declared spans rather than
+// step past it, after which the iterator is invalid and loses its position.
+func (i *EngineIterator) PeekNextAllowed() (allowed bool, err error) {
 	key, err := i.i.UnsafeEngineKey()
 	if err != nil {
 		return false, err
 	}
-	if key.IsMVCCKey() && !i.spansOnly {
-		mvccKey, _ := key.ToMVCCKey()
-		if err := i.spans.CheckAllowedAt(SpanReadOnly, roachpb.Span{Key: mvccKey.Key}, i.ts); err != nil {
-			// Invalid, but no error.
-			return false, nil // nolint:returnerrcheck
+	key = key.Copy()
+	valid, err := i.i.NextEngineKey()
+	if err != nil {
+		return false, err
+	}
+	if valid {
+		next, err := i.i.UnsafeEngineKey()
+		if err != nil {
+			return false, err
 		}
-	} else if err = i.spans.CheckAllowed(SpanReadOnly, roachpb.Span{Key: key.Key}); err != nil {
+		allowed = i.keyAllowed(next)
+	}
+	// Return to the current key. Seeking, rather than stepping back, doesn't
+	// require the iterator to support moving backwards, and since the key
+	// exists, the limit only bounds the work done by the seek.
+	state, err := i.i.SeekEngineKeyGEWithLimit(key, key.Key.Next())
+	if err != nil {
+		return false, err
+	}
+	if state != pebble.IterValid {
+		return false, errors.AssertionFailedf("failed to return to key %s after peeking", key)
+	}
+	return allowed, nil
+}
+
+func (i *EngineIterator) checkKeyAllowed() (valid bool, err error) {
+	key, err := i.i.UnsafeEngineKey()
+	if err != nil {
+		return false, err
+	}
+	if err := i.checkAllowed(key, roachpb.Span{Key: key.Key}); err != nil {
 		// Invalid, but no error.
 		return false, nil // nolint:returnerrcheck
 	}
+	i.observeTimestamp()
 	return true, nil
 }
 
+// checkAllowed checks whether the iterator may access the given engine key.
+// MVCC keys are checked at the iterator's timestamp unless spansOnly is set.
+// All other keys are checked using the given span, which is expected to
+// contain key.Key.
+func (i *EngineIterator) checkAllowed(key storage.EngineKey, span roachpb.Span) error {
+	if i.allowsAll {
+		return nil
+	}
+	if key.IsMVCCKey() && !i.spansOnly {
+		if mvccKey, err := key.ToMVCCKey(); err == nil {
+			return i.violations.record(
+				i.spans.CheckAllowedAt(SpanReadOnly, roachpb.Span{Key: mvccKey.Key}, i.ts))
+		}
+	}
+	return i.violations.record(i.spans.CheckAllowed(SpanReadOnly, span))
+}
+
+// keyAllowed is like checkAllowed for the span containing only the given key,
+// except that it neither constructs an error nor counts violations.
+func (i *EngineIterator) keyAllowed(key storage.EngineKey) bool {
+	if i.allowsAll {
+		return true
+	}
+	if key.IsMVCCKey() && !i.spansOnly {
+		if mvccKey, err := key.ToMVCCKey(); err == nil {
+			return i.spans.ContainsKeyAt(SpanReadOnly, mvccKey.Key, i.ts)
+		}
+	}
+	return i.spans.ContainsKey(SpanReadOnly, key.Key)
+}
+
 // UnsafeEngineKey is part of the storage.EngineIterator interface.
 func (i *EngineIterator) UnsafeEngineKey() (storage.EngineKey, error) {
 	return i.i.UnsafeEngineKey()
@@ -395,6 +650,11 @@ type spanSetReader struct {
 
 	spansOnly bool
 	ts        hlc.Timestamp
+	// allowsAll is set if the spans allow all keys, in which case accesses
+	// are not checked. See SpanSet.AllowsAllKeys.
+	allowsAll bool
+
+	violations *ViolationCounter
 }
 
 var _ storage.Reader = spanSetReader{}
@@ -414,15 +674,20 @@ func (s spanSetReader) ExportMVCCToSst(
 	return s.r.ExportMVCCToSst(ctx, exportOptions, dest)
 }
 
-func (s spanSetReader) MVCCGet(key storage.MVCCKey) ([]byte, error) {
+// checkAllowed checks whether the reader may access the given span.
+func (s spanSetReader) checkAllowed(span roachpb.Span) error {
+	if s.allowsAll {
+		return nil
+	}
 	if s.spansOnly {
-		if err := s.spans.CheckAllowed(SpanReadOnly, roachpb.Span{Key: key.Key}); err != nil {
-			return nil, err
-		}
-	} else {
-		if err := s.spans.CheckAllowedAt(SpanReadOnly, roachpb.Span{Key: key.Key}, s.ts); err != nil {
-			return nil, err
-		}
+		return s.violations.record(s.spans.CheckAllowed(SpanReadOnly, span))
+	}
+	return s.violations.record(s.spans.CheckAllowedAt(SpanReadOnly, span, s.ts))
+}
+
+func (s spanSetReader) MVCCGet(key storage.MVCCKey) ([]byte, error) {
+	if err := s.checkAllowed(roachpb.Span{Key: key.Key}); err != nil {
+		return nil, err
 	}
 	//lint:ignore SA1019 implementing deprecated interface function (Get) is OK
 	return s.r.MVCCGet(key)
@@ -431,14 +696,8 @@ func (s spanSetReader) MVCCGet(key storage.MVCCKey) ([]byte, error) {
 func (s spanSetReader) MVCCGetProto(
 	key storage.MVCCKey, msg protoutil.Message,
 ) (bool, int64, int64, error) {
-	if s.spansOnly {
-		if err := s.spans.CheckAllowed(SpanReadOnly, roachpb.Span{Key: key.Key}); err != nil {
-			return false, 0, 0, err
-		}
-	} else {
-		if err := s.spans.CheckAllowedAt(SpanReadOnly, roachpb.Span{Key: key.Key}, s.ts); err != nil {
-			return false, 0, 0, err
-		}
+	if err := s.checkAllowed(roachpb.Span{Key: key.Key}); err != nil {
+		return false, 0, 0, err
 	}
 	//lint:ignore SA1019 implementing deprecated interface function (MVCCGetProto) is OK
 	return s.r.MVCCGetProto(key, msg)
@@ -447,14 +706,8 @@ func (s spanSetReader) MVCCGetProto(
 func (s spanSetReader) MVCCIterate(
 	start, end roachpb.Key, iterKind storage.MVCCIterKind, f func(storage.MVCCKeyValue) error,
 ) error {
-	if s.spansOnly {
-		if err := s.spans.CheckAllowed(SpanReadOnly, roachpb.Span{Key: start, EndKey: end}); err != nil {
-			return err
-		}
-	} else {
-		if err := s.spans.CheckAllowedAt(SpanReadOnly, roachpb.Span{Key: start, EndKey: end}, s.ts); err != nil {
-			return err
-		}
+	if err := s.checkAllowed(roachpb.Span{Key: start, EndKey: end}); err != nil {
+		return err
 	}
 	return s.r.MVCCIterate(start, end, iterKind, f)
 }
@@ -462,22 +715,24 @@ func (s spanSetReader) MVCCIterate(
 func (s spanSetReader) NewMVCCIterator(
 	iterKind storage.MVCCIterKind, opts storage.IterOptions,
 ) storage.MVCCIterator {
-	if s.spansOnly {
-		return NewIterator(s.r.NewMVCCIterator(iterKind, opts), s.spans)
+	return &MVCCIterator{
+		i:          s.r.NewMVCCIterator(iterKind, opts),
+		spans:      s.spans,
+		spansOnly:  s.spansOnly,
+		ts:         s.ts,
+		allowsAll:  s.allowsAll,
+		violations: s.violations,
 	}
-	return NewIteratorAt(s.r.NewMVCCIterator(iterKind, opts), s.spans, s.ts)
 }
 
 func (s spanSetReader) NewEngineIterator(opts storage.IterOptions) storage.EngineIterator {
-	if !s.spansOnly {
-		log.Warningf(context.Background(),
-			"cannot do strict timestamp checking of EngineIterator, resorting to best effort")
-	}
 	return &EngineIterator{
-		i:         s.r.NewEngineIterator(opts),
-		spans:     s.spans,
-		spansOnly: s.spansOnly,
-		ts:        s.ts,
+		i:          s.r.NewEngineIterator(opts),
+		spans:      s.spans,
+		spansOnly:  s.spansOnly,
+		ts:         s.ts,
+		allowsAll:  s.allowsAll,
+		violations: s.violations,
 	}
 }
 
@@ -497,22 +752,76 @@ type spanSetWriter struct {
 
 	spansOnly bool
 	ts        hlc.Timestamp
+	// allowsAll is set if the spans allow all keys, in which case accesses
+	// are not checked. See SpanSet.AllowsAllKeys.
+	allowsAll bool
+
+	violations *ViolationCounter
 }
 
 var _ storage.Writer = spanSetWriter{}
 
 func (s spanSetWriter) ApplyBatchRepr(repr []byte, sync bool) error {
-	// Assume that the constructor of the batch has bounded it correctly.
+	// Assume that the constructor of the batch has bounded it correctly, unless
+	// this is a test build, in which case we verify it. Decoding the batch is
+	// too expensive to do in production.
+	if buildutil.CrdbTestBuild {
+		if err := s.checkBatchRepr(repr); err != nil {
+			return err
+		}
+	}
 	return s.w.ApplyBatchRepr(repr, sync)
 }
 
+// checkBatchRepr checks that all keys written by the given batch repr are
+// allowed by the spanset.
+func (s spanSetWriter) checkBatchRepr(repr []byte) error {
+	r, err := storage.NewRocksDBBatchReader(repr)
+	if err != nil {
+		return err
+	}
+	for r.Next() {
+		switch r.BatchType() {
+		case storage.BatchTypeDeletion, storage.BatchTypeValue, storage.BatchTypeMerge:
+			key, err := r.EngineKey()
+			if err != nil {
+				return err
+			}
+			if err := s.checkEngineKeyAllowed(key); err != nil {
+				return err
+			}
+		case storage.BatchTypeRangeDeletion:
+			start, err := r.EngineKey()
+			if err != nil {
+				return err
+			}
+			end, err := r.EngineEndKey()
+			if err != nil {
+				return err
+			}
+			if err := s.checkAllowedRange(start.Key, end.Key); err != nil {
+				return err
+			}
+		case storage.BatchTypeSingleDeletion, storage.BatchTypeLogData:
+			// Single deletions are only used for the lock table, see
+			// SingleClearEngineKey, and log data does not write any keys.
+		default:
+			return errors.AssertionFailedf("unexpected batch entry type %d", r.BatchType())
+		}
+	}
+	return r.Error()
+}
+
 func (s spanSetWriter) checkAllowed(key roachpb.Key) error {
+	if s.allowsAll {
+		return nil
+	}
 	if s.spansOnly {
-		if err := s.spans.CheckAllowed(SpanReadWrite, roachpb.Span{Key: key}); err != nil {
+		if err := s.violations.record(s.spans.CheckAllowed(SpanReadWrite, roachpb.Span{Key: key})); err != nil {
 			return err
 		}
 	} else {
-		if err := s.spans.CheckAllowedAt(SpanReadWrite, roachpb.Span{Key: key}, s.ts); err != nil {
+		if err := s.violations.record(s.spans.CheckAllowedAt(SpanReadWrite, roachpb.Span{Key: key}, s.ts)); err != nil {
 			return err
 		}
 	}
@@ -542,11 +851,22 @@ func (s spanSetWriter) ClearIntent(
 	return s.w.ClearIntent(key, txnDidNotUpdateMeta, txnUUID)
 }
 
-func (s spanSetWriter) ClearEngineKey(key storage.EngineKey) error {
-	if !s.spansOnly {
-		panic("cannot do timestamp checking for clearing EngineKey")
+func (s spanSetWriter) checkEngineKeyAllowed(key storage.EngineKey) error {
+	if s.allowsAll {
+		return nil
+	}
+	if s.spansOnly || !key.IsMVCCKey() {
+		return s.violations.record(s.spans.CheckAllowed(SpanReadWrite, roachpb.Span{Key: key.Key}))
 	}
-	if err := s.spans.CheckAllowed(SpanReadWrite, roachpb.Span{Key: key.Key}); err != nil {
+	mvccKey, err := key.ToMVCCKey()
+	if err != nil {
+		panic(fmt.Sprintf("cannot do timestamp checking for EngineKey %s: %v", key, err))
+	}
+	return s.violations.record(s.spans.CheckAllowedAt(SpanReadWrite, roachpb.Span{Key: mvccKey.Key}, s.ts))
+}
+
+func (s spanSetWriter) ClearEngineKey(key storage.EngineKey) error {
+	if err := s.checkEngineKeyAllowed(key); err != nil {
 		return err
 	}
 	return s.w.ClearEngineKey(key)
@@ -559,12 +879,15 @@ func (s spanSetWriter) SingleClearEngineKey(key storage.EngineKey) error {
 }
 
 func (s spanSetWriter) checkAllowedRange(start, end roachpb.Key) error {
+	if s.allowsAll {
+		return nil
+	}
 	if s.spansOnly {
-		if err := s.spans.CheckAllowed(SpanReadWrite, roachpb.Span{Key: start, EndKey: end}); err != nil {
+		if err := s.violations.record(s.spans.CheckAllowed(SpanReadWrite, roachpb.Span{Key: start, EndKey: end})); err != nil {
 			return err
 		}
 	} else {
-		if err := s.spans.CheckAllowedAt(SpanReadWrite, roachpb.Span{Key: start, EndKey: end}, s.ts); err != nil {
+		if err := s.violations.record(s.spans.CheckAllowedAt(SpanReadWrite, roachpb.Span{Key: start, EndKey: end}, s.ts)); err != nil {
 			return err
 		}
 	}
@@ -600,14 +923,8 @@ func (s spanSetWriter) ClearIterRange(iter storage.MVCCIterator, start, end roac
 }
 
 func (s spanSetWriter) Merge(key storage.MVCCKey, value []byte) error {
-	if s.spansOnly {
-		if err := s.spans.CheckAllowed(SpanReadWrite, roachpb.Span{Key: key.Key}); err != nil {
-			return err
-		}
-	} else {
-		if err := s.spans.CheckAllowedAt(SpanReadWrite, roachpb.Span{Key: key.Key}, s.ts); err != nil {
-			return err
-		}
+	if err := s.checkAllowed(key.Key); err != nil {
+		return err
 	}
 	return s.w.Merge(key, value)
 }
@@ -636,10 +953,7 @@ func (s spanSetWriter) PutIntent(
 }
 
 func (s spanSetWriter) PutEngineKey(key storage.EngineKey, value []byte) error {
-	if !s.spansOnly {
-		panic("cannot do timestamp checking for putting EngineKey")
-	}
-	if err := s.spans.CheckAllowed(SpanReadWrite, roachpb.Span{Key: key.Key}); err != nil {
+	if err := s.checkEngineKeyAllowed(key); err != nil {
 		return err
 	}
 	return s.w.PutEngineKey(key, value)
@@ -663,19 +977,57 @@ type ReadWriter struct {
 
 var _ storage.ReadWriter = ReadWriter{}
 
-func makeSpanSetReadWriter(rw storage.ReadWriter, spans *SpanSet) ReadWriter {
+// Violations returns the number of accesses rejected by the ReadWriter and the
+// iterators created from it.
+func (rw ReadWriter) Violations() int64 {
+	return rw.spanSetReader.violations.Count()
+}
+
+// IsSpansOnly returns whether the ReadWriter only checks accesses against the
+// span boundaries, ignoring the timestamps of the declared spans. Otherwise,
+// accesses are checked at Timestamp, and writing an EngineKey which is an MVCC
+// key but cannot be decoded as one panics.
+func (rw ReadWriter) IsSpansOnly() bool {
+	return rw.spanSetReader.spansOnly
+}
+
+// Timestamp returns the timestamp at which the ReadWriter checks accesses. It
+// is not consulted if IsSpansOnly returns true, and accesses at the zero
+// timestamp are considered non-MVCC.
+func (rw ReadWriter) Timestamp() hlc.Timestamp {
+	return rw.spanSetReader.ts
+}
+
+// makeSpanSetReadWriter returns a ReadWriter that asserts access against the
+// given SpanSet. Rejected accesses are counted by violations; if it is nil, a
+// new counter is allocated.
+func makeSpanSetReadWriter(
+	rw storage.ReadWriter, spans *SpanSet, violations *ViolationCounter,
+) ReadWriter {
 	spans = addLockTableSpans(spans)
+	allowsAll := spans.AllowsAllKeys()
+	if violations == nil {
+		violations = NewViolationCounter(nil /* metric */)
+	}
 	return ReadWriter{
-		spanSetReader: spanSetReader{r: rw, spans: spans, spansOnly: true},
-		spanSetWriter: spanSetWriter{w: rw, spans: spans, spansOnly: true},
+		spanSetReader: spanSetReader{r: rw, spans: spans, spansOnly: true, allowsAll: allowsAll, violations: violations},
+		spanSetWriter: spanSetWriter{w: rw, spans: spans, spansOnly: true, allowsAll: allowsAll, violations: violations},
 	}
 }
 
-func makeSpanSetReadWriterAt(rw storage.ReadWriter, spans *SpanSet, ts hlc.Timestamp) ReadWriter {
+// makeSpanSetReadWriterAt is like makeSpanSetReadWriter, but asserts access
+// at the given timestamp.
+func makeSpanSetReadWriterAt(
+	rw storage.ReadWriter, spans *SpanSet, ts hlc.Timestamp, violations *ViolationCounter,
+) ReadWriter {
 	spans = addLockTableSpans(spans)
+	allowsAll := spans.AllowsAllKeys()
+	if violations == nil {
+		violations = NewViolationCounter(nil /* metric */)
+	}
 	return ReadWriter{
-		spanSetReader: spanSetReader{r: rw, spans: spans, ts: ts},
-		spanSetWriter: spanSetWriter{w: rw, spans: spans, ts: ts},
+		spanSetReader: spanSetReader{r: rw, spans: spans, ts: ts, allowsAll: allowsAll, violations: violations},
+		spanSetWriter: spanSetWriter{w: rw, spans: spans, ts: ts, allowsAll: allowsAll, violations: violations},
 	}
 }
 
@@ -683,7 +1035,17 @@ func makeSpanSetReadWriterAt(rw storage.ReadWriter, spans *SpanSet, ts hlc.Times
 // underlying ReadWriter against the given SpanSet at a given timestamp.
 // If zero timestamp is provided, accesses are considered non-MVCC.
 func NewReadWriterAt(rw storage.ReadWriter, spans *SpanSet, ts hlc.Timestamp) storage.ReadWriter {
-	return makeSpanSetReadWriterAt(rw, spans, ts)
+	return makeSpanSetReadWriterAt(rw, spans, ts, nil /* violations */)
+}
+
+// NewReadWriterAtWithViolations is like NewReadWriterAt, but counts rejected
+// accesses using the given ViolationCounter, which may be shared across
+// ReadWriters. If the counter was created by NewLogOnlyViolationCounter,
+// violations are only logged.
+func NewReadWriterAtWithViolations(
+	rw storage.ReadWriter, spans *SpanSet, ts hlc.Timestamp, violations *ViolationCounter,
+) storage.ReadWriter {
+	return makeSpanSetReadWriterAt(rw, spans, ts, violations)
 }
 
 type spanSetBatch struct {
@@ -693,10 +1055,18 @@ type spanSetBatch struct {
 
 	spansOnly bool
 	ts        hlc.Timestamp
+
+	// violations is the counter used by ReadWriter, embedded here to avoid a
+	// separate allocation.
+	violations ViolationCounter
 }
 
 var _ storage.Batch = spanSetBatch{}
 
+var spanSetBatchPool = sync.Pool{
+	New: func() interface{} { return new(spanSetBatch) },
+}
+
 func (s spanSetBatch) Commit(sync bool) error {
 	return s.b.Commit(sync)
 }
@@ -720,24 +1090,94 @@ func (s spanSetBatch) Repr() []byte {
 // NewBatch returns a storage.Batch that asserts access of the underlying
 // Batch against the given SpanSet. We only consider span boundaries, associated
 // timestamps are not considered.
+//
+// The returned batch may be passed to ReleaseBatch once it is no longer in use.
 func NewBatch(b storage.Batch, spans *SpanSet) storage.Batch {
-	return &spanSetBatch{
-		ReadWriter: makeSpanSetReadWriter(b, spans),
-		b:          b,
-		spans:      spans,
-		spansOnly:  true,
-	}
+	return NewBatchFromPool(b, spans, true /* spansOnly */, hlc.Timestamp{})
+}
+
+// NewLogOnlyBatch is like NewBatch, but violations are logged, along with the
+// offending span and a stack trace, and counted instead of being rejected, so
+// that a command under development can run to completion and report all of its
+// violations at once. The number of violations is returned by Violations. It
+// must never be used in production.
+func NewLogOnlyBatch(b storage.Batch, spans *SpanSet) storage.Batch {
+	return newBatchFromPool(b, spans, true /* spansOnly */, hlc.Timestamp{}, true /* logOnly */)
 }
 
 // NewBatchAt returns an storage.Batch that asserts access of the underlying
 // Batch against the given SpanSet at the given timestamp.
 // If the zero timestamp is used, all accesses are considered non-MVCC.
+//
+// The returned batch may be passed to ReleaseBatch once it is no longer in use.
 func NewBatchAt(b storage.Batch, spans *SpanSet, ts hlc.Timestamp) storage.Batch {
-	return &spanSetBatch{
-		ReadWriter: makeSpanSetReadWriterAt(b, spans, ts),
-		b:          b,
-		spans:      spans,
-		ts:         ts,
+	return NewBatchFromPool(b, spans, false /* spansOnly */, ts)
+}
+
+// NewLogOnlyBatchAt is like NewBatchAt, but only logs violations, like
+// NewLogOnlyBatch. It must never be used in production.
+func NewLogOnlyBatchAt(b storage.Batch, spans *SpanSet, ts hlc.Timestamp) storage.Batch {
+	return newBatchFromPool(b, spans, false /* spansOnly */, ts, true /* logOnly */)
+}
+
+// NewBatchFromPool returns a storage.Batch that asserts access of the underlying
+// Batch against the given SpanSet, and is allocated from a pool. If spansOnly
+// is set, only span boundaries are considered, like NewBatch. Otherwise,
+// access is also asserted at the given timestamp, like NewBatchAt.
+//
+// The returned batch should be passed to ReleaseBatch once it is no longer in
+// use, so that it can be reused.
+func NewBatchFromPool(
+	b storage.Batch, spans *SpanSet, spansOnly bool, ts hlc.Timestamp,
+) storage.Batch {
+	return newBatchFromPool(b, spans, spansOnly, ts, false /* logOnly */)
+}
+
+// newBatchFromPool returns a spanSetBatch allocated from spanSetBatchPool.
+func newBatchFromPool(
+	b storage.Batch, spans *SpanSet, spansOnly bool, ts hlc.Timestamp, logOnly bool,
+) *spanSetBatch {
+	sb := spanSetBatchPool.Get().(*spanSetBatch)
+	sb.violations.logOnly = logOnly
+	if spansOnly {
+		sb.ReadWriter = makeSpanSetReadWriter(b, spans, &sb.violations)
+	} else {
+		sb.ReadWriter = makeSpanSetReadWriterAt(b, spans, ts, &sb.violations)
+	}
+	sb.b = b
+	sb.spans = spans
+	sb.spansOnly = spansOnly
+	sb.ts = ts
+	return sb
+}
+
+// ReleaseBatch returns a batch created by NewBatchFromPool, or by any of the
+// NewBatch variants, to a pool so that it can be reused. The batch, and any
+// iterators created from it, must not be used after being released. The
+// underlying storage.Batch is not closed. Batches that were not created by
+// this package are ignored.
+func ReleaseBatch(b storage.Batch) {
+	sb, ok := b.(*spanSetBatch)
+	if !ok {
+		return
+	}
+	// The ReadWriter's SpanSet is a copy created by addLockTableSpans and is
+	// owned by the batch.
+	sb.ReadWriter.spanSetReader.spans.Release()
+	*sb = spanSetBatch{}
+	spanSetBatchPool.Put(sb)
+}
+
+// Violations returns the number of accesses rejected by the given
+// storage.Reader if it asserts access against a SpanSet, and zero otherwise.
+func Violations(reader storage.Reader) int64 {
+	switch v := reader.(type) {
+	case ReadWriter:
+		return v.Violations()
+	case *spanSetBatch:
+		return v.Violations()
+	default:
+		return 0
 	}
 }
 
@@ -18,7 +18,11 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/spanset"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/testutils/skip"
+	"github.com/cockroachdb/cockroach/pkg/util/buildutil"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble"
 	"github.com/stretchr/testify/require"
 )
 
@@ -125,3 +129,632 @@ func TestReadWriterDeclareLockTablePanic(t *testing.T) {
 		}
 	}
 }
+
+// TestEngineIteratorWithLimit tests that the WithLimit variants of stepping an
+// EngineIterator do not allow access to keys outside of the declared spans.
+func TestEngineIteratorWithLimit(t *testing.T) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	for _, k := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, eng.PutUnversioned(roachpb.Key(k), []byte("value")))
+	}
+
+	ss := spanset.New()
+	ss.AddNonMVCC(spanset.SpanReadOnly, roachpb.Span{Key: roachpb.Key("b"), EndKey: roachpb.Key("d")})
+	b := eng.NewBatch()
+	defer b.Close()
+	rw := spanset.NewBatch(b, ss)
+
+	iter := rw.NewEngineIterator(storage.IterOptions{UpperBound: roachpb.Key("z")})
+	defer iter.Close()
+
+	// Stepping forward past the declared span exhausts the iterator.
+	state, err := iter.SeekEngineKeyGEWithLimit(storage.EngineKey{Key: roachpb.Key("b")}, nil)
+	require.NoError(t, err)
+	require.Equal(t, pebble.IterValid, state)
+	state, err = iter.NextEngineKeyWithLimit(nil)
+	require.NoError(t, err)
+	require.Equal(t, pebble.IterValid, state)
+	key, err := iter.UnsafeEngineKey()
+	require.NoError(t, err)
+	require.Equal(t, roachpb.Key("c"), key.Key)
+	state, err = iter.NextEngineKeyWithLimit(nil)
+	require.NoError(t, err)
+	require.Equal(t, pebble.IterExhausted, state)
+
+	// Stepping backward past the declared span exhausts the iterator.
+	state, err = iter.SeekEngineKeyGEWithLimit(storage.EngineKey{Key: roachpb.Key("b")}, nil)
+	require.NoError(t, err)
+	require.Equal(t, pebble.IterValid, state)
+	state, err = iter.PrevEngineKeyWithLimit(nil)
+	require.NoError(t, err)
+	require.Equal(t, pebble.IterExhausted, state)
+}
+
+// TestEngineIteratorPeekNextAllowed tests that PeekNextAllowed reports whether
+// the next key is allowed without moving the iterator or counting violations.
+func TestEngineIteratorPeekNextAllowed(t *testing.T) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	for _, k := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, eng.PutUnversioned(roachpb.Key(k), []byte("value")))
+	}
+	ts := hlc.Timestamp{WallTime: 10}
+	for _, k := range []string{"e", "f"} {
+		require.NoError(t, eng.PutMVCC(storage.MVCCKey{Key: roachpb.Key(k), Timestamp: ts}, []byte("value")))
+	}
+
+	ss := spanset.New()
+	ss.AddNonMVCC(spanset.SpanReadOnly, roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")})
+	ss.AddMVCC(spanset.SpanReadOnly, roachpb.Span{Key: roachpb.Key("d"), EndKey: roachpb.Key("e")}, ts.Add(10, 0))
+	ss.AddMVCC(spanset.SpanReadOnly, roachpb.Span{Key: roachpb.Key("e"), EndKey: roachpb.Key("z")}, ts.Add(-5, 0))
+	b := eng.NewBatch()
+	defer b.Close()
+	rw := spanset.NewBatchAt(b, ss, ts)
+
+	iter := rw.NewEngineIterator(storage.IterOptions{UpperBound: roachpb.Key("z")})
+	defer iter.Close()
+	requireAt := func(expected string) {
+		t.Helper()
+		key, err := iter.UnsafeEngineKey()
+		require.NoError(t, err)
+		require.Equal(t, roachpb.Key(expected), key.Key)
+	}
+
+	for _, tc := range []struct {
+		key     string
+		allowed bool
+	}{
+		{key: "a", allowed: true},
+		// The next key, c, is not declared.
+		{key: "b", allowed: false},
+		// The next key, e, is declared below the timestamp of the reads.
+		{key: "d", allowed: false},
+	} {
+		t.Run(tc.key, func(t *testing.T) {
+			valid, err := iter.SeekEngineKeyGE(storage.EngineKey{Key: roachpb.Key(tc.key)})
+			require.NoError(t, err)
+			require.True(t, valid)
+			allowed, err := iter.(*spanset.EngineIterator).PeekNextAllowed()
+			require.NoError(t, err)
+			require.Equal(t, tc.allowed, allowed)
+			requireAt(tc.key)
+
+			// The iterator can still be stepped.
+			valid, err = iter.NextEngineKey()
+			require.NoError(t, err)
+			require.Equal(t, tc.allowed, valid)
+		})
+	}
+	// Stepping onto the disallowed keys above was counted, but peeking wasn't.
+	require.EqualValues(t, 2, spanset.Violations(rw))
+
+	// The last key has no next key.
+	b2 := eng.NewBatch()
+	defer b2.Close()
+	rw = spanset.NewBatchAt(b2, ss, ts.Add(-5, 0))
+	iter = rw.NewEngineIterator(storage.IterOptions{UpperBound: roachpb.Key("z")})
+	defer iter.Close()
+	valid, err := iter.SeekEngineKeyGE(storage.EngineKey{Key: roachpb.Key("e")})
+	require.NoError(t, err)
+	require.True(t, valid)
+	allowed, err := iter.(*spanset.EngineIterator).PeekNextAllowed()
+	require.NoError(t, err)
+	require.True(t, allowed)
+	valid, err = iter.NextEngineKey()
+	require.NoError(t, err)
+	require.True(t, valid)
+	requireAt("f")
+	allowed, err = iter.(*spanset.EngineIterator).PeekNextAllowed()
+	require.NoError(t, err)
+	require.False(t, allowed)
+	requireAt("f")
+	require.Zero(t, spanset.Violations(rw))
+}
+
+// TestReadWriterAtEngineKey tests that writing engine keys through a batch
+// with timestamp checking asserts MVCC keys against the declared timestamps.
+func TestReadWriterAtEngineKey(t *testing.T) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	b := eng.NewBatch()
+	defer b.Close()
+
+	ts := hlc.Timestamp{WallTime: 10}
+	ss := spanset.New()
+	ss.AddMVCC(spanset.SpanReadWrite, roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")}, ts)
+	ss.AddNonMVCC(spanset.SpanReadWrite, roachpb.Span{Key: roachpb.Key("e")})
+	rw := spanset.NewBatchAt(b, ss, ts)
+
+	engineKey := func(key string, ts hlc.Timestamp) storage.EngineKey {
+		k, ok := storage.DecodeEngineKey(storage.EncodeMVCCKey(storage.MVCCKey{Key: roachpb.Key(key), Timestamp: ts}))
+		require.True(t, ok)
+		return k
+	}
+
+	for _, tc := range []struct {
+		key     storage.EngineKey
+		allowed bool
+	}{
+		{key: engineKey("a", ts), allowed: true},
+		{key: engineKey("b", ts), allowed: true},
+		{key: engineKey("d", ts), allowed: false},
+		{key: engineKey("e", ts), allowed: true},
+		{key: storage.EngineKey{Key: roachpb.Key("e")}, allowed: true},
+		{key: storage.EngineKey{Key: roachpb.Key("f")}, allowed: false},
+	} {
+		t.Run(fmt.Sprint(tc.key), func(t *testing.T) {
+			putErr := rw.PutEngineKey(tc.key, []byte("value"))
+			clearErr := rw.ClearEngineKey(tc.key)
+			if tc.allowed {
+				require.NoError(t, putErr)
+				require.NoError(t, clearErr)
+			} else {
+				require.Error(t, putErr)
+				require.Error(t, clearErr)
+			}
+		})
+	}
+}
+
+// TestReadWriterViolations tests that accesses rejected by a spanset
+// ReadWriter and its iterators are counted.
+func TestReadWriterViolations(t *testing.T) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	for _, k := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, eng.PutUnversioned(roachpb.Key(k), []byte("value")))
+	}
+
+	ss := spanset.New()
+	ss.AddNonMVCC(spanset.SpanReadWrite, roachpb.Span{Key: roachpb.Key("b"), EndKey: roachpb.Key("d")})
+	b := eng.NewBatch()
+	defer b.Close()
+	rw := spanset.NewBatch(b, ss)
+	require.Zero(t, spanset.Violations(rw))
+
+	// Allowed accesses are not counted.
+	_, err := rw.MVCCGet(storage.MakeMVCCMetadataKey(roachpb.Key("b")))
+	require.NoError(t, err)
+	require.NoError(t, rw.PutUnversioned(roachpb.Key("c"), []byte("value")))
+	require.Zero(t, spanset.Violations(rw))
+
+	// Rejected reads and writes are counted.
+	_, err = rw.MVCCGet(storage.MakeMVCCMetadataKey(roachpb.Key("a")))
+	require.Error(t, err)
+	require.Error(t, rw.PutUnversioned(roachpb.Key("d"), []byte("value")))
+	require.EqualValues(t, 2, spanset.Violations(rw))
+
+	// Rejected iterator accesses are counted, whether or not they surface an
+	// error.
+	iter := rw.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.Key("z")})
+	defer iter.Close()
+	iter.SeekGE(storage.MakeMVCCMetadataKey(roachpb.Key("a")))
+	_, err = iter.Valid()
+	require.Error(t, err)
+	require.EqualValues(t, 3, spanset.Violations(rw))
+	iter.SeekGE(storage.MakeMVCCMetadataKey(roachpb.Key("c")))
+	iter.Next()
+	ok, err := iter.Valid()
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.EqualValues(t, 4, spanset.Violations(rw))
+
+	// Readers that don't assert access report no violations.
+	require.Zero(t, spanset.Violations(b))
+}
+
+// TestLogOnlyViolations tests that log-only spanset batches and ReadWriters
+// count violations without rejecting the accesses.
+func TestLogOnlyViolations(t *testing.T) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	for _, k := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, eng.PutUnversioned(roachpb.Key(k), []byte("value")))
+	}
+
+	ss := spanset.New()
+	ss.AddNonMVCC(spanset.SpanReadWrite, roachpb.Span{Key: roachpb.Key("b"), EndKey: roachpb.Key("c")})
+	b := eng.NewBatch()
+	defer b.Close()
+	violations := spanset.NewLogOnlyViolationCounter(nil /* metric */)
+	for _, tc := range []struct {
+		name string
+		rw   storage.ReadWriter
+		// violations returns the number of violations, if they are not
+		// returned by spanset.Violations.
+		violations func() int64
+	}{
+		{
+			name: "batch",
+			rw:   spanset.NewLogOnlyBatch(b, ss),
+		},
+		{
+			name: "batch at",
+			rw:   spanset.NewLogOnlyBatchAt(b, ss, hlc.Timestamp{WallTime: 10}),
+		},
+		{
+			name:       "read writer",
+			rw:         spanset.NewReadWriterAtWithViolations(b, ss, hlc.Timestamp{}, violations),
+			violations: violations.Count,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			count := tc.violations
+			if count == nil {
+				count = func() int64 { return spanset.Violations(tc.rw) }
+			}
+
+			// Disallowed reads and writes succeed, but are counted.
+			v, err := tc.rw.MVCCGet(storage.MakeMVCCMetadataKey(roachpb.Key("a")))
+			require.NoError(t, err)
+			require.Equal(t, []byte("value"), v)
+			require.NoError(t, tc.rw.PutUnversioned(roachpb.Key("d"), []byte("value")))
+			require.EqualValues(t, 2, count())
+
+			// Iterators are not invalidated by disallowed keys.
+			iter := tc.rw.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.Key("z")})
+			defer iter.Close()
+			var keys []string
+			for iter.SeekGE(storage.MakeMVCCMetadataKey(roachpb.Key("a"))); ; iter.Next() {
+				ok, err := iter.Valid()
+				require.NoError(t, err)
+				if !ok {
+					break
+				}
+				keys = append(keys, string(iter.UnsafeKey().Key))
+			}
+			require.Equal(t, []string{"a", "b", "c", "d"}, keys)
+			// The seek to a, and the steps to c and d, are counted.
+			require.EqualValues(t, 5, count())
+		})
+	}
+}
+
+// TestEngineIteratorAt tests that an EngineIterator created by a spanset
+// reader at a timestamp checks MVCC keys against the declared timestamps.
+func TestEngineIteratorAt(t *testing.T) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	ts := hlc.Timestamp{WallTime: 10}
+	for _, k := range []string{"a", "b"} {
+		require.NoError(t, eng.PutMVCC(storage.MVCCKey{Key: roachpb.Key(k), Timestamp: ts}, []byte("value")))
+	}
+
+	ss := spanset.New()
+	ss.AddMVCC(spanset.SpanReadOnly, roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")}, ts)
+	engineKey := func(key string, ts hlc.Timestamp) storage.EngineKey {
+		k, ok := storage.DecodeEngineKey(storage.EncodeMVCCKey(storage.MVCCKey{Key: roachpb.Key(key), Timestamp: ts}))
+		require.True(t, ok)
+		return k
+	}
+
+	for _, tc := range []struct {
+		name    string
+		ts      hlc.Timestamp
+		allowed bool
+	}{
+		{name: "at declared timestamp", ts: ts, allowed: true},
+		{name: "below declared timestamp", ts: ts.Prev(), allowed: true},
+		{name: "above declared timestamp", ts: ts.Next(), allowed: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rw := spanset.NewReadWriterAt(eng, ss, tc.ts)
+			iter := rw.NewEngineIterator(storage.IterOptions{UpperBound: roachpb.Key("z")})
+			defer iter.Close()
+
+			valid, err := iter.SeekEngineKeyGE(engineKey("a", ts))
+			require.Equal(t, tc.allowed, valid)
+			require.Equal(t, tc.allowed, err == nil)
+
+			state, err := iter.SeekEngineKeyGEWithLimit(engineKey("a", ts), nil)
+			require.Equal(t, tc.allowed, state == pebble.IterValid)
+			require.Equal(t, tc.allowed, err == nil)
+			if tc.allowed {
+				valid, err = iter.NextEngineKey()
+				require.NoError(t, err)
+				require.True(t, valid)
+			}
+		})
+	}
+}
+
+// TestIteratorMaxObservedTimestamp tests that spanset iterators track the
+// maximum timestamp of the keys they are positioned at, if requested.
+func TestIteratorMaxObservedTimestamp(t *testing.T) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	ts1, ts2, ts3 := hlc.Timestamp{WallTime: 10}, hlc.Timestamp{WallTime: 20}, hlc.Timestamp{WallTime: 30}
+	for _, kv := range []storage.MVCCKey{
+		{Key: roachpb.Key("a"), Timestamp: ts1},
+		{Key: roachpb.Key("b"), Timestamp: ts2},
+		{Key: roachpb.Key("d"), Timestamp: ts3},
+	} {
+		require.NoError(t, eng.PutMVCC(kv, []byte("value")))
+	}
+
+	// The key at ts3 is outside of the declared span, so it is never observed.
+	ss := spanset.New()
+	ss.AddMVCC(spanset.SpanReadOnly, roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")}, ts3)
+	rw := spanset.NewReadWriterAt(eng, ss, ts3)
+	opts := storage.IterOptions{UpperBound: roachpb.Key("z")}
+
+	t.Run("mvcc", func(t *testing.T) {
+		iter := rw.NewMVCCIterator(storage.MVCCKeyIterKind, opts).(*spanset.MVCCIterator)
+		defer iter.Close()
+		iter.TrackMaxObservedTimestamp()
+		require.Equal(t, hlc.Timestamp{}, iter.MaxObservedTimestamp())
+
+		iter.SeekGE(storage.MakeMVCCMetadataKey(roachpb.Key("a")))
+		require.Equal(t, ts1, iter.MaxObservedTimestamp())
+		for ; ; iter.Next() {
+			if ok, _ := iter.Valid(); !ok {
+				break
+			}
+		}
+		require.Equal(t, ts2, iter.MaxObservedTimestamp())
+	})
+
+	t.Run("engine", func(t *testing.T) {
+		iter := rw.NewEngineIterator(opts).(*spanset.EngineIterator)
+		defer iter.Close()
+		iter.TrackMaxObservedTimestamp()
+
+		valid, err := iter.SeekEngineKeyGE(storage.EngineKey{Key: roachpb.Key("a")})
+		for ; valid; valid, err = iter.NextEngineKey() {
+		}
+		require.NoError(t, err)
+		require.Equal(t, ts2, iter.MaxObservedTimestamp())
+	})
+
+	t.Run("untracked", func(t *testing.T) {
+		iter := rw.NewMVCCIterator(storage.MVCCKeyIterKind, opts).(*spanset.MVCCIterator)
+		defer iter.Close()
+		iter.SeekGE(storage.MakeMVCCMetadataKey(roachpb.Key("a")))
+		iter.Next()
+		require.Equal(t, hlc.Timestamp{}, iter.MaxObservedTimestamp())
+	})
+}
+
+// TestReadWriterSpanAccessError tests that accesses rejected by a spanset
+// ReadWriter return a SpanAccessError.
+func TestReadWriterSpanAccessError(t *testing.T) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	b := eng.NewBatch()
+	defer b.Close()
+
+	ts := hlc.Timestamp{WallTime: 10}
+	ss := spanset.New()
+	ss.AddMVCC(spanset.SpanReadWrite, roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")}, ts)
+	rw := spanset.NewBatchAt(b, ss, ts)
+
+	err := rw.PutMVCC(storage.MVCCKey{Key: roachpb.Key("d"), Timestamp: ts}, []byte("value"))
+	var accessErr *spanset.SpanAccessError
+	require.True(t, errors.As(err, &accessErr))
+	require.Equal(t, spanset.SpanReadWrite, accessErr.Access)
+	require.Equal(t, roachpb.Span{Key: roachpb.Key("d")}, accessErr.Span)
+	require.Equal(t, ts, accessErr.Timestamp)
+
+	_, err = rw.MVCCGet(storage.MVCCKey{Key: roachpb.Key("d"), Timestamp: ts})
+	require.True(t, errors.As(err, &accessErr))
+	require.Equal(t, spanset.SpanReadOnly, accessErr.Access)
+}
+
+// TestReadWriterMode tests that a spanset ReadWriter or Batch reports whether
+// it checks timestamps, and at which timestamp.
+func TestReadWriterMode(t *testing.T) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	b := eng.NewBatch()
+	defer b.Close()
+
+	ts := hlc.Timestamp{WallTime: 10}
+	ss := spanset.New()
+	type mode interface {
+		IsSpansOnly() bool
+		Timestamp() hlc.Timestamp
+	}
+	for _, tc := range []struct {
+		name      string
+		rw        storage.ReadWriter
+		spansOnly bool
+		ts        hlc.Timestamp
+	}{
+		{name: "NewBatch", rw: spanset.NewBatch(b, ss), spansOnly: true},
+		{name: "NewBatchAt", rw: spanset.NewBatchAt(b, ss, ts), ts: ts},
+		{name: "NewReadWriterAt", rw: spanset.NewReadWriterAt(b, ss, ts), ts: ts},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, ok := tc.rw.(mode)
+			require.True(t, ok)
+			require.Equal(t, tc.spansOnly, m.IsSpansOnly())
+			require.Equal(t, tc.ts, m.Timestamp())
+		})
+	}
+	rw := spanset.NewReadWriterAt(b, ss, ts).(spanset.ReadWriter)
+	require.False(t, rw.IsSpansOnly())
+	require.Equal(t, ts, rw.Timestamp())
+}
+
+// TestReleaseBatch tests that a released batch can be reused from the pool
+// without retaining state from its previous use.
+func TestReleaseBatch(t *testing.T) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	b := eng.NewBatch()
+	defer b.Close()
+
+	ss := spanset.New()
+	ss.AddNonMVCC(spanset.SpanReadWrite, roachpb.Span{Key: roachpb.Key("a")})
+	for i := 0; i < 10; i++ {
+		rw := spanset.NewBatch(b, ss)
+		require.Zero(t, spanset.Violations(rw))
+		require.NoError(t, rw.PutUnversioned(roachpb.Key("a"), []byte("value")))
+		require.Error(t, rw.PutUnversioned(roachpb.Key("b"), []byte("value")))
+		require.EqualValues(t, 1, spanset.Violations(rw))
+		spanset.ReleaseBatch(rw)
+	}
+	// Releasing a batch that does not assert access is a no-op.
+	spanset.ReleaseBatch(b)
+}
+
+// TestReadWriterAllowsAllKeys tests that a spanset ReadWriter declaring all
+// keys allows accesses to any key, at any timestamp, without counting
+// violations.
+func TestReadWriterAllowsAllKeys(t *testing.T) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	b := eng.NewBatch()
+	defer b.Close()
+
+	ss := spanset.New()
+	ss.AddNonMVCC(spanset.SpanReadWrite, roachpb.Span{Key: keys.LocalPrefix, EndKey: keys.LocalMax})
+	ss.AddNonMVCC(spanset.SpanReadWrite, roachpb.Span{Key: keys.MinKey, EndKey: keys.MaxKey})
+	require.True(t, ss.AllowsAllKeys())
+	for _, ts := range []hlc.Timestamp{{}, {WallTime: 10}} {
+		rw := spanset.NewBatchAt(b, ss, ts)
+		for _, key := range []roachpb.Key{roachpb.Key("a"), keys.RangeGCThresholdKey(1), keys.RangeDescriptorKey(roachpb.RKey("b"))} {
+			if ts.IsEmpty() {
+				require.NoError(t, rw.PutUnversioned(key, []byte("value")))
+			} else {
+				require.NoError(t, rw.PutMVCC(storage.MVCCKey{Key: key, Timestamp: ts}, []byte("value")))
+			}
+			_, err := rw.MVCCGet(storage.MVCCKey{Key: key, Timestamp: ts})
+			require.NoError(t, err)
+		}
+		require.NoError(t, rw.ClearRawRange(keys.MinKey, keys.MaxKey))
+		iter := rw.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: keys.MaxKey})
+		iter.SeekGE(storage.MVCCKey{Key: keys.MinKey})
+		_, err := iter.Valid()
+		require.NoError(t, err)
+		iter.Close()
+		require.Zero(t, spanset.Violations(rw))
+		spanset.ReleaseBatch(rw)
+	}
+}
+
+// BenchmarkReadWriterAllKeys measures the cost of checking writes against a
+// spanset declaring all keys, whether it is detected as such or, because it
+// uses several spans to cover the global keyspace, checked normally.
+func BenchmarkReadWriterAllKeys(b *testing.B) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	batch := eng.NewBatch()
+	defer batch.Close()
+
+	local := roachpb.Span{Key: keys.LocalPrefix, EndKey: keys.LocalMax}
+	for _, detected := range []bool{false, true} {
+		b.Run(fmt.Sprintf("detected=%t", detected), func(b *testing.B) {
+			ss := spanset.New()
+			ss.AddNonMVCC(spanset.SpanReadWrite, local)
+			if detected {
+				ss.AddNonMVCC(spanset.SpanReadWrite, roachpb.Span{Key: keys.MinKey, EndKey: keys.MaxKey})
+			} else {
+				ss.AddNonMVCC(spanset.SpanReadWrite, roachpb.Span{Key: keys.MinKey, EndKey: roachpb.Key("m")})
+				ss.AddNonMVCC(spanset.SpanReadWrite, roachpb.Span{Key: roachpb.Key("m"), EndKey: keys.MaxKey})
+			}
+			require.Equal(b, detected, ss.AllowsAllKeys())
+			rw := spanset.NewBatchAt(batch, ss, hlc.Timestamp{WallTime: 10})
+			defer spanset.ReleaseBatch(rw)
+			key := storage.MVCCKey{Key: roachpb.Key("z"), Timestamp: hlc.Timestamp{WallTime: 10}}
+			value := []byte("value")
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := rw.PutMVCC(key, value); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkNewBatch measures the allocations of the write path of a replica
+// under race builds, which wraps each engine batch into a spanset batch
+// asserting access against the latch spans, and closes it once the command has
+// been proposed, either releasing the wrapper to the pool or not.
+func BenchmarkNewBatch(b *testing.B) {
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+
+	ss := spanset.New()
+	ss.AddNonMVCC(spanset.SpanReadWrite, roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")})
+	key := roachpb.Key("a")
+	value := []byte("value")
+	for _, release := range []bool{false, true} {
+		b.Run(fmt.Sprintf("release=%t", release), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				batch := spanset.NewBatchFromPool(eng.NewBatch(), ss, true /* spansOnly */, hlc.Timestamp{})
+				if err := batch.PutUnversioned(key, value); err != nil {
+					b.Fatal(err)
+				}
+				batch.Close()
+				if release {
+					spanset.ReleaseBatch(batch)
+				}
+			}
+		})
+	}
+}
+
+// TestApplyBatchRepr tests that, in test builds, ApplyBatchRepr verifies the
+// keys written by the batch against the spanset.
+func TestApplyBatchRepr(t *testing.T) {
+	if !buildutil.CrdbTestBuild {
+		skip.IgnoreLint(t, "batch repr verification requires the crdb_test build tag")
+	}
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+
+	ss := spanset.New()
+	ss.AddNonMVCC(spanset.SpanReadWrite, roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")})
+
+	for _, tc := range []struct {
+		name    string
+		write   func(storage.Writer) error
+		allowed bool
+	}{
+		{
+			name:    "put in span",
+			write:   func(w storage.Writer) error { return w.PutUnversioned(roachpb.Key("a"), []byte("value")) },
+			allowed: true,
+		},
+		{
+			name:    "put outside span",
+			write:   func(w storage.Writer) error { return w.PutUnversioned(roachpb.Key("c"), []byte("value")) },
+			allowed: false,
+		},
+		{
+			name:    "clear outside span",
+			write:   func(w storage.Writer) error { return w.ClearUnversioned(roachpb.Key("d")) },
+			allowed: false,
+		},
+		{
+			name:    "clear range in span",
+			write:   func(w storage.Writer) error { return w.ClearRawRange(roachpb.Key("a"), roachpb.Key("c")) },
+			allowed: true,
+		},
+		{
+			name:    "clear range outside span",
+			write:   func(w storage.Writer) error { return w.ClearRawRange(roachpb.Key("a"), roachpb.Key("d")) },
+			allowed: false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			src := eng.NewBatch()
+			defer src.Close()
+			require.NoError(t, tc.write(src))
+
+			b := eng.NewBatch()
+			defer b.Close()
+			err := spanset.NewBatch(b, ss).ApplyBatchRepr(src.Repr(), false /* sync */)
+			if tc.allowed {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package spanset
+
+import (
+	"context"
+	"io"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/uuid"
+	"github.com/cockroachdb/pebble"
+)
+
+// NewRecordingReader returns a storage.Reader that, instead of asserting
+// access against a SpanSet, records the spans accessed through it and the
+// iterators it creates. The returned function can be called at any time to
+// obtain the minimal SpanSet that would have permitted all accesses recorded
+// so far. This is useful for comparing the spans a command declares with the
+// spans it actually accesses.
+//
+// Accesses to MVCC keys are recorded at the highest timestamp at which any
+// MVCC key was accessed, and all other accesses are recorded as non-MVCC.
+// Accesses to the lock table are recorded as accesses to the corresponding
+// main keys, since lock table spans are implicitly declared (see
+// addLockTableSpans).
+func NewRecordingReader(reader storage.Reader) (storage.Reader, func() *SpanSet) {
+	rec := &spanRecorder{}
+	return recordingReader{r: reader, rec: rec}, rec.spanSet
+}
+
+// spanRecorder accumulates the spans accessed through a recordingReader.
+type spanRecorder struct {
+	mu struct {
+		syncutil.Mutex
+		// mvccSpans are the spans accessed at a non-zero timestamp and maxTS
+		// is the highest such timestamp.
+		mvccSpans []roachpb.Span
+		maxTS     hlc.Timestamp
+		// nonMVCCSpans are the spans accessed without a timestamp.
+		nonMVCCSpans []roachpb.Span
+	}
+}
+
+// record records an access to the given span at the given timestamp. The
+// span's keys are copied.
+func (r *spanRecorder) record(span roachpb.Span, ts hlc.Timestamp) {
+	span = roachpb.Span{Key: span.Key.Clone(), EndKey: span.EndKey.Clone()}
+	if lockedKey, err := keys.DecodeLockTableSingleKey(span.Key); err == nil {
+		span.Key = lockedKey
+		if lockedEndKey, err := keys.DecodeLockTableSingleKey(span.EndKey); err == nil {
+			span.EndKey = lockedEndKey
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ts.IsEmpty() || keys.IsLocal(span.Key) {
+		r.mu.nonMVCCSpans = append(r.mu.nonMVCCSpans, span)
+		return
+	}
+	r.mu.mvccSpans = append(r.mu.mvccSpans, span)
+	r.mu.maxTS.Forward(ts)
+}
+
+// recordKey records an access to the given key at the given timestamp.
+func (r *spanRecorder) recordKey(key roachpb.Key, ts hlc.Timestamp) {
+	r.record(roachpb.Span{Key: key}, ts)
+}
+
+// recordReverseSeek records a reverse seek to the given key, which the SpanSet
+// checks as an access to [,key), i.e. to the key preceding it. landing is the
+// key the iterator is positioned at after the seek.
+func (r *spanRecorder) recordReverseSeek(landing, key roachpb.Key, ts hlc.Timestamp) {
+	if landing.Compare(key) < 0 {
+		r.record(roachpb.Span{Key: landing, EndKey: key}, ts)
+		return
+	}
+	if len(key) == 0 {
+		return
+	}
+	// The iterator landed on another version of key itself, so the span must
+	// start before key. Its longest proper prefix sorts before it.
+	r.record(roachpb.Span{Key: key[:len(key)-1], EndKey: key}, ts)
+}
+
+// recordEngineKey records an access to the given engine key.
+func (r *spanRecorder) recordEngineKey(key storage.EngineKey) {
+	if key.IsMVCCKey() {
+		if mvccKey, err := key.ToMVCCKey(); err == nil {
+			r.recordKey(mvccKey.Key, mvccKey.Timestamp)
+			return
+		}
+	}
+	r.recordKey(key.Key, hlc.Timestamp{})
+}
+
+// spanSet returns the minimal SpanSet that permits all recorded accesses.
+func (r *spanRecorder) spanSet() *SpanSet {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ss := New()
+	for _, span := range r.mu.mvccSpans {
+		ss.AddMVCC(SpanReadOnly, span, r.mu.maxTS)
+	}
+	for _, span := range r.mu.nonMVCCSpans {
+		ss.AddNonMVCC(SpanReadOnly, span)
+	}
+	ss.SortAndDedup()
+	return ss
+}
+
+type recordingReader struct {
+	r   storage.Reader
+	rec *spanRecorder
+}
+
+var _ storage.Reader = recordingReader{}
+
+func (s recordingReader) Close() {
+	s.r.Close()
+}
+
+func (s recordingReader) Closed() bool {
+	return s.r.Closed()
+}
+
+// ExportMVCCToSst is part of the storage.Reader interface.
+func (s recordingReader) ExportMVCCToSst(
+	ctx context.Context, exportOptions storage.ExportOptions, dest io.Writer,
+) (roachpb.BulkOpSummary, roachpb.Key, hlc.Timestamp, error) {
+	s.rec.record(roachpb.Span{Key: exportOptions.StartKey.Key, EndKey: exportOptions.EndKey}, exportOptions.EndTS)
+	return s.r.ExportMVCCToSst(ctx, exportOptions, dest)
+}
+
+func (s recordingReader) MVCCGet(key storage.MVCCKey) ([]byte, error) {
+	s.rec.recordKey(key.Key, key.Timestamp)
+	//lint:ignore SA1019 implementing deprecated interface function (Get) is OK
+	return s.r.MVCCGet(key)
+}
+
+func (s recordingReader) MVCCGetProto(
+	key storage.MVCCKey, msg protoutil.Message,
+) (bool, int64, int64, error) {
+	s.rec.recordKey(key.Key, key.Timestamp)
+	//lint:ignore SA1019 implementing deprecated interface function (MVCCGetProto) is OK
+	return s.r.MVCCGetProto(key, msg)
+}
+
+func (s recordingReader) MVCCIterate(
+	start, end roachpb.Key, iterKind storage.MVCCIterKind, f func(storage.MVCCKeyValue) error,
+) error {
+	s.rec.record(roachpb.Span{Key: start, EndKey: end}, hlc.Timestamp{})
+	return s.r.MVCCIterate(start, end, iterKind, f)
+}
+
+func (s recordingReader) NewMVCCIterator(
+	iterKind storage.MVCCIterKind, opts storage.IterOptions,
+) storage.MVCCIterator {
+	return &recordingMVCCIterator{MVCCIterator: s.r.NewMVCCIterator(iterKind, opts), rec: s.rec}
+}
+
+func (s recordingReader) NewEngineIterator(opts storage.IterOptions) storage.EngineIterator {
+	return &recordingEngineIterator{EngineIterator: s.r.NewEngineIterator(opts), rec: s.rec}
+}
+
+// ConsistentIterators implements the storage.Reader interface.
+func (s recordingReader) ConsistentIterators() bool {
+	return s.r.ConsistentIterators()
+}
+
+// PinEngineStateForIterators implements the storage.Reader interface.
+func (s recordingReader) PinEngineStateForIterators() error {
+	return s.r.PinEngineStateForIterators()
+}
+
+// recordingMVCCIterator wraps a storage.MVCCIterator and records the keys it
+// is positioned at, as well as the keys it seeks to, which the SpanSet checks
+// regardless of the key the iterator lands on.
+type recordingMVCCIterator struct {
+	storage.MVCCIterator
+	rec *spanRecorder
+}
+
+var _ storage.MVCCIterator = &recordingMVCCIterator{}
+
+// SeekGE is part of the storage.MVCCIterator interface.
+func (i *recordingMVCCIterator) SeekGE(key storage.MVCCKey) {
+	i.MVCCIterator.SeekGE(key)
+	i.rec.recordKey(key.Key, key.Timestamp)
+	i.recordPosition()
+}
+
+// SeekIntentGE is part of the storage.MVCCIterator interface.
+func (i *recordingMVCCIterator) SeekIntentGE(key roachpb.Key, txnUUID uuid.UUID) {
+	i.MVCCIterator.SeekIntentGE(key, txnUUID)
+	i.rec.recordKey(key, hlc.Timestamp{})
+	i.recordPosition()
+}
+
+// SeekLT is part of the storage.MVCCIterator interface.
+func (i *recordingMVCCIterator) SeekLT(key storage.MVCCKey) {
+	i.MVCCIterator.SeekLT(key)
+	if ok, _ := i.MVCCIterator.Valid(); ok {
+		i.rec.recordReverseSeek(i.MVCCIterator.UnsafeKey().Key, key.Key, key.Timestamp)
+	}
+	i.recordPosition()
+}
+
+// Next is part of the storage.MVCCIterator interface.
+func (i *recordingMVCCIterator) Next() {
+	i.MVCCIterator.Next()
+	i.recordPosition()
+}
+
+// Prev is part of the storage.MVCCIterator interface.
+func (i *recordingMVCCIterator) Prev() {
+	i.MVCCIterator.Prev()
+	i.recordPosition()
+}
+
+// NextKey is part of the storage.MVCCIterator interface.
+func (i *recordingMVCCIterator) NextKey() {
+	i.MVCCIterator.NextKey()
+	i.recordPosition()
+}
+
+// ComputeStats is part of the storage.MVCCIterator interface.
+func (i *recordingMVCCIterator) ComputeStats(
+	start, end roachpb.Key, nowNanos int64,
+) (enginepb.MVCCStats, error) {
+	i.rec.record(roachpb.Span{Key: start, EndKey: end}, hlc.Timestamp{})
+	return i.MVCCIterator.ComputeStats(start, end, nowNanos)
+}
+
+// FindSplitKey is part of the storage.MVCCIterator interface.
+func (i *recordingMVCCIterator) FindSplitKey(
+	start, end, minSplitKey roachpb.Key, targetSize int64,
+) (storage.MVCCKey, error) {
+	i.rec.record(roachpb.Span{Key: start, EndKey: end}, hlc.Timestamp{})
+	return i.MVCCIterator.FindSplitKey(start, end, minSplitKey, targetSize)
+}
+
+func (i *recordingMVCCIterator) recordPosition() {
+	if ok, _ := i.MVCCIterator.Valid(); !ok {
+		return
+	}
+	key := i.MVCCIterator.UnsafeKey()
+	i.rec.recordKey(key.Key, key.Timestamp)
+}
+
+// recordingEngineIterator wraps a storage.EngineIterator and records the keys
+// it is positioned at, as well as the keys it seeks to.
+type recordingEngineIterator struct {
+	storage.EngineIterator
+	rec *spanRecorder
+}
+
+var _ storage.EngineIterator = &recordingEngineIterator{}
+
+// SeekEngineKeyGE is part of the storage.EngineIterator interface.
+func (i *recordingEngineIterator) SeekEngineKeyGE(key storage.EngineKey) (valid bool, err error) {
+	valid, err = i.EngineIterator.SeekEngineKeyGE(key)
+	i.rec.recordEngineKey(key)
+	return valid, i.recordPosition(valid, err)
+}
+
+// SeekEngineKeyLT is part of the storage.EngineIterator interface.
+func (i *recordingEngineIterator) SeekEngineKeyLT(key storage.EngineKey) (valid bool, err error) {
+	valid, err = i.EngineIterator.SeekEngineKeyLT(key)
+	i.recordReverseSeek(key, valid, err)
+	return valid, i.recordPosition(valid, err)
+}
+
+// NextEngineKey is part of the storage.EngineIterator interface.
+func (i *recordingEngineIterator) NextEngineKey() (valid bool, err error) {
+	valid, err = i.EngineIterator.NextEngineKey()
+	return valid, i.recordPosition(valid, err)
+}
+
+// PrevEngineKey is part of the storage.EngineIterator interface.
+func (i *recordingEngineIterator) PrevEngineKey() (valid bool, err error) {
+	valid, err = i.EngineIterator.PrevEngineKey()
+	return valid, i.recordPosition(valid, err)
+}
+
+// SeekEngineKeyGEWithLimit is part of the storage.EngineIterator interface.
+func (i *recordingEngineIterator) SeekEngineKeyGEWithLimit(
+	key storage.EngineKey, limit roachpb.Key,
+) (state pebble.IterValidityState, err error) {
+	state, err = i.EngineIterator.SeekEngineKeyGEWithLimit(key, limit)
+	i.rec.recordEngineKey(key)
+	return state, i.recordPosition(state == pebble.IterValid, err)
+}
+
+// SeekEngineKeyLTWithLimit is part of the storage.EngineIterator interface.
+func (i *recordingEngineIterator) SeekEngineKeyLTWithLimit(
+	key storage.EngineKey, limit roachpb.Key,
+) (state pebble.IterValidityState, err error) {
+	state, err = i.EngineIterator.SeekEngineKeyLTWithLimit(key, limit)
+	i.recordReverseSeek(key, state == pebble.IterValid, err)
+	return state, i.recordPosition(state == pebble.IterValid, err)
+}
+
+// NextEngineKeyWithLimit is part of the storage.EngineIterator interface.
+func (i *recordingEngineIterator) NextEngineKeyWithLimit(
+	limit roachpb.Key,
+) (state pebble.IterValidityState, err error) {
+	state, err = i.EngineIterator.NextEngineKeyWithLimit(limit)
+	return state, i.recordPosition(state == pebble.IterValid, err)
+}
+
+// PrevEngineKeyWithLimit is part of the storage.EngineIterator interface.
+func (i *recordingEngineIterator) PrevEngineKeyWithLimit(
+	limit roachpb.Key,
+) (state pebble.IterValidityState, err error) {
+	state, err = i.EngineIterator.PrevEngineKeyWithLimit(limit)
+	return state, i.recordPosition(state == pebble.IterValid, err)
+}
+
+// recordReverseSeek records a reverse seek to the given key. Like the SpanSet,
+// MVCC keys are treated as an access to the key itself, and other keys as an
+// access to [,key).
+func (i *recordingEngineIterator) recordReverseSeek(key storage.EngineKey, valid bool, err error) {
+	if key.IsMVCCKey() {
+		i.rec.recordEngineKey(key)
+		return
+	}
+	if !valid || err != nil {
+		return
+	}
+	landing, err := i.EngineIterator.UnsafeEngineKey()
+	if err != nil {
+		return
+	}
+	i.rec.recordReverseSeek(landing.Key, key.Key, hlc.Timestamp{})
+}
+
+// recordPosition records the key the iterator is positioned at, if it is
+// valid, and passes through the error of the preceding positioning operation.
+func (i *recordingEngineIterator) recordPosition(valid bool, err error) error {
+	if !valid || err != nil {
+		return err
+	}
+	key, err := i.EngineIterator.UnsafeEngineKey()
+	if err != nil {
+		return err
+	}
+	i.rec.recordEngineKey(key)
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package spanset_test
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/spanset"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRecordingReader tests that a recording reader records the spans
+// accessed through it and its iterators.
+func TestRecordingReader(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	ts := hlc.Timestamp{WallTime: 10}
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, eng.PutMVCC(storage.MVCCKey{Key: roachpb.Key(k), Timestamp: ts}, []byte("value")))
+	}
+	rangeIDKey := keys.RangeGCThresholdKey(1)
+	require.NoError(t, eng.PutUnversioned(rangeIDKey, []byte("value")))
+
+	r, spans := spanset.NewRecordingReader(eng)
+	require.Zero(t, spans().Len())
+
+	// A point read of an MVCC key.
+	_, err := r.MVCCGet(storage.MVCCKey{Key: roachpb.Key("a"), Timestamp: ts})
+	require.NoError(t, err)
+	// A point read of a non-MVCC key.
+	_, err = r.MVCCGet(storage.MakeMVCCMetadataKey(rangeIDKey))
+	require.NoError(t, err)
+	// A scan over [c, e).
+	iter := r.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.Key("e")})
+	for iter.SeekGE(storage.MakeMVCCMetadataKey(roachpb.Key("c"))); ; iter.Next() {
+		ok, err := iter.Valid()
+		require.NoError(t, err)
+		if !ok {
+			break
+		}
+	}
+	iter.Close()
+
+	ss := spans()
+	require.NoError(t, ss.Validate())
+	for _, k := range []string{"a", "c", "d"} {
+		require.NoError(t, ss.CheckAllowedAt(spanset.SpanReadOnly, roachpb.Span{Key: roachpb.Key(k)}, ts))
+	}
+	for _, k := range []string{"b", "e"} {
+		require.Error(t, ss.CheckAllowedAt(spanset.SpanReadOnly, roachpb.Span{Key: roachpb.Key(k)}, ts))
+	}
+	require.Error(t, ss.CheckAllowedAt(spanset.SpanReadOnly, roachpb.Span{Key: roachpb.Key("a")}, ts.Next()))
+	require.NoError(t, ss.CheckAllowed(spanset.SpanReadOnly, roachpb.Span{Key: rangeIDKey}))
+
+	// The recorded spans are sufficient to replay the accesses through a
+	// spanset reader.
+	b := eng.NewBatch()
+	defer b.Close()
+	rw := spanset.NewBatchAt(b, ss, ts)
+	_, err = rw.MVCCGet(storage.MVCCKey{Key: roachpb.Key("a"), Timestamp: ts})
+	require.NoError(t, err)
+	_, err = rw.MVCCGet(storage.MakeMVCCMetadataKey(rangeIDKey))
+	require.NoError(t, err)
+	require.Zero(t, spanset.Violations(rw))
+}
+
+// TestRecordingReaderSeeks tests that the spans recorded for seeks are
+// sufficient to replay them through a spanset reader, which checks the key
+// sought to rather than the key the iterator lands on.
+func TestRecordingReaderSeeks(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	ts := hlc.Timestamp{WallTime: 10}
+	for _, k := range []string{"a", "c", "e"} {
+		require.NoError(t, eng.PutMVCC(storage.MVCCKey{Key: roachpb.Key(k), Timestamp: ts}, []byte("value")))
+	}
+
+	seeks := func(t *testing.T, r storage.Reader) {
+		iter := r.NewMVCCIterator(storage.MVCCKeyAndIntentsIterKind, storage.IterOptions{
+			LowerBound: roachpb.Key("a"),
+			UpperBound: roachpb.Key("z"),
+		})
+		defer iter.Close()
+		// A seek to a key that does not exist.
+		iter.SeekGE(storage.MakeMVCCMetadataKey(roachpb.Key("b")))
+		ok, err := iter.Valid()
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, roachpb.Key("c"), iter.UnsafeKey().Key)
+		// A reverse seek landing on a preceding key.
+		iter.SeekLT(storage.MakeMVCCMetadataKey(roachpb.Key("e")))
+		ok, err = iter.Valid()
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, roachpb.Key("c"), iter.UnsafeKey().Key)
+		// A reverse seek landing on a newer version of the key sought to.
+		iter.SeekLT(storage.MVCCKey{Key: roachpb.Key("e"), Timestamp: ts.Prev()})
+		ok, err = iter.Valid()
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, roachpb.Key("e"), iter.UnsafeKey().Key)
+	}
+
+	r, spans := spanset.NewRecordingReader(eng)
+	seeks(t, r)
+	ss := spans()
+	require.NoError(t, ss.Validate())
+
+	b := eng.NewBatch()
+	defer b.Close()
+	rw := spanset.NewBatchAt(b, ss, ts)
+	seeks(t, rw)
+	require.Zero(t, spanset.Violations(rw))
+}
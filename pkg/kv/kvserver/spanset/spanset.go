@@ -21,6 +21,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
 	"github.com/cockroachdb/errors"
 )
 
@@ -155,6 +156,47 @@ func (s *SpanSet) Copy() *SpanSet {
 	return n
 }
 
+// Marshal encodes the SpanSet as a SpanSetProto, which preserves the access,
+// scope and timestamp of each of its spans, as well as their order. The
+// encoding is stable, so it can be persisted (e.g. in test fixtures) and
+// decoded by UnmarshalSpanSet.
+func (s *SpanSet) Marshal() ([]byte, error) {
+	var p SpanSetProto
+	s.Iterate(func(sa SpanAccess, ss SpanScope, span Span) {
+		p.Spans = append(p.Spans, DeclaredSpan{
+			Access:    sa,
+			Scope:     ss,
+			Span:      span.Span,
+			Timestamp: span.Timestamp,
+		})
+	})
+	return protoutil.Marshal(&p)
+}
+
+// UnmarshalSpanSet decodes a SpanSet encoded by SpanSet.Marshal.
+func UnmarshalSpanSet(data []byte) (*SpanSet, error) {
+	var p SpanSetProto
+	if err := protoutil.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	s := New()
+	for _, span := range p.Spans {
+		if span.Access < 0 || span.Access >= NumSpanAccess {
+			s.Release()
+			return nil, errors.Errorf("invalid span access %d for span %s", span.Access, span.Span)
+		}
+		if span.Scope < 0 || span.Scope >= NumSpanScope {
+			s.Release()
+			return nil, errors.Errorf("invalid span scope %d for span %s", span.Scope, span.Span)
+		}
+		s.spans[span.Access][span.Scope] = append(s.spans[span.Access][span.Scope], Span{
+			Span:      span.Span,
+			Timestamp: span.Timestamp,
+		})
+	}
+	return s, nil
+}
+
 // Iterate iterates over a SpanSet, calling the given function.
 func (s *SpanSet) Iterate(f func(SpanAccess, SpanScope, Span)) {
 	if s == nil {
@@ -207,6 +249,92 @@ func (s *SpanSet) Merge(s2 *SpanSet) {
 	s.SortAndDedup()
 }
 
+// Union returns a new SpanSet containing the spans of both s and other, each
+// under its original access and scope. The spans of the returned SpanSet are
+// sorted and deduplicated. Neither s nor other is modified.
+func (s *SpanSet) Union(other *SpanSet) *SpanSet {
+	n := s.Copy()
+	if other != nil {
+		n.Merge(other)
+	} else {
+		n.SortAndDedup()
+	}
+	return n
+}
+
+// Intersect returns a new SpanSet containing the parts of the key spans of s
+// that overlap with key spans of other in the same scope. Since higher accesses
+// imply lower ones, each resulting span has the lower of the accesses of the
+// two spans it was derived from. Similarly, its timestamp never allows more
+// accesses than the timestamps of either of them; this can be more restrictive
+// than strictly necessary when a non-MVCC read span overlaps with an MVCC write
+// span. The spans of the returned SpanSet are sorted and deduplicated. Neither
+// s nor other is modified.
+func (s *SpanSet) Intersect(other *SpanSet) *SpanSet {
+	n := New()
+	if other == nil {
+		return n
+	}
+	for ss := SpanScope(0); ss < NumSpanScope; ss++ {
+		for sa := SpanAccess(0); sa < NumSpanAccess; sa++ {
+			for oa := SpanAccess(0); oa < NumSpanAccess; oa++ {
+				access := sa
+				if oa < access {
+					access = oa
+				}
+				for _, cur := range s.spans[sa][ss] {
+					for _, o := range other.spans[oa][ss] {
+						span := cur.Span.Intersect(o.Span)
+						if len(span.Key) == 0 {
+							continue
+						}
+						n.spans[access][ss] = append(n.spans[access][ss], Span{
+							Span:      span,
+							Timestamp: intersectTimestamps(sa, cur.Timestamp, oa, o.Timestamp),
+						})
+					}
+				}
+			}
+		}
+	}
+	n.SortAndDedup()
+	return n
+}
+
+// intersectTimestamps returns the timestamp of the span resulting from the
+// intersection of two spans declared with the given accesses and timestamps.
+// See allowedAt for the accesses allowed by a span at a given timestamp.
+func intersectTimestamps(
+	a1 SpanAccess, ts1 hlc.Timestamp, a2 SpanAccess, ts2 hlc.Timestamp,
+) hlc.Timestamp {
+	// Non-MVCC spans don't restrict the timestamps of accesses.
+	if ts1.IsEmpty() {
+		return ts2
+	}
+	if ts2.IsEmpty() {
+		return ts1
+	}
+	switch {
+	case a1 == SpanReadWrite && a2 == SpanReadWrite:
+		// Writes are allowed at the declared timestamp and above.
+		if ts1.Less(ts2) {
+			return ts2
+		}
+		return ts1
+	case a1 == SpanReadOnly && a2 == SpanReadOnly:
+		// Reads are allowed at the declared timestamp and below.
+		if ts1.Less(ts2) {
+			return ts1
+		}
+		return ts2
+	case a1 == SpanReadOnly:
+		// Write spans allow reads at any timestamp.
+		return ts1
+	default:
+		return ts2
+	}
+}
+
 // SortAndDedup sorts the spans in the SpanSet and removes any duplicates.
 func (s *SpanSet) SortAndDedup() {
 	for sa := SpanAccess(0); sa < NumSpanAccess; sa++ {
@@ -280,7 +408,7 @@ func (s *SpanSet) AssertAllowed(access SpanAccess, span roachpb.Span) {
 // is also a problem if the added spans were read only and the spanset wasn't
 // already SortAndDedup-ed.
 func (s *SpanSet) CheckAllowed(access SpanAccess, span roachpb.Span) error {
-	return s.checkAllowed(access, span, func(_ SpanAccess, _ Span) bool {
+	return s.checkAllowed(access, span, hlc.Timestamp{}, func(_ SpanAccess, _ Span) bool {
 		return true
 	})
 }
@@ -290,8 +418,96 @@ func (s *SpanSet) CheckAllowed(access SpanAccess, span roachpb.Span) error {
 func (s *SpanSet) CheckAllowedAt(
 	access SpanAccess, span roachpb.Span, timestamp hlc.Timestamp,
 ) error {
+	return s.checkAllowed(access, span, timestamp, allowedAt(access, timestamp))
+}
+
+// CheckAllowsExactly returns an error if the access is not allowed over any of
+// the spans in allowed, or if it is allowed over any of the spans in
+// disallowed, as determined by CheckAllowed. Unlike checking each span
+// individually, the returned error lists every mismatched span, which makes it
+// convenient for tests asserting the spans declared by a command.
+func (s *SpanSet) CheckAllowsExactly(
+	access SpanAccess, allowed, disallowed []roachpb.Span,
+) error {
+	var missing, unexpected []roachpb.Span
+	for _, span := range allowed {
+		if err := s.CheckAllowed(access, span); err != nil {
+			missing = append(missing, span)
+		}
+	}
+	for _, span := range disallowed {
+		if err := s.CheckAllowed(access, span); err == nil {
+			unexpected = append(unexpected, span)
+		}
+	}
+	if len(missing) == 0 && len(unexpected) == 0 {
+		return nil
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "spanset does not allow exactly the expected %s spans", access)
+	for _, span := range missing {
+		fmt.Fprintf(&buf, "\nmissing allowed span: %s", span)
+	}
+	for _, span := range unexpected {
+		fmt.Fprintf(&buf, "\nunexpectedly allowed span: %s", span)
+	}
+	fmt.Fprintf(&buf, "\ndeclared:\n%s", s)
+	return errors.Errorf("%s", buf.String())
+}
+
+// ContainsKey returns whether the access is allowed to the given key based on
+// the collection of spans in the spanset. Timestamps associated with the spans
+// in the spanset are not considered, only the span boundaries are checked.
+//
+// It is equivalent to checking that CheckAllowed returns no error for the
+// span containing only key, but it is cheaper, notably because no error is
+// constructed when the access is not allowed.
+func (s *SpanSet) ContainsKey(access SpanAccess, key roachpb.Key) bool {
+	return s.containsKey(access, key, func(_ SpanAccess, _ Span) bool {
+		return true
+	})
+}
+
+// ContainsKeyAt is like ContainsKey, except it returns whether the access is
+// allowed to the given key at the given timestamp.
+func (s *SpanSet) ContainsKeyAt(
+	access SpanAccess, key roachpb.Key, timestamp hlc.Timestamp,
+) bool {
+	return s.containsKey(access, key, allowedAt(access, timestamp))
+}
+
+// AllowsAllKeys returns whether every access to every addressable key is
+// allowed by the spanset, regardless of its timestamp. This is the case for
+// commands which declare the entire keyspace, like TransferLease (see
+// declareAllKeys), and allows the storage wrappers in this package to skip
+// checking accesses against the spanset altogether.
+//
+// The detection is conservative: it requires a single non-MVCC read/write
+// span covering the entire local keyspace and another covering the entire
+// global keyspace. A spanset which covers the keyspace using several spans is
+// not detected, and continues to be checked normally.
+func (s *SpanSet) AllowsAllKeys() bool {
+	return s.allowsAllKeysIn(SpanLocal, roachpb.Span{Key: keys.LocalPrefix, EndKey: keys.LocalMax}) &&
+		s.allowsAllKeysIn(SpanGlobal, roachpb.Span{Key: keys.MinKey, EndKey: keys.MaxKey})
+}
+
+// allowsAllKeysIn returns whether a single non-MVCC read/write span in the
+// given scope contains the given keyspace.
+func (s *SpanSet) allowsAllKeysIn(scope SpanScope, keyspace roachpb.Span) bool {
+	for _, cur := range s.spans[SpanReadWrite][scope] {
+		if cur.Timestamp.IsEmpty() && cur.Span.Contains(keyspace) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedAt returns a function that checks whether a declared span allows the
+// given access at the given timestamp, assuming that it contains the accessed
+// keys.
+func allowedAt(access SpanAccess, timestamp hlc.Timestamp) func(SpanAccess, Span) bool {
 	mvcc := !timestamp.IsEmpty()
-	return s.checkAllowed(access, span, func(declAccess SpanAccess, declSpan Span) bool {
+	return func(declAccess SpanAccess, declSpan Span) bool {
 		declTimestamp := declSpan.Timestamp
 		if declTimestamp.IsEmpty() {
 			// When the span is declared as non-MVCC (i.e. with an empty
@@ -329,11 +545,33 @@ func (s *SpanSet) CheckAllowedAt(
 		default:
 			panic("unexpected span access")
 		}
-	})
+	}
+}
+
+// containsKey is the single-key counterpart of checkAllowed.
+func (s *SpanSet) containsKey(
+	access SpanAccess, key roachpb.Key, check func(SpanAccess, Span) bool,
+) bool {
+	scope := SpanGlobal
+	if keys.IsLocal(key) {
+		scope = SpanLocal
+	}
+
+	for ac := access; ac < NumSpanAccess; ac++ {
+		for _, cur := range s.spans[ac][scope] {
+			if spanContainsKey(cur.Span, key) && check(ac, cur) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (s *SpanSet) checkAllowed(
-	access SpanAccess, span roachpb.Span, check func(SpanAccess, Span) bool,
+	access SpanAccess,
+	span roachpb.Span,
+	timestamp hlc.Timestamp,
+	check func(SpanAccess, Span) bool,
 ) error {
 	scope := SpanGlobal
 	if (span.Key != nil && keys.IsLocal(span.Key)) ||
@@ -349,7 +587,40 @@ func (s *SpanSet) checkAllowed(
 		}
 	}
 
-	return errors.Errorf("cannot %s undeclared span %s\ndeclared:\n%s\nstack:\n%s", access, span, s, debug.Stack())
+	return &SpanAccessError{
+		Access:    access,
+		Span:      span,
+		Timestamp: timestamp,
+		SpanSet:   s,
+		stack:     debug.Stack(),
+	}
+}
+
+// SpanAccessError is returned by CheckAllowed and CheckAllowedAt, and thus by
+// the storage wrappers in this package, when an access is not permitted by a
+// SpanSet.
+type SpanAccessError struct {
+	// Access is the type of the disallowed access.
+	Access SpanAccess
+	// Span is the span that was accessed.
+	Span roachpb.Span
+	// Timestamp is the timestamp of the access. It is empty for non-MVCC
+	// accesses and for accesses checked using CheckAllowed.
+	Timestamp hlc.Timestamp
+	// SpanSet is the SpanSet that was consulted. It is not copied, as the
+	// SpanSet of a request is not modified once its latches have been acquired,
+	// and is only formatted when the error is. It must not be accessed after the
+	// SpanSet has been released.
+	SpanSet *SpanSet
+
+	stack []byte
+}
+
+var _ error = &SpanAccessError{}
+
+func (e *SpanAccessError) Error() string {
+	return fmt.Sprintf("cannot %s undeclared span %s\ndeclared:\n%s\nstack:\n%s",
+		e.Access, e.Span, e.SpanSet, e.stack)
 }
 
 // contains returns whether s1 contains s2. Unlike Span.Contains, this function
@@ -372,6 +643,16 @@ func contains(s1, s2 roachpb.Span) bool {
 	return s1.Key.Compare(s2.EndKey) < 0 && s1.EndKey.Compare(s2.EndKey) >= 0
 }
 
+// spanContainsKey returns whether the span contains the key. Unlike
+// Span.ContainsKey, this function supports spans with a nil end key, which
+// only contain their start key.
+func spanContainsKey(span roachpb.Span, key roachpb.Key) bool {
+	if span.EndKey == nil {
+		return span.Key.Equal(key)
+	}
+	return span.ContainsKey(key)
+}
+
 // Validate returns an error if any spans that have been added to the set
 // are invalid.
 func (s *SpanSet) Validate() error {
@@ -19,6 +19,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
 	"github.com/stretchr/testify/require"
 )
 
@@ -136,6 +138,100 @@ func TestSpanSetMerge(t *testing.T) {
 	require.Equal(t, []Span{{Span: spBE}}, ss2.GetSpans(SpanReadWrite, SpanGlobal))
 }
 
+func TestSpanSetUnion(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	spA := roachpb.Span{Key: roachpb.Key("a")}
+	spAC := roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")}
+	spBD := roachpb.Span{Key: roachpb.Key("b"), EndKey: roachpb.Key("d")}
+	spAD := roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("d")}
+	spLocal := roachpb.Span{Key: keys.RangeGCThresholdKey(1)}
+	ts := hlc.Timestamp{WallTime: 10}
+
+	var ss SpanSet
+	ss.AddNonMVCC(SpanReadOnly, spLocal)
+	ss.AddNonMVCC(SpanReadOnly, spAC)
+	ss.AddMVCC(SpanReadWrite, spA, ts)
+
+	var ss2 SpanSet
+	ss2.AddNonMVCC(SpanReadOnly, spBD)
+	ss2.AddNonMVCC(SpanReadWrite, spBD)
+
+	u := ss.Union(&ss2)
+	require.Equal(t, []Span{{Span: spLocal}}, u.GetSpans(SpanReadOnly, SpanLocal))
+	// Overlapping spans are merged within each access, but not across accesses.
+	require.Equal(t, []Span{{Span: spAD}}, u.GetSpans(SpanReadOnly, SpanGlobal))
+	require.Equal(t, []Span{{Span: spA, Timestamp: ts}, {Span: spBD}}, u.GetSpans(SpanReadWrite, SpanGlobal))
+
+	// Neither input is modified.
+	require.Equal(t, []Span{{Span: spAC}}, ss.GetSpans(SpanReadOnly, SpanGlobal))
+	require.Equal(t, []Span{{Span: spA, Timestamp: ts}}, ss.GetSpans(SpanReadWrite, SpanGlobal))
+	require.Equal(t, []Span{{Span: spBD}}, ss2.GetSpans(SpanReadOnly, SpanGlobal))
+	require.Equal(t, []Span{{Span: spBD}}, ss2.GetSpans(SpanReadWrite, SpanGlobal))
+
+	// The union with a nil SpanSet is a copy.
+	require.Equal(t, ss.Len(), ss.Union(nil).Len())
+}
+
+func TestSpanSetIntersect(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sp := func(key, endKey string) roachpb.Span {
+		s := roachpb.Span{Key: roachpb.Key(key)}
+		if endKey != "" {
+			s.EndKey = roachpb.Key(endKey)
+		}
+		return s
+	}
+	ts10 := hlc.Timestamp{WallTime: 10}
+	ts20 := hlc.Timestamp{WallTime: 20}
+	spLocal := roachpb.Span{Key: keys.RangeGCThresholdKey(1)}
+
+	var ss SpanSet
+	ss.AddNonMVCC(SpanReadOnly, spLocal)
+	ss.AddNonMVCC(SpanReadOnly, sp("a", "e"))
+	ss.AddNonMVCC(SpanReadWrite, sp("f", "k"))
+	ss.AddMVCC(SpanReadWrite, sp("m", "p"), ts10)
+	ss.AddMVCC(SpanReadOnly, sp("r", "t"), ts10)
+
+	var ss2 SpanSet
+	ss2.AddNonMVCC(SpanReadWrite, spLocal)
+	// Write overlapping with both read and write: read and write access,
+	// respectively.
+	ss2.AddNonMVCC(SpanReadWrite, sp("c", "g"))
+	ss2.AddNonMVCC(SpanReadWrite, sp("j", "n"))
+	// A point span within a write span.
+	ss2.AddNonMVCC(SpanReadOnly, sp("h", ""))
+	// MVCC writes at different timestamps: the later one.
+	ss2.AddMVCC(SpanReadWrite, sp("o", "q"), ts20)
+	// MVCC reads at different timestamps: the earlier one.
+	ss2.AddMVCC(SpanReadOnly, sp("s", "u"), ts20)
+	// No overlap.
+	ss2.AddNonMVCC(SpanReadWrite, sp("x", "z"))
+
+	i := ss.Intersect(&ss2)
+	require.Equal(t, []Span{{Span: spLocal}}, i.GetSpans(SpanReadOnly, SpanLocal))
+	require.Empty(t, i.GetSpans(SpanReadWrite, SpanLocal))
+	require.Equal(t, []Span{
+		{Span: sp("c", "e")},
+		{Span: sp("h", "")},
+		{Span: sp("s", "t"), Timestamp: ts10},
+	}, i.GetSpans(SpanReadOnly, SpanGlobal))
+	require.Equal(t, []Span{
+		{Span: sp("f", "g")},
+		{Span: sp("j", "k")},
+		{Span: sp("m", "n"), Timestamp: ts10},
+		{Span: sp("o", "p"), Timestamp: ts20},
+	}, i.GetSpans(SpanReadWrite, SpanGlobal))
+
+	// The intersection is symmetric.
+	require.Equal(t, i, ss2.Intersect(&ss))
+
+	// The intersection with an empty or nil SpanSet is empty.
+	require.True(t, ss.Intersect(New()).Empty())
+	require.True(t, ss.Intersect(nil).Empty())
+}
+
 // Test that CheckAllowed properly enforces span boundaries.
 func TestSpanSetCheckAllowedBoundaries(t *testing.T) {
 	defer leaktest.AfterTest(t)()
@@ -399,3 +495,245 @@ func TestSpanSetWriteImpliesRead(t *testing.T) {
 		t.Errorf("expected to be allowed to read rwSpan, error: %+v", err)
 	}
 }
+
+// TestSpanSetContainsKey verifies that ContainsKey and ContainsKeyAt agree
+// with CheckAllowed and CheckAllowedAt on single keys.
+func TestSpanSetContainsKey(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var ss SpanSet
+	ss.AddMVCC(SpanReadOnly, roachpb.Span{Key: roachpb.Key("b"), EndKey: roachpb.Key("d")}, hlc.Timestamp{WallTime: 2})
+	ss.AddMVCC(SpanReadOnly, roachpb.Span{Key: roachpb.Key("g")}, hlc.Timestamp{WallTime: 2})
+	ss.AddMVCC(SpanReadWrite, roachpb.Span{Key: roachpb.Key("m"), EndKey: roachpb.Key("o")}, hlc.Timestamp{WallTime: 2})
+	ss.AddMVCC(SpanReadWrite, roachpb.Span{Key: roachpb.Key("s")}, hlc.Timestamp{WallTime: 2})
+	ss.AddNonMVCC(SpanReadOnly, roachpb.Span{Key: roachpb.Key("u"), EndKey: roachpb.Key("w")})
+	ss.AddNonMVCC(SpanReadWrite, roachpb.Span{Key: keys.RangeGCThresholdKey(1)})
+
+	testKeys := []roachpb.Key{
+		roachpb.Key("a"), roachpb.Key("b"), roachpb.Key("c"), roachpb.Key("d"),
+		roachpb.Key("g"), roachpb.Key("g\x00"), roachpb.Key("m"), roachpb.Key("n"),
+		roachpb.Key("o"), roachpb.Key("s"), roachpb.Key("t"), roachpb.Key("v"),
+		keys.RangeGCThresholdKey(1), keys.RangeGCThresholdKey(2),
+	}
+	timestamps := []hlc.Timestamp{{}, {WallTime: 1}, {WallTime: 2}, {WallTime: 3}}
+	for _, access := range []SpanAccess{SpanReadOnly, SpanReadWrite} {
+		for _, key := range testKeys {
+			span := roachpb.Span{Key: key}
+			expected := ss.CheckAllowed(access, span) == nil
+			require.Equal(t, expected, ss.ContainsKey(access, key), "%s %s", access, key)
+			for _, ts := range timestamps {
+				expected := ss.CheckAllowedAt(access, span, ts) == nil
+				require.Equal(t, expected, ss.ContainsKeyAt(access, key, ts),
+					"%s %s at %s", access, key, ts)
+			}
+		}
+	}
+
+	// A few spot checks, in case CheckAllowed{At} and ContainsKey{At} are
+	// wrong in the same way.
+	require.True(t, ss.ContainsKey(SpanReadOnly, roachpb.Key("c")))
+	require.False(t, ss.ContainsKey(SpanReadOnly, roachpb.Key("d")))
+	require.False(t, ss.ContainsKey(SpanReadWrite, roachpb.Key("c")))
+	require.True(t, ss.ContainsKey(SpanReadOnly, roachpb.Key("n")))
+	require.True(t, ss.ContainsKeyAt(SpanReadOnly, roachpb.Key("c"), hlc.Timestamp{WallTime: 1}))
+	require.False(t, ss.ContainsKeyAt(SpanReadOnly, roachpb.Key("c"), hlc.Timestamp{WallTime: 3}))
+	require.False(t, ss.ContainsKeyAt(SpanReadWrite, roachpb.Key("n"), hlc.Timestamp{WallTime: 1}))
+	require.True(t, ss.ContainsKeyAt(SpanReadWrite, keys.RangeGCThresholdKey(1), hlc.Timestamp{}))
+}
+
+// TestSpanSetAllowsAllKeys verifies that AllowsAllKeys only detects spansets
+// which allow every access to the entire keyspace.
+func TestSpanSetAllowsAllKeys(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	local := roachpb.Span{Key: keys.LocalPrefix, EndKey: keys.LocalMax}
+	global := roachpb.Span{Key: keys.MinKey, EndKey: keys.MaxKey}
+	ts := hlc.Timestamp{WallTime: 1}
+	testCases := []struct {
+		name     string
+		add      func(ss *SpanSet)
+		expected bool
+	}{
+		{
+			name: "all keys",
+			add: func(ss *SpanSet) {
+				ss.AddNonMVCC(SpanReadWrite, local)
+				ss.AddNonMVCC(SpanReadWrite, global)
+			},
+			expected: true,
+		},
+		{
+			name: "all keys with other spans",
+			add: func(ss *SpanSet) {
+				ss.AddNonMVCC(SpanReadOnly, roachpb.Span{Key: roachpb.Key("a")})
+				ss.AddNonMVCC(SpanReadWrite, local)
+				ss.AddMVCC(SpanReadWrite, roachpb.Span{Key: roachpb.Key("b")}, ts)
+				ss.AddNonMVCC(SpanReadWrite, global)
+			},
+			expected: true,
+		},
+		{
+			name:     "empty",
+			add:      func(ss *SpanSet) {},
+			expected: false,
+		},
+		{
+			name: "global keys only",
+			add: func(ss *SpanSet) {
+				ss.AddNonMVCC(SpanReadWrite, global)
+			},
+			expected: false,
+		},
+		{
+			name: "local keys only",
+			add: func(ss *SpanSet) {
+				ss.AddNonMVCC(SpanReadWrite, local)
+			},
+			expected: false,
+		},
+		{
+			name: "read-only",
+			add: func(ss *SpanSet) {
+				ss.AddNonMVCC(SpanReadOnly, local)
+				ss.AddNonMVCC(SpanReadOnly, global)
+			},
+			expected: false,
+		},
+		{
+			name: "mvcc",
+			add: func(ss *SpanSet) {
+				ss.AddNonMVCC(SpanReadWrite, local)
+				ss.AddMVCC(SpanReadWrite, global, ts)
+			},
+			expected: false,
+		},
+		{
+			name: "bounded global keys",
+			add: func(ss *SpanSet) {
+				ss.AddNonMVCC(SpanReadWrite, local)
+				ss.AddNonMVCC(SpanReadWrite, roachpb.Span{Key: roachpb.Key("a"), EndKey: keys.MaxKey})
+			},
+			expected: false,
+		},
+		{
+			name: "bounded local keys",
+			add: func(ss *SpanSet) {
+				ss.AddNonMVCC(SpanReadWrite, roachpb.Span{Key: keys.LocalRangePrefix, EndKey: keys.LocalMax})
+				ss.AddNonMVCC(SpanReadWrite, global)
+			},
+			expected: false,
+		},
+		{
+			// Adjacent spans covering the keyspace are not detected, although
+			// they allow all keys.
+			name: "split global keys",
+			add: func(ss *SpanSet) {
+				ss.AddNonMVCC(SpanReadWrite, local)
+				ss.AddNonMVCC(SpanReadWrite, roachpb.Span{Key: keys.MinKey, EndKey: roachpb.Key("m")})
+				ss.AddNonMVCC(SpanReadWrite, roachpb.Span{Key: roachpb.Key("m"), EndKey: keys.MaxKey})
+			},
+			expected: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ss SpanSet
+			tc.add(&ss)
+			require.Equal(t, tc.expected, ss.AllowsAllKeys())
+		})
+	}
+}
+
+func TestSpanSetCheckAllowedError(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var ss SpanSet
+	ss.AddMVCC(SpanReadOnly, roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")}, hlc.Timestamp{WallTime: 1})
+
+	span := roachpb.Span{Key: roachpb.Key("b")}
+	ts := hlc.Timestamp{WallTime: 2}
+	err := errors.Wrap(ss.CheckAllowedAt(SpanReadOnly, span, ts), "wrapped")
+	var accessErr *SpanAccessError
+	require.True(t, errors.As(err, &accessErr))
+	require.Equal(t, SpanReadOnly, accessErr.Access)
+	require.Equal(t, span, accessErr.Span)
+	require.Equal(t, ts, accessErr.Timestamp)
+	require.Equal(t, ss.String(), accessErr.SpanSet.String())
+	require.Regexp(t, "cannot read undeclared span b", accessErr)
+
+	err = ss.CheckAllowed(SpanReadWrite, span)
+	require.True(t, errors.As(err, &accessErr))
+	require.Equal(t, SpanReadWrite, accessErr.Access)
+	require.True(t, accessErr.Timestamp.IsEmpty())
+}
+
+func TestSpanSetCheckAllowsExactly(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var ss SpanSet
+	ss.AddNonMVCC(SpanReadOnly, roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")})
+	ss.AddNonMVCC(SpanReadWrite, roachpb.Span{Key: roachpb.Key("e")})
+
+	require.NoError(t, ss.CheckAllowsExactly(SpanReadOnly,
+		[]roachpb.Span{{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")}, {Key: roachpb.Key("e")}},
+		[]roachpb.Span{{Key: roachpb.Key("c")}, {Key: roachpb.Key("b"), EndKey: roachpb.Key("d")}},
+	))
+	require.NoError(t, ss.CheckAllowsExactly(SpanReadWrite,
+		[]roachpb.Span{{Key: roachpb.Key("e")}},
+		[]roachpb.Span{{Key: roachpb.Key("a")}},
+	))
+
+	// All mismatched spans are reported, not just the first one.
+	err := ss.CheckAllowsExactly(SpanReadWrite,
+		[]roachpb.Span{{Key: roachpb.Key("a")}, {Key: roachpb.Key("b")}, {Key: roachpb.Key("e")}},
+		[]roachpb.Span{{Key: roachpb.Key("e")}, {Key: roachpb.Key("f")}},
+	)
+	require.Error(t, err)
+	require.Regexp(t, "missing allowed span: a\n", err)
+	require.Regexp(t, "missing allowed span: b\n", err)
+	require.Regexp(t, "unexpectedly allowed span: e\n", err)
+	require.NotRegexp(t, "missing allowed span: e", err)
+	require.NotRegexp(t, "unexpectedly allowed span: f", err)
+}
+
+func TestSpanSetMarshal(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ss := New()
+	ss.AddNonMVCC(SpanReadOnly, roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")})
+	ss.AddMVCC(SpanReadOnly, roachpb.Span{Key: roachpb.Key("d")}, hlc.Timestamp{WallTime: 1})
+	ss.AddMVCC(SpanReadWrite, roachpb.Span{Key: roachpb.Key("f"), EndKey: roachpb.Key("g")}, hlc.Timestamp{WallTime: 2, Logical: 3})
+	ss.AddNonMVCC(SpanReadWrite, roachpb.Span{Key: keys.RangeGCThresholdKey(1)})
+	ss.AddNonMVCC(SpanReadOnly, roachpb.Span{Key: keys.RangeDescriptorKey(roachpb.RKey("a"))})
+
+	data, err := ss.Marshal()
+	require.NoError(t, err)
+	decoded, err := UnmarshalSpanSet(data)
+	require.NoError(t, err)
+	require.Equal(t, ss.String(), decoded.String())
+	for access := SpanAccess(0); access < NumSpanAccess; access++ {
+		for scope := SpanScope(0); scope < NumSpanScope; scope++ {
+			require.Equal(t, ss.GetSpans(access, scope), decoded.GetSpans(access, scope))
+		}
+	}
+
+	// An empty SpanSet round-trips.
+	data, err = New().Marshal()
+	require.NoError(t, err)
+	decoded, err = UnmarshalSpanSet(data)
+	require.NoError(t, err)
+	require.True(t, decoded.Empty())
+
+	// Invalid accesses and scopes are rejected.
+	for _, p := range []SpanSetProto{
+		{Spans: []DeclaredSpan{{Access: NumSpanAccess, Span: roachpb.Span{Key: roachpb.Key("a")}}}},
+		{Spans: []DeclaredSpan{{Scope: -1, Span: roachpb.Span{Key: roachpb.Key("a")}}}},
+	} {
+		data, err := protoutil.Marshal(&p)
+		require.NoError(t, err)
+		_, err = UnmarshalSpanSet(data)
+		require.Error(t, err)
+	}
+	_, err = UnmarshalSpanSet([]byte{0xff})
+	require.Error(t, err)
+}
@@ -555,6 +555,8 @@ func (cf *cFetcher) StartScan(
 		cf.lockTimeout,
 		cf.kvFetcherMemAcc,
 		forceProductionKVBatchSize,
+		false, /* keysOnly */
+		nil,   /* neededFamilies */
 	)
 	if err != nil {
 		return err
@@ -577,7 +579,7 @@ func (cf *cFetcher) StartScanStreaming(
 	spans roachpb.Spans,
 	limitHint rowinfra.RowLimit,
 ) error {
-	kvBatchFetcher, err := row.NewTxnKVStreamer(ctx, streamer, spans, cf.lockStrength)
+	kvBatchFetcher, err := row.NewTxnKVStreamer(ctx, streamer, spans, cf.reverse, cf.lockStrength)
 	if err != nil {
 		return err
 	}
@@ -90,8 +90,9 @@ type Result struct {
 		// rows (i.e. a single row is never split into different Results).
 		//
 		// When running in InOrder mode, Results for a single scan will be
-		// delivered in key order (in addition to results for different scans
-		// being delivered in request order). When running in OutOfOrder mode,
+		// delivered in key order, or in decreasing key order for a reverse
+		// scan (in addition to results for different scans being delivered in
+		// request order). When running in OutOfOrder mode,
 		// Results for a single scan can be delivered out of key order (in
 		// addition to results for different scans being delivered out of
 		// request order).
@@ -220,6 +221,9 @@ type Streamer struct {
 	waitGroup sync.WaitGroup
 
 	enqueueKeys []int
+	// reverse indicates whether the scans enqueued by the last call to Enqueue
+	// are ReverseScanRequests.
+	reverse bool
 
 	// requestsToServe contains all single-range sub-requests that have yet
 	// to be served.
@@ -237,9 +241,9 @@ type Streamer struct {
 		avgResponseEstimator avgResponseEstimator
 
 		// numRangesLeftPerScanRequest tracks how many ranges a particular
-		// originally enqueued ScanRequest touches, but scanning of those ranges
-		// isn't complete. It is allocated lazily when the first ScanRequest is
-		// encountered in Enqueue.
+		// originally enqueued ScanRequest (or ReverseScanRequest) touches, but
+		// scanning of those ranges isn't complete. It is allocated lazily when
+		// the first scan request is encountered in Enqueue.
 		numRangesLeftPerScanRequest []int
 
 		// numRequestsInFlight tracks the number of single-range batches that
@@ -374,6 +378,12 @@ func (s *Streamer) Init(
 //
 // In InOrder operation mode, responses will be delivered in reqs order.
 //
+// The requests can be GetRequests and either ScanRequests or
+// ReverseScanRequests, but forward and reverse scans cannot be mixed. The
+// Streamer doesn't reorder the responses according to the direction of the
+// scans, so the caller needs to enqueue the requests in decreasing key order if
+// the responses are expected in that order in InOrder mode.
+//
 // It is the caller's responsibility to ensure that the memory footprint of reqs
 // (i.e. roachpb.Spans inside of the requests) is reasonable. Enqueue will
 // return an error if that footprint exceeds the Streamer's limitBytes. The
@@ -421,6 +431,12 @@ func (s *Streamer) Enqueue(
 	}
 	s.enqueueKeys = enqueueKeys
 
+	reverse, err := isReverse(reqs)
+	if err != nil {
+		return err
+	}
+	s.reverse = reverse
+
 	if err := s.results.init(ctx, len(reqs)); err != nil {
 		return err
 	}
@@ -467,7 +483,7 @@ func (s *Streamer) Enqueue(
 			return err
 		}
 		for _, pos := range positions {
-			if _, isScan := reqs[pos].GetInner().(*roachpb.ScanRequest); isScan {
+			if isScanRequest(reqs[pos]) {
 				if firstScanRequest {
 					// We have some ScanRequests, so we have to set up
 					// numRangesLeftPerScanRequest.
@@ -596,19 +612,31 @@ func (s *Streamer) GetResults(ctx context.Context) ([]Result, error) {
 // other calls on s are allowed after this.
 func (s *Streamer) Close(ctx context.Context) {
 	if s.coordinatorStarted {
-		s.coordinatorCtxCancel()
-		s.mu.Lock()
-		s.mu.done = true
-		s.mu.Unlock()
-		s.requestsToServe.close()
+		s.Stop()
 		s.results.close(ctx)
-		// Unblock the coordinator in case it is waiting for the budget.
-		s.budget.mu.waitForBudget.Signal()
 	}
 	s.waitGroup.Wait()
 	*s = Streamer{}
 }
 
+// Stop cancels all in-flight requests and prevents the Streamer from issuing
+// any new ones. Unlike Close, it doesn't release the resources of the
+// Streamer, so the Results already returned by GetResults can still be
+// released, and Close must still be called afterwards. GetResults must not be
+// called after Stop.
+func (s *Streamer) Stop() {
+	if !s.coordinatorStarted {
+		return
+	}
+	s.coordinatorCtxCancel()
+	s.mu.Lock()
+	s.mu.done = true
+	s.mu.Unlock()
+	s.requestsToServe.close()
+	// Unblock the coordinator in case it is waiting for the budget.
+	s.budget.mu.waitForBudget.Signal()
+}
+
 // getNumRequestsInProgress returns the number of requests that are currently
 // "in progress" - already issued requests that are in flight combined with the
 // number of unreleased results. This method should be called without holding
@@ -1186,13 +1214,14 @@ func calculateFootprint(
 				// This Get was completed.
 				memoryFootprintBytes += getResponseSize(get)
 			}
-		case *roachpb.ScanRequest:
-			scan := reply.(*roachpb.ScanResponse)
+		case *roachpb.ScanRequest, *roachpb.ReverseScanRequest:
+			scan := asScanResponse(reply)
 			if len(scan.BatchResponses) > 0 {
 				memoryFootprintBytes += scanResponseSize(scan)
 			}
 			if scan.ResumeSpan != nil {
-				// This Scan wasn't completed.
+				// This Scan wasn't completed. Note that ReverseScanRequest has
+				// the same size as ScanRequest.
 				scanRequestScratch.SetSpan(*scan.ResumeSpan)
 				resumeReqsMemUsage += int64(scanRequestScratch.Size())
 				numIncompleteScans++
@@ -1235,10 +1264,25 @@ func (w *workerCoordinator) processSingleRangeResults(
 		req   roachpb.GetRequest
 		union roachpb.RequestUnion_Get
 	}, numIncompleteGets)
-	scans := make([]struct {
+	var scans []struct {
 		req   roachpb.ScanRequest
 		union roachpb.RequestUnion_Scan
-	}, numIncompleteScans)
+	}
+	var reverseScans []struct {
+		req   roachpb.ReverseScanRequest
+		union roachpb.RequestUnion_ReverseScan
+	}
+	if w.s.reverse {
+		reverseScans = make([]struct {
+			req   roachpb.ReverseScanRequest
+			union roachpb.RequestUnion_ReverseScan
+		}, numIncompleteScans)
+	} else {
+		scans = make([]struct {
+			req   roachpb.ScanRequest
+			union roachpb.RequestUnion_Scan
+		}, numIncompleteScans)
+	}
 	var results []Result
 	var hasNonEmptyScanResponse bool
 	var resumeReqIdx int
@@ -1295,8 +1339,8 @@ func (w *workerCoordinator) processSingleRangeResults(
 				results = append(results, result)
 			}
 
-		case *roachpb.ScanRequest:
-			scan := reply.(*roachpb.ScanResponse)
+		case *roachpb.ScanRequest, *roachpb.ReverseScanRequest:
+			scan := asScanResponse(reply)
 			if len(scan.Rows) > 0 {
 				return errors.AssertionFailedf(
 					"unexpectedly got a ScanResponse using KEY_VALUES response format",
@@ -1334,13 +1378,23 @@ func (w *workerCoordinator) processSingleRangeResults(
 				// This Scan wasn't completed - update the original
 				// request according to the ResumeSpan and include it
 				// into the batch again.
-				newScan := scans[0]
-				scans = scans[1:]
-				newScan.req.SetSpan(*scan.ResumeSpan)
-				newScan.req.ScanFormat = roachpb.BATCH_RESPONSE
-				newScan.req.KeyLocking = origRequest.KeyLocking
-				newScan.union.Scan = &newScan.req
-				resumeReq.reqs[resumeReqIdx].Value = &newScan.union
+				if origScan, ok := origRequest.(*roachpb.ScanRequest); ok {
+					newScan := scans[0]
+					scans = scans[1:]
+					newScan.req.SetSpan(*scan.ResumeSpan)
+					newScan.req.ScanFormat = roachpb.BATCH_RESPONSE
+					newScan.req.KeyLocking = origScan.KeyLocking
+					newScan.union.Scan = &newScan.req
+					resumeReq.reqs[resumeReqIdx].Value = &newScan.union
+				} else {
+					newScan := reverseScans[0]
+					reverseScans = reverseScans[1:]
+					newScan.req.SetSpan(*scan.ResumeSpan)
+					newScan.req.ScanFormat = roachpb.BATCH_RESPONSE
+					newScan.req.KeyLocking = origRequest.(*roachpb.ReverseScanRequest).KeyLocking
+					newScan.union.ReverseScan = &newScan.req
+					resumeReq.reqs[resumeReqIdx].Value = &newScan.union
+				}
 				resumeReq.positions[resumeReqIdx] = req.positions[i]
 				if resumeReq.minTargetBytes == 0 {
 					resumeReq.minTargetBytes = scan.ResumeNextBytes
@@ -1460,6 +1514,44 @@ func init() {
 	zeroIntSlice = make([]int, 1<<10)
 }
 
+// isReverse returns whether reqs contain ReverseScanRequests. An error is
+// returned if reqs contain both ScanRequests and ReverseScanRequests since the
+// DistSender doesn't allow mixing them in batches with limits.
+func isReverse(reqs []roachpb.RequestUnion) (bool, error) {
+	var hasScans, hasReverseScans bool
+	for i := range reqs {
+		switch reqs[i].GetInner().(type) {
+		case *roachpb.ScanRequest:
+			hasScans = true
+		case *roachpb.ReverseScanRequest:
+			hasReverseScans = true
+		}
+	}
+	if hasScans && hasReverseScans {
+		return false, errors.AssertionFailedf("forward and reverse scans cannot be enqueued together")
+	}
+	return hasReverseScans, nil
+}
+
+// isScanRequest returns whether req is a ScanRequest or a ReverseScanRequest.
+func isScanRequest(req roachpb.RequestUnion) bool {
+	switch req.GetInner().(type) {
+	case *roachpb.ScanRequest, *roachpb.ReverseScanRequest:
+		return true
+	}
+	return false
+}
+
+// asScanResponse returns the response to a ScanRequest or a
+// ReverseScanRequest as a ScanResponse. Both responses have the same fields,
+// so the Results don't need to distinguish them.
+func asScanResponse(reply roachpb.Response) *roachpb.ScanResponse {
+	if reverseScan, ok := reply.(*roachpb.ReverseScanResponse); ok {
+		return (*roachpb.ScanResponse)(reverseScan)
+	}
+	return reply.(*roachpb.ScanResponse)
+}
+
 const requestUnionOverhead = int64(unsafe.Sizeof(roachpb.RequestUnion{}))
 
 func requestsMemUsage(reqs []roachpb.RequestUnion) int64 {
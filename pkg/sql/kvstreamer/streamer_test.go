@@ -78,6 +78,19 @@ func TestStreamerLimitations(t *testing.T) {
 		require.Error(t, streamer.Enqueue(ctx, reqs, enqueueKeys))
 	})
 
+	t.Run("mixed forward and reverse scans", func(t *testing.T) {
+		streamer := getStreamer()
+		defer streamer.Close(ctx)
+		streamer.Init(OutOfOrder, Hints{UniqueRequests: true}, 1 /* maxKeysPerRow */, nil /* engine */, nil /* diskMonitor */)
+		scan := roachpb.NewScan(roachpb.Key("a"), roachpb.Key("b"), false /* forUpdate */)
+		reverseScan := roachpb.NewReverseScan(roachpb.Key("c"), roachpb.Key("d"), false /* forUpdate */)
+		reqs := []roachpb.RequestUnion{
+			{Value: &roachpb.RequestUnion_Scan{Scan: scan.(*roachpb.ScanRequest)}},
+			{Value: &roachpb.RequestUnion_ReverseScan{ReverseScan: reverseScan.(*roachpb.ReverseScanRequest)}},
+		}
+		require.Error(t, streamer.Enqueue(ctx, reqs, nil /* enqueueKeys */))
+	})
+
 	t.Run("pipelining unsupported", func(t *testing.T) {
 		streamer := getStreamer()
 		defer streamer.Close(ctx)
@@ -32,25 +32,51 @@ var NoticesEnabled = settings.RegisterBoolSetting(
 // sending notices.
 type noticeSender interface {
 	BufferNotice(pgnotice.Notice)
+	SendNotice(context.Context, pgnotice.Notice) error
 }
 
 // BufferClientNotice implements the tree.ClientNoticeSender interface.
+//
+// If ctx is the context of a plan hook's PlanHookRowFn, the notice is instead
+// sent to the client interleaved with the rows produced by the hook (see
+// hookFnNode).
 func (p *planner) BufferClientNotice(ctx context.Context, notice pgnotice.Notice) {
+	if sendNotice := planHookNoticeSenderFromCtx(ctx); sendNotice != nil {
+		sendNotice(ctx, notice)
+		return
+	}
 	if log.V(2) {
 		log.Infof(ctx, "buffered notice: %+v", notice)
 	}
+	if !p.canSendClientNotice(notice) {
+		return
+	}
+	p.noticeSender.BufferNotice(notice)
+}
+
+// sendClientNotice is like BufferClientNotice, but the notice is sent to the
+// client right away, after the rows already added to the result.
+func (p *planner) sendClientNotice(ctx context.Context, notice pgnotice.Notice) error {
+	if log.V(2) {
+		log.Infof(ctx, "sending notice: %+v", notice)
+	}
+	if !p.canSendClientNotice(notice) {
+		return nil
+	}
+	return p.noticeSender.SendNotice(ctx, notice)
+}
+
+// canSendClientNotice returns whether the notice can flow to the client.
+func (p *planner) canSendClientNotice(notice pgnotice.Notice) bool {
 	noticeSeverity, ok := pgnotice.ParseDisplaySeverity(pgerror.GetSeverity(notice))
 	if !ok {
 		noticeSeverity = pgnotice.DisplaySeverityNotice
 	}
-	if p.noticeSender == nil ||
-		noticeSeverity > pgnotice.DisplaySeverity(p.SessionData().NoticeDisplaySeverity) ||
-		!NoticesEnabled.Get(&p.execCfg.Settings.SV) {
-		// Notice cannot flow to the client - because of one of these conditions:
-		// * there is no client
-		// * the session's NoticeDisplaySeverity is higher than the severity of the notice.
-		// * the notice protocol was disabled
-		return
-	}
-	p.noticeSender.BufferNotice(notice)
+	// Notice cannot flow to the client - because of one of these conditions:
+	// * there is no client
+	// * the session's NoticeDisplaySeverity is higher than the severity of the notice.
+	// * the notice protocol was disabled
+	return p.noticeSender != nil &&
+		noticeSeverity <= pgnotice.DisplaySeverity(p.SessionData().NoticeDisplaySeverity) &&
+		NoticesEnabled.Get(&p.execCfg.Settings.SV)
 }
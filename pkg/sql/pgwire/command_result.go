@@ -194,8 +194,9 @@ func (r *commandResult) SetError(err error) {
 	r.err = err
 }
 
-// addInternal is the skeleton of AddRow and AddBatch implementations.
-// bufferData should update rowsAffected and buffer the data accordingly.
+// addInternal is the skeleton of AddRow, AddBatch and SendNotice
+// implementations. bufferData should update rowsAffected, if needed, and
+// buffer the data accordingly.
 func (r *commandResult) addInternal(bufferData func()) error {
 	r.assertNotReleased()
 	if r.err != nil {
@@ -260,6 +261,15 @@ func (r *commandResult) BufferNotice(notice pgnotice.Notice) {
 	r.buffer.notices = append(r.buffer.notices, notice)
 }
 
+// SendNotice is part of the sql.RestrictedCommandResult interface.
+func (r *commandResult) SendNotice(ctx context.Context, notice pgnotice.Notice) error {
+	return r.addInternal(func() {
+		if err := r.conn.bufferNotice(ctx, notice); err != nil {
+			panic(errors.NewAssertionErrorWithWrappedErrf(err, "unexpected err when sending notice"))
+		}
+	})
+}
+
 // SetColumns is part of the sql.RestrictedCommandResult interface.
 func (r *commandResult) SetColumns(ctx context.Context, cols colinfo.ResultColumns) {
 	r.assertNotReleased()
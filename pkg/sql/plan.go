@@ -525,17 +525,14 @@ func (p *planner) maybePlanHook(ctx context.Context, stmt tree.Statement) (planN
 	// reflection in such a primary codepath is unfortunate. Instead, the
 	// upcoming IR work will provide unique numeric type tags, which will
 	// elegantly solve this.
-	for _, planHook := range planHooks {
-		if fn, header, subplans, avoidBuffering, err := planHook.fn(ctx, stmt, p); err != nil {
-			return nil, err
-		} else if fn != nil {
-			if avoidBuffering {
-				p.curPlan.avoidBuffering = true
-			}
-			return newHookFnNode(planHook.name, fn, header, subplans), nil
-		}
+	planHook, res, err := invokePlanHooks(ctx, stmt, p)
+	if err != nil || planHook == nil {
+		return nil, err
+	}
+	if res.AvoidBuffering {
+		p.curPlan.avoidBuffering = true
 	}
-	return nil, nil
+	return newHookFnNode(planHook, res.Fn, res.Header, res.Subplans), nil
 }
 
 // Mark transaction as operating on the system DB if the descriptor id
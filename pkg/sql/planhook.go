@@ -12,7 +12,11 @@ package sql
 
 import (
 	"context"
+	"sort"
+	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/migration"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
@@ -24,10 +28,16 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/lease"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/resolver"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgnotice"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/log/logcrash"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/errors"
 )
 
 // planHookFn is a function that can intercept a statement being planned and
@@ -50,15 +60,70 @@ type planHookFn func(
 // sends on it when necessary. Any subplans returned by the hook when initially
 // called are passed back, planned and started, for the RowFn's use.
 //
+// Notices buffered through PlanHookState.BufferClientNotice with the context
+// passed to the RowFn are sent to the client in order with the rows sent on
+// the channel, instead of when the statement completes. Notices buffered after
+// the RowFn has returned, e.g. by goroutines it leaked, are dropped.
+//
+// Similarly, the RowFn can report the fraction of its work that is completed
+// through PlanHookState.ReportPlanHookProgress with the context passed to it.
+// See ReportPlanHookProgress for the semantics of the reported progress.
+//
 //TODO(dt): should this take runParams like a normal planNode.Next?
 type PlanHookRowFn func(context.Context, []planNode, chan<- tree.Datums) error
 
 type planHook struct {
-	name string
-	fn   planHookFn
+	name     string
+	priority int
+	fn       planHookFn
+	// timeout, if non-zero, is the maximum duration of the execution of the
+	// PlanHookRowFn returned by fn.
+	timeout time.Duration
+	// noRows, if set, indicates that the PlanHookRowFn returned by fn never
+	// produces any rows, so it is run synchronously.
+	noRows bool
+}
+
+// PlanHookOption is an optional setting of a plan hook passed to AddPlanHook.
+type PlanHookOption func(*planHook)
+
+// WithPlanHookTimeout limits the duration of the execution of the hook's
+// PlanHookRowFn to the given timeout, after which the statement fails with a
+// QueryCanceled error. A zero timeout, which is the default, disables the
+// limit, which is appropriate for hooks running long jobs.
+func WithPlanHookTimeout(timeout time.Duration) PlanHookOption {
+	return func(h *planHook) {
+		h.timeout = timeout
+	}
 }
 
-var planHooks []planHook
+// WithPlanHookNoRows declares that the hook's PlanHookRowFn never produces any
+// rows, which is the case for hooks implementing statements executed only for
+// their side effects. Such a PlanHookRowFn is run synchronously during the
+// start of the execution rather than in a separate goroutine. Sending a row on
+// its results channel results in an assertion failure.
+func WithPlanHookNoRows() PlanHookOption {
+	return func(h *planHook) {
+		h.noRows = true
+	}
+}
+
+// planHooks are the registered plan hooks.
+var planHooks struct {
+	syncutil.RWMutex
+	// hooks are ordered from the highest priority to the lowest one. Hooks
+	// with the same priority are in registration order. The slice is never
+	// modified in place but replaced, so that it can be iterated over without
+	// holding the lock once it is read.
+	hooks []planHook
+}
+
+// getPlanHooks returns the registered plan hooks, which must not be modified.
+func getPlanHooks() []planHook {
+	planHooks.RLock()
+	defer planHooks.RUnlock()
+	return planHooks.hooks
+}
 
 func (p *planner) RunParams(ctx context.Context) runParams {
 	return runParams{ctx, p.ExtendedEvalContext(), p}
@@ -109,6 +174,8 @@ type PlanHookState interface {
 	MigrationJobDeps() migration.JobDeps
 	SpanConfigReconciler() spanconfig.Reconciler
 	BufferClientNotice(ctx context.Context, notice pgnotice.Notice)
+	ReportPlanHookProgress(ctx context.Context, fraction float32) error
+	SetPlanHookProgressJob(ctx context.Context, job *jobs.Job)
 	Txn() *kv.Txn
 }
 
@@ -116,15 +183,130 @@ type PlanHookState interface {
 // tree.Statement. If the func returned by the hook is non-nil, it is used to
 // construct a planNode that runs that func in a goroutine during Start.
 //
+// The name must be unique among the registered hooks; registering a second hook
+// with the same name panics.
+//
 // See PlanHookState comments for information about why plan hooks are needed.
-func AddPlanHook(name string, fn planHookFn) {
-	planHooks = append(planHooks, planHook{name: name, fn: fn})
+func AddPlanHook(name string, fn planHookFn, opts ...PlanHookOption) {
+	AddPlanHookWithPriority(name, DefaultPlanHookPriority, fn, opts...)
+}
+
+// DefaultPlanHookPriority is the priority of the hooks added via AddPlanHook.
+const DefaultPlanHookPriority = 0
+
+// AddPlanHookWithPriority is like AddPlanHook, but the hook is given the
+// specified priority. When planning a statement, hooks are consulted from the
+// highest priority to the lowest one, and the first hook that intercepts the
+// statement is used. Hooks with the same priority are consulted in the order
+// they were registered in.
+func AddPlanHookWithPriority(
+	name string, priority int, fn planHookFn, opts ...PlanHookOption,
+) {
+	hook := planHook{name: name, priority: priority, fn: fn}
+	for _, opt := range opts {
+		opt(&hook)
+	}
+	planHooks.Lock()
+	defer planHooks.Unlock()
+	hooks := planHooks.hooks
+	for i := range hooks {
+		if hooks[i].name == name {
+			panic(errors.AssertionFailedf("plan hook %q already registered", name))
+		}
+	}
+	// Insert the hook after all hooks with the same or higher priority.
+	i := sort.Search(len(hooks), func(i int) bool {
+		return hooks[i].priority < priority
+	})
+	newHooks := make([]planHook, 0, len(hooks)+1)
+	newHooks = append(newHooks, hooks[:i]...)
+	newHooks = append(newHooks, hook)
+	planHooks.hooks = append(newHooks, hooks[i:]...)
+}
+
+// RemovePlanHook removes the plan hook registered with the given name and
+// returns whether such a hook was found. It allows tests to undo the
+// registration of a mocked out plan hook without affecting the others.
+func RemovePlanHook(name string) bool {
+	planHooks.Lock()
+	defer planHooks.Unlock()
+	hooks := planHooks.hooks
+	for i := range hooks {
+		if hooks[i].name == name {
+			planHooks.hooks = append(hooks[:i:i], hooks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ListPlanHooks returns the names of the registered plan hooks in the order in
+// which they are consulted when planning a statement.
+func ListPlanHooks() []string {
+	hooks := getPlanHooks()
+	names := make([]string, len(hooks))
+	for i := range hooks {
+		names[i] = hooks[i].name
+	}
+	return names
 }
 
 // ClearPlanHooks is used by tests to clear out any mocked out plan hooks that
 // were registered.
 func ClearPlanHooks() {
-	planHooks = nil
+	planHooks.Lock()
+	defer planHooks.Unlock()
+	planHooks.hooks = nil
+}
+
+// PlanHookResult is what a plan hook returned when intercepting a statement.
+type PlanHookResult struct {
+	// Name is the name of the plan hook.
+	Name           string
+	Fn             PlanHookRowFn
+	Header         colinfo.ResultColumns
+	Subplans       []PlanNode
+	AvoidBuffering bool
+}
+
+// invokePlanHooks consults the registered plan hooks in order and returns the
+// first one intercepting stmt along with what it returned. The returned hook
+// is nil if none of them intercepts stmt.
+func invokePlanHooks(
+	ctx context.Context, stmt tree.Statement, p PlanHookState,
+) (*planHook, PlanHookResult, error) {
+	hooks := getPlanHooks()
+	for i := range hooks {
+		hook := &hooks[i]
+		fn, header, subplans, avoidBuffering, err := hook.fn(ctx, stmt, p)
+		if err != nil {
+			return nil, PlanHookResult{}, err
+		}
+		if fn != nil {
+			return hook, PlanHookResult{
+				Name:           hook.name,
+				Fn:             fn,
+				Header:         header,
+				Subplans:       subplans,
+				AvoidBuffering: avoidBuffering,
+			}, nil
+		}
+	}
+	return nil, PlanHookResult{}, nil
+}
+
+// InvokePlanHookForTest finds the registered plan hook intercepting stmt, like
+// the planner does, and returns what it returned when given p. The returned
+// PlanHookRowFn is not run, and the subplans are neither started nor closed,
+// which is left to the caller. ok is false if no hook intercepts stmt.
+//
+// It allows tests to assert on the behavior of a plan hook directly, without
+// building a full planner and executing the statement.
+func InvokePlanHookForTest(
+	ctx context.Context, stmt tree.Statement, p PlanHookState,
+) (res PlanHookResult, ok bool, err error) {
+	hook, res, err := invokePlanHooks(ctx, stmt, p)
+	return res, hook != nil, err
 }
 
 // hookFnNode is a planNode implemented in terms of a function. It begins the
@@ -137,6 +319,8 @@ type hookFnNode struct {
 	f        PlanHookRowFn
 	header   colinfo.ResultColumns
 	subplans []planNode
+	timeout  time.Duration
+	noRows   bool
 
 	run hookFnRun
 }
@@ -147,20 +331,54 @@ var _ planNode = &hookFnNode{}
 type hookFnRun struct {
 	resultsCh chan tree.Datums
 	errCh     chan error
+	// noticeCh is used to pass the notices buffered by the hook to Next, so
+	// that they are sent to the client in order with the rows.
+	noticeCh chan pgnotice.Notice
+	// hookDoneCh is closed once the hook's function has returned.
+	hookDoneCh chan struct{}
+	// timeoutCh is closed once the hook's context is done when the hook has a
+	// timeout. It is nil otherwise.
+	timeoutCh <-chan struct{}
+
+	// progress tracks the progress reported by the hook's function.
+	progress struct {
+		syncutil.Mutex
+		// fraction is the highest fraction of completion reported so far.
+		fraction float32
+		// job, if set, is the job that the reported progress is forwarded to.
+		job *jobs.Job
+		// done is set once the hook's function has returned, after which any
+		// reported progress is dropped.
+		done bool
+	}
 
 	row tree.Datums
 }
 
 func newHookFnNode(
-	name string, fn PlanHookRowFn, header colinfo.ResultColumns, subplans []planNode,
+	hook *planHook, fn PlanHookRowFn, header colinfo.ResultColumns, subplans []planNode,
 ) *hookFnNode {
-	return &hookFnNode{name: name, f: fn, header: header, subplans: subplans}
+	return &hookFnNode{
+		name:     hook.name,
+		f:        fn,
+		header:   header,
+		subplans: subplans,
+		timeout:  hook.timeout,
+		noRows:   hook.noRows,
+	}
 }
 
 func (f *hookFnNode) startExec(params runParams) error {
+	if f.noRows {
+		return f.runSync(params)
+	}
 	// TODO(dan): Make sure the resultCollector is set to flush after every row.
 	f.run.resultsCh = make(chan tree.Datums)
-	f.run.errCh = make(chan error)
+	// errCh is buffered so that the final error of the hook is always
+	// delivered, even if Next has already returned.
+	f.run.errCh = make(chan error, 1)
+	f.run.noticeCh = make(chan pgnotice.Notice)
+	f.run.hookDoneCh = make(chan struct{})
 	// Start a new span for the execution of the hook's plan. This is particularly
 	// important since that execution might outlive the span in params.ctx.
 	// Generally speaking, the subplan is not supposed to outlive the caller since
@@ -175,27 +393,203 @@ func (f *hookFnNode) startExec(params runParams) error {
 	// listen for cancellation and guarantee Next() doesn't return false until the
 	// subplan has completely shutdown.
 	subplanCtx, sp := tracing.ChildSpan(params.ctx, f.name)
+	cancel := func() {}
+	if f.timeout != 0 {
+		subplanCtx, cancel = context.WithTimeout(subplanCtx, f.timeout)
+		f.run.timeoutCh = subplanCtx.Done()
+	}
+	subplanCtx = withPlanHookNoticeSender(subplanCtx, f.sendNotice)
+	subplanCtx = withPlanHookProgressNode(subplanCtx, f)
 	go func() {
 		defer sp.Finish()
-		err := f.f(subplanCtx, f.subplans, f.run.resultsCh)
-		select {
-		case <-params.ctx.Done():
-		case f.run.errCh <- err:
+		defer cancel()
+		err := f.runHook(subplanCtx)
+		f.finishProgress(err)
+		close(f.run.hookDoneCh)
+		if err != nil && f.timedOut(params.ctx, subplanCtx) {
+			err = errors.CombineErrors(f.timeoutError(), err)
 		}
+		f.run.errCh <- err
 		close(f.run.errCh)
 		close(f.run.resultsCh)
 	}()
 	return nil
 }
 
-func (f *hookFnNode) Next(params runParams) (bool, error) {
+// runSync runs the hook's function synchronously, which is possible for hooks
+// that don't produce any rows.
+func (f *hookFnNode) runSync(params runParams) error {
+	ctx, sp := tracing.ChildSpan(params.ctx, f.name)
+	defer sp.Finish()
+	if f.timeout != 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+	timeoutCtx := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// The rows sent by the hook are received by a guard, which cancels the
+	// hook's context on the first one, so that the hook fails rather than
+	// blocks. The channel is not closed, since a send on a closed channel
+	// would crash the node if made from a goroutine spawned by the hook. Rows
+	// sent after the hook returned block forever instead.
+	f.run.resultsCh = make(chan tree.Datums)
+	guardDoneCh := make(chan struct{})
+	sentRowCh := make(chan bool, 1)
+	go func() {
+		var sentRow bool
+		for {
+			select {
+			case <-f.run.resultsCh:
+				if !sentRow {
+					sentRow = true
+					cancel()
+				}
+			case <-guardDoneCh:
+				sentRowCh <- sentRow
+				return
+			}
+		}
+	}()
+	err := f.runHook(withPlanHookProgressNode(ctx, f))
+	close(guardDoneCh)
+	if <-sentRowCh {
+		err = errors.CombineErrors(errors.AssertionFailedf(
+			"plan hook %q declared to produce no rows sent a row", f.name,
+		), err)
+	}
+	f.finishProgress(err)
+	if err != nil && f.timedOut(params.ctx, timeoutCtx) {
+		err = errors.CombineErrors(f.timeoutError(), err)
+	}
+	return err
+}
+
+// runHook runs the hook's function. A panic in the function is converted into
+// an error so that the statement fails instead of crashing the node.
+func (f *hookFnNode) runHook(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.NewAssertionErrorWithWrappedErrf(
+				logcrash.PanicAsError(1 /* depth */, r), "panic in plan hook %q", f.name,
+			)
+		}
+	}()
+	return f.f(ctx, f.subplans, f.run.resultsCh)
+}
+
+// timedOut returns whether the hook's context, derived from ctx, is done
+// because of the hook's timeout.
+func (f *hookFnNode) timedOut(ctx, hookCtx context.Context) bool {
+	return f.timeout != 0 && ctx.Err() == nil && errors.Is(hookCtx.Err(), context.DeadlineExceeded)
+}
+
+// sendNotice passes a notice buffered by the hook to Next. The notice is
+// dropped if the hook is done.
+func (f *hookFnNode) sendNotice(ctx context.Context, notice pgnotice.Notice) {
 	select {
-	case <-params.ctx.Done():
-		return false, params.ctx.Err()
-	case err := <-f.run.errCh:
-		return false, err
-	case f.run.row = <-f.run.resultsCh:
-		return true, nil
+	case f.run.noticeCh <- notice:
+	case <-f.run.hookDoneCh:
+		log.VEventf(ctx, 2, "dropping notice buffered after plan hook %q returned: %v", f.name, notice)
+	case <-ctx.Done():
+	}
+}
+
+// reportProgress records the fraction of completion reported by the hook and
+// forwards it to the job set by setProgressJob, if any. Progress lower than
+// the one already reported and progress reported after the hook returned are
+// dropped.
+func (f *hookFnNode) reportProgress(ctx context.Context, fraction float32) error {
+	if fraction < 0 || fraction > 1 {
+		return errors.AssertionFailedf(
+			"plan hook %q reported progress %f outside of [0, 1]", f.name, fraction,
+		)
+	}
+	job, ok := func() (*jobs.Job, bool) {
+		f.run.progress.Lock()
+		defer f.run.progress.Unlock()
+		if f.run.progress.done {
+			log.VEventf(ctx, 2, "dropping progress reported after plan hook %q returned: %f", f.name, fraction)
+			return nil, false
+		}
+		if fraction < f.run.progress.fraction {
+			return nil, false
+		}
+		f.run.progress.fraction = fraction
+		return f.run.progress.job, true
+	}()
+	if !ok || job == nil {
+		return nil
+	}
+	// The job is updated without holding the lock, since it involves a
+	// transaction. Concurrent updates conflict on the job's row, so the update
+	// persists the highest fraction reported at the time it is evaluated rather
+	// than its own, which keeps the persisted progress monotonic.
+	return job.FractionProgressed(ctx, nil /* txn */, func(
+		context.Context, jobspb.ProgressDetails,
+	) float32 {
+		f.run.progress.Lock()
+		defer f.run.progress.Unlock()
+		return f.run.progress.fraction
+	})
+}
+
+// setProgressJob sets the job that the progress reported by the hook is
+// forwarded to.
+func (f *hookFnNode) setProgressJob(job *jobs.Job) {
+	f.run.progress.Lock()
+	defer f.run.progress.Unlock()
+	f.run.progress.job = job
+}
+
+// finishProgress is called once the hook's function has returned with the
+// given error. If the function succeeded, its work is considered completed.
+func (f *hookFnNode) finishProgress(err error) {
+	f.run.progress.Lock()
+	defer f.run.progress.Unlock()
+	f.run.progress.done = true
+	if err == nil {
+		f.run.progress.fraction = 1
+	}
+}
+
+func (f *hookFnNode) timeoutError() error {
+	return pgerror.Newf(pgcode.QueryCanceled, "plan hook %q timed out after %s", f.name, f.timeout)
+}
+
+func (f *hookFnNode) Next(params runParams) (bool, error) {
+	if f.noRows {
+		return false, nil
+	}
+	for {
+		select {
+		case <-params.ctx.Done():
+			return false, params.ctx.Err()
+		case <-f.run.timeoutCh:
+			if err := params.ctx.Err(); err != nil {
+				return false, err
+			}
+			return false, f.timeoutError()
+		case err, ok := <-f.run.errCh:
+			if !ok {
+				// The error of the hook has already been returned.
+				return false, nil
+			}
+			return false, err
+		case notice := <-f.run.noticeCh:
+			if err := params.p.sendClientNotice(params.ctx, notice); err != nil {
+				return false, err
+			}
+		case row, ok := <-f.run.resultsCh:
+			if !ok {
+				// The hook has returned, and its error was sent on errCh
+				// before resultsCh was closed.
+				return false, <-f.run.errCh
+			}
+			f.run.row = row
+			return true, nil
+		}
 	}
 }
 
@@ -206,3 +600,80 @@ func (f *hookFnNode) Close(ctx context.Context) {
 		sub.Close(ctx)
 	}
 }
+
+// contextPlanHookNoticeSenderKey is an empty type for the handle associated
+// with the function sending the notices of a plan hook (see context.Value).
+type contextPlanHookNoticeSenderKey struct{}
+
+// withPlanHookNoticeSender adds the function sending the notices buffered by a
+// plan hook to the provided context.
+func withPlanHookNoticeSender(
+	ctx context.Context, sendNotice func(context.Context, pgnotice.Notice),
+) context.Context {
+	return context.WithValue(ctx, contextPlanHookNoticeSenderKey{}, sendNotice)
+}
+
+// planHookNoticeSenderFromCtx returns the function sending the notices of a
+// plan hook from a context, or nil if unset.
+func planHookNoticeSenderFromCtx(ctx context.Context) func(context.Context, pgnotice.Notice) {
+	sendNotice := ctx.Value(contextPlanHookNoticeSenderKey{})
+	if sendNotice == nil {
+		return nil
+	}
+	return sendNotice.(func(context.Context, pgnotice.Notice))
+}
+
+// contextPlanHookProgressNodeKey is an empty type for the handle associated
+// with the hookFnNode tracking the progress of a plan hook (see
+// context.Value).
+type contextPlanHookProgressNodeKey struct{}
+
+// withPlanHookProgressNode adds the hookFnNode tracking the progress reported
+// by a plan hook to the provided context.
+func withPlanHookProgressNode(ctx context.Context, f *hookFnNode) context.Context {
+	return context.WithValue(ctx, contextPlanHookProgressNodeKey{}, f)
+}
+
+// planHookProgressNodeFromCtx returns the hookFnNode tracking the progress of
+// a plan hook from a context, or nil if unset.
+func planHookProgressNodeFromCtx(ctx context.Context) *hookFnNode {
+	f := ctx.Value(contextPlanHookProgressNodeKey{})
+	if f == nil {
+		return nil
+	}
+	return f.(*hookFnNode)
+}
+
+// ReportPlanHookProgress reports the fraction, between 0 and 1, of the work of
+// a plan hook's PlanHookRowFn that is completed. ctx must be the context passed
+// to the PlanHookRowFn, or one derived from it; progress reported with any
+// other context is ignored.
+//
+// The reported progress is monotonic: a fraction lower than the one already
+// reported is ignored. It is independent of the rows the PlanHookRowFn
+// produces, i.e. reporting a fraction of 1 doesn't end the statement, and the
+// PlanHookRowFn is still expected to send its final rows, if any. Once the
+// PlanHookRowFn successfully returns, its work is considered completed; any
+// progress reported after that is dropped.
+//
+// If a job was set with SetPlanHookProgressJob, the progress is also recorded
+// as the job's fraction completed, and an error is returned if the job's
+// progress cannot be updated.
+func (p *planner) ReportPlanHookProgress(ctx context.Context, fraction float32) error {
+	f := planHookProgressNodeFromCtx(ctx)
+	if f == nil {
+		log.VEventf(ctx, 2, "ignoring progress reported outside of a plan hook: %f", fraction)
+		return nil
+	}
+	return f.reportProgress(ctx, fraction)
+}
+
+// SetPlanHookProgressJob sets the job whose fraction completed is updated with
+// the progress reported through ReportPlanHookProgress, typically the job
+// created by the plan hook. ctx must be the context passed to the plan hook's
+// PlanHookRowFn, or one derived from it.
+func (p *planner) SetPlanHookProgressJob(ctx context.Context, job *jobs.Job) {
+	if f := planHookProgressNodeFromCtx(ctx); f != nil {
+		f.setProgressJob(job)
+	}
+}
@@ -0,0 +1,508 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgnotice"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/sql/sessiondata"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// resetPlanHooksForTest clears out the registered plan hooks and returns a
+// function that restores them.
+func resetPlanHooksForTest() func() {
+	planHooks.Lock()
+	defer planHooks.Unlock()
+	old := planHooks.hooks
+	planHooks.hooks = nil
+	return func() {
+		planHooks.Lock()
+		defer planHooks.Unlock()
+		planHooks.hooks = old
+	}
+}
+
+// makeTestPlanHook returns a plan hook that intercepts all statements of the
+// same type as stmt.
+func makeTestPlanHook(stmt tree.Statement) planHookFn {
+	return func(
+		_ context.Context, s tree.Statement, _ PlanHookState,
+	) (PlanHookRowFn, colinfo.ResultColumns, []planNode, bool, error) {
+		if s.StatementTag() != stmt.StatementTag() {
+			return nil, nil, nil, false, nil
+		}
+		fn := func(context.Context, []planNode, chan<- tree.Datums) error {
+			return nil
+		}
+		return fn, nil, nil, false, nil
+	}
+}
+
+func TestPlanHookPriority(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	defer resetPlanHooksForTest()()
+
+	ctx := context.Background()
+	backup, restore := &tree.Backup{}, &tree.Restore{}
+	AddPlanHook("backup", makeTestPlanHook(backup))
+	AddPlanHook("backup-tie", makeTestPlanHook(backup))
+	AddPlanHookWithPriority("restore-low", -1, makeTestPlanHook(restore))
+	AddPlanHookWithPriority("restore-high", 1, makeTestPlanHook(restore))
+
+	p := &planner{}
+	for _, tc := range []struct {
+		stmt     tree.Statement
+		expected string
+	}{
+		// Hooks with the same priority are consulted in registration order.
+		{stmt: backup, expected: "backup"},
+		{stmt: restore, expected: "restore-high"},
+	} {
+		plan, err := p.maybePlanHook(ctx, tc.stmt)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, plan.(*hookFnNode).name)
+	}
+
+	require.Equal(t, []string{"restore-high", "backup", "backup-tie", "restore-low"}, ListPlanHooks())
+}
+
+func TestRemovePlanHook(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	defer resetPlanHooksForTest()()
+
+	ctx := context.Background()
+	backup := &tree.Backup{}
+	AddPlanHook("backup", makeTestPlanHook(backup))
+	AddPlanHookWithPriority("mock backup", 1, makeTestPlanHook(backup))
+
+	p := &planner{}
+	plan, err := p.maybePlanHook(ctx, backup)
+	require.NoError(t, err)
+	require.Equal(t, "mock backup", plan.(*hookFnNode).name)
+
+	require.True(t, RemovePlanHook("mock backup"))
+	require.False(t, RemovePlanHook("mock backup"))
+	require.Equal(t, []string{"backup"}, ListPlanHooks())
+	plan, err = p.maybePlanHook(ctx, backup)
+	require.NoError(t, err)
+	require.Equal(t, "backup", plan.(*hookFnNode).name)
+}
+
+// TestListPlanHooksConcurrent verifies that the registered plan hooks can be
+// listed while hooks are added and removed concurrently.
+func TestListPlanHooksConcurrent(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	defer resetPlanHooksForTest()()
+
+	backup := &tree.Backup{}
+	AddPlanHook("backup", makeTestPlanHook(backup))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			AddPlanHookWithPriority("mock backup", 1, makeTestPlanHook(backup))
+			RemovePlanHook("mock backup")
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			require.Equal(t, []string{"backup"}, ListPlanHooks())
+			return
+		default:
+			names := ListPlanHooks()
+			require.Contains(t, names, "backup")
+		}
+	}
+}
+
+func TestAddPlanHookDuplicateName(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	defer resetPlanHooksForTest()()
+
+	backup := &tree.Backup{}
+	AddPlanHook("backup", makeTestPlanHook(backup))
+	require.Panics(t, func() {
+		AddPlanHookWithPriority("backup", 1, makeTestPlanHook(backup))
+	})
+	require.Equal(t, []string{"backup"}, ListPlanHooks())
+
+	// The name can be reused once the hooks are cleared.
+	ClearPlanHooks()
+	AddPlanHook("backup", makeTestPlanHook(backup))
+	require.Equal(t, []string{"backup"}, ListPlanHooks())
+}
+
+func TestPlanHookPanic(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	n := newHookFnNode(&planHook{name: "panicky"}, func(context.Context, []planNode, chan<- tree.Datums) error {
+		panic("boom")
+	}, nil /* header */, nil /* subplans */)
+	params := runParams{ctx: ctx}
+	require.NoError(t, n.startExec(params))
+	ok, err := n.Next(params)
+	require.False(t, ok)
+	require.Error(t, err)
+	require.True(t, errors.HasAssertionFailure(err))
+	require.Regexp(t, `panic in plan hook "panicky": .*boom`, err)
+	n.Close(ctx)
+}
+
+func TestPlanHookTimeout(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	unblock := make(chan struct{})
+	defer close(unblock)
+	for _, tc := range []struct {
+		name string
+		fn   PlanHookRowFn
+	}{
+		{
+			name: "respects cancellation",
+			fn: func(ctx context.Context, _ []planNode, _ chan<- tree.Datums) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		{
+			name: "ignores cancellation",
+			fn: func(context.Context, []planNode, chan<- tree.Datums) error {
+				<-unblock
+				return nil
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hook := &planHook{name: "slow", timeout: time.Millisecond}
+			n := newHookFnNode(hook, tc.fn, nil /* header */, nil /* subplans */)
+			params := runParams{ctx: ctx}
+			require.NoError(t, n.startExec(params))
+			ok, err := n.Next(params)
+			require.False(t, ok)
+			require.Equal(t, pgcode.QueryCanceled, pgerror.GetPGCode(err))
+			require.Regexp(t, `plan hook "slow" timed out after 1ms`, err)
+			n.Close(ctx)
+		})
+	}
+}
+
+// TestPlanHookTimeoutRepeated runs hooks that return once their timeout fires
+// many times to verify that the timeout error always reaches the caller, no
+// matter how the hook's completion races with the timeout in Next.
+func TestPlanHookTimeoutRepeated(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name string
+		fn   PlanHookRowFn
+	}{
+		{
+			name: "no rows",
+			fn: func(ctx context.Context, _ []planNode, _ chan<- tree.Datums) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		{
+			name: "rows",
+			fn: func(ctx context.Context, _ []planNode, resultsCh chan<- tree.Datums) error {
+				for {
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case resultsCh <- tree.Datums{tree.DNull}:
+					}
+				}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 200; i++ {
+				hook := &planHook{name: "slow", timeout: time.Millisecond}
+				n := newHookFnNode(hook, tc.fn, nil /* header */, nil /* subplans */)
+				params := runParams{ctx: ctx}
+				require.NoError(t, n.startExec(params))
+				var err error
+				for {
+					var ok bool
+					ok, err = n.Next(params)
+					if !ok {
+						break
+					}
+					require.NotNil(t, n.Values())
+				}
+				require.Equal(t, pgcode.QueryCanceled, pgerror.GetPGCode(err), "run %d: %v", i, err)
+				n.Close(ctx)
+			}
+		})
+	}
+}
+
+func TestPlanHookNoRows(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	hook := &planHook{name: "no rows"}
+	WithPlanHookNoRows()(hook)
+	params := runParams{ctx: ctx}
+
+	// The hook is run synchronously during startExec.
+	var ran bool
+	n := newHookFnNode(hook, func(context.Context, []planNode, chan<- tree.Datums) error {
+		ran = true
+		return nil
+	}, nil /* header */, nil /* subplans */)
+	require.NoError(t, n.startExec(params))
+	require.True(t, ran)
+	ok, err := n.Next(params)
+	require.NoError(t, err)
+	require.False(t, ok)
+	n.Close(ctx)
+
+	// Errors are returned by startExec.
+	n = newHookFnNode(hook, func(context.Context, []planNode, chan<- tree.Datums) error {
+		return errors.New("boom")
+	}, nil /* header */, nil /* subplans */)
+	require.Regexp(t, "boom", n.startExec(params))
+	n.Close(ctx)
+
+	// Producing rows is an assertion failure, including when they are sent
+	// from a goroutine spawned by the hook.
+	for _, fn := range []PlanHookRowFn{
+		func(_ context.Context, _ []planNode, resultsCh chan<- tree.Datums) error {
+			resultsCh <- tree.Datums{}
+			resultsCh <- tree.Datums{}
+			return nil
+		},
+		func(ctx context.Context, _ []planNode, resultsCh chan<- tree.Datums) error {
+			go func() {
+				resultsCh <- tree.Datums{}
+			}()
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	} {
+		n = newHookFnNode(hook, fn, nil /* header */, nil /* subplans */)
+		err = n.startExec(params)
+		require.True(t, errors.HasAssertionFailure(err))
+		require.Regexp(t, `plan hook "no rows" declared to produce no rows sent a row`, err)
+		n.Close(ctx)
+	}
+}
+
+// recordingNoticeSender is a noticeSender recording the notices it is given.
+type recordingNoticeSender struct {
+	events []string
+}
+
+var _ noticeSender = &recordingNoticeSender{}
+
+func (r *recordingNoticeSender) BufferNotice(notice pgnotice.Notice) {
+	r.events = append(r.events, "buffered: "+notice.Error())
+}
+
+func (r *recordingNoticeSender) SendNotice(_ context.Context, notice pgnotice.Notice) error {
+	r.events = append(r.events, "sent: "+notice.Error())
+	return nil
+}
+
+func TestPlanHookNotices(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	sender := &recordingNoticeSender{}
+	p := &planner{
+		execCfg:      &ExecutorConfig{Settings: cluster.MakeTestingClusterSettings()},
+		noticeSender: sender,
+	}
+	sd := &sessiondata.SessionData{}
+	sd.NoticeDisplaySeverity = uint32(pgnotice.DisplaySeverityNotice)
+	p.extendedEvalCtx.SessionDataStack = sessiondata.NewStack(sd)
+	params := runParams{ctx: ctx, p: p}
+
+	// Notices buffered outside of the hook are flushed when the statement
+	// completes.
+	p.BufferClientNotice(ctx, pgnotice.Newf("before"))
+	n := newHookFnNode(&planHook{name: "notices"}, func(
+		ctx context.Context, _ []planNode, resultsCh chan<- tree.Datums,
+	) error {
+		for i := 0; i < 2; i++ {
+			p.BufferClientNotice(ctx, pgnotice.Newf("notice %d", i))
+			resultsCh <- tree.Datums{tree.NewDInt(tree.DInt(i))}
+		}
+		p.BufferClientNotice(ctx, pgnotice.Newf("done"))
+		return nil
+	}, nil /* header */, nil /* subplans */)
+	require.NoError(t, n.startExec(params))
+	for {
+		ok, err := n.Next(params)
+		require.NoError(t, err)
+		if !ok {
+			break
+		}
+		row := n.Values()
+		sender.events = append(sender.events, "row: "+row.String())
+	}
+	n.Close(ctx)
+
+	require.Equal(t, []string{
+		"buffered: before",
+		"sent: notice 0",
+		"row: (0)",
+		"sent: notice 1",
+		"row: (1)",
+		"sent: done",
+	}, sender.events)
+}
+
+func TestPlanHookProgress(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	p := &planner{}
+	params := runParams{ctx: ctx, p: p}
+
+	// Progress reported outside of a plan hook is ignored.
+	require.NoError(t, p.ReportPlanHookProgress(ctx, 0.5))
+
+	for _, tc := range []struct {
+		name     string
+		noRows   bool
+		err      error
+		expected float32
+	}{
+		{name: "rows", expected: 1},
+		{name: "no rows", noRows: true, expected: 1},
+		// The work of a failed hook isn't completed.
+		{name: "error", err: errors.New("boom"), expected: 0.75},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hook := &planHook{name: "progress", noRows: tc.noRows}
+			var n *hookFnNode
+			var reported []float32
+			n = newHookFnNode(hook, func(ctx context.Context, _ []planNode, resultsCh chan<- tree.Datums) error {
+				for _, fraction := range []float32{0.5, 0.25, 0.75} {
+					require.NoError(t, p.ReportPlanHookProgress(ctx, fraction))
+					n.run.progress.Lock()
+					reported = append(reported, n.run.progress.fraction)
+					n.run.progress.Unlock()
+				}
+				// Progress must be between 0 and 1.
+				require.True(t, errors.HasAssertionFailure(p.ReportPlanHookProgress(ctx, 1.5)))
+				if !tc.noRows {
+					// Rows can still be produced after the progress is reported.
+					resultsCh <- tree.Datums{tree.NewDInt(1)}
+				}
+				return tc.err
+			}, nil /* header */, nil /* subplans */)
+			err := n.startExec(params)
+			for err == nil {
+				var ok bool
+				if ok, err = n.Next(params); !ok {
+					break
+				}
+			}
+			if tc.err != nil {
+				require.Regexp(t, "boom", err)
+			} else {
+				require.NoError(t, err)
+			}
+			n.Close(ctx)
+
+			// The progress is monotonic.
+			require.Equal(t, []float32{0.5, 0.5, 0.75}, reported)
+			require.Equal(t, tc.expected, n.run.progress.fraction)
+			// Progress reported after the hook returned is dropped.
+			require.NoError(t, n.reportProgress(ctx, 0.9))
+			require.Equal(t, tc.expected, n.run.progress.fraction)
+		})
+	}
+}
+
+func TestInvokePlanHookForTest(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+	defer resetPlanHooksForTest()()
+
+	ctx := context.Background()
+	backup, restore, importStmt := &tree.Backup{}, &tree.Restore{}, &tree.Import{}
+	header := colinfo.ResultColumns{{Name: "job_id", Typ: types.Int}}
+	AddPlanHook("backup", makeTestPlanHook(backup))
+	AddPlanHookWithPriority("mock backup", 1, func(
+		_ context.Context, s tree.Statement, _ PlanHookState,
+	) (PlanHookRowFn, colinfo.ResultColumns, []planNode, bool, error) {
+		if s.StatementTag() != backup.StatementTag() {
+			return nil, nil, nil, false, nil
+		}
+		fn := func(_ context.Context, _ []planNode, resultsCh chan<- tree.Datums) error {
+			resultsCh <- tree.Datums{tree.NewDInt(1)}
+			return nil
+		}
+		return fn, header, nil, true, nil
+	})
+	AddPlanHook("restore", func(
+		_ context.Context, s tree.Statement, _ PlanHookState,
+	) (PlanHookRowFn, colinfo.ResultColumns, []planNode, bool, error) {
+		if s.StatementTag() != restore.StatementTag() {
+			return nil, nil, nil, false, nil
+		}
+		return nil, nil, nil, false, errors.New("boom")
+	})
+
+	// The hooks are consulted in order of priority.
+	res, ok, err := InvokePlanHookForTest(ctx, backup, nil /* p */)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "mock backup", res.Name)
+	require.Equal(t, header, res.Header)
+	require.True(t, res.AvoidBuffering)
+	// The PlanHookRowFn can be run directly.
+	resultsCh := make(chan tree.Datums, 1)
+	require.NoError(t, res.Fn(ctx, res.Subplans, resultsCh))
+	require.Equal(t, tree.Datums{tree.NewDInt(1)}, <-resultsCh)
+
+	// Errors returned by a hook are returned.
+	_, ok, err = InvokePlanHookForTest(ctx, restore, nil /* p */)
+	require.Regexp(t, "boom", err)
+	require.False(t, ok)
+
+	// Statements which are intercepted by no hook aren't an error.
+	_, ok, err = InvokePlanHookForTest(ctx, importStmt, nil /* p */)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -106,6 +106,12 @@ type txnKVFetcher struct {
 	responses        []roachpb.ResponseUnion
 	remainingBatches [][]byte
 
+	// lastResumeSpan and lastResumeReason describe the resume span of the
+	// response that the most recently returned batch came from. lastResumeSpan
+	// is nil if that response was complete.
+	lastResumeSpan   *roachpb.Span
+	lastResumeReason roachpb.ResumeReason
+
 	acc *mon.BoundAccount
 	// spansAccountedFor and batchResponseAccountedFor track the number of bytes
 	// that we've already registered with acc in regards to spans and the batch
@@ -122,6 +128,7 @@ type txnKVFetcher struct {
 }
 
 var _ KVBatchFetcher = &txnKVFetcher{}
+var _ resumeSpanFetcher = &txnKVFetcher{}
 
 // getBatchKeyLimit returns the max size of the next batch. The size is
 // expressed in number of result keys (i.e. this size will be used for
@@ -225,35 +232,59 @@ func makeKVBatchFetcher(ctx context.Context, args kvBatchFetcherArgs) (txnKVFetc
 			args.firstBatchKeyLimit, args.batchBytesLimit)
 	}
 
-	if args.batchBytesLimit != 0 {
+	f := txnKVFetcher{
+		sendFn:                     args.sendFn,
+		reverse:                    args.reverse,
+		batchBytesLimit:            args.batchBytesLimit,
+		firstBatchKeyLimit:         args.firstBatchKeyLimit,
+		lockStrength:               getKeyLockingStrength(args.lockStrength),
+		lockWaitPolicy:             GetWaitPolicy(args.lockWaitPolicy),
+		lockTimeout:                args.lockTimeout,
+		acc:                        args.acc,
+		forceProductionKVBatchSize: args.forceProductionKVBatchSize,
+		requestAdmissionHeader:     args.requestAdmissionHeader,
+		responseAdmissionQ:         args.responseAdmissionQ,
+	}
+
+	if err := f.setSpans(ctx, args.spans); err != nil {
+		return txnKVFetcher{}, err
+	}
+	return f, nil
+}
+
+// setSpans validates the given spans and takes ownership of them, performing
+// the memory accounting if f.acc is non-nil. See makeKVBatchFetcher for the
+// ownership semantics.
+func (f *txnKVFetcher) setSpans(ctx context.Context, spans roachpb.Spans) error {
+	if f.batchBytesLimit != 0 {
 		// Verify the spans are ordered if a batch limit is used.
-		for i := 1; i < len(args.spans); i++ {
-			prevKey := args.spans[i-1].EndKey
+		for i := 1; i < len(spans); i++ {
+			prevKey := spans[i-1].EndKey
 			if prevKey == nil {
 				// This is the case of a GetRequest.
-				prevKey = args.spans[i-1].Key
+				prevKey = spans[i-1].Key
 			}
-			if args.spans[i].Key.Compare(prevKey) < 0 {
-				return txnKVFetcher{}, errors.Errorf("unordered spans (%s %s)", args.spans[i-1], args.spans[i])
+			if spans[i].Key.Compare(prevKey) < 0 {
+				return errors.Errorf("unordered spans (%s %s)", spans[i-1], spans[i])
 			}
 		}
 	} else if util.RaceEnabled {
 		// Otherwise, just verify the spans don't contain consecutive overlapping
 		// spans.
-		for i := 1; i < len(args.spans); i++ {
-			prevEndKey := args.spans[i-1].EndKey
+		for i := 1; i < len(spans); i++ {
+			prevEndKey := spans[i-1].EndKey
 			if prevEndKey == nil {
-				prevEndKey = args.spans[i-1].Key
+				prevEndKey = spans[i-1].Key
 			}
-			curEndKey := args.spans[i].EndKey
+			curEndKey := spans[i].EndKey
 			if curEndKey == nil {
-				curEndKey = args.spans[i].Key
+				curEndKey = spans[i].Key
 			}
-			if args.spans[i].Key.Compare(prevEndKey) >= 0 {
+			if spans[i].Key.Compare(prevEndKey) >= 0 {
 				// Current span's start key is greater than or equal to the last span's
 				// end key - we're good.
 				continue
-			} else if curEndKey.Compare(args.spans[i-1].Key) <= 0 {
+			} else if curEndKey.Compare(spans[i-1].Key) <= 0 {
 				// Current span's end key is less than or equal to the last span's start
 				// key - also good.
 				continue
@@ -261,29 +292,15 @@ func makeKVBatchFetcher(ctx context.Context, args kvBatchFetcherArgs) (txnKVFetc
 			// Otherwise, the two spans overlap, which isn't allowed - it leaves us at
 			// risk of incorrect results, since the row fetcher can't distinguish
 			// between identical rows in two different batches.
-			return txnKVFetcher{}, errors.Errorf("overlapping neighbor spans (%s %s)", args.spans[i-1], args.spans[i])
+			return errors.Errorf("overlapping neighbor spans (%s %s)", spans[i-1], spans[i])
 		}
 	}
 
-	f := txnKVFetcher{
-		sendFn:                     args.sendFn,
-		reverse:                    args.reverse,
-		batchBytesLimit:            args.batchBytesLimit,
-		firstBatchKeyLimit:         args.firstBatchKeyLimit,
-		lockStrength:               getKeyLockingStrength(args.lockStrength),
-		lockWaitPolicy:             GetWaitPolicy(args.lockWaitPolicy),
-		lockTimeout:                args.lockTimeout,
-		acc:                        args.acc,
-		forceProductionKVBatchSize: args.forceProductionKVBatchSize,
-		requestAdmissionHeader:     args.requestAdmissionHeader,
-		responseAdmissionQ:         args.responseAdmissionQ,
-	}
-
 	// Account for the memory of the spans that we're taking the ownership of.
 	if f.acc != nil {
-		f.spansAccountedFor = args.spans.MemUsage()
+		f.spansAccountedFor = spans.MemUsage()
 		if err := f.acc.Grow(ctx, f.spansAccountedFor); err != nil {
-			return txnKVFetcher{}, err
+			return err
 		}
 	}
 
@@ -291,11 +308,11 @@ func makeKVBatchFetcher(ctx context.Context, args kvBatchFetcherArgs) (txnKVFetc
 	// perform the deep copy. Notably, the spans might be modified (when the
 	// fetcher receives the resume spans), but the fetcher will always keep the
 	// memory accounting up to date.
-	f.spans = args.spans
-	if args.reverse {
+	f.spans = spans
+	if f.reverse {
 		// Reverse scans receive the spans in decreasing order. Note that we
 		// need to be this tricky since we're updating the spans slice in place.
-		i, j := 0, len(args.spans)-1
+		i, j := 0, len(spans)-1
 		for i < j {
 			f.spans[i], f.spans[j] = f.spans[j], f.spans[i]
 			i++
@@ -306,7 +323,29 @@ func makeKVBatchFetcher(ctx context.Context, args kvBatchFetcherArgs) (txnKVFetc
 	// slice for the resume spans.
 	f.spansScratch = f.spans
 
-	return f, nil
+	return nil
+}
+
+// reset re-points the fetcher at the given spans, keeping the configuration
+// it was created with. Any memory accounted for the previous spans and batch
+// response is released. The fetcher takes ownership of the spans slice, like
+// in makeKVBatchFetcher.
+func (f *txnKVFetcher) reset(ctx context.Context, spans roachpb.Spans) error {
+	f.acc.Shrink(ctx, f.batchResponseAccountedFor+f.spansAccountedFor)
+	*f = txnKVFetcher{
+		sendFn:                     f.sendFn,
+		reverse:                    f.reverse,
+		batchBytesLimit:            f.batchBytesLimit,
+		firstBatchKeyLimit:         f.firstBatchKeyLimit,
+		lockStrength:               f.lockStrength,
+		lockWaitPolicy:             f.lockWaitPolicy,
+		lockTimeout:                f.lockTimeout,
+		acc:                        f.acc,
+		forceProductionKVBatchSize: f.forceProductionKVBatchSize,
+		requestAdmissionHeader:     f.requestAdmissionHeader,
+		responseAdmissionQ:         f.responseAdmissionQ,
+	}
+	return f.setSpans(ctx, spans)
 }
 
 // fetch retrieves spans from the kv layer.
@@ -460,6 +499,7 @@ func (f *txnKVFetcher) nextBatch(
 			f.spansScratch[f.newFetchSpansIdx] = *resumeSpan
 			f.newFetchSpansIdx++
 		}
+		f.lastResumeSpan, f.lastResumeReason = header.ResumeSpan, header.ResumeReason
 
 		switch t := reply.(type) {
 		case *roachpb.ScanResponse:
@@ -533,10 +573,16 @@ func (f *txnKVFetcher) nextBatch(
 	return f.nextBatch(ctx)
 }
 
+// resumeSpan implements the resumeSpanFetcher interface.
+func (f *txnKVFetcher) resumeSpan() (*roachpb.Span, roachpb.ResumeReason) {
+	return f.lastResumeSpan, f.lastResumeReason
+}
+
 // close releases the resources of this txnKVFetcher.
 func (f *txnKVFetcher) close(ctx context.Context) {
 	f.responses = nil
 	f.remainingBatches = nil
+	f.lastResumeSpan = nil
 	f.spans = nil
 	f.spansScratch = nil
 	// Release only the allocations made by this fetcher.
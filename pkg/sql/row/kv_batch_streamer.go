@@ -57,22 +57,41 @@ type TxnKVStreamer struct {
 		// Used only for ScanResponses.
 		remainingBatches [][]byte
 	}
+
+	// drained is set once drain has been called, after which no more batches
+	// are returned.
+	drained bool
 }
 
 var _ KVBatchFetcher = &TxnKVStreamer{}
+var _ resumeSpanFetcher = &TxnKVStreamer{}
 
 // NewTxnKVStreamer creates a new TxnKVStreamer.
+//
+// If reverse is set, the spans are scanned using ReverseScanRequests. The
+// Streamer doesn't reorder the results according to the direction of the
+// scans, so, like the txnKVFetcher, the TxnKVStreamer receives the spans in
+// increasing order and enqueues them in decreasing order. As a result, the keys
+// are returned in decreasing order when the Streamer is in InOrder mode.
 func NewTxnKVStreamer(
 	ctx context.Context,
 	streamer *kvstreamer.Streamer,
 	spans roachpb.Spans,
+	reverse bool,
 	lockStrength descpb.ScanLockingStrength,
 ) (*TxnKVStreamer, error) {
 	if log.ExpensiveLogEnabled(ctx, 2) {
 		log.VEventf(ctx, 2, "Scan %s", spans)
 	}
+	if reverse {
+		// Reverse scans receive the spans in decreasing order. The spans are
+		// updated in place since the TxnKVStreamer takes ownership of them.
+		for i, j := 0, len(spans)-1; i < j; i, j = i+1, j-1 {
+			spans[i], spans[j] = spans[j], spans[i]
+		}
+	}
 	keyLocking := getKeyLockingStrength(lockStrength)
-	reqs := spansToRequests(spans, false /* reverse */, keyLocking)
+	reqs := spansToRequests(spans, reverse, keyLocking)
 	if err := streamer.Enqueue(ctx, reqs, nil /* enqueueKeys */); err != nil {
 		return nil, err
 	}
@@ -118,6 +137,15 @@ func (f *TxnKVStreamer) proceedWithLastResult(
 	return false, nil, batchResp, nil
 }
 
+// resumeSpan implements the resumeSpanFetcher interface. Note that the Streamer
+// resumes the partial scans on its own, so the resume span is only informative.
+func (f *TxnKVStreamer) resumeSpan() (*roachpb.Span, roachpb.ResumeReason) {
+	if scan := f.lastResultState.ScanResp.ScanResponse; scan != nil {
+		return scan.ResumeSpan, scan.ResumeReason
+	}
+	return nil, roachpb.RESUME_UNKNOWN
+}
+
 func (f *TxnKVStreamer) releaseLastResult(ctx context.Context) {
 	f.lastResultState.Release(ctx)
 	f.lastResultState.Result = kvstreamer.Result{}
@@ -128,6 +156,9 @@ func (f *TxnKVStreamer) releaseLastResult(ctx context.Context) {
 func (f *TxnKVStreamer) nextBatch(
 	ctx context.Context,
 ) (ok bool, kvs []roachpb.KeyValue, batchResp []byte, err error) {
+	if f.drained {
+		return false, nil, nil, nil
+	}
 	// Check whether there are more batches in the current ScanResponse.
 	if len(f.lastResultState.remainingBatches) > 0 {
 		batchResp, f.lastResultState.remainingBatches = f.lastResultState.remainingBatches[0], f.lastResultState.remainingBatches[1:]
@@ -184,6 +215,25 @@ func (f *TxnKVStreamer) nextBatch(
 	return f.nextBatch(ctx)
 }
 
+// drain stops the streamer from issuing any new requests and releases all of
+// the results that have been received but not emitted yet. No more batches are
+// returned by nextBatch afterwards, but close must still be called.
+func (f *TxnKVStreamer) drain(ctx context.Context) {
+	if f.drained {
+		return
+	}
+	f.streamer.Stop()
+	f.releaseLastResult(ctx)
+	f.lastResultState.numEmitted = 0
+	f.lastResultState.remainingBatches = nil
+	for i := range f.results {
+		f.results[i].Release(ctx)
+		f.results[i] = kvstreamer.Result{}
+	}
+	f.results = nil
+	f.drained = true
+}
+
 // close releases the resources of this TxnKVStreamer.
 func (f *TxnKVStreamer) close(ctx context.Context) {
 	f.lastResultState.Release(ctx)
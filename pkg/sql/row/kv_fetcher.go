@@ -12,18 +12,23 @@ package row
 
 import (
 	"context"
+	"encoding/binary"
 	"sync/atomic"
 	"time"
 
+	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/kv"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/rowinfra"
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/storage/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/cockroachdb/errors"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // KVFetcher wraps KVBatchFetcher, providing a NextKV interface that returns the
@@ -36,10 +41,26 @@ type KVFetcher struct {
 	batchResponse []byte
 	newSpan       bool
 
+	// keysOnly, if set, indicates that the caller is only interested in the
+	// keys, so the returned KVs have empty values (apart from the timestamps
+	// when MVCC decoding is required).
+	keysOnly bool
+
+	// neededFamilies, if not empty, is the set of column family IDs whose KVs
+	// are returned; KVs of other families are skipped.
+	neededFamilies util.FastIntSet
+
+	// callsSinceLastCancelCheck counts the iterations of the NextKV loop; the
+	// context is checked for cancellation every nextKVCancelCheckInterval
+	// iterations.
+	callsSinceLastCancelCheck uint32
+
 	// Observability fields.
 	// Note: these need to be read via an atomic op.
 	atomics struct {
-		bytesRead int64
+		bytesRead   int64
+		kvPairsRead int64
+		batchesRead int64
 	}
 }
 
@@ -50,6 +71,15 @@ type KVFetcher struct {
 // will perform the memory accounting accordingly (if acc is non-nil). The
 // caller can only reuse the spans slice after the fetcher has been closed, and
 // if the caller does, it becomes responsible for the memory accounting.
+//
+// If keysOnly is true, NextKV returns KVs with empty values. Note that KV
+// doesn't support a scan format without values, so the values are still
+// fetched; only their decoding is skipped.
+//
+// If neededFamilies is non-empty, NextKV only returns the KVs of the column
+// families with these IDs, which is determined from the family suffix of the
+// keys. It must then only be used to scan row keys that have such a suffix,
+// like those of primary indexes.
 func NewKVFetcher(
 	ctx context.Context,
 	txn *kv.Txn,
@@ -63,6 +93,8 @@ func NewKVFetcher(
 	lockTimeout time.Duration,
 	acc *mon.BoundAccount,
 	forceProductionKVBatchSize bool,
+	keysOnly bool,
+	neededFamilies []descpb.FamilyID,
 ) (*KVFetcher, error) {
 	var sendFn sendFunc
 	// Avoid the heap allocation by allocating sendFn specifically in the if.
@@ -107,11 +139,17 @@ func NewKVFetcher(
 			responseAdmissionQ:         txn.DB().SQLKVResponseAdmissionQ,
 		},
 	)
-	return newKVFetcher(&kvBatchFetcher), err
+	f := newKVFetcher(&kvBatchFetcher)
+	f.keysOnly = keysOnly
+	for _, id := range neededFamilies {
+		f.neededFamilies.Add(int(id))
+	}
+	return f, err
 }
 
 // NewKVStreamingFetcher returns a new KVFetcher that utilizes the provided
-// TxnKVStreamer to perform KV reads.
+// TxnKVStreamer to perform KV reads. The direction of the reads is determined
+// by the reverse argument of NewTxnKVStreamer.
 func NewKVStreamingFetcher(streamer *TxnKVStreamer) *KVFetcher {
 	return &KVFetcher{
 		KVBatchFetcher: streamer,
@@ -124,6 +162,24 @@ func newKVFetcher(batchFetcher KVBatchFetcher) *KVFetcher {
 	}
 }
 
+// Reset re-points the fetcher at the given spans so that it can be reused for
+// another scan with the same configuration, avoiding the allocation of a new
+// fetcher. Any state from the previous scan is discarded. The number of bytes
+// read is carried over; use ResetBytesRead to reset it.
+//
+// The fetcher takes ownership of the spans slice, with the same semantics as
+// in NewKVFetcher. Only fetchers created by NewKVFetcher can be reset.
+func (f *KVFetcher) Reset(ctx context.Context, spans roachpb.Spans) error {
+	f.kvs = nil
+	f.batchResponse = nil
+	f.newSpan = false
+	batchFetcher, ok := f.KVBatchFetcher.(*txnKVFetcher)
+	if !ok {
+		return errors.AssertionFailedf("cannot reset KVFetcher using %T", f.KVBatchFetcher)
+	}
+	return batchFetcher.reset(ctx, spans)
+}
+
 // GetBytesRead returns the number of bytes read by this fetcher. It is safe for
 // concurrent use and is able to handle a case of uninitialized fetcher.
 func (f *KVFetcher) GetBytesRead() int64 {
@@ -143,6 +199,26 @@ func (f *KVFetcher) ResetBytesRead() int64 {
 	return atomic.SwapInt64(&f.atomics.bytesRead, 0)
 }
 
+// GetKVPairsRead returns the number of key-value pairs returned by this
+// fetcher. It is safe for concurrent use and is able to handle a case of
+// uninitialized fetcher.
+func (f *KVFetcher) GetKVPairsRead() int64 {
+	if f == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&f.atomics.kvPairsRead)
+}
+
+// GetBatchesRead returns the number of batches received by this fetcher from
+// the underlying KVBatchFetcher. It is safe for concurrent use and is able to
+// handle a case of uninitialized fetcher.
+func (f *KVFetcher) GetBatchesRead() int64 {
+	if f == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&f.atomics.batchesRead)
+}
+
 // MVCCDecodingStrategy controls if and how the fetcher should decode MVCC
 // timestamps from returned KV's.
 type MVCCDecodingStrategy int
@@ -154,6 +230,13 @@ const (
 	MVCCDecodingRequired
 )
 
+// nextKVCancelCheckInterval is the number of NextKV iterations between the
+// checks for context cancellation. Decoding a large batch response doesn't
+// involve KV, so without these checks a canceled query could keep running
+// until the whole batch is consumed. The value is a power of 2 to allow the
+// compiler to use bitwise AND instead of division.
+const nextKVCancelCheckInterval = 1024
+
 // NextKV returns the next kv from this fetcher. Returns false if there are no
 // more kvs to fetch, the kv that was fetched, and any errors that may have
 // occurred.
@@ -168,12 +251,27 @@ func (f *KVFetcher) NextKV(
 	ctx context.Context, mvccDecodeStrategy MVCCDecodingStrategy,
 ) (ok bool, kv roachpb.KeyValue, finalReferenceToBatch bool, err error) {
 	for {
+		if f.callsSinceLastCancelCheck%nextKVCancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return false, kv, false, err
+			}
+		}
+		f.callsSinceLastCancelCheck++
 		// Only one of f.kvs or f.batchResponse will be set at a given time. Which
 		// one is set depends on the format returned by a given BatchRequest.
 		nKvs := len(f.kvs)
 		if nKvs != 0 {
 			kv = f.kvs[0]
 			f.kvs = f.kvs[1:]
+			if skip, err := f.skipKey(kv.Key); err != nil {
+				return false, kv, false, err
+			} else if skip {
+				continue
+			}
+			atomic.AddInt64(&f.atomics.kvPairsRead, 1)
+			if f.keysOnly {
+				kv = roachpb.KeyValue{Key: kv.Key, Value: roachpb.Value{Timestamp: kv.Value.Timestamp}}
+			}
 			// We always return "false" for finalReferenceToBatch when returning data in the
 			// KV format, because each of the KVs doesn't share any backing memory -
 			// they are all independently garbage collectable.
@@ -199,6 +297,20 @@ func (f *KVFetcher) NextKV(
 			if lastKey {
 				f.batchResponse = nil
 			}
+			// Note that if the last KV of the batch is skipped, the KV previously
+			// returned is not marked as the final reference to the batch.
+			if skip, err := f.skipKey(key); err != nil {
+				return false, kv, false, err
+			} else if skip {
+				continue
+			}
+			atomic.AddInt64(&f.atomics.kvPairsRead, 1)
+			if f.keysOnly {
+				return true, roachpb.KeyValue{
+					Key:   key[:len(key):len(key)],
+					Value: roachpb.Value{Timestamp: ts},
+				}, lastKey, nil
+			}
 			return true, roachpb.KeyValue{
 				Key: key[:len(key):len(key)],
 				Value: roachpb.Value{
@@ -208,18 +320,175 @@ func (f *KVFetcher) NextKV(
 			}, lastKey, nil
 		}
 
-		ok, f.kvs, f.batchResponse, err = f.nextBatch(ctx)
+		ok, f.kvs, f.batchResponse, err = f.fetchNextBatch(ctx)
 		if err != nil || !ok {
 			return ok, kv, false, err
 		}
 		f.newSpan = true
-		nBytes := len(f.batchResponse)
-		for i := range f.kvs {
-			nBytes += len(f.kvs[i].Key)
-			nBytes += len(f.kvs[i].Value.RawBytes)
+	}
+}
+
+// NextBatch returns the next batch from this fetcher without decoding it, for
+// callers which forward the KVs elsewhere as is (e.g. into an SST) and would
+// otherwise decode each KV via NextKV only to re-encode it. Returns false if
+// there are no more batches to fetch. Only one of kvs or batchResponse is set,
+// depending on the format of the KV response, and batchResponse is in the
+// BATCH_RESPONSE scan format (see enginepb.ScanDecodeKeyValue). The returned
+// slices are owned by the caller.
+//
+// The batches are accounted for in the same way as those fetched by NextKV.
+// NextBatch cannot be used if the fetcher filters or transforms the KVs
+// (keysOnly or neededFamilies), nor to consume the remainder of a batch that
+// NextKV has started returning.
+func (f *KVFetcher) NextBatch(
+	ctx context.Context,
+) (ok bool, kvs []roachpb.KeyValue, batchResponse []byte, err error) {
+	if f.keysOnly || !f.neededFamilies.Empty() {
+		return false, nil, nil, errors.AssertionFailedf(
+			"NextBatch cannot be used with a KVFetcher that filters KVs",
+		)
+	}
+	if len(f.kvs) > 0 || len(f.batchResponse) > 0 {
+		return false, nil, nil, errors.AssertionFailedf(
+			"NextBatch cannot be used while NextKV is returning a batch",
+		)
+	}
+	if err := ctx.Err(); err != nil {
+		return false, nil, nil, err
+	}
+	ok, kvs, batchResponse, err = f.fetchNextBatch(ctx)
+	if err != nil || !ok {
+		return ok, nil, nil, err
+	}
+	f.newSpan = true
+	numKVs := len(kvs) + countBatchResponseKVs(batchResponse)
+	atomic.AddInt64(&f.atomics.kvPairsRead, int64(numKVs))
+	return true, kvs, batchResponse, nil
+}
+
+// fetchNextBatch fetches the next batch from the KVBatchFetcher and accounts
+// for it in the observability counters, apart from the KV pairs, which are
+// counted once returned to the caller.
+func (f *KVFetcher) fetchNextBatch(
+	ctx context.Context,
+) (ok bool, kvs []roachpb.KeyValue, batchResponse []byte, err error) {
+	// Only trace the batch fetches when the recording is verbose, so that
+	// there is no overhead otherwise.
+	batchCtx := ctx
+	var sp *tracing.Span
+	if parent := tracing.SpanFromContext(ctx); parent != nil && parent.IsVerbose() {
+		batchCtx, sp = tracing.ChildSpan(ctx, kvFetcherBatchOpName)
+	}
+	ok, kvs, batchResponse, err = f.nextBatch(batchCtx)
+	if err != nil || !ok {
+		if sp != nil {
+			sp.Finish()
+		}
+		return ok, kvs, batchResponse, err
+	}
+	atomic.AddInt64(&f.atomics.batchesRead, 1)
+	nBytes := len(batchResponse)
+	for i := range kvs {
+		nBytes += len(kvs[i].Key)
+		nBytes += len(kvs[i].Value.RawBytes)
+	}
+	atomic.AddInt64(&f.atomics.bytesRead, int64(nBytes))
+	if sp != nil {
+		numKVs := len(kvs) + countBatchResponseKVs(batchResponse)
+		sp.SetTag(kvFetcherBatchKVsTagKey, attribute.IntValue(numKVs))
+		sp.SetTag(kvFetcherBatchBytesTagKey, attribute.IntValue(nBytes))
+		sp.Finish()
+	}
+	return true, kvs, batchResponse, nil
+}
+
+const (
+	// kvFetcherBatchOpName is the operation name of the tracing spans created
+	// around each batch fetched by a KVFetcher.
+	kvFetcherBatchOpName = "kv-fetcher-batch"
+	// kvFetcherBatchKVsTagKey is the tag of the number of KVs in a batch.
+	kvFetcherBatchKVsTagKey = "kvs"
+	// kvFetcherBatchBytesTagKey is the tag of the number of bytes in a batch.
+	kvFetcherBatchBytesTagKey = "bytes"
+)
+
+// countBatchResponseKVs returns the number of KVs in a batch response in the
+// BATCH_RESPONSE scan format without decoding them.
+func countBatchResponseKVs(batchResponse []byte) int {
+	var n int
+	for len(batchResponse) >= 8 {
+		valueLen := binary.LittleEndian.Uint32(batchResponse[:4])
+		keyLen := binary.LittleEndian.Uint32(batchResponse[4:8])
+		size := 8 + int(keyLen) + int(valueLen)
+		if size > len(batchResponse) {
+			break
+		}
+		batchResponse = batchResponse[size:]
+		n++
+	}
+	return n
+}
+
+// skipKey returns whether the KV with the given key belongs to a column family
+// that isn't needed.
+func (f *KVFetcher) skipKey(key roachpb.Key) (bool, error) {
+	if f.neededFamilies.Empty() {
+		return false, nil
+	}
+	familyID, err := keys.DecodeFamilyKey(key)
+	if err != nil {
+		return false, err
+	}
+	return !f.neededFamilies.Contains(int(familyID)), nil
+}
+
+// resumeSpanFetcher is implemented by the KVBatchFetchers that read from the KV
+// layer and can report the resume span of the KV responses.
+type resumeSpanFetcher interface {
+	// resumeSpan returns the resume span of the KV response that the most
+	// recently returned batch came from along with the reason for it. The span
+	// is nil if that response was complete.
+	resumeSpan() (*roachpb.Span, roachpb.ResumeReason)
+}
+
+// ResumeSpan returns the resume span of the KV response that the KVs most
+// recently returned by NextKV came from, or nil if that response was complete
+// (i.e. the scan wasn't paused because of batchBytesLimit or firstBatchLimit).
+// It is always nil if the fetcher doesn't read from the KV layer.
+func (f *KVFetcher) ResumeSpan() *roachpb.Span {
+	if r, ok := f.KVBatchFetcher.(resumeSpanFetcher); ok {
+		span, _ := r.resumeSpan()
+		return span
+	}
+	return nil
+}
+
+// ResumeReason returns the reason for the span returned by ResumeSpan. It is
+// RESUME_UNKNOWN if there is no resume span.
+func (f *KVFetcher) ResumeReason() roachpb.ResumeReason {
+	if r, ok := f.KVBatchFetcher.(resumeSpanFetcher); ok {
+		if span, reason := r.resumeSpan(); span != nil {
+			return reason
 		}
-		atomic.AddInt64(&f.atomics.bytesRead, int64(nBytes))
 	}
+	return roachpb.RESUME_UNKNOWN
+}
+
+// Drain stops the KVFetcher from fetching any more KVs once the caller is no
+// longer interested in them. When the fetcher is backed by the Streamer, no new
+// requests are issued and the results that have already been received are
+// discarded, releasing their memory. Drain is a no-op for other fetchers. The
+// fetcher returns no more KVs after Drain if it is backed by the Streamer, and
+// Close must still be called.
+func (f *KVFetcher) Drain(ctx context.Context) {
+	streamer, ok := f.KVBatchFetcher.(*TxnKVStreamer)
+	if !ok {
+		return
+	}
+	f.kvs = nil
+	f.batchResponse = nil
+	f.newSpan = false
+	streamer.drain(ctx)
 }
 
 // Close releases the resources held by this KVFetcher. It must be called
@@ -232,69 +501,392 @@ func (f *KVFetcher) Close(ctx context.Context) {
 // SpanKVFetcher is a KVBatchFetcher that returns a set slice of kvs.
 type SpanKVFetcher struct {
 	KVs []roachpb.KeyValue
+
+	// ReadTimestamp, if set, makes the fetcher behave like an MVCC scan at that
+	// timestamp: KVs may contain several versions of each key, and only the
+	// most recent version at or below ReadTimestamp is returned for each key,
+	// unless it is a deletion tombstone. The keys are returned in the order of
+	// their first version in KVs.
+	ReadTimestamp hlc.Timestamp
 }
 
 // nextBatch implements the KVBatchFetcher interface.
 func (f *SpanKVFetcher) nextBatch(
 	ctx context.Context,
 ) (ok bool, kvs []roachpb.KeyValue, batchResponse []byte, err error) {
-	if len(f.KVs) == 0 {
-		return false, nil, nil, nil
-	}
 	res := f.KVs
 	f.KVs = nil
+	if !f.ReadTimestamp.IsEmpty() {
+		res = latestVersionsAsOf(res, f.ReadTimestamp)
+	}
+	if len(res) == 0 {
+		return false, nil, nil, nil
+	}
 	return true, res, nil, nil
 }
 
+// latestVersionsAsOf returns, for each key in kvs, its most recent version at
+// or below the given timestamp, omitting keys whose version is a deletion.
+func latestVersionsAsOf(kvs []roachpb.KeyValue, ts hlc.Timestamp) []roachpb.KeyValue {
+	var res []roachpb.KeyValue
+	latest := make(map[string]int)
+	for _, kv := range kvs {
+		if ts.Less(kv.Value.Timestamp) {
+			continue
+		}
+		if i, ok := latest[string(kv.Key)]; !ok {
+			latest[string(kv.Key)] = len(res)
+			res = append(res, kv)
+		} else if res[i].Value.Timestamp.Less(kv.Value.Timestamp) {
+			res[i] = kv
+		}
+	}
+	filtered := res[:0]
+	for _, kv := range res {
+		if kv.Value.IsPresent() {
+			filtered = append(filtered, kv)
+		}
+	}
+	return filtered
+}
+
 func (f *SpanKVFetcher) close(context.Context) {}
 
+// ChangefeedKVFetcher is a KVBatchFetcher that returns the KVs received on a
+// channel, such as the events produced by a rangefeed, so that they can be
+// decoded into rows.
+type ChangefeedKVFetcher struct {
+	// KVs is the channel the KVs are received on. The producer must close it
+	// once there are no more KVs.
+	KVs <-chan roachpb.KeyValue
+
+	// MaxBatchSize, if positive, limits the number of KVs returned in a batch.
+	MaxBatchSize int
+}
+
+// nextBatch implements the KVBatchFetcher interface. It blocks until at least
+// one KV is received, and then returns it along with the KVs that are already
+// available, without waiting for more.
+func (f *ChangefeedKVFetcher) nextBatch(
+	ctx context.Context,
+) (ok bool, kvs []roachpb.KeyValue, batchResponse []byte, err error) {
+	select {
+	case <-ctx.Done():
+		return false, nil, nil, ctx.Err()
+	case kv, ok := <-f.KVs:
+		if !ok {
+			return false, nil, nil, nil
+		}
+		kvs = append(kvs, kv)
+	}
+	for f.MaxBatchSize <= 0 || len(kvs) < f.MaxBatchSize {
+		select {
+		case kv, ok := <-f.KVs:
+			if !ok {
+				// The channel is closed, which the next call will notice.
+				return true, kvs, nil, nil
+			}
+			kvs = append(kvs, kv)
+		default:
+			return true, kvs, nil, nil
+		}
+	}
+	return true, kvs, nil, nil
+}
+
+func (f *ChangefeedKVFetcher) close(context.Context) {}
+
+// MultiKVBatchFetcher is a KVBatchFetcher that chains several KVBatchFetchers,
+// returning all the batches of the first one, then of the second one, and so
+// on, as if they were a single scan.
+type MultiKVBatchFetcher struct {
+	fetchers []KVBatchFetcher
+	// cur is the index in fetchers of the fetcher batches are returned from.
+	cur int
+}
+
+var _ KVBatchFetcher = &MultiKVBatchFetcher{}
+var _ resumeSpanFetcher = &MultiKVBatchFetcher{}
+
+// NewMultiKVBatchFetcher returns a MultiKVBatchFetcher chaining the given
+// fetchers, in order. The fetchers are closed when the MultiKVBatchFetcher is.
+func NewMultiKVBatchFetcher(fetchers ...KVBatchFetcher) *MultiKVBatchFetcher {
+	return &MultiKVBatchFetcher{fetchers: fetchers}
+}
+
+// nextBatch implements the KVBatchFetcher interface.
+func (f *MultiKVBatchFetcher) nextBatch(
+	ctx context.Context,
+) (ok bool, kvs []roachpb.KeyValue, batchResponse []byte, err error) {
+	for ; f.cur < len(f.fetchers); f.cur++ {
+		ok, kvs, batchResponse, err = f.fetchers[f.cur].nextBatch(ctx)
+		if ok || err != nil {
+			return ok, kvs, batchResponse, err
+		}
+	}
+	return false, nil, nil, nil
+}
+
+// resumeSpan implements the resumeSpanFetcher interface.
+func (f *MultiKVBatchFetcher) resumeSpan() (*roachpb.Span, roachpb.ResumeReason) {
+	if f.cur < len(f.fetchers) {
+		if r, ok := f.fetchers[f.cur].(resumeSpanFetcher); ok {
+			return r.resumeSpan()
+		}
+	}
+	return nil, roachpb.RESUME_UNKNOWN
+}
+
+// close implements the KVBatchFetcher interface.
+func (f *MultiKVBatchFetcher) close(ctx context.Context) {
+	for _, fetcher := range f.fetchers {
+		fetcher.close(ctx)
+	}
+}
+
 // BackupSSTKVFetcher is a KVBatchFetcher that wraps storage.SimpleMVCCIterator
 // and returns a batch of kv from backupSST.
 type BackupSSTKVFetcher struct {
 	iter          storage.SimpleMVCCIterator
+	startKeyMVCC  storage.MVCCKey
 	endKeyMVCC    storage.MVCCKey
 	startTime     hlc.Timestamp
 	endTime       hlc.Timestamp
 	withRevisions bool
+	// skipDeleted, if set, indicates that deletion tombstones are not
+	// returned. When withRevisions is false, a key whose latest revision at
+	// endTime is a deletion is then omitted altogether.
+	skipDeleted bool
+	// reverse, if set, indicates that the keys are returned in decreasing
+	// order. In this case, iter is guaranteed to implement
+	// reverseMVCCIterator.
+	reverse bool
+	// remainingSpans are the spans to iterate over once the current one,
+	// [startKeyMVCC, endKeyMVCC), is exhausted, in the iteration order.
+	remainingSpans roachpb.Spans
+	// summary accumulates the size and the row counts of the KVs returned so
+	// far.
+	summary storage.RowCounter
+	// stepsSinceLastCancelCheck counts the iterations of the nextBatch loops;
+	// the context is checked for cancellation every
+	// backupSSTCancelCheckInterval iterations.
+	stepsSinceLastCancelCheck uint32
+}
+
+// backupSSTCancelCheckInterval is the number of iterator steps between the
+// checks for context cancellation in BackupSSTKVFetcher.nextBatch. A single
+// call can copy all the KVs of a span of a large SST, so without these checks a
+// canceled restore could keep running until the span is exhausted. Steps over
+// an in-memory SST are cheap, hence the interval is larger than
+// nextKVCancelCheckInterval. The value is a power of 2 to allow the compiler
+// to use bitwise AND instead of division.
+const backupSSTCancelCheckInterval = 4096
+
+// checkCancel returns the context error, if any, every
+// backupSSTCancelCheckInterval calls.
+func (f *BackupSSTKVFetcher) checkCancel(ctx context.Context) error {
+	check := f.stepsSinceLastCancelCheck%backupSSTCancelCheckInterval == 0
+	f.stepsSinceLastCancelCheck++
+	if check {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// reverseMVCCIterator is the subset of storage.MVCCIterator needed by the
+// BackupSSTKVFetcher to iterate in reverse.
+type reverseMVCCIterator interface {
+	storage.SimpleMVCCIterator
+	SeekLT(key storage.MVCCKey)
+	Prev()
 }
 
-// MakeBackupSSTKVFetcher creates a BackupSSTKVFetcher and
-// advances the iter to the first key >= startKeyMVCC
+// MakeBackupSSTKVFetcher creates a BackupSSTKVFetcher and advances the iter to
+// the first key >= startKeyMVCC, or, if reverse is true, to the last key <
+// endKeyMVCC. Reverse iteration requires iter to support SeekLT and Prev.
+//
+// To resume a previous fetcher, pass its ResumeKey as startKeyMVCC, or as
+// endKeyMVCC if reverse is true.
 func MakeBackupSSTKVFetcher(
 	startKeyMVCC, endKeyMVCC storage.MVCCKey,
 	iter storage.SimpleMVCCIterator,
 	startTime hlc.Timestamp,
 	endTime hlc.Timestamp,
 	withRev bool,
-) BackupSSTKVFetcher {
+	skipDeleted bool,
+	reverse bool,
+) (BackupSSTKVFetcher, error) {
 	res := BackupSSTKVFetcher{
-		iter,
-		endKeyMVCC,
-		startTime,
-		endTime,
-		withRev,
+		iter:          iter,
+		startKeyMVCC:  startKeyMVCC,
+		endKeyMVCC:    endKeyMVCC,
+		startTime:     startTime,
+		endTime:       endTime,
+		withRevisions: withRev,
+		skipDeleted:   skipDeleted,
+		reverse:       reverse,
+	}
+	if reverse {
+		revIter, ok := iter.(reverseMVCCIterator)
+		if !ok {
+			return BackupSSTKVFetcher{}, errors.Newf("reverse iteration is not supported by %T", iter)
+		}
+		revIter.SeekLT(endKeyMVCC)
+		return res, nil
 	}
 	res.iter.SeekGE(startKeyMVCC)
-	return res
+	return res, nil
+}
+
+// MakeBackupSSTKVFetcherForSpans is like MakeBackupSSTKVFetcher, but iterates
+// over several spans. The spans must be ordered and non-overlapping; they are
+// iterated over in order, or in reverse order if reverse is true.
+func MakeBackupSSTKVFetcherForSpans(
+	spans roachpb.Spans,
+	iter storage.SimpleMVCCIterator,
+	startTime hlc.Timestamp,
+	endTime hlc.Timestamp,
+	withRev bool,
+	skipDeleted bool,
+	reverse bool,
+) (BackupSSTKVFetcher, error) {
+	if len(spans) == 0 {
+		return BackupSSTKVFetcher{}, errors.New("no spans to iterate over")
+	}
+	for i := 1; i < len(spans); i++ {
+		if spans[i].Key.Compare(spans[i-1].EndKey) < 0 {
+			return BackupSSTKVFetcher{}, errors.Newf(
+				"unordered or overlapping spans (%s %s)", spans[i-1], spans[i])
+		}
+	}
+	// Copy the spans in the iteration order so that the caller's slice is not
+	// modified.
+	ordered := make(roachpb.Spans, len(spans))
+	copy(ordered, spans)
+	if reverse {
+		for i, j := 0, len(ordered)-1; i < j; i, j = i+1, j-1 {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		}
+	}
+	res, err := MakeBackupSSTKVFetcher(
+		storage.MVCCKey{Key: ordered[0].Key}, storage.MVCCKey{Key: ordered[0].EndKey},
+		iter, startTime, endTime, withRev, skipDeleted, reverse,
+	)
+	if err != nil {
+		return BackupSSTKVFetcher{}, err
+	}
+	res.remainingSpans = ordered[1:]
+	return res, nil
+}
+
+// seekToSpan makes the given span the current one and positions the iterator
+// at its first key in the iteration order.
+func (f *BackupSSTKVFetcher) seekToSpan(span roachpb.Span) {
+	f.startKeyMVCC = storage.MVCCKey{Key: span.Key}
+	f.endKeyMVCC = storage.MVCCKey{Key: span.EndKey}
+	if f.reverse {
+		f.iter.(reverseMVCCIterator).SeekLT(f.endKeyMVCC)
+	} else {
+		f.iter.SeekGE(f.startKeyMVCC)
+	}
+}
+
+// ResumeKey returns the MVCC key from which the next call to nextBatch would
+// continue, or an empty key if the fetcher is exhausted. It can be passed to
+// MakeBackupSSTKVFetcher to resume the iteration from exactly the same
+// position, for example after a processor stopped at a byte budget. For
+// fetchers over multiple spans, the spans preceding the resume key (following
+// it, in case of reverse iteration) have been fully processed.
+func (f *BackupSSTKVFetcher) ResumeKey() storage.MVCCKey {
+	if valid, err := f.iter.Valid(); err == nil && valid {
+		unsafeKey := f.iter.UnsafeKey()
+		key := storage.MVCCKey{
+			Key:       append(roachpb.Key(nil), unsafeKey.Key...),
+			Timestamp: unsafeKey.Timestamp,
+		}
+		if f.reverse && !key.Less(f.startKeyMVCC) {
+			// The resume key is used as the exclusive end key, so return the key
+			// immediately following the current one.
+			return key.Next()
+		}
+		if !f.reverse && key.Less(f.endKeyMVCC) {
+			return key
+		}
+	}
+	if len(f.remainingSpans) == 0 {
+		return storage.MVCCKey{}
+	}
+	if f.reverse {
+		return storage.MVCCKey{Key: f.remainingSpans[0].EndKey}
+	}
+	return storage.MVCCKey{Key: f.remainingSpans[0].Key}
+}
+
+func copyMVCCKeyValue(mvccKey storage.MVCCKey, value []byte) roachpb.KeyValue {
+	keyCopy := make([]byte, len(mvccKey.Key))
+	copy(keyCopy, mvccKey.Key)
+	valueCopy := make([]byte, len(value))
+	copy(valueCopy, value)
+	return roachpb.KeyValue{
+		Key:   keyCopy,
+		Value: roachpb.Value{RawBytes: valueCopy, Timestamp: mvccKey.Timestamp},
+	}
 }
 
 func (f *BackupSSTKVFetcher) nextBatch(
 	ctx context.Context,
 ) (ok bool, kvs []roachpb.KeyValue, batchResponse []byte, err error) {
-	res := make([]roachpb.KeyValue, 0)
+	for {
+		if f.reverse {
+			ok, kvs, batchResponse, err = f.nextBatchReverse(ctx)
+		} else {
+			ok, kvs, batchResponse, err = f.nextBatchForward(ctx)
+		}
+		if ok && err == nil {
+			if err := f.addToSummary(kvs); err != nil {
+				return false, nil, nil, err
+			}
+		}
+		if ok || err != nil || len(f.remainingSpans) == 0 {
+			return ok, kvs, batchResponse, err
+		}
+		// The current span is exhausted, so move on to the next one.
+		f.seekToSpan(f.remainingSpans[0])
+		f.remainingSpans = f.remainingSpans[1:]
+	}
+}
 
-	copyKV := func(mvccKey storage.MVCCKey, value []byte) roachpb.KeyValue {
-		keyCopy := make([]byte, len(mvccKey.Key))
-		copy(keyCopy, mvccKey.Key)
-		valueCopy := make([]byte, len(value))
-		copy(valueCopy, value)
-		return roachpb.KeyValue{
-			Key:   keyCopy,
-			Value: roachpb.Value{RawBytes: valueCopy, Timestamp: mvccKey.Timestamp},
+// addToSummary accounts for the given KVs, about to be returned, in the
+// summary.
+func (f *BackupSSTKVFetcher) addToSummary(kvs []roachpb.KeyValue) error {
+	for i := range kvs {
+		f.summary.DataSize += int64(len(kvs[i].Key) + len(kvs[i].Value.RawBytes))
+		if err := f.summary.Count(kvs[i].Key); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// Summary returns a summary of the KVs returned by the fetcher so far: their
+// total size and the number of distinct rows per table and index. When
+// iterating with revisions, the revisions of a row are counted once.
+func (f *BackupSSTKVFetcher) Summary() roachpb.BulkOpSummary {
+	var res roachpb.BulkOpSummary
+	res.Add(f.summary.BulkOpSummary)
+	return res
+}
+
+// nextBatchForward returns all remaining KVs in the current span.
+func (f *BackupSSTKVFetcher) nextBatchForward(
+	ctx context.Context,
+) (ok bool, kvs []roachpb.KeyValue, batchResponse []byte, err error) {
+	res := make([]roachpb.KeyValue, 0)
 
 	for {
+		if err := f.checkCancel(ctx); err != nil {
+			return false, nil, nil, err
+		}
 		valid, err := f.iter.Valid()
 		if err != nil {
 			err = errors.Wrapf(err, "iter key value of table data")
@@ -317,12 +909,18 @@ func (f *BackupSSTKVFetcher) nextBatch(
 				f.iter.NextKey()
 				continue
 			}
+			if f.skipDeleted && len(f.iter.UnsafeValue()) == 0 {
+				f.iter.Next()
+				continue
+			}
 		} else {
 			if len(f.iter.UnsafeValue()) == 0 {
 				if f.endTime.IsEmpty() || f.iter.UnsafeKey().Timestamp.Less(f.endTime) {
 					// Value is deleted at endTime.
-					f.iter.NextKey()
-					continue
+					if f.skipDeleted {
+						f.iter.NextKey()
+						continue
+					}
 				} else {
 					// Otherwise we call Next to trace back the correct revision.
 					f.iter.Next()
@@ -331,7 +929,7 @@ func (f *BackupSSTKVFetcher) nextBatch(
 			}
 		}
 
-		res = append(res, copyKV(f.iter.UnsafeKey(), f.iter.UnsafeValue()))
+		res = append(res, copyMVCCKeyValue(f.iter.UnsafeKey(), f.iter.UnsafeValue()))
 
 		if f.withRevisions {
 			f.iter.Next()
@@ -346,6 +944,90 @@ func (f *BackupSSTKVFetcher) nextBatch(
 	return true, res, nil, nil
 }
 
+// nextBatchReverse is the reverse counterpart of nextBatchForward. The
+// revisions of a key are visited from the oldest to the newest, so the
+// revision to return when withRevisions is false is only known once all
+// revisions of the key at or below endTime have been visited.
+func (f *BackupSSTKVFetcher) nextBatchReverse(
+	ctx context.Context,
+) (ok bool, kvs []roachpb.KeyValue, batchResponse []byte, err error) {
+	iter := f.iter.(reverseMVCCIterator)
+	res := make([]roachpb.KeyValue, 0)
+
+	for {
+		if err := f.checkCancel(ctx); err != nil {
+			return false, nil, nil, err
+		}
+		valid, err := iter.Valid()
+		if err != nil {
+			err = errors.Wrapf(err, "iter key value of table data")
+			return false, nil, nil, err
+		}
+
+		if !valid || iter.UnsafeKey().Less(f.startKeyMVCC) {
+			break
+		}
+
+		if f.withRevisions {
+			if !f.endTime.IsEmpty() && f.endTime.Less(iter.UnsafeKey().Timestamp) {
+				// All the remaining revisions of this key are newer.
+				prevKey(iter)
+				continue
+			}
+			if !iter.UnsafeKey().Timestamp.Less(f.startTime) &&
+				!(f.skipDeleted && len(iter.UnsafeValue()) == 0) {
+				res = append(res, copyMVCCKeyValue(iter.UnsafeKey(), iter.UnsafeValue()))
+			}
+			iter.Prev()
+			continue
+		}
+
+		var latest roachpb.KeyValue
+		var found, onSameKey bool
+		key := append(roachpb.Key(nil), iter.UnsafeKey().Key...)
+		for {
+			mvccKey := iter.UnsafeKey()
+			if !f.endTime.IsEmpty() && f.endTime.Less(mvccKey.Timestamp) {
+				onSameKey = true
+				break
+			}
+			// Deletions at exactly endTime are skipped over, the same way as
+			// Next is used to trace back the correct revision in nextBatchForward.
+			value := iter.UnsafeValue()
+			if len(value) != 0 || f.endTime.IsEmpty() || mvccKey.Timestamp.Less(f.endTime) {
+				latest, found = copyMVCCKeyValue(mvccKey, value), true
+			}
+			iter.Prev()
+			if valid, err := iter.Valid(); err != nil || !valid || !iter.UnsafeKey().Key.Equal(key) {
+				// Errors are surfaced by the Valid check in the outer loop.
+				break
+			}
+		}
+		if onSameKey {
+			prevKey(iter)
+		}
+		if found && (len(latest.Value.RawBytes) != 0 || !f.skipDeleted) {
+			res = append(res, latest)
+		}
+	}
+	if len(res) == 0 {
+		return false, nil, nil, err
+	}
+	return true, res, nil, nil
+}
+
+// prevKey moves the iterator to the oldest revision of the previous MVCC key.
+// It is the reverse counterpart of NextKey.
+func prevKey(iter reverseMVCCIterator) {
+	key := append(roachpb.Key(nil), iter.UnsafeKey().Key...)
+	for {
+		iter.Prev()
+		if valid, err := iter.Valid(); err != nil || !valid || !iter.UnsafeKey().Key.Equal(key) {
+			return
+		}
+	}
+}
+
 func (f *BackupSSTKVFetcher) close(context.Context) {
 	f.iter.Close()
 }
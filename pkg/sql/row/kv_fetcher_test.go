@@ -0,0 +1,1132 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package row
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvclient/kvcoord"
+	"github.com/cockroachdb/cockroach/pkg/kv/kvserver/concurrency/lock"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings/cluster"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/kvstreamer"
+	"github.com/cockroachdb/cockroach/pkg/sql/rowinfra"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/mon"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing/tracingpb"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// makeTestKVFetcher returns a KVFetcher that serves GetRequests from the given
+// key/value pairs without going through the KV layer.
+func makeTestKVFetcher(
+	t *testing.T, ctx context.Context, kvs map[string]string, spans roachpb.Spans,
+) *KVFetcher {
+	sendFn := func(_ context.Context, ba roachpb.BatchRequest) (*roachpb.BatchResponse, error) {
+		br := ba.CreateReply()
+		for i, req := range ba.Requests {
+			get := req.GetGet()
+			require.NotNil(t, get)
+			resp := br.Responses[i].GetGet()
+			if v, ok := kvs[string(get.Key)]; ok {
+				val := roachpb.MakeValueFromString(v)
+				resp.Value = &val
+			}
+		}
+		return br, nil
+	}
+	f, err := makeKVBatchFetcher(ctx, kvBatchFetcherArgs{
+		sendFn: sendFn,
+		spans:  spans,
+	})
+	require.NoError(t, err)
+	return newKVFetcher(&f)
+}
+
+// drainKVFetcher returns the keys of all key/value pairs produced by f.
+func drainKVFetcher(t *testing.T, ctx context.Context, f *KVFetcher) []string {
+	var keys []string
+	for {
+		ok, kv, _, err := f.NextKV(ctx, MVCCDecodingNotRequired)
+		require.NoError(t, err)
+		if !ok {
+			return keys
+		}
+		keys = append(keys, string(kv.Key))
+	}
+}
+
+func getSpans(keys ...string) roachpb.Spans {
+	spans := make(roachpb.Spans, len(keys))
+	for i, k := range keys {
+		spans[i] = roachpb.Span{Key: roachpb.Key(k)}
+	}
+	return spans
+}
+
+// TestKVFetcherReset verifies that a KVFetcher can be reused for another scan
+// after being reset.
+func TestKVFetcherReset(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	kvs := map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"}
+	f := makeTestKVFetcher(t, ctx, kvs, getSpans("a", "b"))
+	defer f.Close(ctx)
+
+	require.Equal(t, []string{"a", "b"}, drainKVFetcher(t, ctx, f))
+	bytesRead := f.GetBytesRead()
+	require.NotZero(t, bytesRead)
+
+	require.NoError(t, f.Reset(ctx, getSpans("c", "x", "d")))
+	require.Equal(t, []string{"c", "d"}, drainKVFetcher(t, ctx, f))
+	require.Greater(t, f.GetBytesRead(), bytesRead)
+
+	// Fetchers that don't use a txnKVFetcher cannot be reset.
+	spanFetcher := newKVFetcher(&SpanKVFetcher{})
+	require.Error(t, spanFetcher.Reset(ctx, getSpans("a")))
+}
+
+// TestKVFetcherCounts verifies that a KVFetcher keeps track of the number of
+// key-value pairs and batches it has read.
+func TestKVFetcherCounts(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	var uninitialized *KVFetcher
+	require.Zero(t, uninitialized.GetKVPairsRead())
+	require.Zero(t, uninitialized.GetBatchesRead())
+
+	kvs := map[string]string{"a": "1", "b": "2", "c": "3"}
+	f := makeTestKVFetcher(t, ctx, kvs, getSpans("a", "b", "x", "c"))
+	defer f.Close(ctx)
+
+	require.Equal(t, []string{"a", "b", "c"}, drainKVFetcher(t, ctx, f))
+	require.Equal(t, int64(3), f.GetKVPairsRead())
+	// Every GetResponse with a value is returned as a separate batch.
+	require.Equal(t, int64(3), f.GetBatchesRead())
+}
+
+// TestKVFetcherKeysOnly verifies that a KVFetcher in keys-only mode returns
+// KVs with empty values.
+func TestKVFetcherKeysOnly(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	kvs := map[string]string{"a": "1", "b": "2"}
+	f := makeTestKVFetcher(t, ctx, kvs, getSpans("a", "b"))
+	defer f.Close(ctx)
+	f.keysOnly = true
+
+	for _, expected := range []string{"a", "b"} {
+		ok, kv, _, err := f.NextKV(ctx, MVCCDecodingNotRequired)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, expected, string(kv.Key))
+		require.Nil(t, kv.Value.RawBytes)
+	}
+	ok, _, _, err := f.NextKV(ctx, MVCCDecodingNotRequired)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+// appendBatchResponseKV appends the given key/value pair to a batch response
+// in the BATCH_RESPONSE scan format.
+func appendBatchResponseKV(b []byte, key roachpb.Key, value roachpb.Value) []byte {
+	encKey := storage.EncodeMVCCKey(storage.MVCCKey{Key: key})
+	var lens [8]byte
+	binary.LittleEndian.PutUint32(lens[:4], uint32(len(value.RawBytes)))
+	binary.LittleEndian.PutUint32(lens[4:], uint32(len(encKey)))
+	b = append(b, lens[:]...)
+	b = append(b, encKey...)
+	return append(b, value.RawBytes...)
+}
+
+// TestKVFetcherNeededFamilies verifies that a KVFetcher only returns the KVs
+// of the needed column families, whether they come from a batch response or
+// from a slice of KVs.
+func TestKVFetcherNeededFamilies(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	var kvs []roachpb.KeyValue
+	var batchResponse []byte
+	var expected []string
+	for row := 0; row < 3; row++ {
+		rowKey := encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(104, 1), int64(row))
+		// Family IDs above 7 use a multi-byte varint encoding.
+		for _, familyID := range []uint32{0, 1, 2, 200} {
+			key := keys.MakeFamilyKey(append([]byte(nil), rowKey...), familyID)
+			value := roachpb.MakeValueFromString(fmt.Sprintf("%d/%d", row, familyID))
+			kvs = append(kvs, roachpb.KeyValue{Key: key, Value: value})
+			batchResponse = appendBatchResponseKV(batchResponse, key, value)
+			if familyID == 0 || familyID == 200 {
+				expected = append(expected, string(key))
+			}
+		}
+	}
+
+	setNeededFamilies := func(f *KVFetcher) {
+		f.neededFamilies.Add(0)
+		f.neededFamilies.Add(200)
+	}
+
+	t.Run("kvs", func(t *testing.T) {
+		f := newKVFetcher(&SpanKVFetcher{KVs: kvs})
+		setNeededFamilies(f)
+		require.Equal(t, expected, drainKVFetcher(t, ctx, f))
+		require.Equal(t, int64(len(expected)), f.GetKVPairsRead())
+	})
+
+	t.Run("batch response", func(t *testing.T) {
+		f := newKVFetcher(&SpanKVFetcher{})
+		setNeededFamilies(f)
+		f.batchResponse = batchResponse
+		require.Equal(t, expected, drainKVFetcher(t, ctx, f))
+	})
+
+	t.Run("all families", func(t *testing.T) {
+		f := newKVFetcher(&SpanKVFetcher{KVs: kvs})
+		require.Len(t, drainKVFetcher(t, ctx, f), len(kvs))
+	})
+}
+
+// TestKVFetcherTracing verifies that a KVFetcher records a tracing span for
+// each batch it fetches when the tracing is verbose, and none otherwise.
+func TestKVFetcherTracing(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	kvs := map[string]string{"a": "1", "b": "2", "c": "3"}
+	tr := tracing.NewTracer()
+	for _, verbose := range []bool{false, true} {
+		t.Run(fmt.Sprintf("verbose=%t", verbose), func(t *testing.T) {
+			recType := tracing.RecordingStructured
+			if verbose {
+				recType = tracing.RecordingVerbose
+			}
+			ctx, sp := tr.StartSpanCtx(context.Background(), "test", tracing.WithRecording(recType))
+			f := makeTestKVFetcher(t, ctx, kvs, getSpans("a", "b", "c"))
+			require.Equal(t, []string{"a", "b", "c"}, drainKVFetcher(t, ctx, f))
+			f.Close(ctx)
+
+			var batchSpans []tracingpb.RecordedSpan
+			for _, rs := range sp.FinishAndGetRecording(tracing.RecordingVerbose) {
+				if rs.Operation == kvFetcherBatchOpName {
+					batchSpans = append(batchSpans, rs)
+				}
+			}
+			if !verbose {
+				require.Empty(t, batchSpans)
+				return
+			}
+			// Every GetResponse with a value is returned as a separate batch, and
+			// the last span is for the call that found no more batches.
+			require.Len(t, batchSpans, 4)
+			expectedBytes := len("a") + len(roachpb.MakeValueFromString("1").RawBytes)
+			for _, rs := range batchSpans[:3] {
+				require.Equal(t, "1", rs.Tags[kvFetcherBatchKVsTagKey])
+				require.Equal(t, fmt.Sprint(expectedBytes), rs.Tags[kvFetcherBatchBytesTagKey])
+			}
+		})
+	}
+}
+
+// TestCountBatchResponseKVs verifies that the KVs of a batch response are
+// counted correctly.
+func TestCountBatchResponseKVs(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var batchResponse []byte
+	for i := 0; i < 10; i++ {
+		require.Equal(t, i, countBatchResponseKVs(batchResponse))
+		batchResponse = appendBatchResponseKV(
+			batchResponse, roachpb.Key(fmt.Sprintf("k%d", i)), roachpb.MakeValueFromString("v"),
+		)
+	}
+}
+
+// TestKVFetcherCancellation verifies that KVFetcher.NextKV notices a canceled
+// context while decoding a batch response.
+func TestKVFetcherCancellation(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	// Build a batch response with more KVs than the cancel check interval.
+	var batchResponse []byte
+	const numKVs = 3 * nextKVCancelCheckInterval
+	for i := 0; i < numKVs; i++ {
+		batchResponse = appendBatchResponseKV(
+			batchResponse, roachpb.Key(fmt.Sprintf("k%05d", i)), roachpb.MakeValueFromString("v"),
+		)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := newKVFetcher(&SpanKVFetcher{})
+	f.batchResponse = batchResponse
+
+	var numRead int
+	for {
+		ok, _, _, err := f.NextKV(ctx, MVCCDecodingNotRequired)
+		if err != nil {
+			require.True(t, errors.Is(err, context.Canceled))
+			break
+		}
+		require.True(t, ok)
+		numRead++
+		if numRead == nextKVCancelCheckInterval/2 {
+			cancel()
+		}
+	}
+	require.Less(t, numRead, numKVs)
+}
+
+// TestSpanKVFetcherReadTimestamp verifies that a SpanKVFetcher with a read
+// timestamp returns the most recent version of each key as of that timestamp.
+func TestSpanKVFetcherReadTimestamp(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	makeKV := func(key string, ts int64, value string) roachpb.KeyValue {
+		kv := roachpb.KeyValue{Key: roachpb.Key(key)}
+		if value != "" {
+			kv.Value = roachpb.MakeValueFromString(value)
+		}
+		kv.Value.Timestamp = hlc.Timestamp{WallTime: ts}
+		return kv
+	}
+	kvs := []roachpb.KeyValue{
+		makeKV("a", 3, "a3"), makeKV("a", 1, "a1"),
+		makeKV("b", 2, "b2"), makeKV("b", 4, ""),
+		makeKV("c", 1, "c1"), makeKV("c", 2, ""), makeKV("c", 5, "c5"),
+		makeKV("d", 5, "d5"),
+	}
+
+	for _, tc := range []struct {
+		ts       int64
+		expected []string
+	}{
+		{ts: 0, expected: []string{"a3", "a1", "b2", "", "c1", "", "c5", "d5"}},
+		{ts: 1, expected: []string{"a1", "c1"}},
+		{ts: 2, expected: []string{"a1", "b2"}},
+		{ts: 4, expected: []string{"a3"}},
+		{ts: 5, expected: []string{"a3", "c5", "d5"}},
+	} {
+		t.Run(fmt.Sprintf("ts=%d", tc.ts), func(t *testing.T) {
+			f := newKVFetcher(&SpanKVFetcher{
+				KVs:           append([]roachpb.KeyValue(nil), kvs...),
+				ReadTimestamp: hlc.Timestamp{WallTime: tc.ts},
+			})
+			var values []string
+			for {
+				ok, kv, _, err := f.NextKV(ctx, MVCCDecodingNotRequired)
+				require.NoError(t, err)
+				if !ok {
+					break
+				}
+				var value []byte
+				if kv.Value.IsPresent() {
+					value, err = kv.Value.GetBytes()
+					require.NoError(t, err)
+				}
+				values = append(values, string(value))
+			}
+			require.Equal(t, tc.expected, values)
+		})
+	}
+}
+
+// makeBackupSSTTestEngine returns an in-memory engine with a few revisions,
+// including deletions, of the keys "a" through "e".
+func makeBackupSSTTestEngine(t *testing.T) storage.Engine {
+	eng := storage.NewDefaultInMemForTesting()
+	for _, kv := range []struct {
+		key   string
+		ts    int64
+		value string
+	}{
+		{"a", 1, "a1"}, {"a", 3, "a3"},
+		{"b", 2, "b2"}, {"b", 4, ""},
+		{"c", 1, "c1"}, {"c", 2, ""}, {"c", 5, "c5"},
+		{"d", 3, ""}, {"d", 5, "d5"},
+		{"e", 4, "e4"},
+	} {
+		var value []byte
+		if kv.value != "" {
+			value = roachpb.MakeValueFromString(kv.value).RawBytes
+		}
+		key := storage.MVCCKey{Key: roachpb.Key(kv.key), Timestamp: hlc.Timestamp{WallTime: kv.ts}}
+		require.NoError(t, eng.PutMVCC(key, value))
+	}
+	return eng
+}
+
+// drainBackupSSTKVFetcher returns all key-value pairs produced by f and closes
+// it.
+func drainBackupSSTKVFetcher(
+	t *testing.T, ctx context.Context, f *BackupSSTKVFetcher,
+) []roachpb.KeyValue {
+	defer f.close(ctx)
+	var res []roachpb.KeyValue
+	for {
+		ok, kvs, _, err := f.nextBatch(ctx)
+		require.NoError(t, err)
+		if !ok {
+			return res
+		}
+		res = append(res, kvs...)
+	}
+}
+
+// formatBackupKVs formats the given key-value pairs as key@walltime.
+func formatBackupKVs(kvs []roachpb.KeyValue) []string {
+	var res []string
+	for _, kv := range kvs {
+		res = append(res, fmt.Sprintf("%s@%d", string(kv.Key), kv.Value.Timestamp.WallTime))
+	}
+	return res
+}
+
+// TestBackupSSTKVFetcherReverse verifies that a reverse BackupSSTKVFetcher
+// returns the same key-value pairs as a forward one, in reverse order.
+func TestBackupSSTKVFetcherReverse(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	eng := makeBackupSSTTestEngine(t)
+	defer eng.Close()
+
+	ts := func(wallTime int64) hlc.Timestamp { return hlc.Timestamp{WallTime: wallTime} }
+	fetch := func(
+		startKey, endKey string, startTime, endTime hlc.Timestamp, withRev, skipDeleted, reverse bool,
+	) []roachpb.KeyValue {
+		iter := eng.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.KeyMax})
+		f, err := MakeBackupSSTKVFetcher(
+			storage.MVCCKey{Key: roachpb.Key(startKey)}, storage.MVCCKey{Key: roachpb.Key(endKey)},
+			iter, startTime, endTime, withRev, skipDeleted, reverse,
+		)
+		require.NoError(t, err)
+		return drainBackupSSTKVFetcher(t, ctx, &f)
+	}
+
+	require.Equal(t, []string{"e@4", "d@5", "c@5", "a@3"}, formatBackupKVs(
+		fetch("a", "f", hlc.Timestamp{}, hlc.Timestamp{},
+			false /* withRev */, true /* skipDeleted */, true /* reverse */),
+	))
+
+	for _, withRev := range []bool{false, true} {
+		for _, skipDeleted := range []bool{false, true} {
+			for _, endTime := range []int64{0, 2, 3, 4, 5} {
+				for _, span := range [][2]string{{"a", "f"}, {"b", "d"}, {"a", "a\x00"}} {
+					forward := fetch(span[0], span[1], ts(2), ts(endTime), withRev, skipDeleted, false /* reverse */)
+					reverse := fetch(span[0], span[1], ts(2), ts(endTime), withRev, skipDeleted, true /* reverse */)
+					for i, j := 0, len(reverse)-1; i < j; i, j = i+1, j-1 {
+						reverse[i], reverse[j] = reverse[j], reverse[i]
+					}
+					require.Equal(t, forward, reverse,
+						"withRev=%t skipDeleted=%t endTime=%d span=%s", withRev, skipDeleted, endTime, span)
+				}
+			}
+		}
+	}
+
+	// Iterators that don't support Prev cannot be used in reverse.
+	iter := struct{ storage.SimpleMVCCIterator }{
+		eng.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.KeyMax}),
+	}
+	defer iter.Close()
+	_, err := MakeBackupSSTKVFetcher(
+		storage.MVCCKey{Key: roachpb.Key("a")}, storage.MVCCKey{Key: roachpb.Key("f")},
+		iter, hlc.Timestamp{}, hlc.Timestamp{}, false /* withRev */, true /* skipDeleted */, true, /* reverse */
+	)
+	require.Error(t, err)
+}
+
+// TestBackupSSTKVFetcherForSpans verifies that a BackupSSTKVFetcher created
+// for multiple spans returns the key-value pairs from all of them.
+func TestBackupSSTKVFetcherForSpans(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	eng := makeBackupSSTTestEngine(t)
+	defer eng.Close()
+
+	spans := roachpb.Spans{
+		{Key: roachpb.Key("a"), EndKey: roachpb.Key("b")},
+		{Key: roachpb.Key("b\x00"), EndKey: roachpb.Key("c\x00")},
+		{Key: roachpb.Key("e"), EndKey: roachpb.Key("f")},
+	}
+	for _, tc := range []struct {
+		withRev  bool
+		reverse  bool
+		expected []string
+	}{
+		{expected: []string{"a@3", "c@5", "e@4"}},
+		{reverse: true, expected: []string{"e@4", "c@5", "a@3"}},
+		{withRev: true, expected: []string{"a@3", "a@1", "c@5", "c@2", "c@1", "e@4"}},
+		{withRev: true, reverse: true, expected: []string{"e@4", "c@1", "c@2", "c@5", "a@1", "a@3"}},
+	} {
+		iter := eng.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.KeyMax})
+		f, err := MakeBackupSSTKVFetcherForSpans(
+			spans, iter, hlc.Timestamp{}, hlc.Timestamp{}, tc.withRev, !tc.withRev /* skipDeleted */, tc.reverse,
+		)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, formatBackupKVs(drainBackupSSTKVFetcher(t, ctx, &f)),
+			"withRev=%t reverse=%t", tc.withRev, tc.reverse)
+	}
+	// The spans passed in must not be modified.
+	require.Equal(t, roachpb.Key("a"), spans[0].Key)
+
+	// Overlapping spans are rejected.
+	iter := eng.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.KeyMax})
+	defer iter.Close()
+	_, err := MakeBackupSSTKVFetcherForSpans(
+		roachpb.Spans{
+			{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")},
+			{Key: roachpb.Key("b"), EndKey: roachpb.Key("d")},
+		},
+		iter, hlc.Timestamp{}, hlc.Timestamp{}, false /* withRev */, true /* skipDeleted */, false /* reverse */)
+	require.Error(t, err)
+}
+
+// TestBackupSSTKVFetcherSkipDeleted verifies that deletion tombstones are
+// returned by a BackupSSTKVFetcher unless skipDeleted is set.
+func TestBackupSSTKVFetcherSkipDeleted(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	eng := makeBackupSSTTestEngine(t)
+	defer eng.Close()
+
+	for _, tc := range []struct {
+		withRev     bool
+		skipDeleted bool
+		expected    []string
+	}{
+		{skipDeleted: true, expected: []string{"a@3", "c@5", "d@5", "e@4"}},
+		{skipDeleted: false, expected: []string{"a@3", "b@4", "c@5", "d@5", "e@4"}},
+		{withRev: true, skipDeleted: true, expected: []string{
+			"a@3", "a@1", "b@2", "c@5", "c@1", "d@5", "e@4",
+		}},
+		{withRev: true, skipDeleted: false, expected: []string{
+			"a@3", "a@1", "b@4", "b@2", "c@5", "c@2", "c@1", "d@5", "d@3", "e@4",
+		}},
+	} {
+		iter := eng.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.KeyMax})
+		f, err := MakeBackupSSTKVFetcher(
+			storage.MVCCKey{Key: roachpb.Key("a")}, storage.MVCCKey{Key: roachpb.Key("f")},
+			iter, hlc.Timestamp{}, hlc.Timestamp{}, tc.withRev, tc.skipDeleted, false, /* reverse */
+		)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, formatBackupKVs(drainBackupSSTKVFetcher(t, ctx, &f)),
+			"withRev=%t skipDeleted=%t", tc.withRev, tc.skipDeleted)
+	}
+}
+
+// cancelingMVCCIterator cancels a context once Next or NextKey has been
+// called a given number of times.
+type cancelingMVCCIterator struct {
+	storage.SimpleMVCCIterator
+	cancelAfter int
+	cancel      func()
+	steps       int
+}
+
+func (it *cancelingMVCCIterator) step() {
+	if it.steps++; it.steps == it.cancelAfter {
+		it.cancel()
+	}
+}
+
+func (it *cancelingMVCCIterator) Next() {
+	it.step()
+	it.SimpleMVCCIterator.Next()
+}
+
+func (it *cancelingMVCCIterator) NextKey() {
+	it.step()
+	it.SimpleMVCCIterator.NextKey()
+}
+
+// TestBackupSSTKVFetcherCancellation verifies that BackupSSTKVFetcher.nextBatch
+// notices a canceled context before exhausting the current span.
+func TestBackupSSTKVFetcherCancellation(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+	const numKVs = 3 * backupSSTCancelCheckInterval
+	for i := 0; i < numKVs; i++ {
+		key := storage.MVCCKey{Key: roachpb.Key(fmt.Sprintf("k%05d", i)), Timestamp: hlc.Timestamp{WallTime: 1}}
+		require.NoError(t, eng.PutMVCC(key, roachpb.MakeValueFromString("v").RawBytes))
+	}
+
+	for _, withRev := range []bool{false, true} {
+		ctx, cancel := context.WithCancel(context.Background())
+		iter := &cancelingMVCCIterator{
+			SimpleMVCCIterator: eng.NewMVCCIterator(
+				storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.KeyMax},
+			),
+			cancelAfter: backupSSTCancelCheckInterval / 2,
+			cancel:      cancel,
+		}
+		f, err := MakeBackupSSTKVFetcher(
+			storage.MVCCKey{Key: roachpb.Key("k")}, storage.MVCCKey{Key: roachpb.Key("l")},
+			iter, hlc.Timestamp{}, hlc.Timestamp{}, withRev, true /* skipDeleted */, false, /* reverse */
+		)
+		require.NoError(t, err)
+		_, _, _, err = f.nextBatch(ctx)
+		require.True(t, errors.Is(err, context.Canceled), "withRev=%t: %v", withRev, err)
+		require.Less(t, iter.steps, numKVs, "withRev=%t", withRev)
+		f.close(ctx)
+		cancel()
+	}
+}
+
+// TestBackupSSTKVFetcherResumeKey verifies that a BackupSSTKVFetcher can be
+// resumed from its ResumeKey.
+func TestBackupSSTKVFetcherResumeKey(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	eng := makeBackupSSTTestEngine(t)
+	defer eng.Close()
+
+	spans := roachpb.Spans{
+		{Key: roachpb.Key("a"), EndKey: roachpb.Key("b\x00")},
+		{Key: roachpb.Key("d"), EndKey: roachpb.Key("f")},
+	}
+	for _, reverse := range []bool{false, true} {
+		iter := eng.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.KeyMax})
+		f, err := MakeBackupSSTKVFetcherForSpans(
+			spans, iter, hlc.Timestamp{}, hlc.Timestamp{}, true /* withRev */, false /* skipDeleted */, reverse,
+		)
+		require.NoError(t, err)
+		defer f.close(ctx)
+
+		// Process the first span and then stop.
+		ok, first, _, err := f.nextBatch(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		resumeKey := f.ResumeKey()
+
+		// Resume from the second span.
+		startKey, endKey := resumeKey, storage.MVCCKey{Key: spans[1].EndKey}
+		if reverse {
+			startKey, endKey = storage.MVCCKey{Key: spans[0].Key}, resumeKey
+		}
+		resumed, err := MakeBackupSSTKVFetcher(
+			startKey, endKey, eng.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.KeyMax}),
+			hlc.Timestamp{}, hlc.Timestamp{}, true /* withRev */, false /* skipDeleted */, reverse,
+		)
+		require.NoError(t, err)
+		if !reverse {
+			require.Equal(t, []string{"a@3", "a@1", "b@4", "b@2"}, formatBackupKVs(first))
+			require.Equal(t, []string{"d@5", "d@3", "e@4"}, formatBackupKVs(drainBackupSSTKVFetcher(t, ctx, &resumed)))
+		} else {
+			require.Equal(t, []string{"e@4", "d@3", "d@5"}, formatBackupKVs(first))
+			require.Equal(t, []string{"b@2", "b@4", "a@1", "a@3"}, formatBackupKVs(drainBackupSSTKVFetcher(t, ctx, &resumed)))
+		}
+
+	}
+
+	// Resuming in the middle of a key continues from the exact revision.
+	c2 := storage.MVCCKey{Key: roachpb.Key("c"), Timestamp: hlc.Timestamp{WallTime: 2}}
+	for _, reverse := range []bool{false, true} {
+		startKey, endKey := c2, storage.MVCCKey{Key: roachpb.Key("d")}
+		expected := []string{"c@2", "c@1"}
+		if reverse {
+			startKey, endKey = storage.MVCCKey{Key: roachpb.Key("c")}, c2.Next()
+			expected = []string{"c@2", "c@5"}
+		}
+		iter := eng.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.KeyMax})
+		f, err := MakeBackupSSTKVFetcher(
+			startKey, endKey, iter, hlc.Timestamp{}, hlc.Timestamp{},
+			true /* withRev */, false /* skipDeleted */, reverse,
+		)
+		require.NoError(t, err)
+		if reverse {
+			require.Equal(t, endKey, f.ResumeKey())
+		} else {
+			require.Equal(t, startKey, f.ResumeKey())
+		}
+		require.Equal(t, expected, formatBackupKVs(drainBackupSSTKVFetcher(t, ctx, &f)))
+	}
+}
+
+// TestBackupSSTKVFetcherSummary verifies that a BackupSSTKVFetcher reports
+// the size and the row counts of the KVs it returned.
+func TestBackupSSTKVFetcherSummary(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	eng := storage.NewDefaultInMemForTesting()
+	defer eng.Close()
+
+	const tableID = 104
+	rowKey := func(indexID uint32, row int64, familyID uint32) roachpb.Key {
+		key := encoding.EncodeVarintAscending(keys.SystemSQLCodec.IndexPrefix(tableID, indexID), row)
+		return keys.MakeFamilyKey(key, familyID)
+	}
+	for _, kv := range []struct {
+		key roachpb.Key
+		ts  int64
+	}{
+		{rowKey(1, 1, 0), 1}, {rowKey(1, 1, 0), 2}, {rowKey(1, 1, 1), 1},
+		{rowKey(1, 2, 0), 1},
+		{rowKey(2, 1, 0), 1},
+	} {
+		key := storage.MVCCKey{Key: kv.key, Timestamp: hlc.Timestamp{WallTime: kv.ts}}
+		require.NoError(t, eng.PutMVCC(key, roachpb.MakeValueFromString("v").RawBytes))
+	}
+
+	for _, withRev := range []bool{false, true} {
+		iter := eng.NewMVCCIterator(storage.MVCCKeyIterKind, storage.IterOptions{UpperBound: roachpb.KeyMax})
+		f, err := MakeBackupSSTKVFetcher(
+			storage.MVCCKey{Key: keys.SystemSQLCodec.TablePrefix(tableID)},
+			storage.MVCCKey{Key: keys.SystemSQLCodec.TablePrefix(tableID).PrefixEnd()},
+			iter, hlc.Timestamp{}, hlc.Timestamp{}, withRev, true /* skipDeleted */, false, /* reverse */
+		)
+		require.NoError(t, err)
+		require.Empty(t, f.Summary().EntryCounts)
+
+		var dataSize int64
+		for _, kv := range drainBackupSSTKVFetcher(t, ctx, &f) {
+			dataSize += int64(len(kv.Key) + len(kv.Value.RawBytes))
+		}
+		summary := f.Summary()
+		require.Equal(t, dataSize, summary.DataSize, "withRev=%t", withRev)
+		require.Equal(t, map[uint64]int64{
+			roachpb.BulkOpSummaryID(tableID, 1): 2,
+			roachpb.BulkOpSummaryID(tableID, 2): 1,
+		}, summary.EntryCounts, "withRev=%t", withRev)
+	}
+}
+
+func TestChangefeedKVFetcher(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	kvCh := make(chan roachpb.KeyValue, 10)
+	cf := &ChangefeedKVFetcher{KVs: kvCh, MaxBatchSize: 2}
+	send := func(keys ...string) {
+		for _, k := range keys {
+			kvCh <- roachpb.KeyValue{Key: roachpb.Key(k), Value: roachpb.MakeValueFromString(k)}
+		}
+	}
+	batchKeys := func(kvs []roachpb.KeyValue) []string {
+		var keys []string
+		for _, kv := range kvs {
+			keys = append(keys, string(kv.Key))
+		}
+		return keys
+	}
+
+	// The available KVs are returned in batches of at most MaxBatchSize.
+	send("a", "b", "c")
+	ok, kvs, _, err := cf.nextBatch(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []string{"a", "b"}, batchKeys(kvs))
+	ok, kvs, _, err = cf.nextBatch(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []string{"c"}, batchKeys(kvs))
+
+	// nextBatch blocks until a KV is available.
+	go send("d")
+	ok, kvs, _, err = cf.nextBatch(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []string{"d"}, batchKeys(kvs))
+
+	// nextBatch returns if the context is canceled while it is blocked.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	go cancel()
+	_, _, _, err = cf.nextBatch(cancelCtx)
+	require.True(t, errors.Is(err, context.Canceled))
+
+	// The KVs can be decoded by a KVFetcher, which stops once the channel is
+	// closed.
+	send("e", "f", "g")
+	close(kvCh)
+	require.Equal(t, []string{"e", "f", "g"}, drainKVFetcher(t, ctx, newKVFetcher(cf)))
+}
+
+// testKVBatchFetcher is a KVBatchFetcher which returns a single batch of
+// either kvs or batchResponse, and records whether it has been closed.
+type testKVBatchFetcher struct {
+	kvs           []roachpb.KeyValue
+	batchResponse []byte
+	closed        bool
+}
+
+func (f *testKVBatchFetcher) nextBatch(
+	context.Context,
+) (ok bool, kvs []roachpb.KeyValue, batchResponse []byte, err error) {
+	if f.kvs == nil && f.batchResponse == nil {
+		return false, nil, nil, nil
+	}
+	kvs, batchResponse = f.kvs, f.batchResponse
+	f.kvs, f.batchResponse = nil, nil
+	return true, kvs, batchResponse, nil
+}
+
+func (f *testKVBatchFetcher) close(context.Context) {
+	f.closed = true
+}
+
+func TestMultiKVBatchFetcher(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	var kvs []roachpb.KeyValue
+	for _, k := range []string{"a", "b"} {
+		kvs = append(kvs, roachpb.KeyValue{Key: roachpb.Key(k), Value: roachpb.MakeValueFromString(k)})
+	}
+	var batchResponse []byte
+	for _, k := range []string{"c", "d"} {
+		batchResponse = appendBatchResponseKV(batchResponse, roachpb.Key(k), roachpb.MakeValueFromString(k))
+	}
+	fetchers := []*testKVBatchFetcher{
+		{kvs: kvs},
+		// Exhausted fetchers are skipped.
+		{},
+		{batchResponse: batchResponse},
+	}
+	var batchFetchers []KVBatchFetcher
+	for _, f := range fetchers {
+		batchFetchers = append(batchFetchers, f)
+	}
+	f := newKVFetcher(NewMultiKVBatchFetcher(batchFetchers...))
+	require.Equal(t, []string{"a", "b", "c", "d"}, drainKVFetcher(t, ctx, f))
+
+	f.Close(ctx)
+	for _, fetcher := range fetchers {
+		require.True(t, fetcher.closed)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		require.Empty(t, drainKVFetcher(t, ctx, newKVFetcher(NewMultiKVBatchFetcher())))
+	})
+}
+
+// TestKVFetcherDrain verifies that a KVFetcher backed by the Streamer returns
+// no more KVs once it has been drained, and that Drain is a no-op for other
+// fetchers.
+func TestKVFetcherDrain(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(ctx)
+
+	sqlDB := sqlutils.MakeSQLRunner(db)
+	sqlDB.Exec(t, "CREATE TABLE t (k INT PRIMARY KEY)")
+	sqlDB.Exec(t, "INSERT INTO t SELECT generate_series(1, 100)")
+	// Split the table so that the scan is served by multiple requests.
+	sqlDB.Exec(t, "ALTER TABLE t SPLIT AT VALUES (25), (50), (75)")
+	var tableID uint32
+	sqlDB.QueryRow(t, "SELECT 't'::regclass::oid").Scan(&tableID)
+	prefix := keys.SystemSQLCodec.TablePrefix(tableID)
+
+	t.Run("streamer", func(t *testing.T) {
+		rootTxn := kv.NewTxn(ctx, s.DB(), s.NodeID())
+		streamer := kvstreamer.NewStreamer(
+			s.DistSenderI().(*kvcoord.DistSender),
+			s.Stopper(),
+			kv.NewLeafTxn(ctx, s.DB(), s.NodeID(), rootTxn.GetLeafTxnInputState(ctx)),
+			cluster.MakeTestingClusterSettings(),
+			lock.WaitPolicy(0),
+			math.MaxInt64, /* limitBytes */
+			nil,           /* acc */
+		)
+		defer streamer.Close(ctx)
+		streamer.Init(
+			kvstreamer.OutOfOrder, kvstreamer.Hints{UniqueRequests: true},
+			1 /* maxKeysPerRow */, nil /* engine */, nil, /* diskMonitor */
+		)
+		spans := roachpb.Spans{{Key: prefix, EndKey: prefix.PrefixEnd()}}
+		txnStreamer, err := NewTxnKVStreamer(ctx, streamer, spans, false /* reverse */, descpb.ScanLockingStrength_FOR_NONE)
+		require.NoError(t, err)
+		f := NewKVStreamingFetcher(txnStreamer)
+		defer f.Close(ctx)
+
+		ok, _, _, err := f.NextKV(ctx, MVCCDecodingNotRequired)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		f.Drain(ctx)
+		ok, _, _, err = f.NextKV(ctx, MVCCDecodingNotRequired)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("non-streamer", func(t *testing.T) {
+		f := newKVFetcher(&SpanKVFetcher{KVs: []roachpb.KeyValue{
+			{Key: roachpb.Key("a"), Value: roachpb.MakeValueFromString("1")},
+			{Key: roachpb.Key("b"), Value: roachpb.MakeValueFromString("2")},
+		}})
+		f.Drain(ctx)
+		require.Equal(t, []string{"a", "b"}, drainKVFetcher(t, ctx, f))
+	})
+}
+
+// TestKVStreamingFetcherReverse verifies that a KVFetcher backed by the
+// Streamer in InOrder mode returns the keys in decreasing order when the spans
+// are scanned in reverse.
+func TestKVStreamingFetcherReverse(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(ctx)
+
+	sqlDB := sqlutils.MakeSQLRunner(db)
+	sqlDB.Exec(t, "CREATE TABLE t (k INT PRIMARY KEY)")
+	sqlDB.Exec(t, "INSERT INTO t SELECT generate_series(1, 100)")
+	sqlDB.Exec(t, "ALTER TABLE t SPLIT AT VALUES (25), (50), (75)")
+	var tableID uint32
+	sqlDB.QueryRow(t, "SELECT 't'::regclass::oid").Scan(&tableID)
+	prefix := keys.SystemSQLCodec.IndexPrefix(tableID, 1 /* indexID */)
+
+	st := cluster.MakeTestingClusterSettings()
+	tempEngine, _, err := storage.NewTempEngine(ctx, base.DefaultTestTempStorageConfig(st), base.DefaultTestStoreSpec)
+	require.NoError(t, err)
+	defer tempEngine.Close()
+	diskMonitor := mon.NewMonitor(
+		"test-disk",
+		mon.DiskResource,
+		nil,           /* curCount */
+		nil,           /* maxHist */
+		-1,            /* increment */
+		math.MaxInt64, /* noteworthy */
+		st,
+	)
+	diskMonitor.Start(ctx, nil, mon.MakeStandaloneBudget(math.MaxInt64))
+	defer diskMonitor.Stop(ctx)
+
+	rootTxn := kv.NewTxn(ctx, s.DB(), s.NodeID())
+	streamer := kvstreamer.NewStreamer(
+		s.DistSenderI().(*kvcoord.DistSender),
+		s.Stopper(),
+		kv.NewLeafTxn(ctx, s.DB(), s.NodeID(), rootTxn.GetLeafTxnInputState(ctx)),
+		st,
+		lock.WaitPolicy(0),
+		math.MaxInt64, /* limitBytes */
+		nil,           /* acc */
+	)
+	defer streamer.Close(ctx)
+	streamer.Init(
+		kvstreamer.InOrder, kvstreamer.Hints{UniqueRequests: true},
+		1 /* maxKeysPerRow */, tempEngine, diskMonitor,
+	)
+
+	// Each span is within a single range, in increasing order.
+	span := func(start, end int64) roachpb.Span {
+		return roachpb.Span{
+			Key:    encoding.EncodeVarintAscending(prefix.Clone(), start),
+			EndKey: encoding.EncodeVarintAscending(prefix.Clone(), end),
+		}
+	}
+	spans := roachpb.Spans{span(1, 4), span(30, 33), span(60, 63)}
+	txnStreamer, err := NewTxnKVStreamer(ctx, streamer, spans, true /* reverse */, descpb.ScanLockingStrength_FOR_NONE)
+	require.NoError(t, err)
+	f := NewKVStreamingFetcher(txnStreamer)
+	defer f.Close(ctx)
+
+	var ks []int64
+	for _, key := range drainKVFetcher(t, ctx, f) {
+		_, k, err := encoding.DecodeVarintAscending([]byte(key)[len(prefix):])
+		require.NoError(t, err)
+		ks = append(ks, k)
+	}
+	require.Equal(t, []int64{62, 61, 60, 32, 31, 30, 3, 2, 1}, ks)
+}
+
+// TestKVFetcherResumeSpan verifies that a KVFetcher exposes the resume span of
+// the KV response that the returned KVs came from.
+func TestKVFetcherResumeSpan(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	keys := []string{"a", "b", "c", "d", "e"}
+	// sendFn serves a single ScanRequest over keys, respecting the key limit.
+	sendFn := func(_ context.Context, ba roachpb.BatchRequest) (*roachpb.BatchResponse, error) {
+		br := ba.CreateReply()
+		scan := ba.Requests[0].GetScan()
+		require.NotNil(t, scan)
+		resp := br.Responses[0].GetScan()
+		var batchResponse []byte
+		for _, k := range keys {
+			key := roachpb.Key(k)
+			if key.Compare(scan.Key) < 0 || key.Compare(scan.EndKey) >= 0 {
+				continue
+			}
+			if ba.MaxSpanRequestKeys > 0 && resp.NumKeys == ba.MaxSpanRequestKeys {
+				resp.ResumeSpan = &roachpb.Span{Key: key, EndKey: scan.EndKey}
+				resp.ResumeReason = roachpb.RESUME_KEY_LIMIT
+				break
+			}
+			batchResponse = appendBatchResponseKV(batchResponse, key, roachpb.MakeValueFromString("v"))
+			resp.NumKeys++
+		}
+		resp.BatchResponses = [][]byte{batchResponse}
+		return br, nil
+	}
+	batchFetcher, err := makeKVBatchFetcher(ctx, kvBatchFetcherArgs{
+		sendFn:                     sendFn,
+		spans:                      roachpb.Spans{{Key: roachpb.Key("a"), EndKey: roachpb.Key("z")}},
+		batchBytesLimit:            rowinfra.BytesLimit(1 << 20),
+		firstBatchKeyLimit:         2,
+		forceProductionKVBatchSize: true,
+	})
+	require.NoError(t, err)
+	f := newKVFetcher(&batchFetcher)
+	defer f.Close(ctx)
+
+	require.Nil(t, f.ResumeSpan())
+	for _, tc := range []struct {
+		key          string
+		resumeSpan   *roachpb.Span
+		resumeReason roachpb.ResumeReason
+	}{
+		// The first batch is limited to two keys.
+		{"a", &roachpb.Span{Key: roachpb.Key("c"), EndKey: roachpb.Key("z")}, roachpb.RESUME_KEY_LIMIT},
+		{"b", &roachpb.Span{Key: roachpb.Key("c"), EndKey: roachpb.Key("z")}, roachpb.RESUME_KEY_LIMIT},
+		// The second batch completes the scan.
+		{"c", nil, roachpb.RESUME_UNKNOWN},
+		{"d", nil, roachpb.RESUME_UNKNOWN},
+		{"e", nil, roachpb.RESUME_UNKNOWN},
+	} {
+		ok, kv, _, err := f.NextKV(ctx, MVCCDecodingNotRequired)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, tc.key, string(kv.Key))
+		require.Equal(t, tc.resumeSpan, f.ResumeSpan())
+		require.Equal(t, tc.resumeReason, f.ResumeReason())
+	}
+	ok, _, _, err := f.NextKV(ctx, MVCCDecodingNotRequired)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Fetchers that don't read from the KV layer have no resume span.
+	spanFetcher := newKVFetcher(&SpanKVFetcher{})
+	require.Nil(t, spanFetcher.ResumeSpan())
+	require.Equal(t, roachpb.RESUME_UNKNOWN, spanFetcher.ResumeReason())
+}
+
+// TestKVFetcherNextBatch verifies that a KVFetcher returns the batches it
+// fetches undecoded from NextBatch, with the same accounting as NextKV.
+func TestKVFetcherNextBatch(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	var expected []byte
+	for _, k := range []string{"a", "b", "c"} {
+		expected = appendBatchResponseKV(expected, roachpb.Key(k), roachpb.MakeValueFromString("v"))
+	}
+	sendFn := func(_ context.Context, ba roachpb.BatchRequest) (*roachpb.BatchResponse, error) {
+		br := ba.CreateReply()
+		require.NotNil(t, ba.Requests[0].GetScan())
+		br.Responses[0].GetScan().BatchResponses = [][]byte{expected}
+		return br, nil
+	}
+	makeFetcher := func() *KVFetcher {
+		batchFetcher, err := makeKVBatchFetcher(ctx, kvBatchFetcherArgs{
+			sendFn: sendFn,
+			spans:  roachpb.Spans{{Key: roachpb.Key("a"), EndKey: roachpb.Key("z")}},
+		})
+		require.NoError(t, err)
+		return newKVFetcher(&batchFetcher)
+	}
+
+	f := makeFetcher()
+	defer f.Close(ctx)
+	ok, kvs, batchResponse, err := f.NextBatch(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Nil(t, kvs)
+	require.Equal(t, expected, batchResponse)
+	ok, _, _, err = f.NextBatch(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, int64(len(expected)), f.GetBytesRead())
+	require.Equal(t, int64(3), f.GetKVPairsRead())
+	require.Equal(t, int64(1), f.GetBatchesRead())
+
+	// The accounting matches that of NextKV.
+	kvFetcher := makeFetcher()
+	defer kvFetcher.Close(ctx)
+	require.Equal(t, []string{"a", "b", "c"}, drainKVFetcher(t, ctx, kvFetcher))
+	require.Equal(t, f.GetBytesRead(), kvFetcher.GetBytesRead())
+	require.Equal(t, f.GetKVPairsRead(), kvFetcher.GetKVPairsRead())
+
+	// Batches in the KV format are returned as is.
+	getFetcher := makeTestKVFetcher(t, ctx, map[string]string{"a": "1"}, getSpans("a"))
+	defer getFetcher.Close(ctx)
+	ok, kvs, batchResponse, err = getFetcher.NextBatch(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Nil(t, batchResponse)
+	require.Len(t, kvs, 1)
+	require.Equal(t, "a", string(kvs[0].Key))
+
+	// NextBatch cannot skip the remainder of a batch returned by NextKV.
+	mixedFetcher := makeFetcher()
+	defer mixedFetcher.Close(ctx)
+	ok, _, _, err = mixedFetcher.NextKV(ctx, MVCCDecodingNotRequired)
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, _, _, err = mixedFetcher.NextBatch(ctx)
+	require.Error(t, err)
+
+	// NextBatch cannot be used by fetchers which filter KVs.
+	keysOnlyFetcher := makeFetcher()
+	defer keysOnlyFetcher.Close(ctx)
+	keysOnlyFetcher.keysOnly = true
+	_, _, _, err = keysOnlyFetcher.NextBatch(ctx)
+	require.Error(t, err)
+}
@@ -862,7 +862,9 @@ func (jr *joinReader) readInput() (
 	// joinReaderStrategy doesn't account for any memory used by the spans.
 	if jr.usesStreamer {
 		var kvBatchFetcher *row.TxnKVStreamer
-		kvBatchFetcher, err = row.NewTxnKVStreamer(jr.Ctx, jr.streamerInfo.Streamer, spans, jr.keyLocking)
+		kvBatchFetcher, err = row.NewTxnKVStreamer(
+			jr.Ctx, jr.streamerInfo.Streamer, spans, false /* reverse */, jr.keyLocking,
+		)
 		if err != nil {
 			jr.MoveToDraining(err)
 			return jrStateUnknown, nil, jr.DrainHelper()
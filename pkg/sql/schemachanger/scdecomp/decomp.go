@@ -219,10 +219,17 @@ func (w *walkCtx) walkRelation(tbl catalog.TableDescriptor) {
 	}
 	{
 		if comment, ok, err := w.commentCache.GetTableComment(w.ctx, tbl.GetID()); err == nil && ok {
-			w.ev(scpb.Status_PUBLIC, &scpb.TableComment{
-				TableID: tbl.GetID(),
-				Comment: comment,
-			})
+			if tbl.IsSequence() {
+				w.ev(scpb.Status_PUBLIC, &scpb.SequenceComment{
+					TableID: tbl.GetID(),
+					Comment: comment,
+				})
+			} else {
+				w.ev(scpb.Status_PUBLIC, &scpb.TableComment{
+					TableID: tbl.GetID(),
+					Comment: comment,
+				})
+			}
 		} else if err != nil {
 			panic(err)
 		}
@@ -446,6 +446,18 @@ func (mvs *mutationVisitorState) DeleteComment(
 		})
 }
 
+func (mvs *mutationVisitorState) UpsertComment(
+	id descpb.ID, subID int, commentType keys.CommentType, comment string,
+) {
+	mvs.commentsToUpdate = append(mvs.commentsToUpdate,
+		commentToUpdate{
+			id:          int64(id),
+			subID:       int64(subID),
+			commentType: commentType,
+			comment:     comment,
+		})
+}
+
 func (mvs *mutationVisitorState) DeleteConstraintComment(
 	ctx context.Context, tblID descpb.ID, constraintID descpb.ConstraintID,
 ) error {
@@ -457,6 +469,18 @@ func (mvs *mutationVisitorState) DeleteConstraintComment(
 	return nil
 }
 
+func (mvs *mutationVisitorState) UpsertConstraintComment(
+	ctx context.Context, tblID descpb.ID, constraintID descpb.ConstraintID, comment string,
+) error {
+	mvs.constraintCommentsToUpdate = append(mvs.constraintCommentsToUpdate,
+		constraintCommentToUpdate{
+			tblID:        tblID,
+			constraintID: constraintID,
+			comment:      comment,
+		})
+	return nil
+}
+
 func (mvs *mutationVisitorState) DeleteDatabaseRoleSettings(
 	ctx context.Context, dbID descpb.ID,
 ) error {
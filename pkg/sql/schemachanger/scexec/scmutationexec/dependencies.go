@@ -79,6 +79,9 @@ type MutationVisitorStateUpdater interface {
 	// DeleteComment removes comments for a descriptor
 	DeleteComment(id descpb.ID, subID int, commentType keys.CommentType)
 
+	// UpsertComment adds or replaces the comment for a descriptor
+	UpsertComment(id descpb.ID, subID int, commentType keys.CommentType, comment string)
+
 	// DeleteConstraintComment removes comments for a descriptor
 	DeleteConstraintComment(
 		ctx context.Context,
@@ -86,6 +89,14 @@ type MutationVisitorStateUpdater interface {
 		constraintID descpb.ConstraintID,
 	) error
 
+	// UpsertConstraintComment adds or replaces the comment for a constraint
+	UpsertConstraintComment(
+		ctx context.Context,
+		tblID descpb.ID,
+		constraintID descpb.ConstraintID,
+		comment string,
+	) error
+
 	// DeleteDatabaseRoleSettings removes a database role setting
 	DeleteDatabaseRoleSettings(ctx context.Context, dbID descpb.ID) error
 
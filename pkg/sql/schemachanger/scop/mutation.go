@@ -425,18 +425,39 @@ type RemoveTableComment struct {
 	TableID descpb.ID
 }
 
+// UpsertTableComment is used to add a comment to a table.
+type UpsertTableComment struct {
+	mutationOp
+	TableID descpb.ID
+	Comment string
+}
+
 // RemoveDatabaseComment is used to delete a comment associated with a database.
 type RemoveDatabaseComment struct {
 	mutationOp
 	DatabaseID descpb.ID
 }
 
+// UpsertDatabaseComment is used to add a comment to a database.
+type UpsertDatabaseComment struct {
+	mutationOp
+	DatabaseID descpb.ID
+	Comment    string
+}
+
 // RemoveSchemaComment is used to delete a comment associated with a schema.
 type RemoveSchemaComment struct {
 	mutationOp
 	SchemaID descpb.ID
 }
 
+// UpsertSchemaComment is used to add a comment to a schema.
+type UpsertSchemaComment struct {
+	mutationOp
+	SchemaID descpb.ID
+	Comment  string
+}
+
 // RemoveIndexComment is used to delete a comment associated with an index.
 type RemoveIndexComment struct {
 	mutationOp
@@ -444,6 +465,14 @@ type RemoveIndexComment struct {
 	IndexID descpb.IndexID
 }
 
+// UpsertIndexComment is used to add a comment to an index.
+type UpsertIndexComment struct {
+	mutationOp
+	TableID descpb.ID
+	IndexID descpb.IndexID
+	Comment string
+}
+
 // RemoveColumnComment is used to delete a comment associated with a column.
 type RemoveColumnComment struct {
 	mutationOp
@@ -452,6 +481,15 @@ type RemoveColumnComment struct {
 	PgAttributeNum descpb.PGAttributeNum
 }
 
+// UpsertColumnComment is used to add a comment to a column.
+type UpsertColumnComment struct {
+	mutationOp
+	TableID        descpb.ID
+	ColumnID       descpb.ColumnID
+	PgAttributeNum descpb.PGAttributeNum
+	Comment        string
+}
+
 // RemoveConstraintComment is used to delete a comment associated with a
 // constraint.
 type RemoveConstraintComment struct {
@@ -460,6 +498,14 @@ type RemoveConstraintComment struct {
 	ConstraintID descpb.ConstraintID
 }
 
+// UpsertConstraintComment is used to add a comment to a constraint.
+type UpsertConstraintComment struct {
+	mutationOp
+	TableID      descpb.ID
+	ConstraintID descpb.ConstraintID
+	Comment      string
+}
+
 // RemoveDatabaseRoleSettings is used to delete a role setting for a database.
 type RemoveDatabaseRoleSettings struct {
 	mutationOp
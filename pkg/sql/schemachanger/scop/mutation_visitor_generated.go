@@ -73,11 +73,17 @@ type MutationVisitor interface {
 	CreateSchemaChangerJob(context.Context, CreateSchemaChangerJob) error
 	RemoveAllTableComments(context.Context, RemoveAllTableComments) error
 	RemoveTableComment(context.Context, RemoveTableComment) error
+	UpsertTableComment(context.Context, UpsertTableComment) error
 	RemoveDatabaseComment(context.Context, RemoveDatabaseComment) error
+	UpsertDatabaseComment(context.Context, UpsertDatabaseComment) error
 	RemoveSchemaComment(context.Context, RemoveSchemaComment) error
+	UpsertSchemaComment(context.Context, UpsertSchemaComment) error
 	RemoveIndexComment(context.Context, RemoveIndexComment) error
+	UpsertIndexComment(context.Context, UpsertIndexComment) error
 	RemoveColumnComment(context.Context, RemoveColumnComment) error
+	UpsertColumnComment(context.Context, UpsertColumnComment) error
 	RemoveConstraintComment(context.Context, RemoveConstraintComment) error
+	UpsertConstraintComment(context.Context, UpsertConstraintComment) error
 	RemoveDatabaseRoleSettings(context.Context, RemoveDatabaseRoleSettings) error
 	DeleteSchedule(context.Context, DeleteSchedule) error
 }
@@ -337,31 +343,61 @@ func (op RemoveTableComment) Visit(ctx context.Context, v MutationVisitor) error
 	return v.RemoveTableComment(ctx, op)
 }
 
+// Visit is part of the MutationOp interface.
+func (op UpsertTableComment) Visit(ctx context.Context, v MutationVisitor) error {
+	return v.UpsertTableComment(ctx, op)
+}
+
 // Visit is part of the MutationOp interface.
 func (op RemoveDatabaseComment) Visit(ctx context.Context, v MutationVisitor) error {
 	return v.RemoveDatabaseComment(ctx, op)
 }
 
+// Visit is part of the MutationOp interface.
+func (op UpsertDatabaseComment) Visit(ctx context.Context, v MutationVisitor) error {
+	return v.UpsertDatabaseComment(ctx, op)
+}
+
 // Visit is part of the MutationOp interface.
 func (op RemoveSchemaComment) Visit(ctx context.Context, v MutationVisitor) error {
 	return v.RemoveSchemaComment(ctx, op)
 }
 
+// Visit is part of the MutationOp interface.
+func (op UpsertSchemaComment) Visit(ctx context.Context, v MutationVisitor) error {
+	return v.UpsertSchemaComment(ctx, op)
+}
+
 // Visit is part of the MutationOp interface.
 func (op RemoveIndexComment) Visit(ctx context.Context, v MutationVisitor) error {
 	return v.RemoveIndexComment(ctx, op)
 }
 
+// Visit is part of the MutationOp interface.
+func (op UpsertIndexComment) Visit(ctx context.Context, v MutationVisitor) error {
+	return v.UpsertIndexComment(ctx, op)
+}
+
 // Visit is part of the MutationOp interface.
 func (op RemoveColumnComment) Visit(ctx context.Context, v MutationVisitor) error {
 	return v.RemoveColumnComment(ctx, op)
 }
 
+// Visit is part of the MutationOp interface.
+func (op UpsertColumnComment) Visit(ctx context.Context, v MutationVisitor) error {
+	return v.UpsertColumnComment(ctx, op)
+}
+
 // Visit is part of the MutationOp interface.
 func (op RemoveConstraintComment) Visit(ctx context.Context, v MutationVisitor) error {
 	return v.RemoveConstraintComment(ctx, op)
 }
 
+// Visit is part of the MutationOp interface.
+func (op UpsertConstraintComment) Visit(ctx context.Context, v MutationVisitor) error {
+	return v.UpsertConstraintComment(ctx, op)
+}
+
 // Visit is part of the MutationOp interface.
 func (op RemoveDatabaseRoleSettings) Visit(ctx context.Context, v MutationVisitor) error {
 	return v.RemoveDatabaseRoleSettings(ctx, op)
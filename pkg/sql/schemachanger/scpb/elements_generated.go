@@ -978,6 +978,37 @@ func FindSequence(b ElementStatusIterator) (current Status, target TargetStatus,
 	return current, target, element
 }
 
+func (e SequenceComment) element() {}
+
+// ForEachSequenceComment iterates over elements of type SequenceComment.
+func ForEachSequenceComment(
+	b ElementStatusIterator, fn func(current Status, target TargetStatus, e *SequenceComment),
+) {
+  if b == nil {
+    return
+  }
+	b.ForEachElementStatus(func(current Status, target TargetStatus, e Element) {
+		if elt, ok := e.(*SequenceComment); ok {
+			fn(current, target, elt)
+		}
+	})
+}
+
+// FindSequenceComment finds the first element of type SequenceComment.
+func FindSequenceComment(b ElementStatusIterator) (current Status, target TargetStatus, element *SequenceComment) {
+  if b == nil {
+    return current, target, element
+  }
+	b.ForEachElementStatus(func(c Status, t TargetStatus, e Element) {
+		if elt, ok := e.(*SequenceComment); ok {
+			element = elt
+			current = c
+			target = t
+		}
+	})
+	return current, target, element
+}
+
 func (e SequenceOwner) element() {}
 
 // ForEachSequenceOwner iterates over elements of type SequenceOwner.
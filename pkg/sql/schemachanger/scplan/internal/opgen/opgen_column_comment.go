@@ -13,6 +13,7 @@ package opgen
 import (
 	"github.com/cockroachdb/cockroach/pkg/sql/schemachanger/scop"
 	"github.com/cockroachdb/cockroach/pkg/sql/schemachanger/scpb"
+	"github.com/cockroachdb/errors"
 )
 
 func init() {
@@ -20,8 +21,14 @@ func init() {
 		toPublic(
 			scpb.Status_ABSENT,
 			to(scpb.Status_PUBLIC,
-				emit(func(this *scpb.ColumnComment) scop.Op {
-					return notImplemented(this)
+				emit(func(this *scpb.ColumnComment, md *targetsWithElementMap) scop.Op {
+					checkColumnCommentPgAttributeNum(this, md)
+					return &scop.UpsertColumnComment{
+						TableID:        this.TableID,
+						ColumnID:       this.ColumnID,
+						PgAttributeNum: this.PgAttributeNum,
+						Comment:        this.Comment,
+					}
 				}),
 			),
 		),
@@ -31,7 +38,8 @@ func init() {
 				minPhase(scop.PreCommitPhase),
 				// TODO(postamar): remove revertibility constraint when possible
 				revertible(false),
-				emit(func(this *scpb.ColumnComment) scop.Op {
+				emit(func(this *scpb.ColumnComment, md *targetsWithElementMap) scop.Op {
+					checkColumnCommentPgAttributeNum(this, md)
 					return &scop.RemoveColumnComment{
 						TableID:        this.TableID,
 						ColumnID:       this.ColumnID,
@@ -42,3 +50,29 @@ func init() {
 		),
 	)
 }
+
+// checkColumnCommentPgAttributeNum panics if the column of the comment is
+// not among the targets, or has a different attribute number than the
+// comment's. Column comments are keyed by attribute number in system.comments,
+// so a mismatch would otherwise silently affect the comment of another column.
+// The column is always a target alongside its comment, as comments are only
+// added with their column and removed when their column or table is dropped.
+func checkColumnCommentPgAttributeNum(this *scpb.ColumnComment, md *targetsWithElementMap) {
+	for i := range md.Targets {
+		col, ok := md.Targets[i].Element().(*scpb.Column)
+		if !ok || col.TableID != this.TableID || col.ColumnID != this.ColumnID {
+			continue
+		}
+		if col.PgAttributeNum != this.PgAttributeNum {
+			panic(errors.AssertionFailedf(
+				"comment on column %d of table %d has attribute number %d, expected %d",
+				this.ColumnID, this.TableID, this.PgAttributeNum, col.PgAttributeNum,
+			))
+		}
+		return
+	}
+	panic(errors.AssertionFailedf(
+		"comment on column %d of table %d has no column among the targets",
+		this.ColumnID, this.TableID,
+	))
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package opgen
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/schemachanger/scop"
+	"github.com/cockroachdb/cockroach/pkg/sql/schemachanger/scpb"
+	"github.com/stretchr/testify/require"
+)
+
+// opsForTarget returns the ops emitted along the op edges that take the
+// element from its initial status to the target status, in order. The other
+// elements are added as targets with the same statuses.
+func opsForTarget(
+	t *testing.T,
+	e scpb.Element,
+	initial scpb.Status,
+	target scpb.TargetStatus,
+	others ...scpb.Element,
+) []scop.Op {
+	cs := scpb.CurrentState{
+		TargetState: scpb.TargetState{
+			Targets:    []scpb.Target{scpb.MakeTarget(target, e, nil /* metadata */)},
+			Statements: []scpb.Statement{{Statement: "ALTER TABLE t DROP COLUMN c"}},
+		},
+		Current: []scpb.Status{initial},
+	}
+	for _, other := range others {
+		cs.Targets = append(cs.Targets, scpb.MakeTarget(target, other, nil /* metadata */))
+		cs.Current = append(cs.Current, initial)
+	}
+	g, err := BuildGraph(cs)
+	require.NoError(t, err)
+	var ops []scop.Op
+	n, ok := g.GetNode(&cs.Targets[0], initial)
+	require.True(t, ok)
+	for n.CurrentStatus != target.Status() {
+		oe, ok := g.GetOpEdgeFrom(n)
+		require.Truef(t, ok, "no op edge from %s", n.CurrentStatus)
+		ops = append(ops, oe.Op()...)
+		n = oe.To()
+	}
+	return ops
+}
+
+func TestCommentOps(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		element scpb.Element
+		others  []scpb.Element
+		addOps  []scop.Op
+		dropOps []scop.Op
+	}{
+		{
+			name:    "table",
+			element: &scpb.TableComment{TableID: 104, Comment: "hello"},
+			addOps:  []scop.Op{&scop.UpsertTableComment{TableID: 104, Comment: "hello"}},
+			dropOps: []scop.Op{&scop.RemoveTableComment{TableID: 104}},
+		},
+		{
+			name:    "database",
+			element: &scpb.DatabaseComment{DatabaseID: 100, Comment: "hello"},
+			addOps:  []scop.Op{&scop.UpsertDatabaseComment{DatabaseID: 100, Comment: "hello"}},
+			dropOps: []scop.Op{&scop.RemoveDatabaseComment{DatabaseID: 100}},
+		},
+		{
+			name:    "schema",
+			element: &scpb.SchemaComment{SchemaID: 101, Comment: "hello"},
+			addOps:  []scop.Op{&scop.UpsertSchemaComment{SchemaID: 101, Comment: "hello"}},
+			dropOps: []scop.Op{&scop.RemoveSchemaComment{SchemaID: 101}},
+		},
+		{
+			name:    "sequence",
+			element: &scpb.SequenceComment{TableID: 105, Comment: "hello"},
+			addOps:  []scop.Op{&scop.UpsertTableComment{TableID: 105, Comment: "hello"}},
+			dropOps: []scop.Op{&scop.RemoveTableComment{TableID: 105}},
+		},
+		{
+			name:    "index",
+			element: &scpb.IndexComment{TableID: 104, IndexID: 2, Comment: "hello"},
+			addOps: []scop.Op{&scop.UpsertIndexComment{
+				TableID: 104, IndexID: 2, Comment: "hello",
+			}},
+			dropOps: []scop.Op{&scop.RemoveIndexComment{TableID: 104, IndexID: 2}},
+		},
+		{
+			name: "column",
+			element: &scpb.ColumnComment{
+				TableID: 104, ColumnID: 2, PgAttributeNum: 3, Comment: "hello",
+			},
+			others: []scpb.Element{&scpb.Column{TableID: 104, ColumnID: 2, PgAttributeNum: 3}},
+			addOps: []scop.Op{&scop.UpsertColumnComment{
+				TableID: 104, ColumnID: 2, PgAttributeNum: 3, Comment: "hello",
+			}},
+			dropOps: []scop.Op{&scop.RemoveColumnComment{
+				TableID: 104, ColumnID: 2, PgAttributeNum: 3,
+			}},
+		},
+		{
+			name:    "constraint",
+			element: &scpb.ConstraintComment{TableID: 104, ConstraintID: 2, Comment: "hello"},
+			addOps: []scop.Op{&scop.UpsertConstraintComment{
+				TableID: 104, ConstraintID: 2, Comment: "hello",
+			}},
+			dropOps: []scop.Op{&scop.RemoveConstraintComment{TableID: 104, ConstraintID: 2}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.addOps,
+				opsForTarget(t, tc.element, scpb.Status_ABSENT, scpb.ToPublic, tc.others...))
+			require.Equal(t, tc.dropOps,
+				opsForTarget(t, tc.element, scpb.Status_PUBLIC, scpb.ToAbsent, tc.others...))
+		})
+	}
+}
+
+func TestColumnCommentPgAttributeNumMismatch(t *testing.T) {
+	column := &scpb.Column{TableID: 104, ColumnID: 2, PgAttributeNum: 2}
+	comment := &scpb.ColumnComment{TableID: 104, ColumnID: 2, Comment: "hello"}
+	buildGraph := func(target scpb.TargetStatus, elements ...scpb.Element) error {
+		initial := scpb.Status_ABSENT
+		if target == scpb.ToAbsent {
+			initial = scpb.Status_PUBLIC
+		}
+		cs := scpb.CurrentState{
+			TargetState: scpb.TargetState{
+				Statements: []scpb.Statement{{Statement: "ALTER TABLE t DROP COLUMN c"}},
+			},
+		}
+		for _, e := range elements {
+			cs.Targets = append(cs.Targets, scpb.MakeTarget(target, e, nil /* metadata */))
+			cs.Current = append(cs.Current, initial)
+		}
+		_, err := BuildGraph(cs)
+		return err
+	}
+
+	for _, target := range []scpb.TargetStatus{scpb.ToPublic, scpb.ToAbsent} {
+		comment.PgAttributeNum = 2
+		require.NoError(t, buildGraph(target, column, comment))
+
+		// The column of the comment must be among the targets.
+		require.PanicsWithError(t,
+			"comment on column 2 of table 104 has no column among the targets",
+			func() { _ = buildGraph(target, comment) },
+		)
+
+		comment.PgAttributeNum = 3
+		require.PanicsWithError(t,
+			"comment on column 2 of table 104 has attribute number 3, expected 2",
+			func() { _ = buildGraph(target, column, comment) },
+		)
+	}
+}
@@ -21,7 +21,11 @@ func init() {
 			scpb.Status_ABSENT,
 			to(scpb.Status_PUBLIC,
 				emit(func(this *scpb.ConstraintComment) scop.Op {
-					return notImplemented(this)
+					return &scop.UpsertConstraintComment{
+						TableID:      this.TableID,
+						ConstraintID: this.ConstraintID,
+						Comment:      this.Comment,
+					}
 				}),
 			),
 		),
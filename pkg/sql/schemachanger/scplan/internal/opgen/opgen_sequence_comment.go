@@ -0,0 +1,47 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package opgen
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/schemachanger/scop"
+	"github.com/cockroachdb/cockroach/pkg/sql/schemachanger/scpb"
+)
+
+// Sequences are table descriptors, so their comments are upserted and removed
+// like those of tables.
+func init() {
+	opRegistry.register((*scpb.SequenceComment)(nil),
+		toPublic(
+			scpb.Status_ABSENT,
+			to(scpb.Status_PUBLIC,
+				emit(func(this *scpb.SequenceComment) scop.Op {
+					return &scop.UpsertTableComment{
+						TableID: this.TableID,
+						Comment: this.Comment,
+					}
+				}),
+			),
+		),
+		toAbsent(
+			scpb.Status_PUBLIC,
+			to(scpb.Status_ABSENT,
+				minPhase(scop.PreCommitPhase),
+				// Like table comment removal, this is not revertible.
+				revertible(false),
+				emit(func(this *scpb.SequenceComment) scop.Op {
+					return &scop.RemoveTableComment{
+						TableID: this.TableID,
+					}
+				}),
+			),
+		),
+	)
+}
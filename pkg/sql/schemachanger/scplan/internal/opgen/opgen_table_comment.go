@@ -21,7 +21,10 @@ func init() {
 			scpb.Status_ABSENT,
 			to(scpb.Status_PUBLIC,
 				emit(func(this *scpb.TableComment) scop.Op {
-					return notImplemented(this)
+					return &scop.UpsertTableComment{
+						TableID: this.TableID,
+						Comment: this.Comment,
+					}
 				}),
 			),
 		),
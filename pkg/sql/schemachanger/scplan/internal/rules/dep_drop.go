@@ -239,6 +239,29 @@ func init() {
 
 }
 
+// This rule ensures that a constraint's comment is removed no later than the
+// constraint itself when both are dropped, for instance when a constraint with
+// a comment is dropped without dropping its table.
+func init() {
+	depRule(
+		"constraint comment removed before constraint",
+		scgraph.Precedence,
+		scpb.ToAbsent,
+		element(scpb.Status_ABSENT,
+			(*scpb.ConstraintComment)(nil),
+		),
+		element(scpb.Status_ABSENT,
+			(*scpb.PrimaryIndex)(nil),
+			(*scpb.SecondaryIndex)(nil),
+			(*scpb.UniqueWithoutIndexConstraint)(nil),
+			(*scpb.CheckConstraint)(nil),
+			(*scpb.ForeignKeyConstraint)(nil),
+		),
+		screl.DescID,
+		screl.ConstraintID,
+	).register()
+}
+
 // These rules ensure that:
 // - when a descriptor element reaches the DROPPED state in the pre-commit phase
 //   its dependent elements (namespace entry, comments, column names, etc) have
@@ -291,6 +314,8 @@ func init() {
 			(*scpb.SchemaComment)(nil),
 			// Object elements.
 			(*scpb.ObjectParent)(nil),
+			// Sequence elements.
+			(*scpb.SequenceComment)(nil),
 		),
 		element(scpb.Status_DROPPED,
 			(*scpb.Database)(nil),
@@ -52,6 +52,38 @@ func init() {
 	)
 }
 
+// This rule ensures that when a column is renamed, its old name is removed in
+// the same stage as, and right before, its new name is set. Removing the old
+// name sets a placeholder name, which would otherwise overwrite the new name.
+// The column's comment is keyed by its attribute number rather than its name,
+// so it remains PUBLIC across the rename and needs no rule.
+func init() {
+	newName, newNameTarget, newNameNode := targetNodeVars("new-column-name")
+	oldName, oldNameTarget, oldNameNode := targetNodeVars("old-column-name")
+	var tableID rel.Var = "table-id"
+	var columnID rel.Var = "column-id"
+
+	registerDepRule(
+		"column rename",
+		scgraph.SameStagePrecedence,
+		oldNameNode, newNameNode,
+		screl.MustQuery(
+			newName.Type((*scpb.ColumnName)(nil)),
+			oldName.Type((*scpb.ColumnName)(nil)),
+			tableID.Entities(screl.DescID, newName, oldName),
+			columnID.Entities(screl.ColumnID, newName, oldName),
+
+			screl.JoinTargetNode(newName, newNameTarget, newNameNode),
+			newNameTarget.AttrEq(screl.TargetStatus, scpb.Status_PUBLIC),
+			newNameNode.AttrEq(screl.CurrentStatus, scpb.Status_PUBLIC),
+
+			screl.JoinTargetNode(oldName, oldNameTarget, oldNameNode),
+			oldNameTarget.AttrEq(screl.TargetStatus, scpb.Status_ABSENT),
+			oldNameNode.AttrEq(screl.CurrentStatus, scpb.Status_ABSENT),
+		),
+	)
+}
+
 // These rules ensure that index-dependent elements, like an index's name, its
 // partitioning, etc. appear once the index reaches a suitable state.
 // Vice-versa for index removal.
@@ -308,6 +340,9 @@ func init() {
 		screl.ColumnID,
 	).register()
 
+	// Column comments are keyed by the column's attribute number, which like
+	// its ID identifies the column within its table. This rule prevents the
+	// removal of a comment from being scheduled after its column is gone.
 	depRule(
 		"column comment removed before column",
 		scgraph.Precedence,
@@ -0,0 +1,97 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/schemachanger/scpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/schemachanger/scplan/internal/opgen"
+	"github.com/cockroachdb/cockroach/pkg/sql/schemachanger/scplan/internal/scgraph"
+	"github.com/cockroachdb/cockroach/pkg/sql/schemachanger/screl"
+	"github.com/stretchr/testify/require"
+)
+
+// depEdges returns the dependency edges of the graph built for dropping the
+// specified elements, formatted as strings and sorted.
+func depEdges(t *testing.T, elements ...scpb.Element) []string {
+	cs := scpb.CurrentState{
+		TargetState: scpb.TargetState{
+			Statements: []scpb.Statement{{Statement: "ALTER TABLE t DROP COLUMN c"}},
+		},
+	}
+	for _, e := range elements {
+		cs.Targets = append(cs.Targets, scpb.MakeTarget(scpb.ToAbsent, e, nil /* metadata */))
+		cs.Current = append(cs.Current, scpb.Status_PUBLIC)
+	}
+	g, err := opgen.BuildGraph(cs)
+	require.NoError(t, err)
+	require.NoError(t, ApplyDepRules(g))
+
+	var edges []string
+	require.NoError(t, g.ForEachNode(func(n *screl.Node) error {
+		return g.ForEachDepEdgeFrom(n, func(de *scgraph.DepEdge) error {
+			edges = append(edges, fmt.Sprintf("%s: %s %s -> %s %s",
+				de.Name(),
+				screl.ElementString(de.From().Element()), de.From().CurrentStatus,
+				screl.ElementString(de.To().Element()), de.To().CurrentStatus,
+			))
+			return nil
+		})
+	}))
+	sort.Strings(edges)
+	return edges
+}
+
+// TestColumnCommentRemovalOrdering checks that a column's comment is removed
+// after the column stops being public but before the column itself is
+// removed.
+func TestColumnCommentRemovalOrdering(t *testing.T) {
+	column := &scpb.Column{TableID: 104, ColumnID: 2, PgAttributeNum: 2}
+	comment := &scpb.ColumnComment{
+		TableID: 104, ColumnID: 2, PgAttributeNum: 2, Comment: "hello",
+	}
+	require.Equal(t, []string{
+		"column comment removed before column: " +
+			"ColumnComment:{DescID: 104, PgAttributeNum: 2, Comment: hello} ABSENT -> " +
+			"Column:{DescID: 104, ColumnID: 2, PgAttributeNum: 2} ABSENT",
+		"column comments removed after column no longer public: " +
+			"Column:{DescID: 104, ColumnID: 2, PgAttributeNum: 2} WRITE_ONLY -> " +
+			"ColumnComment:{DescID: 104, PgAttributeNum: 2, Comment: hello} ABSENT",
+	}, depEdges(t, column, comment))
+
+	// Comments on other columns, or on columns of other tables, are only
+	// ordered with respect to their own column.
+	otherColumn := &scpb.Column{TableID: 104, ColumnID: 3, PgAttributeNum: 3}
+	otherColumnComment := &scpb.ColumnComment{
+		TableID: 104, ColumnID: 3, PgAttributeNum: 3, Comment: "hello",
+	}
+	otherTable := &scpb.Column{TableID: 105, ColumnID: 2, PgAttributeNum: 2}
+	otherTableComment := &scpb.ColumnComment{
+		TableID: 105, ColumnID: 2, PgAttributeNum: 2, Comment: "hello",
+	}
+	require.Equal(t, []string{
+		"column comment removed before column: " +
+			"ColumnComment:{DescID: 104, PgAttributeNum: 3, Comment: hello} ABSENT -> " +
+			"Column:{DescID: 104, ColumnID: 3, PgAttributeNum: 3} ABSENT",
+		"column comment removed before column: " +
+			"ColumnComment:{DescID: 105, PgAttributeNum: 2, Comment: hello} ABSENT -> " +
+			"Column:{DescID: 105, ColumnID: 2, PgAttributeNum: 2} ABSENT",
+		"column comments removed after column no longer public: " +
+			"Column:{DescID: 104, ColumnID: 3, PgAttributeNum: 3} WRITE_ONLY -> " +
+			"ColumnComment:{DescID: 104, PgAttributeNum: 3, Comment: hello} ABSENT",
+		"column comments removed after column no longer public: " +
+			"Column:{DescID: 105, ColumnID: 2, PgAttributeNum: 2} WRITE_ONLY -> " +
+			"ColumnComment:{DescID: 105, PgAttributeNum: 2, Comment: hello} ABSENT",
+	}, depEdges(t, column, otherColumn, otherColumnComment, otherTable, otherTableComment))
+}
@@ -274,6 +274,7 @@ func init() {
 				(*scpb.IndexComment)(nil),
 				(*scpb.ConstraintComment)(nil),
 				(*scpb.TableComment)(nil),
+				(*scpb.SequenceComment)(nil),
 			),
 
 			descID.Entities(screl.DescID, desc, dep),
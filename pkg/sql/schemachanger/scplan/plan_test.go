@@ -261,3 +261,53 @@ func marshalOps(t *testing.T, ts scpb.TargetState, stages []scstage.Stage) strin
 	}
 	return sb.String()
 }
+
+// TestPlanColumnRename checks that renaming a commented column sets the new
+// name after the old one is removed, and leaves the column's comment alone.
+func TestPlanColumnRename(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	// The column itself is unaffected by the rename, so it has no target. The
+	// new name's target precedes the old name's, so that the order of the
+	// name ops is determined by the dependency rules rather than the targets.
+	elements := []struct {
+		e       scpb.Element
+		target  scpb.TargetStatus
+		current scpb.Status
+	}{
+		{&scpb.ColumnName{TableID: 104, ColumnID: 2, Name: "b"}, scpb.ToPublic, scpb.Status_ABSENT},
+		{&scpb.ColumnName{TableID: 104, ColumnID: 2, Name: "a"}, scpb.ToAbsent, scpb.Status_PUBLIC},
+		{&scpb.ColumnComment{
+			TableID: 104, ColumnID: 2, PgAttributeNum: 2, Comment: "hello",
+		}, scpb.ToPublic, scpb.Status_PUBLIC},
+	}
+	cs := scpb.CurrentState{
+		TargetState: scpb.TargetState{
+			Statements: []scpb.Statement{{Statement: "ALTER TABLE t RENAME COLUMN a TO b"}},
+		},
+	}
+	for _, el := range elements {
+		cs.Targets = append(cs.Targets, scpb.MakeTarget(el.target, el.e, nil /* metadata */))
+		cs.Current = append(cs.Current, el.current)
+	}
+	plan := sctestutils.MakePlan(t, cs, scop.EarliestPhase)
+
+	var names []string
+	for _, stage := range plan.Stages {
+		for _, op := range stage.EdgeOps {
+			switch op := op.(type) {
+			case *scop.SetColumnName:
+				names = append(names, op.Name)
+			case *scop.UpsertColumnComment, *scop.RemoveColumnComment:
+				t.Fatalf("unexpected comment op %T in stage %s", op, stage)
+			}
+		}
+	}
+	require.Equal(t, []string{"crdb_internal_column_2_name_placeholder", "b"}, names)
+
+	final := plan.Stages[len(plan.Stages)-1].After
+	require.Equal(t, []scpb.Status{
+		scpb.Status_PUBLIC, scpb.Status_ABSENT, scpb.Status_PUBLIC,
+	}, final)
+}
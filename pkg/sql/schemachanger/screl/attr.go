@@ -251,6 +251,10 @@ var elementSchemaOptions = []rel.SchemaOption{
 		rel.EntityAttr(DescID, "TableID"),
 		rel.EntityAttr(Comment, "Comment"),
 	),
+	rel.EntityMapping(t((*scpb.SequenceComment)(nil)),
+		rel.EntityAttr(DescID, "TableID"),
+		rel.EntityAttr(Comment, "Comment"),
+	),
 	rel.EntityMapping(t((*scpb.DatabaseComment)(nil)),
 		rel.EntityAttr(DescID, "DatabaseID"),
 		rel.EntityAttr(Comment, "Comment"),
@@ -0,0 +1,72 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package builtins
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streampb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/streaming"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicationBuiltinsWithFakeManager(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	fake := &streaming.FakeManager{
+		StreamID: 42,
+		Status: streampb.StreamReplicationStatus{
+			StreamStatus: streampb.StreamReplicationStatus_STREAM_PAUSED,
+		},
+	}
+	defer streaming.TestingSetFakeManager(fake)()
+
+	evalCtx := &tree.EvalContext{}
+	call := func(name string, args ...tree.Datum) (tree.Datum, error) {
+		return builtins[name].overloads[0].Fn(evalCtx, args)
+	}
+
+	res, err := call("crdb_internal.start_replication_stream", tree.NewDInt(10))
+	require.NoError(t, err)
+	require.Equal(t, tree.NewDInt(42), res)
+
+	frontier := hlc.Timestamp{WallTime: 100, Logical: 1}
+	res, err = call("crdb_internal.replication_stream_progress",
+		tree.NewDInt(42), tree.NewDString(frontier.String()))
+	require.NoError(t, err)
+	var status streampb.StreamReplicationStatus
+	require.NoError(t, protoutil.Unmarshal([]byte(tree.MustBeDBytes(res)), &status))
+	require.Equal(t, fake.Status, status)
+
+	cutover := time.Unix(0, 2000).UTC()
+	cutoverDatum, err := tree.MakeDTimestampTZ(cutover, time.Microsecond)
+	require.NoError(t, err)
+	_, err = call("crdb_internal.complete_stream_ingestion_job", tree.NewDInt(7), cutoverDatum)
+	require.NoError(t, err)
+
+	require.Equal(t, []streaming.FakeManagerCall{
+		{Method: "StartReplicationStream", TenantID: 10},
+		{Method: "UpdateReplicationStreamProgress", StreamID: 42, Frontier: frontier},
+		{Method: "CompleteStreamIngestion", StreamID: 7,
+			CutoverTimestamp: hlc.Timestamp{WallTime: cutover.UnixNano()}},
+	}, fake.Calls())
+
+	// Errors of the manager are returned by the builtins.
+	fake.Err = errors.New("boom")
+	_, err = call("crdb_internal.complete_replication_stream", tree.NewDInt(42))
+	require.EqualError(t, err, "boom")
+}
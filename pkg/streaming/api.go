@@ -11,8 +11,11 @@
 package streaming
 
 import (
+	"time"
+
 	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streampb"
 	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/errors"
@@ -27,6 +30,28 @@ func (j StreamID) SafeValue() {}
 // InvalidStreamID is the zero value for StreamID corresponding to no stream.
 const InvalidStreamID StreamID = 0
 
+// PartitionStats are the statistics of a partition of a replication stream on
+// the producer side.
+type PartitionStats struct {
+	// EmittedEvents is the number of events emitted to the consumer.
+	EmittedEvents int64
+	// EmittedBytes is the total size of the events emitted to the consumer.
+	EmittedBytes int64
+}
+
+// PartitionProgress is the progress of a span of a partition of a replication
+// stream on the producer side.
+type PartitionProgress struct {
+	// Span is the span, which is part of the partition streamed by NodeID.
+	Span roachpb.Span
+	// NodeID identifies the partition the span currently belongs to, i.e. the
+	// node which should stream it.
+	NodeID roachpb.NodeID
+	// ResolvedTimestamp is the timestamp up to which all the changes to the span
+	// have been emitted to the consumer. It is empty if it is not known.
+	ResolvedTimestamp hlc.Timestamp
+}
+
 // GetReplicationStreamManagerHook is the hook to get access to the producer side replication APIs.
 // Used by builtin functions to trigger streaming replication.
 var GetReplicationStreamManagerHook func(evalCtx *tree.EvalContext) (ReplicationStreamManager, error)
@@ -45,13 +70,41 @@ type ReplicationStreamManager interface {
 		tenantID uint64,
 	) (StreamID, error)
 
+	// StartReplicationStreamForSpans is like StartReplicationStream, but the stream only replicates
+	// the specified spans, which must be within the keyspace of the tenant.
+	StartReplicationStreamForSpans(
+		evalCtx *tree.EvalContext,
+		txn *kv.Txn,
+		tenantID uint64,
+		spans []roachpb.Span,
+	) (StreamID, error)
+
 	// UpdateReplicationStreamProgress updates the progress of a replication stream on the producer side.
+	// It extends the liveness of the stream and advances its protected timestamp to the frontier.
 	UpdateReplicationStreamProgress(
 		evalCtx *tree.EvalContext,
 		streamID StreamID,
 		frontier hlc.Timestamp,
 		txn *kv.Txn) (streampb.StreamReplicationStatus, error)
 
+	// HeartbeatReplicationStream extends the liveness of a replication stream on the producer side
+	// without advancing its protected timestamp, which allows a consumer whose frontier has not
+	// advanced to keep the stream alive. It returns the status of the stream so that the consumer
+	// learns whether the producer considers the stream inactive.
+	HeartbeatReplicationStream(
+		evalCtx *tree.EvalContext,
+		streamID StreamID,
+		frontier hlc.Timestamp,
+		txn *kv.Txn) (streampb.StreamReplicationStatus, error)
+
+	// GetReplicationStreamStatus returns the status of a replication stream on the producer side:
+	// whether it is active, its protected timestamp and the error it failed with, if any. Unlike
+	// UpdateReplicationStreamProgress and HeartbeatReplicationStream, it has no side effects.
+	GetReplicationStreamStatus(
+		evalCtx *tree.EvalContext,
+		streamID StreamID,
+	) (streampb.StreamReplicationStatus, error)
+
 	// StreamPartition starts streaming replication on the producer side for the partition specified
 	// by opaqueSpec which contains serialized streampb.StreamPartitionSpec protocol message and
 	// returns a value generator which yields events for the specified partition.
@@ -61,6 +114,16 @@ type ReplicationStreamManager interface {
 		opaqueSpec []byte,
 	) (tree.ValueGenerator, error)
 
+	// GetPartitionStats returns the statistics of the partition of a replication stream
+	// streamed by the specified partition, which is identified by the NodeID of its
+	// streampb.ReplicationStreamSpec_Partition. The statistics are only tracked while the
+	// partition is being streamed, and only on the node streaming it.
+	GetPartitionStats(
+		evalCtx *tree.EvalContext,
+		streamID StreamID,
+		partitionID roachpb.NodeID,
+	) (PartitionStats, error)
+
 	// GetReplicationStreamSpec gets a stream replication spec on the producer side.
 	GetReplicationStreamSpec(
 		evalCtx *tree.EvalContext,
@@ -68,22 +131,66 @@ type ReplicationStreamManager interface {
 		streamID StreamID,
 	) (*streampb.ReplicationStreamSpec, error)
 
+	// ListReplicationStreamPartitions lists the spans of the partitions of a replication stream
+	// on the producer side along with their resolved timestamps. Unlike the spec returned by
+	// GetReplicationStreamSpec when the stream started, the partitions reflect the current
+	// ranges of the replicated spans. Resolved timestamps are known for the spans streamed by
+	// any node, as of the last checkpoints the nodes persisted with the stream.
+	ListReplicationStreamPartitions(
+		evalCtx *tree.EvalContext,
+		txn *kv.Txn,
+		streamID StreamID,
+	) ([]PartitionProgress, error)
+
 	// CompleteReplicationStream completes a replication stream job on the producer side.
 	CompleteReplicationStream(
 		evalCtx *tree.EvalContext, txn *kv.Txn, streamID StreamID,
 	) error
+
+	// StopReplicationStream stops a replication stream on the producer side while leaving it
+	// resumable, unlike CompleteReplicationStream. The stream stops emitting events, after
+	// draining the events in flight to the consumer if drain is set, and its protected timestamp
+	// is kept for a grace period. Consumers observe the stream as paused through its status.
+	StopReplicationStream(
+		evalCtx *tree.EvalContext, txn *kv.Txn, streamID StreamID, drain bool,
+	) error
+
+	// ResumeReplicationStream resumes a replication stream stopped by StopReplicationStream
+	// on the producer side, so that its partitions can be streamed again. Consumers observe
+	// the stream as active through its status.
+	ResumeReplicationStream(
+		evalCtx *tree.EvalContext, txn *kv.Txn, streamID StreamID,
+	) error
+
+	// SetReplicationStreamLagLimit sets the maximum duration the frontier reported by the consumer
+	// of a replication stream may lag behind the present time on the producer side. Beyond it,
+	// the stream applies backpressure instead of buffering events, and its status reports that it
+	// is lag-limited. A lag limit of zero disables it.
+	SetReplicationStreamLagLimit(
+		evalCtx *tree.EvalContext, txn *kv.Txn, streamID StreamID, lagLimit time.Duration,
+	) error
 }
 
 // StreamIngestManager represents a collection of APIs that streaming replication supports
 // on the ingestion side.
 type StreamIngestManager interface {
 	// CompleteStreamIngestion signals a running stream ingestion job to complete on the consumer side.
+	// If the job cannot be signaled to complete, the returned error contains a
+	// CompleteStreamIngestionError describing why; see GetCompleteStreamIngestionErrorReason.
 	CompleteStreamIngestion(
 		evalCtx *tree.EvalContext,
 		txn *kv.Txn,
 		streamID StreamID,
 		cutoverTimestamp hlc.Timestamp,
 	) error
+
+	// GetStreamIngestionStats returns the statistics of a stream ingestion job on the consumer
+	// side: the amount of ingested data, its resolved timestamp and the progress of its cutover.
+	GetStreamIngestionStats(
+		evalCtx *tree.EvalContext,
+		txn *kv.Txn,
+		streamID StreamID,
+	) (*streampb.StreamIngestionStats, error)
 }
 
 // GetReplicationStreamManager returns a ReplicationStreamManager if a CCL binary is loaded.
@@ -96,7 +203,7 @@ func GetReplicationStreamManager(evalCtx *tree.EvalContext) (ReplicationStreamMa
 
 // GetStreamIngestManager returns a StreamIngestManager if a CCL binary is loaded.
 func GetStreamIngestManager(evalCtx *tree.EvalContext) (StreamIngestManager, error) {
-	if GetReplicationStreamManagerHook == nil {
+	if GetStreamIngestManagerHook == nil {
 		return nil, errors.New("replication streaming requires a CCL binary")
 	}
 	return GetStreamIngestManagerHook(evalCtx)
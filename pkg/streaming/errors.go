@@ -0,0 +1,158 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package streaming
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/errorspb"
+	"github.com/gogo/protobuf/proto"
+)
+
+// CompleteStreamIngestionErrorReason is the reason why signaling a stream
+// ingestion to complete failed.
+type CompleteStreamIngestionErrorReason int
+
+const (
+	// StreamIngestionNotFound indicates that there is no stream ingestion job
+	// for the stream. This may be transient, e.g. during a failover.
+	StreamIngestionNotFound CompleteStreamIngestionErrorReason = iota + 1
+	// StreamIngestionAlreadyCompleted indicates that the stream ingestion has
+	// already been signaled to complete.
+	StreamIngestionAlreadyCompleted
+	// CutoverTimestampNotResolved indicates that the cutover timestamp is after
+	// the resolved timestamp of the stream ingestion, which is a user error.
+	CutoverTimestampNotResolved
+)
+
+// String implements the fmt.Stringer interface.
+func (r CompleteStreamIngestionErrorReason) String() string {
+	switch r {
+	case StreamIngestionNotFound:
+		return "stream ingestion not found"
+	case StreamIngestionAlreadyCompleted:
+		return "stream ingestion already completed"
+	case CutoverTimestampNotResolved:
+		return "cutover timestamp not resolved"
+	default:
+		return fmt.Sprintf("unknown reason %d", int(r))
+	}
+}
+
+// SafeValue implements the redact.SafeValue interface.
+func (r CompleteStreamIngestionErrorReason) SafeValue() {}
+
+// CompleteStreamIngestionError is returned by
+// StreamIngestManager.CompleteStreamIngestion when signaling the stream
+// ingestion to complete fails for one of the reasons above.
+type CompleteStreamIngestionError struct {
+	StreamID StreamID
+	Reason   CompleteStreamIngestionErrorReason
+	cause    error
+}
+
+// NewCompleteStreamIngestionError returns a CompleteStreamIngestionError for
+// the specified stream and reason with the formatted message, which is
+// redactable like that of errors.Newf.
+func NewCompleteStreamIngestionError(
+	streamID StreamID, reason CompleteStreamIngestionErrorReason, format string, args ...interface{},
+) error {
+	return &CompleteStreamIngestionError{
+		StreamID: streamID,
+		Reason:   reason,
+		cause:    errors.NewWithDepthf(1, format, args...),
+	}
+}
+
+// Error makes CompleteStreamIngestionError an error.
+func (e *CompleteStreamIngestionError) Error() string {
+	return e.cause.Error()
+}
+
+// Cause exposes the underlying error.
+func (e *CompleteStreamIngestionError) Cause() error { return e.cause }
+
+// Unwrap exposes the underlying error.
+func (e *CompleteStreamIngestionError) Unwrap() error { return e.cause }
+
+// Format formats the error.
+func (e *CompleteStreamIngestionError) Format(s fmt.State, verb rune) { errors.FormatError(e, s, verb) }
+
+// SafeFormatError formats the error safely.
+func (e *CompleteStreamIngestionError) SafeFormatError(p errors.Printer) error {
+	if p.Detail() {
+		p.Printf("stream %d: %s", int64(e.StreamID), e.Reason)
+	}
+	return e.cause
+}
+
+// encodeCompleteStreamIngestionError serializes a
+// CompleteStreamIngestionError. The stream ID and the reason are not
+// sensitive, so they are also reported as safe details.
+func encodeCompleteStreamIngestionError(
+	_ context.Context, err error,
+) (msgPrefix string, safe []string, details proto.Message) {
+	e := err.(*CompleteStreamIngestionError)
+	fields := []string{
+		strconv.FormatInt(int64(e.StreamID), 10),
+		strconv.Itoa(int(e.Reason)),
+	}
+	return "", fields, &errorspb.StringsPayload{Details: fields}
+}
+
+func decodeCompleteStreamIngestionError(
+	_ context.Context, cause error, _ string, _ []string, payload proto.Message,
+) error {
+	m, ok := payload.(*errorspb.StringsPayload)
+	if !ok || len(m.Details) < 2 {
+		// If this ever happens, this means some version of the library
+		// (presumably future) changed the payload type, and we're
+		// receiving this here. In this case, give up and let
+		// DecodeError use the opaque type.
+		return nil
+	}
+	streamID, err := strconv.ParseInt(m.Details[0], 10, 64)
+	if err != nil {
+		// Not encoded by our encode function. Bail out.
+		return nil //nolint:returnerrcheck
+	}
+	reason, err := strconv.Atoi(m.Details[1])
+	if err != nil {
+		// Not encoded by our encode function. Bail out.
+		return nil //nolint:returnerrcheck
+	}
+	return &CompleteStreamIngestionError{
+		StreamID: StreamID(streamID),
+		Reason:   CompleteStreamIngestionErrorReason(reason),
+		cause:    cause,
+	}
+}
+
+func init() {
+	key := errors.GetTypeKey((*CompleteStreamIngestionError)(nil))
+	errors.RegisterWrapperEncoder(key, encodeCompleteStreamIngestionError)
+	errors.RegisterWrapperDecoder(key, decodeCompleteStreamIngestionError)
+}
+
+// GetCompleteStreamIngestionErrorReason returns the reason of the
+// CompleteStreamIngestionError contained in the error, if there is one.
+func GetCompleteStreamIngestionErrorReason(
+	err error,
+) (CompleteStreamIngestionErrorReason, bool) {
+	var completeErr *CompleteStreamIngestionError
+	if errors.As(err, &completeErr) {
+		return completeErr.Reason, true
+	}
+	return 0, false
+}
@@ -0,0 +1,62 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package streaming
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/redact"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompleteStreamIngestionError(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	err := NewCompleteStreamIngestionError(7, CutoverTimestampNotResolved, "job %d: too early", 7)
+	require.EqualError(t, err, "job 7: too early")
+
+	// The reason can be retrieved from wrapped errors.
+	wrapped := errors.Wrap(err, "completing stream ingestion")
+	reason, ok := GetCompleteStreamIngestionErrorReason(wrapped)
+	require.True(t, ok)
+	require.Equal(t, CutoverTimestampNotResolved, reason)
+
+	var completeErr *CompleteStreamIngestionError
+	require.True(t, errors.As(wrapped, &completeErr))
+	require.Equal(t, StreamID(7), completeErr.StreamID)
+
+	_, ok = GetCompleteStreamIngestionErrorReason(errors.New("some other error"))
+	require.False(t, ok)
+	require.Equal(t, "stream ingestion not found", StreamIngestionNotFound.String())
+
+	// The message is redactable, and the error carries a stack trace.
+	err = NewCompleteStreamIngestionError(7, StreamIngestionNotFound, "job %d: %s", 7, "secret")
+	require.EqualValues(t, "job 7: ‹×›", redact.Sprint(err).Redact())
+	require.Contains(t, fmt.Sprintf("%+v", err), "stream 7: stream ingestion not found")
+	require.Contains(t, fmt.Sprintf("%+v", err), "TestCompleteStreamIngestionError")
+
+	// The error survives being encoded and decoded, e.g. when it is sent to
+	// another node.
+	wrapped = errors.Wrap(err, "completing stream ingestion")
+	decoded := errors.DecodeError(context.Background(), errors.EncodeError(context.Background(), wrapped))
+	require.EqualError(t, decoded, wrapped.Error())
+	require.True(t, errors.As(decoded, &completeErr))
+	require.Equal(t, StreamID(7), completeErr.StreamID)
+	require.Equal(t, StreamIngestionNotFound, completeErr.Reason)
+	reason, ok = GetCompleteStreamIngestionErrorReason(decoded)
+	require.True(t, ok)
+	require.Equal(t, StreamIngestionNotFound, reason)
+	require.EqualValues(t, "completing stream ingestion: job 7: ‹×›", redact.Sprint(decoded).Redact())
+}
@@ -0,0 +1,232 @@
+// Copyright 2022 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package streaming
+
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/streamingccl/streampb"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// FakeManagerCall records a call made to a FakeManager. Only the fields
+// corresponding to the arguments of the called method are set.
+type FakeManagerCall struct {
+	// Method is the name of the called method.
+	Method           string
+	TenantID         uint64
+	Spans            []roachpb.Span
+	StreamID         StreamID
+	Frontier         hlc.Timestamp
+	CutoverTimestamp hlc.Timestamp
+	OpaqueSpec       []byte
+	PartitionID      roachpb.NodeID
+	Drain            bool
+	LagLimit         time.Duration
+}
+
+// FakeManager is a fake implementation of both ReplicationStreamManager and
+// StreamIngestManager for tests. It records the calls made to it and returns
+// the configured results.
+type FakeManager struct {
+	// Err, if set, is returned by all the methods of the manager.
+	Err error
+	// StreamID is returned by StartReplicationStream and
+	// StartReplicationStreamForSpans.
+	StreamID StreamID
+	// Status is returned by UpdateReplicationStreamProgress,
+	// HeartbeatReplicationStream and GetReplicationStreamStatus.
+	Status streampb.StreamReplicationStatus
+	// Spec is returned by GetReplicationStreamSpec.
+	Spec *streampb.ReplicationStreamSpec
+	// Partitions is returned by ListReplicationStreamPartitions.
+	Partitions []PartitionProgress
+	// Generator is returned by StreamPartition.
+	Generator tree.ValueGenerator
+	// PartitionStats is returned by GetPartitionStats.
+	PartitionStats PartitionStats
+	// IngestionStats is returned by GetStreamIngestionStats.
+	IngestionStats *streampb.StreamIngestionStats
+
+	mu struct {
+		syncutil.Mutex
+		calls []FakeManagerCall
+	}
+}
+
+var _ ReplicationStreamManager = (*FakeManager)(nil)
+var _ StreamIngestManager = (*FakeManager)(nil)
+
+// TestingSetFakeManager installs the specified fake as both the
+// ReplicationStreamManager and the StreamIngestManager, regardless of whether
+// a CCL binary is loaded. It returns a function that restores the previous
+// hooks.
+func TestingSetFakeManager(fake *FakeManager) func() {
+	oldReplicationHook, oldIngestHook := GetReplicationStreamManagerHook, GetStreamIngestManagerHook
+	GetReplicationStreamManagerHook = func(*tree.EvalContext) (ReplicationStreamManager, error) {
+		return fake, nil
+	}
+	GetStreamIngestManagerHook = func(*tree.EvalContext) (StreamIngestManager, error) {
+		return fake, nil
+	}
+	return func() {
+		GetReplicationStreamManagerHook, GetStreamIngestManagerHook = oldReplicationHook, oldIngestHook
+	}
+}
+
+// Calls returns the calls made to the manager so far, in order.
+func (m *FakeManager) Calls() []FakeManagerCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]FakeManagerCall(nil), m.mu.calls...)
+}
+
+func (m *FakeManager) record(call FakeManagerCall) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mu.calls = append(m.mu.calls, call)
+}
+
+// StartReplicationStream implements ReplicationStreamManager interface.
+func (m *FakeManager) StartReplicationStream(
+	_ *tree.EvalContext, _ *kv.Txn, tenantID uint64,
+) (StreamID, error) {
+	m.record(FakeManagerCall{Method: "StartReplicationStream", TenantID: tenantID})
+	return m.StreamID, m.Err
+}
+
+// StartReplicationStreamForSpans implements ReplicationStreamManager interface.
+func (m *FakeManager) StartReplicationStreamForSpans(
+	_ *tree.EvalContext, _ *kv.Txn, tenantID uint64, spans []roachpb.Span,
+) (StreamID, error) {
+	m.record(FakeManagerCall{
+		Method: "StartReplicationStreamForSpans", TenantID: tenantID, Spans: spans,
+	})
+	return m.StreamID, m.Err
+}
+
+// UpdateReplicationStreamProgress implements ReplicationStreamManager interface.
+func (m *FakeManager) UpdateReplicationStreamProgress(
+	_ *tree.EvalContext, streamID StreamID, frontier hlc.Timestamp, _ *kv.Txn,
+) (streampb.StreamReplicationStatus, error) {
+	m.record(FakeManagerCall{
+		Method: "UpdateReplicationStreamProgress", StreamID: streamID, Frontier: frontier,
+	})
+	return m.Status, m.Err
+}
+
+// HeartbeatReplicationStream implements ReplicationStreamManager interface.
+func (m *FakeManager) HeartbeatReplicationStream(
+	_ *tree.EvalContext, streamID StreamID, frontier hlc.Timestamp, _ *kv.Txn,
+) (streampb.StreamReplicationStatus, error) {
+	m.record(FakeManagerCall{
+		Method: "HeartbeatReplicationStream", StreamID: streamID, Frontier: frontier,
+	})
+	return m.Status, m.Err
+}
+
+// GetReplicationStreamStatus implements ReplicationStreamManager interface.
+func (m *FakeManager) GetReplicationStreamStatus(
+	_ *tree.EvalContext, streamID StreamID,
+) (streampb.StreamReplicationStatus, error) {
+	m.record(FakeManagerCall{Method: "GetReplicationStreamStatus", StreamID: streamID})
+	return m.Status, m.Err
+}
+
+// StreamPartition implements ReplicationStreamManager interface.
+func (m *FakeManager) StreamPartition(
+	_ *tree.EvalContext, streamID StreamID, opaqueSpec []byte,
+) (tree.ValueGenerator, error) {
+	m.record(FakeManagerCall{Method: "StreamPartition", StreamID: streamID, OpaqueSpec: opaqueSpec})
+	return m.Generator, m.Err
+}
+
+// GetPartitionStats implements ReplicationStreamManager interface.
+func (m *FakeManager) GetPartitionStats(
+	_ *tree.EvalContext, streamID StreamID, partitionID roachpb.NodeID,
+) (PartitionStats, error) {
+	m.record(FakeManagerCall{
+		Method: "GetPartitionStats", StreamID: streamID, PartitionID: partitionID,
+	})
+	return m.PartitionStats, m.Err
+}
+
+// GetReplicationStreamSpec implements ReplicationStreamManager interface.
+func (m *FakeManager) GetReplicationStreamSpec(
+	_ *tree.EvalContext, _ *kv.Txn, streamID StreamID,
+) (*streampb.ReplicationStreamSpec, error) {
+	m.record(FakeManagerCall{Method: "GetReplicationStreamSpec", StreamID: streamID})
+	return m.Spec, m.Err
+}
+
+// ListReplicationStreamPartitions implements ReplicationStreamManager interface.
+func (m *FakeManager) ListReplicationStreamPartitions(
+	_ *tree.EvalContext, _ *kv.Txn, streamID StreamID,
+) ([]PartitionProgress, error) {
+	m.record(FakeManagerCall{Method: "ListReplicationStreamPartitions", StreamID: streamID})
+	return m.Partitions, m.Err
+}
+
+// CompleteReplicationStream implements ReplicationStreamManager interface.
+func (m *FakeManager) CompleteReplicationStream(
+	_ *tree.EvalContext, _ *kv.Txn, streamID StreamID,
+) error {
+	m.record(FakeManagerCall{Method: "CompleteReplicationStream", StreamID: streamID})
+	return m.Err
+}
+
+// StopReplicationStream implements ReplicationStreamManager interface.
+func (m *FakeManager) StopReplicationStream(
+	_ *tree.EvalContext, _ *kv.Txn, streamID StreamID, drain bool,
+) error {
+	m.record(FakeManagerCall{Method: "StopReplicationStream", StreamID: streamID, Drain: drain})
+	return m.Err
+}
+
+// ResumeReplicationStream implements ReplicationStreamManager interface.
+func (m *FakeManager) ResumeReplicationStream(
+	_ *tree.EvalContext, _ *kv.Txn, streamID StreamID,
+) error {
+	m.record(FakeManagerCall{Method: "ResumeReplicationStream", StreamID: streamID})
+	return m.Err
+}
+
+// SetReplicationStreamLagLimit implements ReplicationStreamManager interface.
+func (m *FakeManager) SetReplicationStreamLagLimit(
+	_ *tree.EvalContext, _ *kv.Txn, streamID StreamID, lagLimit time.Duration,
+) error {
+	m.record(FakeManagerCall{
+		Method: "SetReplicationStreamLagLimit", StreamID: streamID, LagLimit: lagLimit,
+	})
+	return m.Err
+}
+
+// CompleteStreamIngestion implements StreamIngestManager interface.
+func (m *FakeManager) CompleteStreamIngestion(
+	_ *tree.EvalContext, _ *kv.Txn, streamID StreamID, cutoverTimestamp hlc.Timestamp,
+) error {
+	m.record(FakeManagerCall{
+		Method: "CompleteStreamIngestion", StreamID: streamID, CutoverTimestamp: cutoverTimestamp,
+	})
+	return m.Err
+}
+
+// GetStreamIngestionStats implements StreamIngestManager interface.
+func (m *FakeManager) GetStreamIngestionStats(
+	_ *tree.EvalContext, _ *kv.Txn, streamID StreamID,
+) (*streampb.StreamIngestionStats, error) {
+	m.record(FakeManagerCall{Method: "GetStreamIngestionStats", StreamID: streamID})
+	return m.IngestionStats, m.Err
+}